@@ -1,8 +1,12 @@
 package config
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"os"
+	"strings"
 )
 
 // DBConfig maps to the "db" section of local.json
@@ -15,9 +19,159 @@ type DBConfig struct {
   SSLMode  string `json:"sslmode"`
 }
 
+// MakeupConfig maps to the "makeup" section of local.json
+type MakeupConfig struct {
+  // CreditValidityDays is how long a make-up credit can be booked after the absence.
+  CreditValidityDays int `json:"credit_validity_days"`
+  // ValidReasons lists the absence reasons that earn a make-up credit.
+  ValidReasons []string `json:"valid_reasons"`
+}
+
+// TrialConfig maps to the "trial" section of local.json
+type TrialConfig struct {
+  // MaxSessions caps how many sessions a trial enrollment may attend.
+  MaxSessions int `json:"max_sessions"`
+  // ValidityDays is how long a trial stays open after it starts.
+  ValidityDays int `json:"validity_days"`
+}
+
+// PricingConfig maps to the "pricing" section of local.json
+type PricingConfig struct {
+  // TaxPct is the tax rate of plans without a tax code.
+  TaxPct float64 `json:"tax_pct"`
+  // QuoteTTLMinutes is how long a signed price quote can be honored.
+  QuoteTTLMinutes int `json:"quote_ttl_minutes"`
+  // QuoteSecret signs quote IDs. It is required, and every instance must
+  // share it so that a quote signed by one is honored by the others.
+  QuoteSecret string `json:"quote_secret"`
+}
+
+// FamilyConfig maps to the "family" section of local.json
+type FamilyConfig struct {
+  // SecondChildDiscountPct is taken off the second child's enrollments.
+  SecondChildDiscountPct float64 `json:"second_child_discount_pct"`
+  // FurtherChildDiscountPct is taken off the third and later children's.
+  // Set either to a negative value to turn that discount off.
+  FurtherChildDiscountPct float64 `json:"further_child_discount_pct"`
+}
+
+// SubscriptionConfig maps to the "subscription" section of local.json
+type SubscriptionConfig struct {
+  // GraceDays is how long a renewal charge may stay unpaid after it is due
+  // before it turns overdue and the enrollment is paused.
+  GraceDays int `json:"grace_days"`
+}
+
+// DuesConfig maps to the "dues" section of local.json
+type DuesConfig struct {
+  // GraceDays is how long a balance may stay unpaid after it is due before
+  // the enrollment is flagged overdue.
+  GraceDays int `json:"grace_days"`
+}
+
+// InstallmentConfig maps to the "installment" section of local.json
+type InstallmentConfig struct {
+  // SuspendAfterDays is how long an installment may stay unpaid after it
+  // is due before it turns overdue and the enrollment is paused.
+  SuspendAfterDays int `json:"suspend_after_days"`
+}
+
+// ImportConfig maps to the "import" section of local.json
+type ImportConfig struct {
+  // SyncMaxRows is the largest CSV imported while the request waits; larger
+  // files are imported in the background.
+  SyncMaxRows int `json:"sync_max_rows"`
+}
+
+// ReferralConfig maps to the "referral" section of local.json
+type ReferralConfig struct {
+  // RewardType is "wallet" for wallet credit or "renewal" for credit taken
+  // off the referrer's next renewal charges.
+  RewardType string `json:"reward_type"`
+  // RewardCents is credited to the referrer when a referral converts.
+  RewardCents int `json:"reward_cents"`
+  // PublicEmailDomains are shared by unrelated people, so a referrer and a
+  // referred student on one of them are not treated as the same person.
+  PublicEmailDomains []string `json:"public_email_domains"`
+}
+
+// InvoiceConfig maps to the "invoice" section of local.json
+type InvoiceConfig struct {
+  // BusinessName, Address and TaxID (e.g. GSTIN) head every invoice PDF.
+  BusinessName string `json:"business_name"`
+  Address      string `json:"address"`
+  TaxID        string `json:"tax_id"`
+  // AccentColor is the hex color of the PDF header band, e.g. "#0B5FFF".
+  AccentColor string `json:"accent_color"`
+  // Footer is printed at the bottom of every invoice PDF.
+  Footer string `json:"footer"`
+}
+
+// CurrencyConfig maps to the "currency" section of local.json
+type CurrencyConfig struct {
+  // Base is the ISO code of the business's own currency. Venues, plans and
+  // referral rewards default to it, and reports are converted to it unless
+  // another reporting currency is asked for.
+  Base string `json:"base"`
+}
+
+// GatewayConfig maps to the "gateway" section of local.json
+type GatewayConfig struct {
+  // Provider is "razorpay", or "fake" for an in-memory gateway that needs
+  // no account, for local development and tests. It has no default, so a
+  // deployment cannot end up on the fake gateway by leaving it out.
+  Provider string `json:"provider"`
+  // KeyID and KeySecret authenticate API calls to the gateway.
+  KeyID     string `json:"key_id"`
+  KeySecret string `json:"key_secret"`
+  // WebhookSecret signs the gateway's webhooks. If empty with the fake
+  // gateway a random one is generated.
+  WebhookSecret string `json:"webhook_secret"`
+  // BaseURL is the gateway's API root.
+  BaseURL string `json:"base_url"`
+}
+
+// IdempotencyConfig maps to the "idempotency" section of local.json
+type IdempotencyConfig struct {
+  // TTLHours is how long a response is kept for retries with the same
+  // Idempotency-Key.
+  TTLHours int `json:"ttl_hours"`
+}
+
+// MailConfig maps to the "mail" section of local.json
+type MailConfig struct {
+  // Provider is "smtp", or "file" to write each message to Dir as an .eml
+  // file instead of sending it, for local development and tests.
+  Provider string `json:"provider"`
+  Host     string `json:"host"`
+  Port     int    `json:"port"`
+  Username string `json:"username"`
+  Password string `json:"password"`
+  // From is the sender address of outgoing mail.
+  From string `json:"from"`
+  Dir  string `json:"dir"`
+  // MaxAttempts is how often delivery of a receipt is tried before it is
+  // marked failed.
+  MaxAttempts int `json:"max_attempts"`
+}
+
 // Config holds all app config sections
 type Config struct {
-  DB DBConfig `json:"db"`
+  DB           DBConfig           `json:"db"`
+  Makeup       MakeupConfig       `json:"makeup"`
+  Trial        TrialConfig        `json:"trial"`
+  Pricing      PricingConfig      `json:"pricing"`
+  Family       FamilyConfig       `json:"family"`
+  Subscription SubscriptionConfig `json:"subscription"`
+  Import       ImportConfig       `json:"import"`
+  Referral     ReferralConfig     `json:"referral"`
+  Invoice      InvoiceConfig      `json:"invoice"`
+  Dues         DuesConfig         `json:"dues"`
+  Gateway      GatewayConfig      `json:"gateway"`
+  Idempotency  IdempotencyConfig  `json:"idempotency"`
+  Mail         MailConfig         `json:"mail"`
+  Installment  InstallmentConfig  `json:"installment"`
+  Currency     CurrencyConfig     `json:"currency"`
 }
 
 // LoadConfig reads a JSON config file into a Config struct
@@ -33,5 +187,111 @@ func LoadConfig(path string) (*Config, error) {
   if err := decoder.Decode(&cfg); err != nil {
     return nil, err
   }
+  cfg.applyDefaults()
   return &cfg, nil
-}
\ No newline at end of file
+}
+
+// applyDefaults fills in sections that were left out of the config file.
+func (c *Config) applyDefaults() {
+  if c.Makeup.CreditValidityDays <= 0 {
+    c.Makeup.CreditValidityDays = 30
+  }
+  if len(c.Makeup.ValidReasons) == 0 {
+    c.Makeup.ValidReasons = []string{"medical", "travel", "exam", "family"}
+  }
+  if c.Trial.MaxSessions <= 0 {
+    c.Trial.MaxSessions = 2
+  }
+  if c.Trial.ValidityDays <= 0 {
+    c.Trial.ValidityDays = 14
+  }
+  if c.Pricing.QuoteTTLMinutes <= 0 {
+    c.Pricing.QuoteTTLMinutes = 30
+  }
+  if c.Family.SecondChildDiscountPct == 0 {
+    c.Family.SecondChildDiscountPct = 10
+  }
+  if c.Family.FurtherChildDiscountPct == 0 {
+    c.Family.FurtherChildDiscountPct = 15
+  }
+  if c.Subscription.GraceDays <= 0 {
+    c.Subscription.GraceDays = 7
+  }
+  if c.Import.SyncMaxRows <= 0 {
+    c.Import.SyncMaxRows = 200
+  }
+  if c.Dues.GraceDays <= 0 {
+    c.Dues.GraceDays = 15
+  }
+  if c.Installment.SuspendAfterDays <= 0 {
+    c.Installment.SuspendAfterDays = 15
+  }
+  if c.Currency.Base == "" {
+    c.Currency.Base = "INR"
+  }
+  c.Currency.Base = strings.ToUpper(c.Currency.Base)
+  if c.Invoice.BusinessName == "" {
+    c.Invoice.BusinessName = "Spodemy"
+  }
+  if c.Invoice.AccentColor == "" {
+    c.Invoice.AccentColor = "#0B5FFF"
+  }
+  if c.Referral.RewardType == "" {
+    c.Referral.RewardType = "wallet"
+  }
+  if c.Referral.RewardCents <= 0 {
+    c.Referral.RewardCents = 1000
+  }
+  if len(c.Referral.PublicEmailDomains) == 0 {
+    c.Referral.PublicEmailDomains = []string{"gmail.com", "yahoo.com", "outlook.com", "hotmail.com", "icloud.com"}
+  }
+  if c.Idempotency.TTLHours <= 0 {
+    c.Idempotency.TTLHours = 24
+  }
+  if c.Gateway.BaseURL == "" {
+    c.Gateway.BaseURL = "https://api.razorpay.com/v1"
+  }
+  if c.Gateway.WebhookSecret == "" && c.Gateway.Provider == "fake" {
+    c.Gateway.WebhookSecret = randomSecret()
+  }
+  if c.Mail.Provider == "" {
+    c.Mail.Provider = "file"
+  }
+  if c.Mail.Port <= 0 {
+    c.Mail.Port = 587
+  }
+  if c.Mail.From == "" {
+    c.Mail.From = "no-reply@spodemy.local"
+  }
+  if c.Mail.Dir == "" {
+    c.Mail.Dir = "mail"
+  }
+  if c.Mail.MaxAttempts <= 0 {
+    c.Mail.MaxAttempts = 5
+  }
+}
+
+// Validate checks settings the server cannot start without.
+func (c *Config) Validate() error {
+  if c.Pricing.QuoteSecret == "" {
+    return errors.New("pricing.quote_secret must be set")
+  }
+  if c.Gateway.Provider == "razorpay" {
+    if c.Gateway.KeyID == "" || c.Gateway.KeySecret == "" {
+      return errors.New("gateway.key_id and gateway.key_secret must be set for razorpay")
+    }
+    if c.Gateway.WebhookSecret == "" {
+      return errors.New("gateway.webhook_secret must be set for razorpay")
+    }
+  }
+  return nil
+}
+
+// randomSecret returns 32 random bytes, hex encoded.
+func randomSecret() string {
+  b := make([]byte, 32)
+  if _, err := rand.Read(b); err != nil {
+    panic(err)
+  }
+  return hex.EncodeToString(b)
+}
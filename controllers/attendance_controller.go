@@ -40,13 +40,13 @@ func (ctrl *AttendanceController) List(c *gin.Context) {
 // @Summary      List attendance by enrollment
 // @Tags         attendance
 // @Produce      json
-// @Param        enrollmentId path string true "Enrollment UUID"
+// @Param        id path string true "Enrollment UUID"
 // @Success      200 {array} models.Attendance
 // @Failure      400 {object} map[string]string
 // @Failure      500 {object} map[string]string
-// @Router       /enrollments/{enrollmentId}/attendance [get]
+// @Router       /enrollments/{id}/attendance [get]
 func (ctrl *AttendanceController) ListByEnrollment(c *gin.Context) {
-    eid, err := uuid.Parse(c.Param("enrollmentId"))
+    eid, err := uuid.Parse(c.Param("id"))
     if err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
         return
@@ -59,6 +59,29 @@ func (ctrl *AttendanceController) ListByEnrollment(c *gin.Context) {
     c.JSON(http.StatusOK, recs)
 }
 
+// Summary godoc
+// @Summary      Attendance summary of an enrollment, frozen days excluded
+// @Tags         attendance
+// @Produce      json
+// @Param        id path string true "Enrollment UUID"
+// @Success      200 {object} services.AttendanceSummary
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /enrollments/{id}/attendance/summary [get]
+func (ctrl *AttendanceController) Summary(c *gin.Context) {
+    eid, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    sum, err := ctrl.service.Summary(eid)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, sum)
+}
 
 // Get godoc
 // @Summary      Get an attendance record by ID
@@ -91,6 +114,8 @@ func (ctrl *AttendanceController) Get(c *gin.Context) {
 // @Param        attendance body models.Attendance true "Attendance object"
 // @Success      201 {object} models.Attendance
 // @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
 // @Failure      500 {object} map[string]string
 // @Router       /attendance [post]
 func (ctrl *AttendanceController) Create(c *gin.Context) {
@@ -100,7 +125,7 @@ func (ctrl *AttendanceController) Create(c *gin.Context) {
         return
     }
     if err := ctrl.service.Create(&a); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        respondError(c, err)
         return
     }
     c.JSON(http.StatusCreated, a)
@@ -115,6 +140,7 @@ func (ctrl *AttendanceController) Create(c *gin.Context) {
 // @Param        attendance body models.Attendance true "Updated attendance object"
 // @Success      200 {object} models.Attendance
 // @Failure      400 {object} map[string]string
+// @Failure      409 {object} map[string]string
 // @Failure      500 {object} map[string]string
 // @Router       /attendance/{id} [put]
 func (ctrl *AttendanceController) Update(c *gin.Context) {
@@ -130,7 +156,7 @@ func (ctrl *AttendanceController) Update(c *gin.Context) {
     }
     a.ID = id
     if err := ctrl.service.Update(&a); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        respondError(c, err)
         return
     }
     c.JSON(http.StatusOK, a)
@@ -155,4 +181,4 @@ func (ctrl *AttendanceController) Delete(c *gin.Context) {
         return
     }
     c.Status(http.StatusNoContent)
-}
\ No newline at end of file
+}
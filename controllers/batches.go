@@ -40,14 +40,14 @@ func (ctrl *BatchController) List(c *gin.Context) {
 // @Summary      List batches by venue
 // @Tags         batches
 // @Produce      json
-// @Param        venueId  path  string  true  "Venue ID (UUID)"
+// @Param        id  path  string  true  "Venue ID (UUID)"
 // @Success      200 {array} models.Batch
 // @Failure      400 {object} map[string]string
 // @Failure      500 {object} map[string]string
 // @Security     ApiKeyAuth
-// @Router       /venues/{venueId}/batches [get]
+// @Router       /venues/{id}/batches [get]
 func (ctrl *BatchController) ListByVenue(c *gin.Context) {
-    venueID, err := uuid.Parse(c.Param("venueId"))
+    venueID, err := uuid.Parse(c.Param("id"))
     if err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": "invalid venue UUID"})
         return
@@ -89,15 +89,15 @@ func (ctrl *BatchController) Get(c *gin.Context) {
 // @Tags         batches
 // @Accept       json
 // @Produce      json
-// @Param        venueId  path  string      true  "Venue ID (UUID)"
+// @Param        id  path  string      true  "Venue ID (UUID)"
 // @Param        batch    body  models.Batch  true  "Batch object"
 // @Success      201 {object} models.Batch
 // @Failure      400 {object} map[string]string
 // @Failure      500 {object} map[string]string
 // @Security     ApiKeyAuth
-// @Router       /venues/{venueId}/batches [post]
+// @Router       /venues/{id}/batches [post]
 func (ctrl *BatchController) Create(c *gin.Context) {
-    venueID, err := uuid.Parse(c.Param("venueId"))
+    venueID, err := uuid.Parse(c.Param("id"))
     if err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": "invalid venue UUID"})
         return
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"net/http"
+
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CheckoutController handles HTTP for online payments.
+type CheckoutController struct {
+    service *services.CheckoutService
+}
+
+// NewCheckoutController constructs a CheckoutController.
+func NewCheckoutController(s *services.CheckoutService) *CheckoutController {
+    return &CheckoutController{service: s}
+}
+
+// Start godoc
+// @Summary      Start an online payment
+// @Description  Opens a gateway order for the payment. The fee payment is recorded when the gateway's webhook confirms it.
+// @Tags         payments
+// @Accept       json
+// @Produce      json
+// @Param        checkout body services.CheckoutRequest true "Payment to take"
+// @Success      201 {object} services.Checkout
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /payments/checkout [post]
+func (ctrl *CheckoutController) Start(c *gin.Context) {
+    var req services.CheckoutRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    co, err := ctrl.service.Start(req)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusCreated, co)
+}
+
+// Get godoc
+// @Summary      Get an online payment order
+// @Description  An order still open is checked with the gateway first.
+// @Tags         payments
+// @Produce      json
+// @Param        id path string true "Payment order UUID"
+// @Success      200 {object} models.PaymentOrder
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /payments/checkout/{id} [get]
+func (ctrl *CheckoutController) Get(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    o, err := ctrl.service.Get(id)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, o)
+}
+
+// Webhook godoc
+// @Summary      Receive a payment gateway webhook
+// @Description  The body must be signed with the webhook secret in X-Razorpay-Signature. A captured payment is recorded once however often it is delivered; refund events settle pending refunds.
+// @Tags         payments
+// @Accept       json
+// @Produce      json
+// @Param        X-Razorpay-Signature header string true "Hex HMAC-SHA256 of the body"
+// @Success      200 {object} map[string]interface{}
+// @Failure      400 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Router       /payments/webhook [post]
+func (ctrl *CheckoutController) Webhook(c *gin.Context) {
+    body, err := c.GetRawData()
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    p, err := ctrl.service.Webhook(body, c.GetHeader("X-Razorpay-Signature"))
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    if p == nil {
+        c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"status": "recorded", "payment_id": p.ID})
+}
+
+// Simulate godoc
+// @Summary      Pay or decline an order on the fake gateway
+// @Description  Only registered when the fake gateway is configured. Sends the webhook the gateway would.
+// @Tags         payments
+// @Produce      json
+// @Param        id      path  string true  "Payment order UUID"
+// @Param        outcome query string false "paid (default) or failed"
+// @Success      200 {object} models.PaymentOrder
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /payments/checkout/{id}/simulate [post]
+func (ctrl *CheckoutController) Simulate(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    o, err := ctrl.service.Simulate(id, c.Query("outcome") == "failed")
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, o)
+}
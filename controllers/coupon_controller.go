@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"net/http"
+
+	"spodemy-backend/models"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CouponController handles HTTP requests for coupon codes.
+type CouponController struct {
+    service *services.CouponService
+}
+
+// NewCouponController constructs a CouponController.
+func NewCouponController(s *services.CouponService) *CouponController {
+    return &CouponController{service: s}
+}
+
+// List godoc
+// @Summary      List coupons
+// @Tags         coupons
+// @Produce      json
+// @Success      200 {array} models.Coupon
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /coupons [get]
+func (ctrl *CouponController) List(c *gin.Context) {
+    cs, err := ctrl.service.List()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, cs)
+}
+
+// Get godoc
+// @Summary      Get a coupon
+// @Tags         coupons
+// @Produce      json
+// @Param        id path string true "Coupon ID (UUID)"
+// @Success      200 {object} models.Coupon
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /coupons/{id} [get]
+func (ctrl *CouponController) Get(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    coupon, err := ctrl.service.Get(id)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, coupon)
+}
+
+// Create godoc
+// @Summary      Create a coupon
+// @Description  The code is stored upper-case and must be unique.
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Param        coupon body models.Coupon true "Coupon object"
+// @Success      201 {object} models.Coupon
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /coupons [post]
+func (ctrl *CouponController) Create(c *gin.Context) {
+    var coupon models.Coupon
+    if err := c.ShouldBindJSON(&coupon); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := ctrl.service.Create(&coupon); err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusCreated, coupon)
+}
+
+// Update godoc
+// @Summary      Update a coupon
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Param        id     path string        true "Coupon ID (UUID)"
+// @Param        coupon body models.Coupon true "Updated coupon object"
+// @Success      200 {object} models.Coupon
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /coupons/{id} [put]
+func (ctrl *CouponController) Update(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    var coupon models.Coupon
+    if err := c.ShouldBindJSON(&coupon); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    coupon.ID = id
+    if err := ctrl.service.Update(&coupon); err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, coupon)
+}
+
+// Delete godoc
+// @Summary      Delete a coupon
+// @Tags         coupons
+// @Param        id path string true "Coupon ID (UUID)"
+// @Success      204 {string} string ""
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /coupons/{id} [delete]
+func (ctrl *CouponController) Delete(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    if err := ctrl.service.Delete(id); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.Status(http.StatusNoContent)
+}
+
+// Redemptions godoc
+// @Summary      List a coupon's redemptions
+// @Tags         coupons
+// @Produce      json
+// @Param        id path string true "Coupon ID (UUID)"
+// @Success      200 {array} models.CouponRedemption
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /coupons/{id}/redemptions [get]
+func (ctrl *CouponController) Redemptions(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    rs, err := ctrl.service.Redemptions(id)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, rs)
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"net/http"
+
+	"spodemy-backend/models"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CurrencyController handles HTTP requests for exchange rates.
+type CurrencyController struct {
+    service *services.CurrencyService
+}
+
+// NewCurrencyController constructs a CurrencyController.
+func NewCurrencyController(s *services.CurrencyService) *CurrencyController {
+    return &CurrencyController{service: s}
+}
+
+// Rates godoc
+// @Summary      List exchange rates
+// @Description  Rates newest first. A rate applies from its effective date until the next rate of the pair.
+// @Tags         currencies
+// @Produce      json
+// @Param        base  query string false "ISO code of the currency priced"
+// @Param        quote query string false "ISO code of the currency it is priced in"
+// @Success      200 {array} models.ExchangeRate
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /exchange-rates [get]
+func (ctrl *CurrencyController) Rates(c *gin.Context) {
+    rates, err := ctrl.service.Rates(c.Query("base"), c.Query("quote"))
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, rates)
+}
+
+// CreateRate godoc
+// @Summary      Add an exchange rate
+// @Description  One unit of base is worth rate units of quote from effective_on, today if it is left out. Reports use the inverse for the opposite pair.
+// @Tags         currencies
+// @Accept       json
+// @Produce      json
+// @Param        rate body models.ExchangeRate true "Base, quote, rate and effective date"
+// @Success      201 {object} models.ExchangeRate
+// @Failure      400 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /exchange-rates [post]
+func (ctrl *CurrencyController) CreateRate(c *gin.Context) {
+    var rate models.ExchangeRate
+    if err := c.ShouldBindJSON(&rate); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := ctrl.service.CreateRate(&rate); err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusCreated, rate)
+}
+
+// DeleteRate godoc
+// @Summary      Delete an exchange rate
+// @Tags         currencies
+// @Param        id path string true "Exchange rate UUID"
+// @Success      204 {string} string ""
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /exchange-rates/{id} [delete]
+func (ctrl *CurrencyController) DeleteRate(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    if err := ctrl.service.DeleteRate(id); err != nil {
+        respondError(c, err)
+        return
+    }
+    c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DuesController reports outstanding balances.
+type DuesController struct {
+    service *services.DuesService
+}
+
+// NewDuesController constructs a DuesController.
+func NewDuesController(s *services.DuesService) *DuesController {
+    return &DuesController{service: s}
+}
+
+// List godoc
+// @Summary      List enrollments with an outstanding balance
+// @Description  Charged is the enrollment price after offers plus its renewal charges; paid is the sum of its payments less refunds.
+// @Tags         dues
+// @Produce      json
+// @Param        venue        query string false "Venue UUID"
+// @Param        batch        query string false "Batch UUID"
+// @Param        overdue_days query int    false "Only balances unpaid for at least this many days"
+// @Success      200 {array} services.Balance
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /dues [get]
+func (ctrl *DuesController) List(c *gin.Context) {
+    var q services.DuesQuery
+    if v := c.Query("venue"); v != "" {
+        id, err := uuid.Parse(v)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "invalid venue"})
+            return
+        }
+        q.VenueID = &id
+    }
+    if v := c.Query("batch"); v != "" {
+        id, err := uuid.Parse(v)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "invalid batch"})
+            return
+        }
+        q.BatchID = &id
+    }
+    if v := c.Query("overdue_days"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "invalid overdue_days"})
+            return
+        }
+        q.OverdueDays = &n
+    }
+    dues, err := ctrl.service.List(q)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, dues)
+}
+
+// Balance godoc
+// @Summary      Get what an enrollment has been charged and paid
+// @Tags         dues
+// @Produce      json
+// @Param        id path string true "Enrollment UUID"
+// @Success      200 {object} services.Balance
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /enrollments/{id}/balance [get]
+func (ctrl *DuesController) Balance(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    b, err := ctrl.service.Get(id)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, b)
+}
@@ -2,7 +2,9 @@ package controllers
 
 import (
 	"net/http"
+	"strconv"
 
+	"spodemy-backend/middlewares"
 	"spodemy-backend/models"
 	"spodemy-backend/services"
 
@@ -36,6 +38,53 @@ func (ctrl *EnrollmentController) List(c *gin.Context) {
     c.JSON(http.StatusOK, ens)
 }
 
+// ListByBatch godoc
+// @Summary      List enrollments by batch
+// @Tags         enrollments
+// @Produce      json
+// @Param        id path string true "Batch UUID"
+// @Success      200 {array} models.Enrollment
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /batches/{id}/enrollments [get]
+func (ctrl *EnrollmentController) ListByBatch(c *gin.Context) {
+    batchID, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    ens, err := ctrl.service.ListByBatch(batchID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, ens)
+}
+
+// ListExpiring godoc
+// @Summary      List enrollments expiring soon
+// @Description  Active enrollments whose plan validity ends within the given number of days, for renewal calls.
+// @Tags         enrollments
+// @Produce      json
+// @Param        days query int false "Look-ahead window in days (default 7)"
+// @Success      200 {array} models.Enrollment
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /enrollments/expiring [get]
+func (ctrl *EnrollmentController) ListExpiring(c *gin.Context) {
+    days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid days"})
+        return
+    }
+    ens, err := ctrl.service.ListExpiring(days)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, ens)
+}
+
 // Get godoc
 // @Summary      Get an enrollment by ID
 // @Tags         enrollments
@@ -75,8 +124,8 @@ func (ctrl *EnrollmentController) Create(c *gin.Context) {
         c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
-    if err := ctrl.service.Create(&e); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+    if err := ctrl.service.Create(&e, middlewares.CurrentUserID(c)); err != nil {
+        respondError(c, err)
         return
     }
     c.JSON(http.StatusCreated, e)
@@ -84,6 +133,7 @@ func (ctrl *EnrollmentController) Create(c *gin.Context) {
 
 // Update godoc
 // @Summary      Update an existing enrollment
+// @Description  The status, type and trial fields are not changed here; use the transition and trial conversion endpoints.
 // @Tags         enrollments
 // @Accept       json
 // @Produce      json
@@ -106,7 +156,7 @@ func (ctrl *EnrollmentController) Update(c *gin.Context) {
     }
     e.ID = id
     if err := ctrl.service.Update(&e); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        respondError(c, err)
         return
     }
     c.JSON(http.StatusOK, e)
@@ -132,3 +182,235 @@ func (ctrl *EnrollmentController) Delete(c *gin.Context) {
     }
     c.Status(http.StatusNoContent)
 }
+
+// ConvertTrial godoc
+// @Summary      Convert a trial into a paid enrollment
+// @Tags         enrollments
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Trial enrollment UUID"
+// @Param        conversion body services.ConvertTrialRequest true "Plan and optional batch/start date"
+// @Success      201 {object} models.Enrollment
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /enrollments/{id}/convert [post]
+func (ctrl *EnrollmentController) ConvertTrial(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    var req services.ConvertTrialRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    e, err := ctrl.service.ConvertTrial(id, req, middlewares.CurrentUserID(c))
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusCreated, e)
+}
+
+// TransitionRequest carries the reason for a status change.
+type TransitionRequest struct {
+    Reason string `json:"reason" binding:"required"`
+}
+
+// Activate godoc
+// @Summary      Activate an enrollment
+// @Tags         enrollments
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Enrollment UUID"
+// @Param        transition body TransitionRequest true "Reason for the change"
+// @Success      200 {object} models.Enrollment
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Router       /enrollments/{id}/activate [post]
+func (ctrl *EnrollmentController) Activate(c *gin.Context) {
+    ctrl.transition(c, models.EnrollmentActive)
+}
+
+// Pause godoc
+// @Summary      Pause an active enrollment
+// @Tags         enrollments
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Enrollment UUID"
+// @Param        transition body TransitionRequest true "Reason for the change"
+// @Success      200 {object} models.Enrollment
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Router       /enrollments/{id}/pause [post]
+func (ctrl *EnrollmentController) Pause(c *gin.Context) {
+    ctrl.transition(c, models.EnrollmentPaused)
+}
+
+// Resume godoc
+// @Summary      Resume a paused enrollment
+// @Tags         enrollments
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Enrollment UUID"
+// @Param        transition body TransitionRequest true "Reason for the change"
+// @Success      200 {object} models.Enrollment
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Router       /enrollments/{id}/resume [post]
+func (ctrl *EnrollmentController) Resume(c *gin.Context) {
+    ctrl.transition(c, models.EnrollmentActive)
+}
+
+// Complete godoc
+// @Summary      Complete an enrollment
+// @Tags         enrollments
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Enrollment UUID"
+// @Param        transition body TransitionRequest true "Reason for the change"
+// @Success      200 {object} models.Enrollment
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Router       /enrollments/{id}/complete [post]
+func (ctrl *EnrollmentController) Complete(c *gin.Context) {
+    ctrl.transition(c, models.EnrollmentCompleted)
+}
+
+// Drop godoc
+// @Summary      Drop an enrollment
+// @Tags         enrollments
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Enrollment UUID"
+// @Param        transition body TransitionRequest true "Reason for the change"
+// @Success      200 {object} models.Enrollment
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Router       /enrollments/{id}/drop [post]
+func (ctrl *EnrollmentController) Drop(c *gin.Context) {
+    ctrl.transition(c, models.EnrollmentDropped)
+}
+
+// Waitlist godoc
+// @Summary      Move an enrollment to the waitlist
+// @Tags         enrollments
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Enrollment UUID"
+// @Param        transition body TransitionRequest true "Reason for the change"
+// @Success      200 {object} models.Enrollment
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Router       /enrollments/{id}/waitlist [post]
+func (ctrl *EnrollmentController) Waitlist(c *gin.Context) {
+    ctrl.transition(c, models.EnrollmentWaitlisted)
+}
+
+// History godoc
+// @Summary      List the status history of an enrollment
+// @Tags         enrollments
+// @Produce      json
+// @Param        id path string true "Enrollment UUID"
+// @Success      200 {array} models.EnrollmentStatusHistory
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /enrollments/{id}/history [get]
+func (ctrl *EnrollmentController) History(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    hs, err := ctrl.service.History(id)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, hs)
+}
+
+// Freeze godoc
+// @Summary      Put an enrollment on hold and extend its validity
+// @Tags         enrollments
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Enrollment UUID"
+// @Param        freeze body services.FreezeRequest true "Freeze period, both days included"
+// @Success      200 {object} models.Enrollment
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Router       /enrollments/{id}/freeze [post]
+func (ctrl *EnrollmentController) Freeze(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    var req services.FreezeRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    e, err := ctrl.service.Freeze(id, req, middlewares.CurrentUserID(c))
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, e)
+}
+
+// Freezes godoc
+// @Summary      List the freezes of an enrollment
+// @Tags         enrollments
+// @Produce      json
+// @Param        id path string true "Enrollment UUID"
+// @Success      200 {array} models.EnrollmentFreeze
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /enrollments/{id}/freezes [get]
+func (ctrl *EnrollmentController) Freezes(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    fs, err := ctrl.service.Freezes(id)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, fs)
+}
+
+// transition moves the enrollment in the path to status to.
+func (ctrl *EnrollmentController) transition(c *gin.Context, to string) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    var req TransitionRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    e, err := ctrl.service.Transition(id, to, req.Reason, middlewares.CurrentUserID(c))
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, e)
+}
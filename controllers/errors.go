@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// respondError writes err with a status code derived from its kind.
+func respondError(c *gin.Context, err error) {
+    status := http.StatusInternalServerError
+    switch {
+    case errors.Is(err, gorm.ErrRecordNotFound):
+        status = http.StatusNotFound
+    case errors.Is(err, services.ErrInvalidInput):
+        status = http.StatusBadRequest
+    case errors.Is(err, services.ErrConflict):
+        status = http.StatusConflict
+    }
+    c.JSON(status, gin.H{"error": err.Error()})
+}
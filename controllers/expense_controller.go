@@ -79,7 +79,7 @@ func (ctrl *ExpenseController) Create(c *gin.Context) {
         return
     }
     if err := ctrl.service.Create(&e); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        respondError(c, err)
         return
     }
     c.JSON(http.StatusCreated, e)
@@ -110,7 +110,7 @@ func (ctrl *ExpenseController) Update(c *gin.Context) {
     }
     e.ID = id
     if err := ctrl.service.Update(&e); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        respondError(c, err)
         return
     }
     c.JSON(http.StatusOK, e)
@@ -122,6 +122,7 @@ func (ctrl *ExpenseController) Update(c *gin.Context) {
 // @Param        id path string true "Expense UUID"
 // @Success      204
 // @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
 // @Failure      500 {object} map[string]string
 // @Security     ApiKeyAuth
 // @Router       /expenses/{id} [delete]
@@ -132,7 +133,7 @@ func (ctrl *ExpenseController) Delete(c *gin.Context) {
         return
     }
     if err := ctrl.service.Delete(id); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        respondError(c, err)
         return
     }
     c.Status(http.StatusNoContent)
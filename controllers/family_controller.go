@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"spodemy-backend/models"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// FamilyController handles HTTP requests for families.
+type FamilyController struct {
+    service *services.FamilyService
+}
+
+// NewFamilyController constructs a FamilyController.
+func NewFamilyController(s *services.FamilyService) *FamilyController {
+    return &FamilyController{service: s}
+}
+
+// List godoc
+// @Summary      List families
+// @Tags         families
+// @Produce      json
+// @Success      200 {array} models.Family
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /families [get]
+func (ctrl *FamilyController) List(c *gin.Context) {
+    fs, err := ctrl.service.List()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, fs)
+}
+
+// Get godoc
+// @Summary      Get a family with its members
+// @Tags         families
+// @Produce      json
+// @Param        id path string true "Family ID (UUID)"
+// @Success      200 {object} models.Family
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /families/{id} [get]
+func (ctrl *FamilyController) Get(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    f, err := ctrl.service.Get(id)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, f)
+}
+
+// Create godoc
+// @Summary      Create a family
+// @Tags         families
+// @Accept       json
+// @Produce      json
+// @Param        family body models.Family true "Family object"
+// @Success      201 {object} models.Family
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /families [post]
+func (ctrl *FamilyController) Create(c *gin.Context) {
+    var f models.Family
+    if err := c.ShouldBindJSON(&f); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := ctrl.service.Create(&f); err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusCreated, f)
+}
+
+// Update godoc
+// @Summary      Rename a family
+// @Tags         families
+// @Accept       json
+// @Produce      json
+// @Param        id     path string        true "Family ID (UUID)"
+// @Param        family body models.Family true "Updated family object"
+// @Success      200 {object} models.Family
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /families/{id} [put]
+func (ctrl *FamilyController) Update(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    var f models.Family
+    if err := c.ShouldBindJSON(&f); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    f.ID = id
+    if err := ctrl.service.Update(&f); err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, f)
+}
+
+// Delete godoc
+// @Summary      Delete a family
+// @Tags         families
+// @Param        id path string true "Family ID (UUID)"
+// @Success      204 {string} string ""
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /families/{id} [delete]
+func (ctrl *FamilyController) Delete(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    if err := ctrl.service.Delete(id); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.Status(http.StatusNoContent)
+}
+
+// AddMember godoc
+// @Summary      Add a student or guardian to a family
+// @Tags         families
+// @Accept       json
+// @Produce      json
+// @Param        id     path string              true "Family ID (UUID)"
+// @Param        member body models.FamilyMember true "User and role"
+// @Success      200 {object} models.Family
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /families/{id}/members [post]
+func (ctrl *FamilyController) AddMember(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    var m models.FamilyMember
+    if err := c.ShouldBindJSON(&m); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    f, err := ctrl.service.AddMember(id, &m)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, f)
+}
+
+// RemoveMember godoc
+// @Summary      Remove a user from a family
+// @Tags         families
+// @Param        id     path string true "Family ID (UUID)"
+// @Param        userId path string true "User ID (UUID)"
+// @Success      204 {string} string ""
+// @Failure      400 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /families/{id}/members/{userId} [delete]
+func (ctrl *FamilyController) RemoveMember(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    userID, err := uuid.Parse(c.Param("userId"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user UUID"})
+        return
+    }
+    if err := ctrl.service.RemoveMember(id, userID); err != nil {
+        respondError(c, err)
+        return
+    }
+    c.Status(http.StatusNoContent)
+}
+
+// Statement godoc
+// @Summary      Consolidated monthly statement of a family
+// @Description  Totals are converted to the reporting currency at the exchange rates of the month's last day.
+// @Tags         families
+// @Produce      json
+// @Param        id       path  string true  "Family ID (UUID)"
+// @Param        month    query string false "Month (YYYY-MM), defaults to the current month"
+// @Param        currency query string false "Reporting currency, defaults to the base currency"
+// @Success      200 {object} services.FamilyStatement
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /families/{id}/statement [get]
+func (ctrl *FamilyController) Statement(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    month := time.Now()
+    if v := c.Query("month"); v != "" {
+        if month, err = time.Parse("2006-01", v); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "invalid month, expected YYYY-MM"})
+            return
+        }
+    }
+    st, err := ctrl.service.Statement(id, month, c.Query("currency"))
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, st)
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"spodemy-backend/middlewares"
+	"spodemy-backend/models"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ImportController handles bulk CSV imports.
+type ImportController struct {
+    service *services.ImportService
+}
+
+// NewImportController constructs an ImportController.
+func NewImportController(s *services.ImportService) *ImportController {
+    return &ImportController{service: s}
+}
+
+// Enrollments godoc
+// @Summary      Import students and enrollments from CSV
+// @Description  Columns: name, email, phone, dob, batch, plan, start_date, amount_paid; name, email and batch are required. Every row is validated and nothing is saved unless all rows are valid. Large files are imported in the background: the response is 202 and the job can be polled.
+// @Tags         imports
+// @Accept       text/csv
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file     formData file   false "CSV file, if sent as a form"
+// @Param        dry_run  query    bool   false "Validate and preview without saving"
+// @Param        venue_id query    string false "Resolve batch names within this venue"
+// @Success      200 {object} models.ImportJob
+// @Success      202 {object} models.ImportJob
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /imports/enrollments [post]
+func (ctrl *ImportController) Enrollments(c *gin.Context) {
+    var opts services.ImportOptions
+    if v := c.Query("dry_run"); v != "" {
+        dry, err := strconv.ParseBool(v)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dry_run"})
+            return
+        }
+        opts.DryRun = dry
+    }
+    if v := c.Query("venue_id"); v != "" {
+        id, err := uuid.Parse(v)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "invalid venue_id"})
+            return
+        }
+        opts.VenueID = &id
+    }
+
+    var body io.Reader = c.Request.Body
+    if c.ContentType() == "multipart/form-data" {
+        fh, err := c.FormFile("file")
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+            return
+        }
+        f, err := fh.Open()
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        defer f.Close()
+        body = f
+    }
+
+    job, err := ctrl.service.ImportEnrollments(body, opts, middlewares.CurrentUserID(c))
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    status := http.StatusOK
+    if job.Status == models.ImportQueued {
+        status = http.StatusAccepted
+    }
+    c.JSON(status, job)
+}
+
+// List godoc
+// @Summary      List import jobs
+// @Tags         imports
+// @Produce      json
+// @Success      200 {array} models.ImportJob
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /imports [get]
+func (ctrl *ImportController) List(c *gin.Context) {
+    jobs, err := ctrl.service.List()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, jobs)
+}
+
+// Get godoc
+// @Summary      Get an import job, to poll its progress and results
+// @Tags         imports
+// @Produce      json
+// @Param        id path string true "Import job UUID"
+// @Success      200 {object} models.ImportJob
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /imports/{id} [get]
+func (ctrl *ImportController) Get(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    job, err := ctrl.service.Get(id)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, job)
+}
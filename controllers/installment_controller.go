@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"net/http"
+
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// InstallmentController handles HTTP requests for installments.
+type InstallmentController struct {
+    service *services.InstallmentService
+}
+
+// NewInstallmentController constructs an InstallmentController.
+func NewInstallmentController(s *services.InstallmentService) *InstallmentController {
+    return &InstallmentController{service: s}
+}
+
+// List godoc
+// @Summary      List installments
+// @Description  Optionally filtered by status; overdue installments have paused their enrollment.
+// @Tags         installments
+// @Produce      json
+// @Param        status query string false "due, paid or overdue"
+// @Success      200 {array} models.Installment
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /installments [get]
+func (ctrl *InstallmentController) List(c *gin.Context) {
+    is, err := ctrl.service.List(c.Query("status"))
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, is)
+}
+
+// ListByEnrollment godoc
+// @Summary      List the installments of an enrollment
+// @Tags         installments
+// @Produce      json
+// @Param        id path string true "Enrollment UUID"
+// @Success      200 {array} models.Installment
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /enrollments/{id}/installments [get]
+func (ctrl *InstallmentController) ListByEnrollment(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    is, err := ctrl.service.ListByEnrollment(id)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, is)
+}
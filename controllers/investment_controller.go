@@ -192,3 +192,42 @@ func (ctrl *InvestmentController) CreateTransaction(c *gin.Context) {
     }
     c.JSON(http.StatusCreated, txn)
 }
+
+// ReverseTransaction godoc
+// @Summary      Reverse a transaction of an investment
+// @Description  Transactions cannot be edited or deleted. One recorded by mistake is cancelled by a reversal of the negated units, which is returned.
+// @Tags         investments
+// @Accept       json
+// @Produce      json
+// @Param        id       path string         true "Investment UUID"
+// @Param        txnId    path string         true "Transaction UUID"
+// @Param        reversal body ReverseRequest true "Why the transaction is reversed"
+// @Success      201 {object} models.InvestmentTransaction
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /investments/{id}/transactions/{txnId}/reverse [post]
+func (ctrl *InvestmentController) ReverseTransaction(c *gin.Context) {
+    invID, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    txnID, err := uuid.Parse(c.Param("txnId"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transaction UUID"})
+        return
+    }
+    var req ReverseRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    rev, err := ctrl.service.ReverseTransaction(invID, txnID, req.Reason)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusCreated, rev)
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"spodemy-backend/repositories"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// InvoiceController handles HTTP requests for invoices.
+type InvoiceController struct {
+    service *services.InvoiceService
+}
+
+// NewInvoiceController constructs an InvoiceController.
+func NewInvoiceController(s *services.InvoiceService) *InvoiceController {
+    return &InvoiceController{service: s}
+}
+
+// List godoc
+// @Summary      List invoices
+// @Tags         invoices
+// @Produce      json
+// @Param        enrollment_id query string false "Enrollment UUID"
+// @Param        student_id    query string false "Student UUID"
+// @Param        venue_id      query string false "Venue UUID"
+// @Param        status        query string false "open, partially_paid or paid"
+// @Success      200 {array} models.Invoice
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /invoices [get]
+func (ctrl *InvoiceController) List(c *gin.Context) {
+    f := repositories.InvoiceFilter{Status: c.Query("status")}
+    for _, q := range []struct {
+        name string
+        dst  **uuid.UUID
+    }{{"enrollment_id", &f.EnrollmentID}, {"student_id", &f.StudentID}, {"venue_id", &f.VenueID}} {
+        if v := c.Query(q.name); v != "" {
+            id, err := uuid.Parse(v)
+            if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + q.name})
+                return
+            }
+            *q.dst = &id
+        }
+    }
+    invs, err := ctrl.service.List(f)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, invs)
+}
+
+// ListByEnrollment godoc
+// @Summary      List the invoices of an enrollment
+// @Tags         invoices
+// @Produce      json
+// @Param        id path string true "Enrollment UUID"
+// @Success      200 {array} models.Invoice
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /enrollments/{id}/invoices [get]
+func (ctrl *InvoiceController) ListByEnrollment(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    invs, err := ctrl.service.List(repositories.InvoiceFilter{EnrollmentID: &id})
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, invs)
+}
+
+// Get godoc
+// @Summary      Get an invoice, or its PDF when the ID ends in ".pdf"
+// @Tags         invoices
+// @Produce      json
+// @Produce      application/pdf
+// @Param        id path string true "Invoice UUID, optionally followed by .pdf"
+// @Success      200 {object} models.Invoice
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /invoices/{id} [get]
+func (ctrl *InvoiceController) Get(c *gin.Context) {
+    param, pdf := strings.CutSuffix(c.Param("id"), ".pdf")
+    id, err := uuid.Parse(param)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    if !pdf {
+        inv, err := ctrl.service.Get(id)
+        if err != nil {
+            respondError(c, err)
+            return
+        }
+        c.JSON(http.StatusOK, inv)
+        return
+    }
+    inv, body, err := ctrl.service.PDF(id)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.Header("Content-Disposition", `inline; filename="`+inv.Number+`.pdf"`)
+    c.Data(http.StatusOK, "application/pdf", body)
+}
@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// LedgerController handles HTTP for the general ledger.
+type LedgerController struct {
+    service *services.LedgerService
+}
+
+// NewLedgerController constructs a LedgerController.
+func NewLedgerController(s *services.LedgerService) *LedgerController {
+    return &LedgerController{service: s}
+}
+
+// Accounts godoc
+// @Summary      List the chart of accounts
+// @Tags         ledger
+// @Produce      json
+// @Success      200 {array} models.Account
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /ledger/accounts [get]
+func (ctrl *LedgerController) Accounts(c *gin.Context) {
+    accts, err := ctrl.service.Accounts()
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, accts)
+}
+
+// CreateAccount godoc
+// @Summary      Add an account to the chart of accounts
+// @Tags         ledger
+// @Accept       json
+// @Produce      json
+// @Param        account body models.Account true "Code, name and type"
+// @Success      201 {object} models.Account
+// @Failure      400 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /ledger/accounts [post]
+func (ctrl *LedgerController) CreateAccount(c *gin.Context) {
+    var a models.Account
+    if err := c.ShouldBindJSON(&a); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := ctrl.service.CreateAccount(&a); err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusCreated, a)
+}
+
+// Statement godoc
+// @Summary      Get the postings to an account with a running balance
+// @Description  Covers one currency, the base currency unless another is given.
+// @Tags         ledger
+// @Produce      json
+// @Param        id       path  string true  "Account UUID"
+// @Param        venue_id query string false "Venue UUID"
+// @Param        currency query string false "ISO currency code"
+// @Param        from     query string false "Start date (YYYY-MM-DD)"
+// @Param        to       query string false "End date, exclusive (YYYY-MM-DD)"
+// @Success      200 {object} services.AccountStatement
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /ledger/accounts/{id}/statement [get]
+func (ctrl *LedgerController) Statement(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    f, ok := ledgerFilter(c)
+    if !ok {
+        return
+    }
+    st, err := ctrl.service.Statement(id, f)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, st)
+}
+
+// Entries godoc
+// @Summary      List journal entries
+// @Tags         ledger
+// @Produce      json
+// @Param        venue_id    query string false "Venue UUID"
+// @Param        currency    query string false "ISO currency code"
+// @Param        from        query string false "Start date (YYYY-MM-DD)"
+// @Param        to          query string false "End date, exclusive (YYYY-MM-DD)"
+// @Param        source_type query string false "manual, fee_payment, refund, expense or investment_transaction"
+// @Success      200 {array} models.JournalEntry
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /ledger/journal-entries [get]
+func (ctrl *LedgerController) Entries(c *gin.Context) {
+    f, ok := ledgerFilter(c)
+    if !ok {
+        return
+    }
+    f.SourceType = c.Query("source_type")
+    es, err := ctrl.service.Entries(f)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, es)
+}
+
+// Entry godoc
+// @Summary      Get a journal entry
+// @Tags         ledger
+// @Produce      json
+// @Param        id path string true "Journal entry UUID"
+// @Success      200 {object} models.JournalEntry
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /ledger/journal-entries/{id} [get]
+func (ctrl *LedgerController) Entry(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    e, err := ctrl.service.Entry(id)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, e)
+}
+
+// CreateEntry godoc
+// @Summary      Post a manual journal entry
+// @Description  Each line debits or credits one account; total debits must equal total credits.
+// @Tags         ledger
+// @Accept       json
+// @Produce      json
+// @Param        entry body models.JournalEntry true "Entry with its lines"
+// @Success      201 {object} models.JournalEntry
+// @Failure      400 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /ledger/journal-entries [post]
+func (ctrl *LedgerController) CreateEntry(c *gin.Context) {
+    var e models.JournalEntry
+    if err := c.ShouldBindJSON(&e); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := ctrl.service.CreateEntry(&e); err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusCreated, e)
+}
+
+// TrialBalance godoc
+// @Summary      Get the trial balance
+// @Description  Debit and credit totals by currency and account for the postings in the period, or all postings without one.
+// @Tags         ledger
+// @Produce      json
+// @Param        venue_id query string false "Venue UUID"
+// @Param        currency query string false "ISO currency code"
+// @Param        from     query string false "Start date (YYYY-MM-DD)"
+// @Param        to       query string false "End date, exclusive (YYYY-MM-DD)"
+// @Success      200 {object} services.TrialBalance
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /ledger/trial-balance [get]
+func (ctrl *LedgerController) TrialBalance(c *gin.Context) {
+    f, ok := ledgerFilter(c)
+    if !ok {
+        return
+    }
+    tb, err := ctrl.service.TrialBalance(f)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, tb)
+}
+
+// ledgerFilter reads the optional venue_id, from and to query parameters,
+// writing a 400 and returning false if one is malformed.
+func ledgerFilter(c *gin.Context) (repositories.LedgerFilter, bool) {
+    var f repositories.LedgerFilter
+    if v := c.Query("venue_id"); v != "" {
+        id, err := uuid.Parse(v)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "invalid venue_id"})
+            return f, false
+        }
+        f.VenueID = &id
+    }
+    f.Currency = strings.ToUpper(c.Query("currency"))
+    for _, q := range []struct {
+        name string
+        dst  **time.Time
+    }{{"from", &f.From}, {"to", &f.To}} {
+        if v := c.Query(q.name); v != "" {
+            t, err := time.Parse("2006-01-02", v)
+            if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + q.name + " date"})
+                return f, false
+            }
+            *q.dst = &t
+        }
+    }
+    return f, true
+}
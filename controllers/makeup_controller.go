@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// MakeupCreditController handles HTTP requests for make-up credits.
+type MakeupCreditController struct {
+    service *services.MakeupCreditService
+}
+
+// NewMakeupCreditController constructs a MakeupCreditController.
+func NewMakeupCreditController(s *services.MakeupCreditService) *MakeupCreditController {
+    return &MakeupCreditController{service: s}
+}
+
+// BookMakeupRequest selects the session a make-up credit is booked into.
+type BookMakeupRequest struct {
+    BatchID uuid.UUID `json:"batch_id" binding:"required"`
+    Date    time.Time `json:"date" binding:"required"`
+}
+
+// ListByEnrollment godoc
+// @Summary      List make-up credits of an enrollment
+// @Tags         makeup-credits
+// @Produce      json
+// @Param        id path string true "Enrollment UUID"
+// @Success      200 {array} models.MakeupCredit
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /enrollments/{id}/makeup-credits [get]
+func (ctrl *MakeupCreditController) ListByEnrollment(c *gin.Context) {
+    enrID, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    mcs, err := ctrl.service.ListByEnrollment(enrID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, mcs)
+}
+
+// Get godoc
+// @Summary      Get a make-up credit
+// @Tags         makeup-credits
+// @Produce      json
+// @Param        id path string true "Make-up credit UUID"
+// @Success      200 {object} models.MakeupCredit
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Router       /makeup-credits/{id} [get]
+func (ctrl *MakeupCreditController) Get(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    mc, err := ctrl.service.Get(id)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "make-up credit not found"})
+        return
+    }
+    c.JSON(http.StatusOK, mc)
+}
+
+// Book godoc
+// @Summary      Book a make-up credit into another batch's session
+// @Tags         makeup-credits
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Make-up credit UUID"
+// @Param        booking body BookMakeupRequest true "Target batch and date"
+// @Success      200 {object} models.MakeupCredit
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /makeup-credits/{id}/book [post]
+func (ctrl *MakeupCreditController) Book(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    var req BookMakeupRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    mc, err := ctrl.service.Book(id, req.BatchID, req.Date)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, mc)
+}
+
+// CancelBooking godoc
+// @Summary      Cancel a make-up booking
+// @Tags         makeup-credits
+// @Produce      json
+// @Param        id path string true "Make-up credit UUID"
+// @Success      200 {object} models.MakeupCredit
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /makeup-credits/{id}/book [delete]
+func (ctrl *MakeupCreditController) CancelBooking(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    mc, err := ctrl.service.CancelBooking(id)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, mc)
+}
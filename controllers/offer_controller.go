@@ -78,7 +78,7 @@ func (ctrl *OfferController) Create(c *gin.Context) {
         return
     }
     if err := ctrl.service.Create(&offer); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        respondError(c, err)
         return
     }
     c.JSON(http.StatusCreated, offer)
@@ -93,6 +93,7 @@ func (ctrl *OfferController) Create(c *gin.Context) {
 // @Param        offer body models.Offer true "Updated offer object"
 // @Success      200 {object} models.Offer
 // @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
 // @Failure      500 {object} map[string]string
 // @Security     ApiKeyAuth
 // @Router       /offers/{id} [put]
@@ -109,7 +110,7 @@ func (ctrl *OfferController) Update(c *gin.Context) {
     }
     offer.ID = id
     if err := ctrl.service.Update(&offer); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        respondError(c, err)
         return
     }
     c.JSON(http.StatusOK, offer)
@@ -41,14 +41,14 @@ func (ctrl *PaymentController) List(c *gin.Context) {
 // @Summary      List payments by enrollment
 // @Tags         payments
 // @Produce      json
-// @Param        enrollmentId path string true "Enrollment UUID"
+// @Param        id path string true "Enrollment UUID"
 // @Success      200 {array} models.FeePayment
 // @Failure      400 {object} map[string]string
 // @Failure      500 {object} map[string]string
 // @Security     ApiKeyAuth
-// @Router       /enrollments/{enrollmentId}/payments [get]
+// @Router       /enrollments/{id}/payments [get]
 func (ctrl *PaymentController) ListByEnrollment(c *gin.Context) {
-    enrID, err := uuid.Parse(c.Param("enrollmentId"))
+    enrID, err := uuid.Parse(c.Param("id"))
     if err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
         return
@@ -103,61 +103,46 @@ func (ctrl *PaymentController) Create(c *gin.Context) {
         return
     }
     if err := ctrl.service.Create(&p); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        respondError(c, err)
         return
     }
     c.JSON(http.StatusCreated, p)
 }
 
-// Update godoc
-// @Summary      Update a payment
+// ReverseRequest is the body of a payment or transaction reversal.
+type ReverseRequest struct {
+    Reason string `json:"reason" binding:"required"`
+}
+
+// Reverse godoc
+// @Summary      Reverse a payment
+// @Description  Payments cannot be edited or deleted. A payment recorded by mistake is cancelled by a reversal entry of the negated amount, which is returned.
 // @Tags         payments
 // @Accept       json
 // @Produce      json
-// @Param        id path string true "Payment UUID"
-// @Param        payment body models.FeePayment true "Updated payment object"
-// @Success      200 {object} models.FeePayment
+// @Param        id      path string         true "Payment UUID"
+// @Param        reversal body ReverseRequest true "Why the payment is reversed"
+// @Success      201 {object} models.FeePayment
 // @Failure      400 {object} map[string]string
-// @Failure      500 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
 // @Security     ApiKeyAuth
-// @Router       /payments/{id} [put]
-func (ctrl *PaymentController) Update(c *gin.Context) {
+// @Router       /payments/{id}/reverse [post]
+func (ctrl *PaymentController) Reverse(c *gin.Context) {
     id, err := uuid.Parse(c.Param("id"))
     if err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
         return
     }
-    var p models.FeePayment
-    if err := c.ShouldBindJSON(&p); err != nil {
+    var req ReverseRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
-    p.ID = id
-    if err := ctrl.service.Update(&p); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-        return
-    }
-    c.JSON(http.StatusOK, p)
-}
-
-// Delete godoc
-// @Summary      Delete a payment
-// @Tags         payments
-// @Param        id path string true "Payment UUID"
-// @Success      204
-// @Failure      400 {object} map[string]string
-// @Failure      500 {object} map[string]string
-// @Security     ApiKeyAuth
-// @Router       /payments/{id} [delete]
-func (ctrl *PaymentController) Delete(c *gin.Context) {
-    id, err := uuid.Parse(c.Param("id"))
+    rev, err := ctrl.service.Reverse(id, req.Reason)
     if err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
-        return
-    }
-    if err := ctrl.service.Delete(id); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        respondError(c, err)
         return
     }
-    c.Status(http.StatusNoContent)
-}
\ No newline at end of file
+    c.JSON(http.StatusCreated, rev)
+}
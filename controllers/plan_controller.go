@@ -78,14 +78,14 @@ func (ctrl *PlanController) Create(c *gin.Context) {
         return
     }
     if err := ctrl.service.Create(&p); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        respondError(c, err)
         return
     }
     c.JSON(http.StatusCreated, p)
 }
 
 // Update godoc
-// @Summary      Update a plan
+// @Summary      Update a plan, adding a new version if its terms change
 // @Tags         plans
 // @Accept       json
 // @Produce      json
@@ -93,6 +93,7 @@ func (ctrl *PlanController) Create(c *gin.Context) {
 // @Param        plan body models.Plan true "Updated plan object"
 // @Success      200 {object} models.Plan
 // @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
 // @Failure      500 {object} map[string]string
 // @Security     ApiKeyAuth
 // @Router       /plans/{id} [put]
@@ -109,7 +110,7 @@ func (ctrl *PlanController) Update(c *gin.Context) {
     }
     p.ID = id
     if err := ctrl.service.Update(&p); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        respondError(c, err)
         return
     }
     c.JSON(http.StatusOK, p)
@@ -135,4 +136,97 @@ func (ctrl *PlanController) Delete(c *gin.Context) {
         return
     }
     c.Status(http.StatusNoContent)
-}
\ No newline at end of file
+}
+
+// AttachOffer godoc
+// @Summary      Attach an offer to a plan
+// @Tags         plans
+// @Produce      json
+// @Param        id      path string true "Plan ID (UUID)"
+// @Param        offerId path string true "Offer ID (UUID)"
+// @Success      200 {object} models.Plan
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /plans/{id}/offers/{offerId} [post]
+func (ctrl *PlanController) AttachOffer(c *gin.Context) {
+    planID, offerID, ok := planOfferParams(c)
+    if !ok {
+        return
+    }
+    if err := ctrl.service.AttachOffer(planID, offerID); err != nil {
+        respondError(c, err)
+        return
+    }
+    plan, err := ctrl.service.Get(planID)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, plan)
+}
+
+// Versions godoc
+// @Summary      List the versions of a plan, for its price history
+// @Tags         plans
+// @Produce      json
+// @Param        id path string true "Plan ID (UUID)"
+// @Success      200 {array} models.PlanVersion
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /plans/{id}/versions [get]
+func (ctrl *PlanController) Versions(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    vs, err := ctrl.service.Versions(id)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, vs)
+}
+
+// DetachOffer godoc
+// @Summary      Detach an offer from a plan
+// @Tags         plans
+// @Param        id      path string true "Plan ID (UUID)"
+// @Param        offerId path string true "Offer ID (UUID)"
+// @Success      204 {string} string ""
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /plans/{id}/offers/{offerId} [delete]
+func (ctrl *PlanController) DetachOffer(c *gin.Context) {
+    planID, offerID, ok := planOfferParams(c)
+    if !ok {
+        return
+    }
+    if err := ctrl.service.DetachOffer(planID, offerID); err != nil {
+        respondError(c, err)
+        return
+    }
+    c.Status(http.StatusNoContent)
+}
+
+// planOfferParams parses the plan and offer IDs from the path, writing a 400
+// and returning false if either is malformed.
+func planOfferParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+    planID, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid plan UUID"})
+        return planID, uuid.Nil, false
+    }
+    offerID, err := uuid.Parse(c.Param("offerId"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offer UUID"})
+        return planID, offerID, false
+    }
+    return planID, offerID, true
+}
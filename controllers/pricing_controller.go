@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"net/http"
+
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PricingController serves price quotes.
+type PricingController struct {
+    service *services.PricingService
+}
+
+// NewPricingController constructs a PricingController.
+func NewPricingController(s *services.PricingService) *PricingController {
+    return &PricingController{service: s}
+}
+
+// Quote godoc
+// @Summary      Quote a plan's price for a student
+// @Description  Applies the best offer valid on the date and adds tax. The returned quote_id can be passed once to enrollment creation and once to payment recording until it expires.
+// @Tags         plans
+// @Accept       json
+// @Produce      json
+// @Param        id   path string               true "Plan ID (UUID)"
+// @Param        body body services.QuoteRequest true "Student, venue and date"
+// @Success      200 {object} services.Quote
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /plans/{id}/quote [post]
+func (ctrl *PricingController) Quote(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    var req services.QuoteRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    q, err := ctrl.service.Quote(id, req)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, q)
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"net/http"
+
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ReceiptController handles HTTP requests for payment receipts.
+type ReceiptController struct {
+    service *services.ReceiptService
+}
+
+// NewReceiptController constructs a ReceiptController.
+func NewReceiptController(s *services.ReceiptService) *ReceiptController {
+    return &ReceiptController{service: s}
+}
+
+// ResendReceiptRequest optionally redirects a resent receipt.
+type ResendReceiptRequest struct {
+    Email string `json:"email" binding:"omitempty,email"` // defaults to the student's email
+}
+
+// Get godoc
+// @Summary      Download the receipt of a payment
+// @Description  Returns the PDF, or the plain-text receipt with format=text.
+// @Tags         payments
+// @Produce      application/pdf
+// @Produce      plain
+// @Param        id     path  string true  "Payment UUID"
+// @Param        format query string false "pdf (default) or text"
+// @Success      200 {file} file
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /payments/{id}/receipt [get]
+func (ctrl *ReceiptController) Get(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    format := c.DefaultQuery("format", "pdf")
+    if format != "pdf" && format != "text" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "format must be pdf or text"})
+        return
+    }
+    rc, err := ctrl.service.Get(id)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    if format == "text" {
+        c.Header("Content-Disposition", `inline; filename="`+rc.Number+`.txt"`)
+        c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(rc.Text))
+        return
+    }
+    c.Header("Content-Disposition", `inline; filename="`+rc.Number+`.pdf"`)
+    c.Data(http.StatusOK, "application/pdf", rc.PDF)
+}
+
+// Resend godoc
+// @Summary      Mail the receipt of a payment again
+// @Tags         payments
+// @Accept       json
+// @Produce      json
+// @Param        id      path string               true  "Payment UUID"
+// @Param        request body ResendReceiptRequest false "Address to send to instead of the student's"
+// @Success      200 {object} models.Receipt
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /payments/{id}/receipt/resend [post]
+func (ctrl *ReceiptController) Resend(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    var req ResendReceiptRequest
+    if c.Request.ContentLength != 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+    }
+    rc, err := ctrl.service.Resend(id, req.Email)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, rc)
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"net/http"
+
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ReferralController handles referrals and the wallet credit they earn.
+type ReferralController struct {
+    service *services.ReferralService
+}
+
+// NewReferralController constructs a ReferralController.
+func NewReferralController(s *services.ReferralService) *ReferralController {
+    return &ReferralController{service: s}
+}
+
+// List godoc
+// @Summary      List referrals
+// @Tags         referrals
+// @Produce      json
+// @Param        referrer_id query string false "Only referrals made by this user (UUID)"
+// @Success      200 {array} models.Referral
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /referrals [get]
+func (ctrl *ReferralController) List(c *gin.Context) {
+    var referrerID *uuid.UUID
+    if v := c.Query("referrer_id"); v != "" {
+        id, err := uuid.Parse(v)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "invalid referrer_id"})
+            return
+        }
+        referrerID = &id
+    }
+    rs, err := ctrl.service.List(referrerID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, rs)
+}
+
+// Create godoc
+// @Summary      Record who referred a student
+// @Description  Referrals between accounts sharing a private email domain or phone are stored as rejected and earn no reward.
+// @Tags         referrals
+// @Accept       json
+// @Produce      json
+// @Param        referral body services.ReferralRequest true "Referral code and referred student"
+// @Success      201 {object} models.Referral
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /referrals [post]
+func (ctrl *ReferralController) Create(c *gin.Context) {
+    var req services.ReferralRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    ref, err := ctrl.service.Record(req)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusCreated, ref)
+}
+
+// Wallet godoc
+// @Summary      Get a user's wallet and renewal credit
+// @Tags         referrals
+// @Produce      json
+// @Param        id path string true "User ID (UUID)"
+// @Success      200 {object} services.Wallet
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /users/{id}/wallet [get]
+func (ctrl *ReferralController) Wallet(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    w, err := ctrl.service.Wallet(id)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, w)
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"net/http"
+
+	"spodemy-backend/middlewares"
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RefundController handles HTTP for refunds.
+type RefundController struct {
+    service *services.RefundService
+}
+
+// NewRefundController constructs a RefundController.
+func NewRefundController(s *services.RefundService) *RefundController {
+    return &RefundController{service: s}
+}
+
+// List godoc
+// @Summary      List refunds
+// @Tags         refunds
+// @Produce      json
+// @Param        fee_payment_id query string false "Payment UUID"
+// @Param        enrollment_id  query string false "Enrollment UUID"
+// @Param        status         query string false "pending, processed or failed"
+// @Success      200 {array} models.Refund
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /refunds [get]
+func (ctrl *RefundController) List(c *gin.Context) {
+    f := repositories.RefundFilter{Status: c.Query("status")}
+    for _, q := range []struct {
+        name string
+        dst  **uuid.UUID
+    }{{"fee_payment_id", &f.FeePaymentID}, {"enrollment_id", &f.EnrollmentID}} {
+        if v := c.Query(q.name); v != "" {
+            id, err := uuid.Parse(v)
+            if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + q.name})
+                return
+            }
+            *q.dst = &id
+        }
+    }
+    refunds, err := ctrl.service.List(f)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, refunds)
+}
+
+// ListByPayment godoc
+// @Summary      List the refunds of a payment
+// @Tags         refunds
+// @Produce      json
+// @Param        id path string true "Payment UUID"
+// @Success      200 {array} models.Refund
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /payments/{id}/refunds [get]
+func (ctrl *RefundController) ListByPayment(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    refunds, err := ctrl.service.List(repositories.RefundFilter{FeePaymentID: &id})
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, refunds)
+}
+
+// Get godoc
+// @Summary      Get a refund
+// @Tags         refunds
+// @Produce      json
+// @Param        id path string true "Refund UUID"
+// @Success      200 {object} models.Refund
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /refunds/{id} [get]
+func (ctrl *RefundController) Get(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    rf, err := ctrl.service.Get(id)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, rf)
+}
+
+// Create godoc
+// @Summary      Refund a payment
+// @Description  Refunds amount_cents, or all that is left of the payment. Online payments are refunded through their gateway. The approver defaults to the signed-in user.
+// @Tags         refunds
+// @Accept       json
+// @Produce      json
+// @Param        id     path string        true "Payment UUID"
+// @Param        refund body models.Refund true "Amount, reason and approver"
+// @Success      201 {object} models.Refund
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /payments/{id}/refunds [post]
+func (ctrl *RefundController) Create(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    var rf models.Refund
+    if err := c.ShouldBindJSON(&rf); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    rf.FeePaymentID = id
+    if user := middlewares.CurrentUserID(c); rf.ApprovedByID == uuid.Nil && user != nil {
+        rf.ApprovedByID = *user
+    }
+    if err := ctrl.service.Create(&rf); err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusCreated, rf)
+}
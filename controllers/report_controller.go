@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReportController serves management reports.
+type ReportController struct {
+    service *services.ReportService
+}
+
+// NewReportController constructs a ReportController.
+func NewReportController(s *services.ReportService) *ReportController {
+    return &ReportController{service: s}
+}
+
+// TrialConversions godoc
+// @Summary      Trial-to-paid conversion by venue, sport and coach
+// @Tags         reports
+// @Produce      json
+// @Param        from query string false "Start date (YYYY-MM-DD), defaults to 30 days ago"
+// @Param        to   query string false "End date, exclusive (YYYY-MM-DD), defaults to tomorrow"
+// @Success      200 {array} services.TrialConversion
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /reports/trial-conversions [get]
+func (ctrl *ReportController) TrialConversions(c *gin.Context) {
+    today := time.Now().UTC().Truncate(24 * time.Hour)
+    from, to, ok := periodQuery(c, today.AddDate(0, 0, -30), today.AddDate(0, 0, 1))
+    if !ok {
+        return
+    }
+    rows, err := ctrl.service.TrialConversions(from, to)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, rows)
+}
+
+// ReferralLeaderboard godoc
+// @Summary      Rank referrers by referrals and rewards earned
+// @Tags         reports
+// @Produce      json
+// @Param        from query string false "Start date (YYYY-MM-DD), defaults to 90 days ago"
+// @Param        to   query string false "End date, exclusive (YYYY-MM-DD), defaults to tomorrow"
+// @Success      200 {array} repositories.ReferralLeaderboardRow
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /reports/referrals [get]
+func (ctrl *ReportController) ReferralLeaderboard(c *gin.Context) {
+    today := time.Now().UTC().Truncate(24 * time.Hour)
+    from, to, ok := periodQuery(c, today.AddDate(0, 0, -90), today.AddDate(0, 0, 1))
+    if !ok {
+        return
+    }
+    rows, err := ctrl.service.ReferralLeaderboard(from, to)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, rows)
+}
+
+// GSTSummary godoc
+// @Summary      Monthly GST collected, by venue, currency and tax rate
+// @Description  Totals are converted to the reporting currency at the exchange rates of the month's last day.
+// @Tags         reports
+// @Produce      json
+// @Param        month    query string false "Month (YYYY-MM), defaults to the current month"
+// @Param        currency query string false "Reporting currency, defaults to the base currency"
+// @Success      200 {object} services.GSTSummary
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /reports/gst [get]
+func (ctrl *ReportController) GSTSummary(c *gin.Context) {
+    month := time.Now()
+    if v := c.Query("month"); v != "" {
+        var err error
+        if month, err = time.Parse("2006-01", v); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "invalid month, expected YYYY-MM"})
+            return
+        }
+    }
+    sum, err := ctrl.service.GSTSummary(month, c.Query("currency"))
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, sum)
+}
+
+// periodQuery reads the optional from/to query dates, writing a 400 and
+// returning false if either is malformed.
+func periodQuery(c *gin.Context, defFrom, defTo time.Time) (time.Time, time.Time, bool) {
+    from, to := defFrom, defTo
+    if v := c.Query("from"); v != "" {
+        t, err := time.Parse("2006-01-02", v)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from date"})
+            return from, to, false
+        }
+        from = t
+    }
+    if v := c.Query("to"); v != "" {
+        t, err := time.Parse("2006-01-02", v)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to date"})
+            return from, to, false
+        }
+        to = t
+    }
+    return from, to, true
+}
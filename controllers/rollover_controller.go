@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"net/http"
+
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RolloverController handles term rollover of a venue's batches.
+type RolloverController struct {
+    service *services.RolloverService
+}
+
+// NewRolloverController constructs a RolloverController.
+func NewRolloverController(s *services.RolloverService) *RolloverController {
+    return &RolloverController{service: s}
+}
+
+// Rollover godoc
+// @Summary      Roll a venue's batches over into the next term
+// @Description  Clones batches with shifted dates, coach and schedule, and creates pending re-enrollments for opted-in students. Without batch_ids every batch running on term_date (default today) is rolled over. With dry_run nothing is saved.
+// @Tags         batches
+// @Accept       json
+// @Produce      json
+// @Param        id       path  string                    true  "Venue ID (UUID)"
+// @Param        rollover body  services.RolloverRequest  true  "Rollover options"
+// @Success      200 {object} services.RolloverPlan
+// @Success      201 {object} services.RolloverPlan
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /venues/{id}/rollover [post]
+func (ctrl *RolloverController) Rollover(c *gin.Context) {
+    venueID, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid venue UUID"})
+        return
+    }
+    var req services.RolloverRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    plan, err := ctrl.service.Rollover(venueID, req)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    if plan.DryRun {
+        c.JSON(http.StatusOK, plan)
+        return
+    }
+    c.JSON(http.StatusCreated, plan)
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"net/http"
+
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SubscriptionController handles renewal settings and renewal charges.
+type SubscriptionController struct {
+    service *services.SubscriptionService
+}
+
+// NewSubscriptionController constructs a SubscriptionController.
+func NewSubscriptionController(s *services.SubscriptionService) *SubscriptionController {
+    return &SubscriptionController{service: s}
+}
+
+// Cancel godoc
+// @Summary      Stop an enrollment from renewing at the end of its cycle
+// @Tags         subscriptions
+// @Produce      json
+// @Param        id path string true "Enrollment UUID"
+// @Success      200 {object} models.Enrollment
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /enrollments/{id}/cancel-renewal [post]
+func (ctrl *SubscriptionController) Cancel(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    e, err := ctrl.service.Cancel(id)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, e)
+}
+
+// Reinstate godoc
+// @Summary      Undo a renewal cancellation before the cycle ends
+// @Tags         subscriptions
+// @Produce      json
+// @Param        id path string true "Enrollment UUID"
+// @Success      200 {object} models.Enrollment
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /enrollments/{id}/reinstate-renewal [post]
+func (ctrl *SubscriptionController) Reinstate(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    e, err := ctrl.service.Reinstate(id)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, e)
+}
+
+// ListByEnrollment godoc
+// @Summary      List the renewal charges of an enrollment
+// @Tags         subscriptions
+// @Produce      json
+// @Param        id path string true "Enrollment UUID"
+// @Success      200 {array} models.Charge
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /enrollments/{id}/charges [get]
+func (ctrl *SubscriptionController) ListByEnrollment(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    cs, err := ctrl.service.Charges(id)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, cs)
+}
+
+// List godoc
+// @Summary      List renewal charges, e.g. the overdue ones
+// @Tags         subscriptions
+// @Produce      json
+// @Param        status query string false "due, paid, overdue or void"
+// @Success      200 {array} models.Charge
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /charges [get]
+func (ctrl *SubscriptionController) List(c *gin.Context) {
+    cs, err := ctrl.service.ListCharges(c.Query("status"))
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, cs)
+}
+
+// Get godoc
+// @Summary      Get a renewal charge
+// @Tags         subscriptions
+// @Produce      json
+// @Param        id path string true "Charge UUID"
+// @Success      200 {object} models.Charge
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /charges/{id} [get]
+func (ctrl *SubscriptionController) Get(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    ch, err := ctrl.service.GetCharge(id)
+    if err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, ch)
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"net/http"
+
+	"spodemy-backend/models"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TaxController handles HTTP requests for tax rates.
+type TaxController struct {
+    service *services.TaxService
+}
+
+// NewTaxController constructs a TaxController.
+func NewTaxController(s *services.TaxService) *TaxController {
+    return &TaxController{service: s}
+}
+
+// List godoc
+// @Summary      List tax rates
+// @Tags         taxes
+// @Produce      json
+// @Success      200 {array} models.TaxRate
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /tax-rates [get]
+func (ctrl *TaxController) List(c *gin.Context) {
+    rates, err := ctrl.service.List()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, rates)
+}
+
+// Get godoc
+// @Summary      Get a tax rate
+// @Tags         taxes
+// @Produce      json
+// @Param        id path string true "Tax rate ID (UUID)"
+// @Success      200 {object} models.TaxRate
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /tax-rates/{id} [get]
+func (ctrl *TaxController) Get(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    rate, err := ctrl.service.Get(id)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "tax rate not found"})
+        return
+    }
+    c.JSON(http.StatusOK, rate)
+}
+
+// Create godoc
+// @Summary      Create a tax rate
+// @Tags         taxes
+// @Accept       json
+// @Produce      json
+// @Param        rate body models.TaxRate true "Tax rate object"
+// @Success      201 {object} models.TaxRate
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /tax-rates [post]
+func (ctrl *TaxController) Create(c *gin.Context) {
+    var rate models.TaxRate
+    if err := c.ShouldBindJSON(&rate); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    if err := ctrl.service.Create(&rate); err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusCreated, rate)
+}
+
+// Update godoc
+// @Summary      Update a tax rate's name or percentage
+// @Description  Amounts already priced keep the rate they were priced with. The code cannot be changed.
+// @Tags         taxes
+// @Accept       json
+// @Produce      json
+// @Param        id   path string         true "Tax rate ID (UUID)"
+// @Param        rate body models.TaxRate true "Updated tax rate object"
+// @Success      200 {object} models.TaxRate
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /tax-rates/{id} [put]
+func (ctrl *TaxController) Update(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    var rate models.TaxRate
+    if err := c.ShouldBindJSON(&rate); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    rate.ID = id
+    if err := ctrl.service.Update(&rate); err != nil {
+        respondError(c, err)
+        return
+    }
+    c.JSON(http.StatusOK, rate)
+}
+
+// Delete godoc
+// @Summary      Delete a tax rate no plan uses
+// @Tags         taxes
+// @Param        id path string true "Tax rate ID (UUID)"
+// @Success      204 {string} string ""
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Security     ApiKeyAuth
+// @Router       /tax-rates/{id} [delete]
+func (ctrl *TaxController) Delete(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid UUID"})
+        return
+    }
+    if err := ctrl.service.Delete(id); err != nil {
+        respondError(c, err)
+        return
+    }
+    c.Status(http.StatusNoContent)
+}
@@ -82,7 +82,7 @@ func (ctrl *UserController) Create(c *gin.Context) {
         return
     }
     if err := ctrl.service.Create(&u); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        respondError(c, err)
         return
     }
     c.JSON(http.StatusCreated, u)
@@ -65,7 +65,7 @@ func (ctrl *VenueController) Get(c *gin.Context) {
 
 // Create godoc
 // @Summary      Create a venue
-// @Description  Create a new venue record
+// @Description  Create a new venue record. Currency defaults to the base currency.
 // @Tags         venues
 // @Accept       json
 // @Produce      json
@@ -82,7 +82,7 @@ func (ctrl *VenueController) Create(c *gin.Context) {
         return
     }
     if err := ctrl.service.CreateVenue(&v); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        respondError(c, err)
         return
     }
     c.JSON(http.StatusCreated, v)
@@ -99,6 +99,7 @@ func (ctrl *VenueController) Create(c *gin.Context) {
 // @Success      200    {object}  models.Venue
 // @Failure      400    {object}  map[string]string "Invalid UUID format or request body"
 // @Failure      404    {object}  map[string]string "Venue not found"
+// @Failure      409    {object}  map[string]string "Currency in use"
 // @Failure      500    {object}  map[string]string "Server error"
 // @Security     ApiKeyAuth
 // @Router       /venues/{id} [put]
@@ -116,7 +117,7 @@ func (ctrl *VenueController) Update(c *gin.Context) {
     }
     v.ID = id
     if err := ctrl.service.UpdateVenue(&v); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        respondError(c, err)
         return
     }
     c.JSON(http.StatusOK, v)
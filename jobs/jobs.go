@@ -0,0 +1,109 @@
+// Package jobs runs the periodic background work of the service.
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"spodemy-backend/config"
+	"spodemy-backend/repositories"
+	"spodemy-backend/services"
+
+	"gorm.io/gorm"
+)
+
+// Start launches every background job and returns immediately.
+func Start(db *gorm.DB, cfg *config.Config) {
+    plans := repositories.NewPlanRepository(db)
+    batches := repositories.NewBatchRepository(db)
+    makeups := services.NewMakeupCreditService(repositories.NewMakeupCreditRepository(db), batches, cfg.Makeup)
+    enrollmentRepo := repositories.NewEnrollmentRepository(db)
+    pricing := services.NewPricingService(plans, repositories.NewVenueRepository(db), repositories.NewUserRepository(db),
+        batches, enrollmentRepo, repositories.NewCouponRepository(db), repositories.NewFamilyRepository(db),
+        repositories.NewTaxRepository(db), repositories.NewQuoteRepository(db), cfg.Pricing, cfg.Family)
+    enrollments := services.NewEnrollmentService(enrollmentRepo, plans, batches, pricing, cfg.Trial)
+    subscriptions := services.NewSubscriptionService(repositories.NewSubscriptionRepository(db), enrollmentRepo,
+        pricing, cfg.Subscription)
+    installments := services.NewInstallmentService(repositories.NewInstallmentRepository(db), enrollmentRepo, cfg.Installment)
+    idempotency := services.NewIdempotencyService(repositories.NewIdempotencyRepository(db), cfg.Idempotency)
+    mailer, err := services.NewMailer(cfg.Mail)
+    if err != nil {
+        log.Fatalf("mailer: %v", err)
+    }
+    receipts := services.NewReceiptService(repositories.NewReceiptRepository(db), mailer, cfg.Invoice, cfg.Mail)
+
+    every(time.Hour, "expire make-up credits", func(now time.Time) error {
+        n, err := makeups.ExpireDue(now)
+        if n > 0 {
+            log.Printf("released or expired %d make-up credits", n)
+        }
+        return err
+    })
+
+    every(time.Hour, "expire trials", func(now time.Time) error {
+        n, err := enrollments.ExpireTrials(now)
+        if n > 0 {
+            log.Printf("closed %d expired trials", n)
+        }
+        return err
+    })
+
+    every(time.Hour, "renew subscriptions", func(now time.Time) error {
+        n, err := subscriptions.RenewDue(now)
+        if n > 0 {
+            log.Printf("renewed %d subscriptions", n)
+        }
+        return err
+    })
+
+    every(time.Hour, "mark overdue renewals", func(now time.Time) error {
+        n, err := subscriptions.MarkOverdue(now)
+        if n > 0 {
+            log.Printf("marked %d renewal charges overdue", n)
+        }
+        return err
+    })
+
+    every(time.Minute, "deliver receipts", func(now time.Time) error {
+        n, err := receipts.DeliverPending()
+        if n > 0 {
+            log.Printf("mailed %d payment receipts", n)
+        }
+        return err
+    })
+
+    every(time.Hour, "suspend overdue installments", func(now time.Time) error {
+        n, err := installments.SuspendOverdue(now)
+        if n > 0 {
+            log.Printf("marked %d installments overdue", n)
+        }
+        return err
+    })
+
+    every(time.Hour, "expire idempotency keys", func(now time.Time) error {
+        _, err := idempotency.ExpireDue(now)
+        return err
+    })
+
+    every(time.Hour, "complete expired enrollments", func(now time.Time) error {
+        n, err := enrollments.CompleteExpired(now)
+        if n > 0 {
+            log.Printf("completed %d enrollments past their validity", n)
+        }
+        return err
+    })
+}
+
+// every runs fn immediately and then once per interval in its own goroutine.
+func every(interval time.Duration, name string, fn func(now time.Time) error) {
+    go func() {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for {
+            if err := fn(time.Now()); err != nil {
+                log.Printf("job %q failed: %v", name, err)
+            }
+            <-ticker.C
+        }
+    }()
+}
@@ -6,9 +6,11 @@
 package main
 
 import (
+	"log"
 	"spodemy-backend/config"
 	"spodemy-backend/database"
 	_ "spodemy-backend/docs" // ← the generated Swagger docs
+	"spodemy-backend/jobs"
 	"spodemy-backend/routes"
 
 	swaggerFiles "github.com/swaggo/files"
@@ -20,18 +22,27 @@ import (
 
 func main() {
     // Load config
-    _,_ = config.LoadConfig("config/local.json")
+    cfg, err := config.LoadConfig("config/local.json")
+    if err != nil {
+        log.Fatalf("could not load config: %v", err)
+    }
+    if err := cfg.Validate(); err != nil {
+        log.Fatalf("invalid config: %v", err)
+    }
 
     // Initialize DB connection
     database.Connect()
 
     // Create Gin router
     r := gin.Default()
-    
+
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+    // Start background jobs
+    jobs.Start(database.DB, cfg)
+
     // Setup routes with database.DB
-    routes.SetupRoutes(r, database.DB)
+    routes.SetupRoutes(r, database.DB, cfg)
 
     r.Run(":8080")
-}
\ No newline at end of file
+}
@@ -0,0 +1,100 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Idempotency makes a POST endpoint safe to retry. A request carrying an
+// Idempotency-Key header is run once; retries with the same key and body
+// get the stored response with an Idempotent-Replayed header, and reusing
+// the key with a different body is rejected. Requests without the header
+// are not affected.
+func Idempotency(s *services.IdempotencyService) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        key := c.GetHeader("Idempotency-Key")
+        if key == "" {
+            c.Next()
+            return
+        }
+        if len(key) > 255 {
+            c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+            return
+        }
+        body, err := io.ReadAll(c.Request.Body)
+        if err != nil {
+            c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+        scope := c.Request.Method + " " + c.FullPath()
+        stored, err := s.Begin(scope, key, requestHash(c.Request.Method, c.Request.URL.Path, body))
+        switch {
+        case errors.Is(err, services.ErrIdempotencyMismatch):
+            c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+            return
+        case errors.Is(err, services.ErrIdempotencyInProgress):
+            c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+            return
+        case err != nil:
+            c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        case stored != nil:
+            c.Header("Idempotent-Replayed", "true")
+            c.Data(stored.Status, stored.ContentType, stored.Body)
+            c.Abort()
+            return
+        }
+
+        w := &recordingWriter{ResponseWriter: c.Writer}
+        c.Writer = w
+        finished := false
+        defer func() {
+            // a panicking handler leaves no response worth keeping
+            if !finished {
+                if err := s.Abandon(scope, key); err != nil {
+                    log.Printf("releasing idempotency key %q: %v", key, err)
+                }
+            }
+        }()
+        c.Next()
+        finished = true
+        if err := s.Finish(scope, key, w.Status(), w.Header().Get("Content-Type"), w.body.Bytes()); err != nil {
+            log.Printf("storing response for idempotency key %q: %v", key, err)
+        }
+    }
+}
+
+// requestHash identifies a request by its method, path and body.
+func requestHash(method, path string, body []byte) string {
+    h := sha256.New()
+    io.WriteString(h, method+" "+path+"\n")
+    h.Write(body)
+    return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter keeps a copy of the response body.
+type recordingWriter struct {
+    gin.ResponseWriter
+    body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+    w.body.Write(b)
+    return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+    w.body.WriteString(s)
+    return w.ResponseWriter.WriteString(s)
+}
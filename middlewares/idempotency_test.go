@@ -0,0 +1,125 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"spodemy-backend/config"
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestRequestHash(t *testing.T) {
+    base := requestHash("POST", "/api/payments", []byte(`{"amount_cents":500}`))
+    if again := requestHash("POST", "/api/payments", []byte(`{"amount_cents":500}`)); again != base {
+        t.Errorf("same request hashed to %s and %s", base, again)
+    }
+    others := map[string]string{
+        "body":   requestHash("POST", "/api/payments", []byte(`{"amount_cents":501}`)),
+        "path":   requestHash("POST", "/api/payments/1/refunds", []byte(`{"amount_cents":500}`)),
+        "method": requestHash("PUT", "/api/payments", []byte(`{"amount_cents":500}`)),
+    }
+    for name, h := range others {
+        if h == base {
+            t.Errorf("request with another %s hashed the same", name)
+        }
+    }
+}
+
+// idempotentRouter serves POST /things behind Idempotency, answering with
+// status and counting the calls that reach the handler.
+func idempotentRouter(t *testing.T, ttlHours int, status *int, calls *int) *gin.Engine {
+    t.Helper()
+    dsn := os.Getenv("SPODEMY_TEST_DSN")
+    if dsn == "" {
+        t.Skip("SPODEMY_TEST_DSN is not set")
+    }
+    db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := db.AutoMigrate(&models.IdempotencyKey{}); err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { db.Where("scope = ?", "POST /things").Delete(&models.IdempotencyKey{}) })
+
+    gin.SetMode(gin.TestMode)
+    r := gin.New()
+    s := services.NewIdempotencyService(repositories.NewIdempotencyRepository(db), config.IdempotencyConfig{TTLHours: ttlHours})
+    r.POST("/things", Idempotency(s), func(c *gin.Context) {
+        *calls++
+        c.JSON(*status, gin.H{"call": *calls})
+    })
+    return r
+}
+
+// send posts body to /things, with key as the Idempotency-Key if set.
+func send(r *gin.Engine, key, body string) *httptest.ResponseRecorder {
+    req := httptest.NewRequest(http.MethodPost, "/things", strings.NewReader(body))
+    if key != "" {
+        req.Header.Set("Idempotency-Key", key)
+    }
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    return w
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+    status, calls := http.StatusCreated, 0
+    r := idempotentRouter(t, 24, &status, &calls)
+    key := uuid.NewString()
+
+    first := send(r, key, `{"a":1}`)
+    if first.Code != http.StatusCreated || first.Header().Get("Idempotent-Replayed") != "" {
+        t.Fatalf("first: %d %v", first.Code, first.Header())
+    }
+    again := send(r, key, `{"a":1}`)
+    if again.Code != http.StatusCreated || again.Header().Get("Idempotent-Replayed") != "true" || again.Body.String() != first.Body.String() {
+        t.Errorf("retry: %d %q replayed=%q, want the first response replayed", again.Code, again.Body, again.Header().Get("Idempotent-Replayed"))
+    }
+    if other := send(r, key, `{"a":2}`); other.Code != http.StatusUnprocessableEntity {
+        t.Errorf("reused key with another body: %d, want %d", other.Code, http.StatusUnprocessableEntity)
+    }
+    send(r, "", `{"a":1}`)
+    if calls != 2 {
+        t.Errorf("handler ran %d times, want 2: once for the key, once without one", calls)
+    }
+}
+
+func TestIdempotencyReleasesServerErrors(t *testing.T) {
+    status, calls := http.StatusInternalServerError, 0
+    r := idempotentRouter(t, 24, &status, &calls)
+    key := uuid.NewString()
+
+    if w := send(r, key, `{"a":1}`); w.Code != http.StatusInternalServerError {
+        t.Fatalf("first: %d", w.Code)
+    }
+    status = http.StatusCreated
+    w := send(r, key, `{"a":1}`)
+    if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" || calls != 2 {
+        t.Errorf("retry after a server error: %d replayed=%q after %d calls, want it run again", w.Code, w.Header().Get("Idempotent-Replayed"), calls)
+    }
+}
+
+func TestIdempotencyExpiry(t *testing.T) {
+    status, calls := http.StatusCreated, 0
+    // keys expire as soon as they are stored
+    r := idempotentRouter(t, 0, &status, &calls)
+    key := uuid.NewString()
+
+    send(r, key, `{"a":1}`)
+    w := send(r, key, `{"a":2}`)
+    if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" || calls != 2 {
+        t.Errorf("expired key: %d replayed=%q after %d calls, want the request run again", w.Code, w.Header().Get("Idempotent-Replayed"), calls)
+    }
+}
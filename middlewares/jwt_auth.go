@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
 // JWTAuth validates JWT from Authorization header and sets claims in context.
@@ -30,3 +31,24 @@ func JWTAuth() gin.HandlerFunc {
     }
 }
 
+
+// CurrentUserID returns the subject of the authenticated request's token, or
+// nil when the request carries no usable claims.
+func CurrentUserID(c *gin.Context) *uuid.UUID {
+    v, ok := c.Get("claims")
+    if !ok {
+        return nil
+    }
+    var sub string
+    switch claims := v.(type) {
+    case jwt.MapClaims:
+        sub, _ = claims["sub"].(string)
+    case *CustomClaims:
+        sub = claims.Subject
+    }
+    id, err := uuid.Parse(sub)
+    if err != nil {
+        return nil
+    }
+    return &id
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"database/sql"
 	"fmt"
 	"log"
 	"spodemy-backend/config"
@@ -177,6 +178,683 @@ func main() {
   },
 },
 
+    {
+      ID: "20261019_add_makeup_credits",
+      Migrate: func(tx *gorm.DB) error {
+        return tx.AutoMigrate(
+          &models.Batch{},
+          &models.Attendance{},
+          &models.MakeupCredit{},
+        )
+      },
+      Rollback: func(tx *gorm.DB) error {
+        if err := tx.Migrator().DropTable("makeup_credits"); err != nil {
+          return err
+        }
+        for _, col := range []string{"reason", "batch_id", "makeup_credit_id"} {
+          if err := tx.Migrator().DropColumn(&models.Attendance{}, col); err != nil {
+            return err
+          }
+        }
+        for _, col := range []string{"sport", "capacity"} {
+          if err := tx.Migrator().DropColumn(&models.Batch{}, col); err != nil {
+            return err
+          }
+        }
+        return nil
+      },
+    },
+    {
+      ID: "20261020_add_batch_coach_schedules",
+      Migrate: func(tx *gorm.DB) error {
+        return tx.AutoMigrate(
+          &models.Batch{},
+          &models.BatchSchedule{},
+          &models.Enrollment{},
+        )
+      },
+      Rollback: func(tx *gorm.DB) error {
+        if err := tx.Migrator().DropTable("batch_schedules"); err != nil {
+          return err
+        }
+        if err := tx.Migrator().DropColumn(&models.Enrollment{}, "renewal_opt_in"); err != nil {
+          return err
+        }
+        for _, col := range []string{"coach_id", "rolled_from_id"} {
+          if err := tx.Migrator().DropColumn(&models.Batch{}, col); err != nil {
+            return err
+          }
+        }
+        return nil
+      },
+    },
+    {
+      ID: "20261021_add_trial_enrollments",
+      Migrate: func(tx *gorm.DB) error {
+        return tx.AutoMigrate(
+          &models.Enrollment{},
+        )
+      },
+      Rollback: func(tx *gorm.DB) error {
+        for _, col := range []string{"type", "trial_sessions", "trial_ends_on", "plan_id", "converted_from_id"} {
+          if err := tx.Migrator().DropColumn(&models.Enrollment{}, col); err != nil {
+            return err
+          }
+        }
+        return nil
+      },
+    },
+    {
+      ID: "20261022_link_enrollment_offer_validity",
+      Migrate: func(tx *gorm.DB) error {
+        if err := tx.AutoMigrate(&models.Enrollment{}); err != nil {
+          return err
+        }
+        // backfill validity for enrollments that already reference a plan
+        return tx.Exec(`
+          UPDATE enrollments e
+          SET valid_until = date_trunc('day', e.enrolled_on) + p.duration_days * INTERVAL '1 day'
+          FROM plans p
+          WHERE p.id = e.plan_id AND p.duration_days > 0 AND e.valid_until IS NULL`).Error
+      },
+      Rollback: func(tx *gorm.DB) error {
+        for _, col := range []string{"offer_id", "valid_until"} {
+          if err := tx.Migrator().DropColumn(&models.Enrollment{}, col); err != nil {
+            return err
+          }
+        }
+        return nil
+      },
+    },
+    {
+      ID: "20261023_enrollment_status_machine",
+      Migrate: func(tx *gorm.DB) error {
+        // map legacy free-form statuses onto the state machine; anything
+        // unrecognised ends up dropped, a terminal state, rather than
+        // failing the check constraint
+        for _, q := range []string{
+          `UPDATE enrollments SET status = regexp_replace(lower(trim(COALESCE(status, ''))), '[ -]+', '_', 'g')`,
+          `UPDATE enrollments SET status = CASE
+             WHEN status = '' THEN 'active'
+             WHEN status IN ('pending', 'unpaid', 'awaiting_payment') THEN 'pending_payment'
+             WHEN status IN ('on_hold', 'hold', 'suspended') THEN 'paused'
+             WHEN status IN ('complete', 'finished', 'expired', 'ended') THEN 'completed'
+             WHEN status IN ('waiting', 'waitlist') THEN 'waitlisted'
+             ELSE 'dropped' END
+           WHERE status NOT IN ('pending_payment', 'active', 'paused', 'completed', 'dropped', 'waitlisted')`,
+        } {
+          if err := tx.Exec(q).Error; err != nil {
+            return err
+          }
+        }
+        if err := tx.AutoMigrate(&models.Enrollment{}, &models.EnrollmentStatusHistory{}); err != nil {
+          return err
+        }
+        if err := tx.Exec(`
+          ALTER TABLE enrollments ADD CONSTRAINT chk_enrollments_status
+          CHECK (status IN ('pending_payment','active','paused','completed','dropped','waitlisted'))`).Error; err != nil {
+          return err
+        }
+        // seed the history with each enrollment's current status
+        return tx.Exec(`
+          INSERT INTO enrollment_status_history (enrollment_id, from_status, to_status, reason, changed_at)
+          SELECT id, '', status, 'status before history tracking', now() FROM enrollments`).Error
+      },
+      Rollback: func(tx *gorm.DB) error {
+        if err := tx.Exec(`ALTER TABLE enrollments DROP CONSTRAINT IF EXISTS chk_enrollments_status`).Error; err != nil {
+          return err
+        }
+        return tx.Migrator().DropTable("enrollment_status_history")
+      },
+    },
+    {
+      ID: "20261024_add_enrollment_price",
+      Migrate: func(tx *gorm.DB) error {
+        if err := tx.Migrator().AddColumn(&models.Enrollment{}, "PriceCents"); err != nil {
+          return err
+        }
+        // price existing enrollments from their plan and offer, before tax;
+        // offers may already be fixed amounts where the offers table was
+        // created from the current model
+        discount := `p.price_cents * o.discount_pct / 100`
+        if tx.Migrator().HasColumn(&models.Offer{}, "discount_type") {
+          discount = `CASE WHEN o.discount_type = 'fixed' THEN o.discount_cents ELSE ` + discount + ` END`
+        }
+        return tx.Exec(`
+          UPDATE enrollments e SET price_cents = GREATEST(p.price_cents - ROUND(COALESCE(
+            (SELECT ` + discount + ` FROM offers o WHERE o.id = e.offer_id), 0)), 0)
+          FROM plans p WHERE p.id = e.plan_id`).Error
+      },
+      Rollback: func(tx *gorm.DB) error {
+        return tx.Migrator().DropColumn(&models.Enrollment{}, "price_cents")
+      },
+    },
+    {
+      ID: "20261025_offer_rules",
+      Migrate: func(tx *gorm.DB) error {
+        if err := tx.AutoMigrate(&models.Offer{}, &models.User{}, &models.EnrollmentOffer{}); err != nil {
+          return err
+        }
+        // record the offers already applied so usage caps count them
+        return tx.Exec(`
+          INSERT INTO enrollment_offers (enrollment_id, offer_id, amount_cents)
+          SELECT e.id, e.offer_id, ROUND(p.price_cents * o.discount_pct / 100)
+          FROM enrollments e
+          JOIN offers o ON o.id = e.offer_id
+          JOIN plans p ON p.id = e.plan_id`).Error
+      },
+      Rollback: func(tx *gorm.DB) error {
+        if err := tx.Migrator().DropTable("enrollment_offers"); err != nil {
+          return err
+        }
+        if err := tx.Migrator().DropColumn(&models.User{}, "date_of_birth"); err != nil {
+          return err
+        }
+        for _, col := range []string{"name", "discount_type", "discount_cents", "new_students_only", "venue_ids", "sports",
+          "min_duration_days", "min_age", "max_age", "stackable", "priority", "max_uses"} {
+          if err := tx.Migrator().DropColumn(&models.Offer{}, col); err != nil {
+            return err
+          }
+        }
+        return nil
+      },
+    },
+    {
+      ID: "20261026_add_coupons",
+      Migrate: func(tx *gorm.DB) error {
+        return tx.AutoMigrate(&models.Coupon{}, &models.CouponRedemption{}, &models.Enrollment{}, &models.FeePayment{})
+      },
+      Rollback: func(tx *gorm.DB) error {
+        if err := tx.Migrator().DropTable("coupon_redemptions", "coupons"); err != nil {
+          return err
+        }
+        if err := tx.Migrator().DropColumn(&models.Enrollment{}, "coupon_id"); err != nil {
+          return err
+        }
+        for _, col := range []string{"coupon_id", "discount_cents"} {
+          if err := tx.Migrator().DropColumn(&models.FeePayment{}, col); err != nil {
+            return err
+          }
+        }
+        return nil
+      },
+    },
+    {
+      ID: "20261027_add_families",
+      Migrate: func(tx *gorm.DB) error {
+        return tx.AutoMigrate(&models.Family{}, &models.FamilyMember{}, &models.Enrollment{})
+      },
+      Rollback: func(tx *gorm.DB) error {
+        if err := tx.Migrator().DropTable("family_members", "families"); err != nil {
+          return err
+        }
+        return tx.Migrator().DropColumn(&models.Enrollment{}, "sibling_discount_cents")
+      },
+    },
+    {
+      ID: "20261028_add_subscriptions",
+      Migrate: func(tx *gorm.DB) error {
+        return tx.AutoMigrate(&models.Plan{}, &models.Enrollment{}, &models.FeePayment{}, &models.Charge{})
+      },
+      Rollback: func(tx *gorm.DB) error {
+        if err := tx.Migrator().DropTable("charges"); err != nil {
+          return err
+        }
+        if err := tx.Migrator().DropColumn(&models.FeePayment{}, "charge_id"); err != nil {
+          return err
+        }
+        for _, col := range []string{"auto_renew", "cancel_at_period_end", "canceled_at"} {
+          if err := tx.Migrator().DropColumn(&models.Enrollment{}, col); err != nil {
+            return err
+          }
+        }
+        for _, col := range []string{"billing_interval", "auto_renew"} {
+          if err := tx.Migrator().DropColumn(&models.Plan{}, col); err != nil {
+            return err
+          }
+        }
+        return nil
+      },
+    },
+    {
+      ID: "20261029_add_enrollment_freezes",
+      Migrate: func(tx *gorm.DB) error {
+        return tx.AutoMigrate(&models.Plan{}, &models.EnrollmentFreeze{})
+      },
+      Rollback: func(tx *gorm.DB) error {
+        if err := tx.Migrator().DropTable("enrollment_freezes"); err != nil {
+          return err
+        }
+        return tx.Migrator().DropColumn(&models.Plan{}, "max_freeze_days_per_year")
+      },
+    },
+    {
+      ID: "20261030_add_plan_versions",
+      Migrate: func(tx *gorm.DB) error {
+        if err := tx.AutoMigrate(&models.Plan{}, &models.PlanVersion{}, &models.Enrollment{}); err != nil {
+          return err
+        }
+        // snapshot each plan's terms as version 1 and pin existing enrollments to it
+        if err := tx.Exec(`
+          INSERT INTO plan_versions (plan_id, version, name, description, price_cents, duration_days,
+                                     billing_interval, auto_renew, max_freeze_days_per_year, effective_from, created_at)
+          SELECT p.id, 1, p.name, p.description, p.price_cents, p.duration_days,
+                 p.billing_interval, p.auto_renew, p.max_freeze_days_per_year, now(), now()
+          FROM plans p
+          WHERE NOT EXISTS (SELECT 1 FROM plan_versions v WHERE v.plan_id = p.id)`).Error; err != nil {
+          return err
+        }
+        if err := tx.Exec(`
+          UPDATE plans p SET version = v.version, current_version_id = v.id
+          FROM plan_versions v
+          WHERE v.plan_id = p.id AND v.version = 1 AND p.current_version_id IS NULL`).Error; err != nil {
+          return err
+        }
+        return tx.Exec(`
+          UPDATE enrollments e SET plan_version_id = p.current_version_id
+          FROM plans p
+          WHERE p.id = e.plan_id AND e.plan_version_id IS NULL`).Error
+      },
+      Rollback: func(tx *gorm.DB) error {
+        if err := tx.Migrator().DropColumn(&models.Enrollment{}, "plan_version_id"); err != nil {
+          return err
+        }
+        for _, col := range []string{"version", "current_version_id"} {
+          if err := tx.Migrator().DropColumn(&models.Plan{}, col); err != nil {
+            return err
+          }
+        }
+        return tx.Migrator().DropTable("plan_versions")
+      },
+    },
+    {
+      ID: "20261031_add_import_jobs",
+      Migrate: func(tx *gorm.DB) error {
+        return tx.AutoMigrate(&models.User{}, &models.ImportJob{})
+      },
+      Rollback: func(tx *gorm.DB) error {
+        if err := tx.Migrator().DropTable("import_jobs"); err != nil {
+          return err
+        }
+        return tx.Migrator().DropColumn(&models.User{}, "phone")
+      },
+    },
+    {
+      ID: "20261101_add_referrals",
+      Migrate: func(tx *gorm.DB) error {
+        if err := tx.Migrator().AddColumn(&models.User{}, "ReferralCode"); err != nil {
+          return err
+        }
+        if err := tx.Exec(`UPDATE users SET referral_code = upper(substr(md5(random()::text || id::text), 1, 8))
+          WHERE referral_code IS NULL OR referral_code = ''`).Error; err != nil {
+          return err
+        }
+        if err := tx.Migrator().CreateIndex(&models.User{}, "ReferralCode"); err != nil {
+          return err
+        }
+        return tx.AutoMigrate(&models.Referral{}, &models.WalletEntry{}, &models.Charge{})
+      },
+      Rollback: func(tx *gorm.DB) error {
+        if err := tx.Migrator().DropTable("wallet_entries", "referrals"); err != nil {
+          return err
+        }
+        if err := tx.Migrator().DropColumn(&models.Charge{}, "credit_cents"); err != nil {
+          return err
+        }
+        return tx.Migrator().DropColumn(&models.User{}, "referral_code")
+      },
+    },
+    {
+      ID: "20261102_add_tax_rates",
+      Migrate: func(tx *gorm.DB) error {
+        if err := tx.AutoMigrate(&models.TaxRate{}, &models.Plan{}, &models.PlanVersion{},
+          &models.Enrollment{}, &models.Charge{}, &models.FeePayment{}); err != nil {
+          return err
+        }
+        // amounts so far were priced at the default rate
+        for _, q := range []string{
+          `UPDATE enrollments SET tax_pct = ?, tax_cents = round(price_cents * ? / (100 + ?)),
+             taxable_cents = price_cents - round(price_cents * ? / (100 + ?))
+           WHERE plan_id IS NOT NULL AND taxable_cents = 0`,
+          `UPDATE charges SET tax_pct = ?, tax_cents = round(amount_cents * ? / (100 + ?)),
+             taxable_cents = amount_cents - round(amount_cents * ? / (100 + ?))
+           WHERE taxable_cents = 0`,
+          `UPDATE fee_payments SET tax_pct = ?, tax_cents = round(amount_cents * ? / (100 + ?)),
+             taxable_cents = amount_cents - round(amount_cents * ? / (100 + ?))
+           WHERE taxable_cents = 0`,
+        } {
+          pct := cfg.Pricing.TaxPct
+          if err := tx.Exec(q, pct, pct, pct, pct, pct).Error; err != nil {
+            return err
+          }
+        }
+        return nil
+      },
+      Rollback: func(tx *gorm.DB) error {
+        for _, m := range []interface{}{&models.Enrollment{}, &models.Charge{}, &models.FeePayment{}} {
+          for _, col := range []string{"tax_code", "tax_pct", "taxable_cents", "tax_cents"} {
+            if err := tx.Migrator().DropColumn(m, col); err != nil {
+              return err
+            }
+          }
+        }
+        for _, m := range []interface{}{&models.Plan{}, &models.PlanVersion{}} {
+          for _, col := range []string{"tax_code", "tax_inclusive"} {
+            if err := tx.Migrator().DropColumn(m, col); err != nil {
+              return err
+            }
+          }
+        }
+        return tx.Migrator().DropTable("tax_rates")
+      },
+    },
+    {
+      ID: "20261103_add_invoices",
+      Migrate: func(tx *gorm.DB) error {
+        return tx.AutoMigrate(&models.Venue{}, &models.FeePayment{}, &models.Invoice{},
+          &models.InvoiceLine{}, &models.PaymentAllocation{}, &models.InvoiceSequence{})
+      },
+      Rollback: func(tx *gorm.DB) error {
+        if err := tx.Migrator().DropTable("payment_allocations", "invoice_lines", "invoices", "invoice_sequences"); err != nil {
+          return err
+        }
+        if err := tx.Migrator().DropColumn(&models.FeePayment{}, "unallocated_cents"); err != nil {
+          return err
+        }
+        return tx.Migrator().DropColumn(&models.Venue{}, "invoice_prefix")
+      },
+    },
+    {
+      ID: "20261104_add_payment_orders",
+      Migrate: func(tx *gorm.DB) error {
+        return tx.AutoMigrate(&models.PaymentOrder{})
+      },
+      Rollback: func(tx *gorm.DB) error {
+        return tx.Migrator().DropTable("payment_orders")
+      },
+    },
+    {
+      ID: "20261105_add_refunds",
+      Migrate: func(tx *gorm.DB) error {
+        return tx.AutoMigrate(&models.FeePayment{}, &models.Refund{})
+      },
+      Rollback: func(tx *gorm.DB) error {
+        if err := tx.Migrator().DropTable("refunds"); err != nil {
+          return err
+        }
+        return tx.Migrator().DropColumn(&models.FeePayment{}, "refunded_cents")
+      },
+    },
+    {
+      ID: "20261106_add_idempotency_keys",
+      Migrate: func(tx *gorm.DB) error {
+        return tx.AutoMigrate(&models.IdempotencyKey{})
+      },
+      Rollback: func(tx *gorm.DB) error {
+        return tx.Migrator().DropTable("idempotency_keys")
+      },
+    },
+    {
+      ID: "20261107_make_payments_append_only",
+      Migrate: func(tx *gorm.DB) error {
+        if err := tx.AutoMigrate(&models.FeePayment{}, &models.InvestmentTransaction{}); err != nil {
+          return err
+        }
+        // Only the bookkeeping of invoice allocation and refunds may change
+        // on a payment; everything else is fixed once written.
+        return tx.Exec(`
+          CREATE OR REPLACE FUNCTION fee_payments_append_only() RETURNS trigger AS $$
+          BEGIN
+            IF TG_OP = 'DELETE' THEN
+              RAISE EXCEPTION 'fee payments cannot be deleted; reverse them instead';
+            END IF;
+            IF to_jsonb(NEW) - 'unallocated_cents' - 'refunded_cents'
+               IS DISTINCT FROM to_jsonb(OLD) - 'unallocated_cents' - 'refunded_cents' THEN
+              RAISE EXCEPTION 'fee payments cannot be changed; reverse them instead';
+            END IF;
+            RETURN NEW;
+          END $$ LANGUAGE plpgsql;
+          CREATE TRIGGER fee_payments_append_only BEFORE UPDATE OR DELETE ON fee_payments
+            FOR EACH ROW EXECUTE FUNCTION fee_payments_append_only();
+
+          CREATE OR REPLACE FUNCTION investment_transactions_append_only() RETURNS trigger AS $$
+          BEGIN
+            RAISE EXCEPTION 'investment transactions cannot be changed or deleted; reverse them instead';
+          END $$ LANGUAGE plpgsql;
+          CREATE TRIGGER investment_transactions_append_only BEFORE UPDATE OR DELETE ON investment_transactions
+            FOR EACH ROW EXECUTE FUNCTION investment_transactions_append_only();`).Error
+      },
+      Rollback: func(tx *gorm.DB) error {
+        if err := tx.Exec(`
+          DROP TRIGGER IF EXISTS investment_transactions_append_only ON investment_transactions;
+          DROP FUNCTION IF EXISTS investment_transactions_append_only();
+          DROP TRIGGER IF EXISTS fee_payments_append_only ON fee_payments;
+          DROP FUNCTION IF EXISTS fee_payments_append_only();`).Error; err != nil {
+          return err
+        }
+        for _, m := range []interface{}{&models.FeePayment{}, &models.InvestmentTransaction{}} {
+          for _, col := range []string{"reversal_of_id", "reason"} {
+            if err := tx.Migrator().DropColumn(m, col); err != nil {
+              return err
+            }
+          }
+        }
+        return nil
+      },
+    },
+    {
+      ID: "20261108_add_ledger",
+      Migrate: func(tx *gorm.DB) error {
+        if err := tx.AutoMigrate(&models.Expense{}, &models.Account{}, &models.JournalEntry{}, &models.JournalLine{}); err != nil {
+          return err
+        }
+        if err := tx.Exec(`
+          INSERT INTO accounts (code, name, type, system, created_at) VALUES
+            ('1000', 'Cash and bank', 'asset', TRUE, now()),
+            ('2100', 'GST payable', 'liability', TRUE, now()),
+            ('3000', 'Owner capital', 'equity', TRUE, now()),
+            ('4000', 'Fee income', 'income', TRUE, now()),
+            ('5000', 'Operating expenses', 'expense', TRUE, now())
+          ON CONFLICT (code) DO NOTHING`).Error; err != nil {
+          return err
+        }
+        // post the history so the ledger agrees with the existing records;
+        // postings are signed, debits positive
+        postings := `
+          SELECT 'fee_payment' AS source_type, p.id AS source_id, b.venue_id, p.paid_on AS entry_date,
+                 CASE WHEN p.reversal_of_id IS NULL THEN 'Fee payment' ELSE 'Fee payment reversal: ' || p.reason END AS description,
+                 ARRAY['1000', '4000', '2100'] AS codes,
+                 ARRAY[p.amount_cents, -(p.amount_cents - p.tax_cents), -p.tax_cents] AS cents
+          FROM fee_payments p
+          JOIN enrollments e ON e.id = p.enrollment_id
+          JOIN batches b ON b.id = e.batch_id
+          UNION ALL
+          SELECT 'refund', r.id, b.venue_id, r.refunded_on, 'Refund: ' || r.reason,
+                 ARRAY['4000', '2100', '1000'],
+                 ARRAY[r.amount_cents - r.tax_cents, r.tax_cents, -r.amount_cents]
+          FROM refunds r
+          JOIN enrollments e ON e.id = r.enrollment_id
+          JOIN batches b ON b.id = e.batch_id
+          WHERE r.status = 'processed'
+          UNION ALL
+          SELECT 'expense', x.id, x.venue_id, x.incurred_on, 'Expense: ' || x.description,
+                 ARRAY['5000', '1000'], ARRAY[x.amount_cents, -x.amount_cents]
+          FROM expenses x
+          UNION ALL
+          SELECT 'investment_transaction', t.id, i.venue_id, t.txn_date,
+                 'Investment ' || lower(t.type) || CASE WHEN t.reversal_of_id IS NULL THEN '' ELSE ' reversal: ' || t.reason END,
+                 ARRAY['1000', '3000'],
+                 ARRAY[s.cents, -s.cents]
+          FROM investment_transactions t
+          JOIN investments i ON i.id = t.investment_id
+          CROSS JOIN LATERAL (SELECT CASE WHEN lower(t.type) = 'sell' THEN -1 ELSE 1 END * t.units * t.price_cents AS cents) s`
+        if err := tx.Exec(`
+          WITH f AS (` + postings + `)
+          INSERT INTO journal_entries (venue_id, entry_date, description, source_type, source_id, created_at)
+          SELECT venue_id, entry_date::date, description, source_type, source_id, now()
+          FROM f`).Error; err != nil {
+          return err
+        }
+        return tx.Exec(`
+          WITH f AS (` + postings + `)
+          INSERT INTO journal_lines (journal_entry_id, account_id, debit_cents, credit_cents, memo)
+          SELECT j.id, a.id, GREATEST(p.cents, 0), GREATEST(-p.cents, 0), ''
+          FROM f
+          CROSS JOIN LATERAL unnest(f.codes, f.cents) AS p(code, cents)
+          JOIN journal_entries j ON j.source_type = f.source_type AND j.source_id = f.source_id
+          JOIN accounts a ON a.code = p.code
+          WHERE p.cents <> 0`).Error
+      },
+      Rollback: func(tx *gorm.DB) error {
+        for _, t := range []string{"journal_lines", "journal_entries", "accounts"} {
+          if err := tx.Migrator().DropTable(t); err != nil {
+            return err
+          }
+        }
+        return tx.Migrator().DropColumn(&models.Expense{}, "venue_id")
+      },
+    },
+    {
+      ID: "20261109_add_receipts",
+      Migrate: func(tx *gorm.DB) error {
+        // payments made before receipts existed get none
+        return tx.AutoMigrate(&models.ReceiptSequence{}, &models.Receipt{})
+      },
+      Rollback: func(tx *gorm.DB) error {
+        return tx.Migrator().DropTable("receipts", "receipt_sequences")
+      },
+    },
+    {
+      ID: "20261110_add_installments",
+      Migrate: func(tx *gorm.DB) error {
+        // existing enrollments keep paying in full
+        return tx.AutoMigrate(&models.Plan{}, &models.PlanVersion{}, &models.Installment{})
+      },
+      Rollback: func(tx *gorm.DB) error {
+        if err := tx.Migrator().DropTable("installments"); err != nil {
+          return err
+        }
+        for _, m := range []interface{}{&models.Plan{}, &models.PlanVersion{}} {
+          if err := tx.Migrator().DropColumn(m, "installments"); err != nil {
+            return err
+          }
+        }
+        return nil
+      },
+    },
+    {
+      ID: "20261111_add_currencies",
+      Migrate: func(tx *gorm.DB) error {
+        if err := tx.AutoMigrate(&models.ExchangeRate{}); err != nil {
+          return err
+        }
+        tables := []string{
+          "venues", "plans", "plan_versions", "enrollments", "charges", "invoices",
+          "fee_payments", "refunds", "installments", "expenses", "investments",
+          "investment_transactions", "referrals", "wallet_entries", "journal_entries",
+        }
+        for _, t := range append(tables, "offers") {
+          if err := tx.Exec("ALTER TABLE " + t + " ADD COLUMN IF NOT EXISTS currency varchar(3)").Error; err != nil {
+            return err
+          }
+        }
+        // everything so far was in the base currency; money rows follow
+        // their venue, enrollment or payment so later edits stay consistent
+        base := cfg.Currency.Base
+        for _, q := range []string{
+          `UPDATE venues SET currency = @base WHERE currency IS NULL`,
+          `UPDATE plans SET currency = @base WHERE currency IS NULL`,
+          `UPDATE plan_versions SET currency = @base WHERE currency IS NULL`,
+          `UPDATE enrollments e SET currency = v.currency FROM batches b JOIN venues v ON v.id = b.venue_id
+           WHERE b.id = e.batch_id AND e.currency IS NULL`,
+          `UPDATE charges c SET currency = e.currency FROM enrollments e WHERE e.id = c.enrollment_id AND c.currency IS NULL`,
+          `UPDATE invoices i SET currency = e.currency FROM enrollments e WHERE e.id = i.enrollment_id AND i.currency IS NULL`,
+          `UPDATE installments i SET currency = e.currency FROM enrollments e WHERE e.id = i.enrollment_id AND i.currency IS NULL`,
+          `ALTER TABLE fee_payments DISABLE TRIGGER fee_payments_append_only`,
+          `UPDATE fee_payments p SET currency = e.currency FROM enrollments e WHERE e.id = p.enrollment_id AND p.currency IS NULL`,
+          `ALTER TABLE fee_payments ENABLE TRIGGER fee_payments_append_only`,
+          `UPDATE refunds r SET currency = p.currency FROM fee_payments p WHERE p.id = r.fee_payment_id AND r.currency IS NULL`,
+          `UPDATE expenses x SET currency = COALESCE((SELECT v.currency FROM venues v WHERE v.id = x.venue_id), @base)
+           WHERE x.currency IS NULL`,
+          `UPDATE investments i SET currency = v.currency FROM venues v WHERE v.id = i.venue_id AND i.currency IS NULL`,
+          `ALTER TABLE investment_transactions DISABLE TRIGGER investment_transactions_append_only`,
+          `UPDATE investment_transactions t SET currency = i.currency FROM investments i
+           WHERE i.id = t.investment_id AND t.currency IS NULL`,
+          `ALTER TABLE investment_transactions ENABLE TRIGGER investment_transactions_append_only`,
+          `UPDATE referrals SET currency = @base WHERE currency IS NULL`,
+          `UPDATE wallet_entries SET currency = @base WHERE currency IS NULL`,
+          `UPDATE journal_entries j SET currency = COALESCE(
+             CASE j.source_type
+               WHEN 'fee_payment' THEN (SELECT currency FROM fee_payments WHERE id = j.source_id)
+               WHEN 'refund' THEN (SELECT currency FROM refunds WHERE id = j.source_id)
+               WHEN 'expense' THEN (SELECT currency FROM expenses WHERE id = j.source_id)
+               WHEN 'investment_transaction' THEN (SELECT currency FROM investment_transactions WHERE id = j.source_id)
+             END, @base)
+           WHERE j.currency IS NULL`,
+          `UPDATE offers SET currency = @base WHERE discount_type = 'fixed' AND currency IS NULL`,
+        } {
+          if err := tx.Exec(q, sql.Named("base", base)).Error; err != nil {
+            return err
+          }
+        }
+        for _, t := range tables {
+          if err := tx.Exec("ALTER TABLE " + t + " ALTER COLUMN currency SET NOT NULL").Error; err != nil {
+            return err
+          }
+        }
+        return nil
+      },
+      Rollback: func(tx *gorm.DB) error {
+        for _, t := range []string{
+          "venues", "plans", "plan_versions", "enrollments", "charges", "invoices",
+          "fee_payments", "refunds", "installments", "expenses", "investments",
+          "investment_transactions", "referrals", "wallet_entries", "journal_entries", "offers",
+        } {
+          if err := tx.Exec("ALTER TABLE " + t + " DROP COLUMN IF EXISTS currency").Error; err != nil {
+            return err
+          }
+        }
+        return tx.Migrator().DropTable("exchange_rates")
+      },
+    },
+    {
+      ID: "20261112_unique_batch_rollover",
+      Migrate: func(tx *gorm.DB) error {
+        // a batch is cloned into the next term at most once
+        return tx.Exec(`
+          DROP INDEX IF EXISTS idx_batches_rolled_from_id;
+          CREATE UNIQUE INDEX idx_batches_rolled_from_id ON batches (rolled_from_id);`).Error
+      },
+      Rollback: func(tx *gorm.DB) error {
+        return tx.Exec(`
+          DROP INDEX IF EXISTS idx_batches_rolled_from_id;
+          CREATE INDEX idx_batches_rolled_from_id ON batches (rolled_from_id);`).Error
+      },
+    },
+    {
+      ID: "20261113_add_quote_uses",
+      Migrate: func(tx *gorm.DB) error {
+        return tx.AutoMigrate(&models.QuoteUse{}, &models.PaymentOrder{})
+      },
+      Rollback: func(tx *gorm.DB) error {
+        if err := tx.Migrator().DropColumn(&models.PaymentOrder{}, "quote_nonce"); err != nil {
+          return err
+        }
+        return tx.Migrator().DropTable("quote_uses")
+      },
+    },
+    {
+      ID: "20261114_index_refund_gateway_ref",
+      Migrate: func(tx *gorm.DB) error {
+        if tx.Migrator().HasIndex(&models.Refund{}, "GatewayRef") {
+          return nil
+        }
+        return tx.Migrator().CreateIndex(&models.Refund{}, "GatewayRef")
+      },
+      Rollback: func(tx *gorm.DB) error {
+        return tx.Migrator().DropIndex(&models.Refund{}, "GatewayRef")
+      },
+    },
   }
 
   // 4. Run migrations
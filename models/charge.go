@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Charge is the amount due for one billing cycle of a renewing enrollment.
+type Charge struct {
+    ID           uuid.UUID   `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    EnrollmentID uuid.UUID   `gorm:"type:uuid;not null;uniqueIndex:idx_charge_period" json:"enrollment_id"`
+    Enrollment   *Enrollment `json:"enrollment,omitempty"`
+    PeriodStart  time.Time   `gorm:"not null;uniqueIndex:idx_charge_period" json:"period_start"`
+    PeriodEnd    time.Time   `gorm:"not null" json:"period_end"`
+    AmountCents  int         `json:"amount_cents"`                    // due after CreditCents
+    CreditCents  int         `json:"credit_cents"`                    // renewal credit taken off
+    Currency     string      `gorm:"size:3;not null" json:"currency"` // the enrollment's
+    TaxBreakdown             // tax included in AmountCents
+    DueOn        time.Time   `gorm:"not null;index" json:"due_on"`
+    Status       string      `gorm:"not null;default:due;index" json:"status"` // "due","paid","overdue","void"
+    FeePaymentID *uuid.UUID  `gorm:"type:uuid;index" json:"fee_payment_id,omitempty"`
+    PaidAt       *time.Time  `json:"paid_at,omitempty"`
+    CreatedAt    time.Time   `json:"created_at"`
+}
+
+// Charge statuses.
+const (
+    ChargeDue     = "due"
+    ChargePaid    = "paid"
+    ChargeOverdue = "overdue"
+    ChargeVoid    = "void"
+)
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PaymentOrder is an online payment started through a payment gateway. Its
+// fee payment is recorded once the gateway confirms the money was captured.
+type PaymentOrder struct {
+    ID            uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id"`
+    Provider      string     `gorm:"not null;uniqueIndex:idx_payment_order_ref" json:"provider"`
+    OrderRef      string     `gorm:"not null;uniqueIndex:idx_payment_order_ref" json:"order_ref"` // the gateway's order ID
+    EnrollmentID  uuid.UUID  `gorm:"type:uuid;not null;index" json:"enrollment_id"`
+    StudentID     uuid.UUID  `gorm:"type:uuid;not null" json:"student_id"`
+    ChargeID      *uuid.UUID `gorm:"type:uuid" json:"charge_id,omitempty"`
+    CouponID      *uuid.UUID `gorm:"type:uuid" json:"coupon_id,omitempty"`
+    QuoteNonce    *uuid.UUID `gorm:"type:uuid" json:"-"` // quote the amount was checked against
+    DiscountCents int        `json:"discount_cents"`
+    AmountCents   int        `json:"amount_cents"`
+    Currency      string     `json:"currency"`
+    TaxBreakdown             // tax included in AmountCents
+    Status        string     `gorm:"not null;default:created;index" json:"status"` // "created","paid","failed"
+    PaymentRef    string     `json:"payment_ref,omitempty"`                        // the gateway's payment ID
+    FeePaymentID  *uuid.UUID `gorm:"type:uuid;uniqueIndex" json:"fee_payment_id,omitempty"`
+    FailureReason string     `json:"failure_reason,omitempty"`
+    CreatedAt     time.Time  `json:"created_at"`
+    UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Payment order statuses.
+const (
+    OrderCreated = "created"
+    OrderPaid    = "paid"
+    OrderFailed  = "failed"
+)
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Coupon is a promo code, like SUMMER25, that grants an Offer.
+type Coupon struct {
+    ID             uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    Code           string     `gorm:"not null;uniqueIndex" json:"code"` // stored upper-case
+    OfferID        uuid.UUID  `gorm:"type:uuid;not null;index" json:"offer_id"`
+    Offer          *Offer     `json:"offer,omitempty"`
+    MaxRedemptions int        `json:"max_redemptions"` // 0 means unlimited
+    MaxPerUser     int        `json:"max_per_user"`    // 0 means unlimited
+    ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+    Disabled       bool       `json:"disabled"`
+    CreatedAt      time.Time  `json:"created_at"`
+}
+
+// CouponRedemption records a coupon used by a student on an enrollment, and
+// on the payment it was applied to if it was redeemed at payment time.
+type CouponRedemption struct {
+    ID           uuid.UUID   `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    CouponID     uuid.UUID   `gorm:"type:uuid;not null;uniqueIndex:idx_coupon_enrollment" json:"coupon_id"`
+    UserID       uuid.UUID   `gorm:"type:uuid;not null;index" json:"user_id"`
+    EnrollmentID uuid.UUID   `gorm:"type:uuid;not null;uniqueIndex:idx_coupon_enrollment" json:"enrollment_id"`
+    Enrollment   *Enrollment `json:"enrollment,omitempty"`
+    FeePaymentID *uuid.UUID  `gorm:"type:uuid;index" json:"fee_payment_id,omitempty"`
+    FeePayment   *FeePayment `json:"fee_payment,omitempty"`
+    RedeemedAt   time.Time   `json:"redeemed_at"`
+}
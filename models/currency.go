@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExchangeRate is the price of one unit of Base in Quote from EffectiveOn
+// until the next rate for the pair takes effect.
+type ExchangeRate struct {
+    ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    Base        string    `gorm:"size:3;not null;uniqueIndex:idx_exchange_rate" json:"base"`
+    Quote       string    `gorm:"size:3;not null;uniqueIndex:idx_exchange_rate" json:"quote"`
+    Rate        float64   `gorm:"type:numeric(20,10);not null" json:"rate"`
+    EffectiveOn time.Time `gorm:"type:date;not null;uniqueIndex:idx_exchange_rate" json:"effective_on"`
+    CreatedAt   time.Time `json:"created_at"`
+}
@@ -8,20 +8,106 @@ import (
 
 // Enrollment ties a Student (User) to a Batch.
 type Enrollment struct {
-    ID         uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
-    StudentID  uuid.UUID `gorm:"type:uuid;not null;index" json:"student_id"`
-    Student    User      `json:"student"`
-    BatchID    uuid.UUID `gorm:"type:uuid;not null;index" json:"batch_id"`
-    Batch      Batch     `json:"batch"`
-    EnrolledOn time.Time `json:"enrolled_on"`
-    Status     string    `json:"status"` // "active","completed","dropped"
+    ID                   uuid.UUID          `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    StudentID            uuid.UUID          `gorm:"type:uuid;not null;index" json:"student_id"`
+    Student              User               `json:"student"`
+    BatchID              uuid.UUID          `gorm:"type:uuid;not null;index" json:"batch_id"`
+    Batch                Batch              `json:"batch"`
+    EnrolledOn           time.Time          `json:"enrolled_on"`
+    Status               string             `gorm:"not null;default:pending_payment;index" json:"status"` // see EnrollmentStatuses; changed only through transitions
+    RenewalOptIn         bool               `json:"renewal_opt_in"`                                       // continue into next term's batch on rollover
+    Type                 string             `gorm:"not null;default:regular;index" json:"type"`           // "regular","trial"
+    TrialSessions        int                `json:"trial_sessions,omitempty"`                             // sessions a trial may attend
+    TrialEndsOn          *time.Time         `json:"trial_ends_on,omitempty"`
+    PlanID               *uuid.UUID         `gorm:"type:uuid;index" json:"plan_id,omitempty"`
+    Plan                 *Plan              `json:"plan,omitempty"`
+    PlanVersionID        *uuid.UUID         `gorm:"type:uuid;index" json:"plan_version_id,omitempty" binding:"-"` // plan terms the enrollment bought
+    PlanVersion          *PlanVersion       `json:"plan_version,omitempty" binding:"-"`
+    OfferID              *uuid.UUID         `gorm:"type:uuid;index" json:"offer_id,omitempty"`
+    Offer                *Offer             `json:"offer,omitempty"`
+    ValidUntil           *time.Time         `gorm:"index" json:"valid_until,omitempty"`                       // EnrolledOn + Plan.DurationDays
+    ConvertedFromID      *uuid.UUID         `gorm:"type:uuid;uniqueIndex" json:"converted_from_id,omitempty"` // trial this paid enrollment was converted from
+    PriceCents           int                `json:"price_cents"`                                              // amount due after offers and tax
+    Currency             string             `gorm:"size:3;not null" json:"currency" binding:"-"`              // the venue's; every amount owed or paid for the enrollment is in it
+    SiblingDiscountCents int                `json:"sibling_discount_cents"`                                   // included in the discount behind PriceCents
+    QuoteID              string             `gorm:"-" json:"quote_id,omitempty"`                              // signed quote to honor on create
+    QuoteNonce           *uuid.UUID         `gorm:"-" json:"-"`                                               // of QuoteID once verified, recorded as used
+    AppliedOffers        []EnrollmentOffer  `gorm:"foreignKey:EnrollmentID" json:"applied_offers,omitempty" binding:"-"`
+    CouponID             *uuid.UUID         `gorm:"type:uuid;index" json:"coupon_id,omitempty" binding:"-"` // coupon redeemed when enrolling
+    CouponCode           string             `gorm:"-" json:"coupon_code,omitempty"`
+    AutoRenew            bool               `json:"auto_renew" binding:"-"`           // taken from the plan; renews at the end of each cycle
+    CancelAtPeriodEnd    bool               `json:"cancel_at_period_end" binding:"-"` // stop renewing once ValidUntil passes
+    CanceledAt           *time.Time         `json:"canceled_at,omitempty" binding:"-"`
+    Freezes              []EnrollmentFreeze `gorm:"foreignKey:EnrollmentID" json:"freezes,omitempty" binding:"-"`
+    TaxBreakdown                            // tax included in PriceCents
 }
 
-// Attendance per enrollment per date.
-type Attendance struct {
+// EnrollmentOffer records an offer applied to an enrollment's price. Offer
+// usage caps count these rows.
+type EnrollmentOffer struct {
+    ID           uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    EnrollmentID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_enrollment_offer" json:"enrollment_id"`
+    OfferID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_enrollment_offer;index" json:"offer_id"`
+    AmountCents  int       `json:"amount_cents"`
+}
+
+// EnrollmentFreeze is a period, both days included, in which an enrollment
+// is on hold. Its days are added to the enrollment's validity.
+type EnrollmentFreeze struct {
     ID           uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
     EnrollmentID uuid.UUID  `gorm:"type:uuid;not null;index" json:"enrollment_id"`
-    Enrollment   Enrollment `json:"enrollment"`
-    Date         time.Time  `json:"date"`
-    Status       string     `json:"status"` // "present","absent"
+    StartDate    time.Time  `gorm:"not null" json:"start_date"`
+    EndDate      time.Time  `gorm:"not null" json:"end_date"`
+    Days         int        `json:"days"`
+    Reason       string     `json:"reason"`
+    CreatedByID  *uuid.UUID `gorm:"type:uuid" json:"created_by_id,omitempty"`
+    CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Enrollment types.
+const (
+    EnrollmentRegular = "regular"
+    EnrollmentTrial   = "trial"
+)
+
+// Enrollment statuses.
+const (
+    EnrollmentPendingPayment = "pending_payment"
+    EnrollmentActive         = "active"
+    EnrollmentPaused         = "paused"
+    EnrollmentCompleted      = "completed"
+    EnrollmentDropped        = "dropped"
+    EnrollmentWaitlisted     = "waitlisted"
+)
+
+// EnrollmentStatuses lists every status an enrollment can be in.
+var EnrollmentStatuses = []string{
+    EnrollmentPendingPayment, EnrollmentActive, EnrollmentPaused,
+    EnrollmentCompleted, EnrollmentDropped, EnrollmentWaitlisted,
+}
+
+// EnrollmentStatusHistory records every status change of an enrollment.
+type EnrollmentStatusHistory struct {
+    ID           uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    EnrollmentID uuid.UUID  `gorm:"type:uuid;not null;index" json:"enrollment_id"`
+    FromStatus   string     `json:"from_status"` // empty for the initial status
+    ToStatus     string     `gorm:"not null" json:"to_status"`
+    Reason       string     `gorm:"not null" json:"reason"`
+    ChangedByID  *uuid.UUID `gorm:"type:uuid;index" json:"changed_by_id,omitempty"` // nil for system changes
+    ChangedAt    time.Time  `gorm:"not null;index" json:"changed_at"`
+}
+
+// TableName keeps the history table singular.
+func (EnrollmentStatusHistory) TableName() string { return "enrollment_status_history" }
+
+// Attendance per enrollment per date.
+type Attendance struct {
+    ID             uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    EnrollmentID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"enrollment_id"`
+    Enrollment     Enrollment `json:"enrollment"`
+    Date           time.Time  `json:"date"`
+    Status         string     `json:"status"` // "present","absent"
+    Reason         string     `json:"reason,omitempty"`
+    BatchID        *uuid.UUID `gorm:"type:uuid;index" json:"batch_id,omitempty"`         // set when attended outside the enrolled batch
+    MakeupCreditID *uuid.UUID `gorm:"type:uuid;index" json:"makeup_credit_id,omitempty"` // credit redeemed by this session
 }
@@ -8,8 +8,10 @@ import (
 
 // Expense logged for operational costs.
 type Expense struct {
-    ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
-    Description string    `json:"description"`
-    AmountCents int       `json:"amount_cents"`
-    IncurredOn  time.Time `json:"incurred_on"`
-}
\ No newline at end of file
+    ID          uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    Description string     `json:"description"`
+    AmountCents int        `json:"amount_cents"`
+    Currency    string     `gorm:"size:3;not null" json:"currency"` // the venue's when VenueID is set
+    IncurredOn  time.Time  `json:"incurred_on"`
+    VenueID     *uuid.UUID `gorm:"type:uuid;index" json:"venue_id,omitempty"` // nil for academy-wide costs
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Family groups the children and guardians billed together.
+type Family struct {
+    ID        uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    Name      string         `gorm:"not null" json:"name" binding:"required"`
+    Members   []FamilyMember `json:"members,omitempty" binding:"-"`
+    CreatedAt time.Time      `json:"created_at"`
+}
+
+// FamilyMember puts a user in a family. A user belongs to at most one family.
+type FamilyMember struct {
+    ID       uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    FamilyID uuid.UUID `gorm:"type:uuid;not null;index" json:"family_id"`
+    UserID   uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"user_id"`
+    User     *User     `json:"user,omitempty"`
+    Role     string    `gorm:"not null;default:student" json:"role"` // "student","guardian"
+}
+
+// Family member roles.
+const (
+    FamilyStudent  = "student"
+    FamilyGuardian = "guardian"
+)
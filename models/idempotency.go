@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// IdempotencyKey remembers the response to a request sent with an
+// Idempotency-Key header so that a retry gets the same response instead of
+// repeating the request.
+type IdempotencyKey struct {
+    Scope       string    `gorm:"primaryKey" json:"scope"` // method and route the key was used on
+    Key         string    `gorm:"primaryKey" json:"key"`
+    RequestHash string    `gorm:"not null" json:"request_hash"`
+    Status      int       `json:"status"` // 0 while the first request is in progress
+    ContentType string    `json:"content_type"`
+    Body        []byte    `json:"-"`
+    CreatedAt   time.Time `json:"created_at"`
+    ExpiresAt   time.Time `gorm:"not null;index" json:"expires_at"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImportJob tracks a bulk CSV import. Small files are imported while the
+// request waits; larger ones run in the background and are polled.
+type ImportJob struct {
+    ID            uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id"`
+    Kind          string            `gorm:"not null" json:"kind"`                        // "enrollments"
+    Status        string            `gorm:"not null;default:queued;index" json:"status"` // "queued","running","completed","failed"
+    DryRun        bool              `json:"dry_run"`
+    TotalRows     int               `json:"total_rows"`
+    ProcessedRows int               `json:"processed_rows"` // imported so far; every row once a dry run is validated
+    CreatedRows   int               `json:"created_rows"`
+    RowErrors     []ImportRowError  `gorm:"type:jsonb;serializer:json" json:"row_errors"`
+    Rows          []ImportRowResult `gorm:"type:jsonb;serializer:json" json:"rows,omitempty"` // preview or outcome per row
+    Error         string            `json:"error,omitempty"`                                  // why the import as a whole failed
+    CreatedByID   *uuid.UUID        `gorm:"type:uuid" json:"created_by_id,omitempty"`
+    CreatedAt     time.Time         `json:"created_at"`
+    FinishedAt    *time.Time        `json:"finished_at,omitempty"`
+}
+
+// ImportRowError is a problem with one field of one CSV row. Row counts data
+// rows from 1; the header is not counted.
+type ImportRowError struct {
+    Row     int    `json:"row"`
+    Field   string `json:"field"`
+    Message string `json:"message"`
+}
+
+// ImportRowResult is what one CSV row creates.
+type ImportRowResult struct {
+    Row             int        `json:"row"`
+    Email           string     `json:"email"`
+    StudentID       *uuid.UUID `json:"student_id,omitempty"` // set for existing students, and once imported
+    NewStudent      bool       `json:"new_student"`
+    BatchID         uuid.UUID  `json:"batch_id"`
+    PlanID          *uuid.UUID `json:"plan_id,omitempty"`
+    EnrolledOn      time.Time  `json:"enrolled_on"`
+    PriceCents      int        `json:"price_cents"`
+    AmountPaidCents int        `json:"amount_paid_cents"`
+    Status          string     `json:"status"`
+    EnrollmentID    *uuid.UUID `json:"enrollment_id,omitempty"` // set once imported
+}
+
+// Import job kinds and statuses.
+const (
+    ImportEnrollments = "enrollments"
+
+    ImportQueued    = "queued"
+    ImportRunning   = "running"
+    ImportCompleted = "completed"
+    ImportFailed    = "failed"
+)
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Installment is one dated part of the price of an enrollment on a plan paid
+// in installments. Payments go to the oldest unpaid installment first.
+type Installment struct {
+    ID           uuid.UUID   `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id"`
+    EnrollmentID uuid.UUID   `gorm:"type:uuid;not null;uniqueIndex:idx_installment_seq" json:"enrollment_id"`
+    Enrollment   *Enrollment `json:"enrollment,omitempty"`
+    Seq          int         `gorm:"not null;uniqueIndex:idx_installment_seq" json:"seq"`
+    DueOn        time.Time   `gorm:"not null;index" json:"due_on"`
+    AmountCents  int         `json:"amount_cents"`
+    PaidCents    int         `json:"paid_cents"`
+    Currency     string      `gorm:"size:3;not null" json:"currency"`
+    Status       string      `gorm:"not null;default:due;index" json:"status"` // "due","paid","overdue"
+    PaidAt       *time.Time  `json:"paid_at,omitempty"`
+    CreatedAt    time.Time   `json:"created_at"`
+}
+
+// Installment statuses.
+const (
+    InstallmentDue     = "due"
+    InstallmentPaid    = "paid"
+    InstallmentOverdue = "overdue"
+)
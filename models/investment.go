@@ -15,10 +15,13 @@ type Investment struct {
     Investor      User      `json:"investor"`
     Units         int       `json:"units"`
     AvgPriceCents int       `json:"avg_price_cents"`
+    Currency      string    `gorm:"size:3;not null" json:"currency" binding:"-"` // the venue's
     CreatedAt     time.Time `json:"created_at"`
 }
 
-// InvestmentTransaction tracks buy/sell actions.
+// InvestmentTransaction tracks buy/sell actions. Transactions are
+// append-only: a mistaken one is cancelled by a reversal of the negated
+// units that refers back to it.
 type InvestmentTransaction struct {
     ID             uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
     InvestmentID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"investment_id"`
@@ -26,6 +29,9 @@ type InvestmentTransaction struct {
     Type           string     `json:"type"`
     Units          int        `json:"units"`
     PriceCents     int        `json:"price_cents"`
+    Currency       string     `gorm:"size:3;not null" json:"currency" binding:"-"` // the investment's
     TransactionRef string     `json:"transaction_ref"`
     TxnDate        time.Time  `json:"txn_date"`
-}
\ No newline at end of file
+    ReversalOfID   *uuid.UUID `gorm:"type:uuid;uniqueIndex" json:"reversal_of_id,omitempty" binding:"-"` // transaction this one reverses
+    Reason         string     `json:"reason,omitempty" binding:"-"`                                      // why it was reversed
+}
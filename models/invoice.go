@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Invoice is what a student owes for an enrollment or one of its renewals.
+// Numbers run without gaps per venue.
+type Invoice struct {
+    ID            uuid.UUID     `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id"`
+    VenueID       uuid.UUID     `gorm:"type:uuid;not null;uniqueIndex:idx_invoice_seq" json:"venue_id"`
+    Venue         *Venue        `json:"venue,omitempty"`
+    Seq           int           `gorm:"not null;uniqueIndex:idx_invoice_seq" json:"seq"`
+    Number        string        `gorm:"not null;index" json:"number"`
+    StudentID     uuid.UUID     `gorm:"type:uuid;not null;index" json:"student_id"`
+    Student       *User         `json:"student,omitempty"`
+    EnrollmentID  uuid.UUID     `gorm:"type:uuid;not null;index" json:"enrollment_id"`
+    ChargeID      *uuid.UUID    `gorm:"type:uuid;uniqueIndex" json:"charge_id,omitempty"` // renewal charge invoiced
+    IssuedOn      time.Time     `gorm:"not null" json:"issued_on"`
+    DueOn         time.Time     `gorm:"not null" json:"due_on"`
+    Status        string        `gorm:"not null;default:open;index" json:"status"` // "open","partially_paid","paid","void"
+    Currency      string        `gorm:"size:3;not null" json:"currency"`
+    Lines         []InvoiceLine `gorm:"foreignKey:InvoiceID" json:"lines,omitempty"`
+    SubtotalCents int           `json:"subtotal_cents"` // sum of the lines
+    TaxInclusive  bool          `json:"tax_inclusive"`  // the lines already include tax
+    TaxBreakdown
+    TotalCents  int                 `json:"total_cents"`
+    PaidCents   int                 `json:"paid_cents"`
+    Allocations []PaymentAllocation `gorm:"foreignKey:InvoiceID" json:"allocations,omitempty"`
+    CreatedAt   time.Time           `json:"created_at"`
+}
+
+// InvoiceLine is one item on an invoice; discounts are negative.
+type InvoiceLine struct {
+    ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id"`
+    InvoiceID   uuid.UUID `gorm:"type:uuid;not null;index" json:"invoice_id"`
+    Position    int       `json:"position"`
+    Description string    `json:"description"`
+    AmountCents int       `json:"amount_cents"`
+}
+
+// PaymentAllocation is the part of a payment put towards an invoice.
+type PaymentAllocation struct {
+    ID           uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id"`
+    InvoiceID    uuid.UUID `gorm:"type:uuid;not null;index" json:"invoice_id"`
+    FeePaymentID uuid.UUID `gorm:"type:uuid;not null;index" json:"fee_payment_id"`
+    AmountCents  int       `json:"amount_cents"`
+    CreatedAt    time.Time `json:"created_at"`
+}
+
+// InvoiceSequence holds the last invoice number used at a venue.
+type InvoiceSequence struct {
+    VenueID uuid.UUID `gorm:"type:uuid;primaryKey"`
+    LastSeq int       `gorm:"not null"`
+}
+
+// Invoice statuses. A void invoice was replaced after its enrollment was
+// repriced and no longer counts as owed.
+const (
+    InvoiceOpen          = "open"
+    InvoicePartiallyPaid = "partially_paid"
+    InvoicePaid          = "paid"
+    InvoiceVoid          = "void"
+)
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Account is an account in the chart of accounts.
+type Account struct {
+    ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    Code      string    `gorm:"not null;uniqueIndex" json:"code"`
+    Name      string    `gorm:"not null" json:"name"`
+    Type      string    `gorm:"not null" json:"type"` // "asset","liability","equity","income","expense"
+    System    bool      `json:"system" binding:"-"`   // used by automatic postings
+    CreatedAt time.Time `json:"created_at"`
+}
+
+// Account types.
+const (
+    AccountAsset     = "asset"
+    AccountLiability = "liability"
+    AccountEquity    = "equity"
+    AccountIncome    = "income"
+    AccountExpense   = "expense"
+)
+
+// Codes of the system accounts that automatic postings use.
+const (
+    AccountCodeCash       = "1000"
+    AccountCodeGSTPayable = "2100"
+    AccountCodeCapital    = "3000"
+    AccountCodeFeeIncome  = "4000"
+    AccountCodeExpenses   = "5000"
+)
+
+// DebitNormal reports whether the account type's balance grows with debits.
+func DebitNormal(accountType string) bool {
+    return accountType == AccountAsset || accountType == AccountExpense
+}
+
+// JournalEntry is a balanced set of postings: its lines' debits equal its
+// credits. Entries are posted automatically for the records in Source, or
+// by hand.
+type JournalEntry struct {
+    ID          uuid.UUID     `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    VenueID     *uuid.UUID    `gorm:"type:uuid;index" json:"venue_id,omitempty"`
+    EntryDate   time.Time     `gorm:"type:date;not null;index" json:"entry_date"`
+    Description string        `gorm:"not null" json:"description"`
+    Currency    string        `gorm:"size:3;not null" json:"currency"` // of every line
+    SourceType  string        `gorm:"not null;index:idx_journal_source" json:"source_type" binding:"-"`
+    SourceID    *uuid.UUID    `gorm:"type:uuid;index:idx_journal_source" json:"source_id,omitempty" binding:"-"`
+    Lines       []JournalLine `gorm:"constraint:OnDelete:CASCADE" json:"lines"`
+    CreatedAt   time.Time     `json:"created_at"`
+}
+
+// Journal entry sources.
+const (
+    SourceManual     = "manual"
+    SourceFeePayment = "fee_payment"
+    SourceRefund     = "refund"
+    SourceExpense    = "expense"
+    SourceInvestment = "investment_transaction"
+)
+
+// JournalLine debits or credits one account.
+type JournalLine struct {
+    ID             uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    JournalEntryID uuid.UUID `gorm:"type:uuid;not null;index" json:"journal_entry_id" binding:"-"`
+    AccountID      uuid.UUID `gorm:"type:uuid;not null;index" json:"account_id"`
+    Account        *Account  `json:"account,omitempty" binding:"-"`
+    DebitCents     int       `json:"debit_cents"`
+    CreditCents    int       `json:"credit_cents"`
+    Memo           string    `json:"memo,omitempty"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MakeupCredit is issued for an excused absence and can be booked into a
+// session of another batch of the same sport.
+type MakeupCredit struct {
+    ID                 uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    EnrollmentID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"enrollment_id"`
+    Enrollment         Enrollment `json:"enrollment"`
+    AbsenceID          uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex" json:"absence_id"`
+    Reason             string     `json:"reason"`
+    IssuedOn           time.Time  `json:"issued_on"`
+    ExpiresOn          time.Time  `gorm:"index" json:"expires_on"`
+    Status             string     `gorm:"not null;default:available;index" json:"status"` // "available","booked","used","expired","revoked"
+    BookedBatchID      *uuid.UUID `gorm:"type:uuid;index" json:"booked_batch_id,omitempty"`
+    BookedBatch        *Batch     `json:"booked_batch,omitempty"`
+    BookedDate         *time.Time `json:"booked_date,omitempty"`
+    MakeupAttendanceID *uuid.UUID `gorm:"type:uuid" json:"makeup_attendance_id,omitempty"`
+}
+
+// Make-up credit statuses.
+const (
+    MakeupAvailable = "available"
+    MakeupBooked    = "booked"
+    MakeupUsed      = "used"
+    MakeupExpired   = "expired"
+    MakeupRevoked   = "revoked" // the absence was corrected and no longer earns it
+)
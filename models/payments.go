@@ -6,13 +6,27 @@ import (
 	"github.com/google/uuid"
 )
 
-// FeePayment records each fee transaction.
+// FeePayment records each fee transaction. Payments are append-only: a
+// mistaken one is cancelled by a reversal, a payment of the negated amount
+// that refers back to it.
 type FeePayment struct {
-    ID             uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
-    EnrollmentID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"enrollment_id"`
-    Enrollment     Enrollment `json:"enrollment"`
-    AmountCents    int        `json:"amount_cents"`
-    PaidOn         time.Time  `json:"paid_on"`
-    Method         string     `json:"method"`
-    TransactionRef string     `json:"transaction_ref"`
+    ID               uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    EnrollmentID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"enrollment_id"`
+    Enrollment       Enrollment `json:"enrollment"`
+    AmountCents      int        `json:"amount_cents"`
+    Currency         string     `gorm:"size:3;not null" json:"currency" binding:"-"` // the enrollment's
+    PaidOn           time.Time  `json:"paid_on"`
+    Method           string     `json:"method"`
+    TransactionRef   string     `json:"transaction_ref"`
+    QuoteID          string     `gorm:"-" json:"quote_id,omitempty"` // signed quote the amount must match
+    QuoteNonce       *uuid.UUID `gorm:"-" json:"-"`                  // of QuoteID once verified, recorded as used
+    CouponID         *uuid.UUID `gorm:"type:uuid;index" json:"coupon_id,omitempty" binding:"-"`
+    CouponCode       string     `gorm:"-" json:"coupon_code,omitempty"`             // coupon to take off AmountCents
+    DiscountCents    int        `json:"discount_cents"`                             // taken off by the coupon
+    ChargeID         *uuid.UUID `gorm:"type:uuid;index" json:"charge_id,omitempty"` // renewal charge this payment settles
+    TaxBreakdown                // tax included in AmountCents
+    UnallocatedCents int        `json:"unallocated_cents" binding:"-"`                                     // not yet put towards an invoice
+    RefundedCents    int        `json:"refunded_cents" binding:"-"`                                        // refunded or being refunded
+    ReversalOfID     *uuid.UUID `gorm:"type:uuid;uniqueIndex" json:"reversal_of_id,omitempty" binding:"-"` // payment this one reverses
+    Reason           string     `json:"reason,omitempty" binding:"-"`                                      // why it was reversed
 }
@@ -1,26 +1,100 @@
 package models
 
 import (
+	"reflect"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-// Plan defines pricing and duration for enrollment.
+// Plan defines pricing and duration for enrollment. Its terms are those of
+// its current version; every change of terms adds a new PlanVersion.
 type Plan struct {
-    ID           uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
-    Name         string    `json:"name"`
-    Description  string    `json:"description"`
-    PriceCents   int       `json:"price_cents"`
-    DurationDays int       `json:"duration_days"`
-    Offers       []*Offer  `gorm:"many2many:plan_offers;" json:"offers,omitempty"`
+    ID uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    PlanTerms
+    Version          int        `gorm:"not null;default:1" json:"version" binding:"-"`
+    CurrentVersionID *uuid.UUID `gorm:"type:uuid" json:"current_version_id,omitempty" binding:"-"`
+    Offers           []*Offer   `gorm:"many2many:plan_offers;" json:"offers,omitempty"`
+}
+
+// PlanTerms are the versioned fields of a plan.
+type PlanTerms struct {
+    Name                 string `json:"name"`
+    Description          string `json:"description"`
+    PriceCents           int    `json:"price_cents"`
+    Currency             string `gorm:"size:3;not null" json:"currency"` // of PriceCents; must be the venue's to enroll
+    DurationDays         int    `json:"duration_days"`
+    BillingInterval      string `json:"billing_interval"`         // "", "weekly", "monthly", "yearly"; empty for one-off plans
+    AutoRenew            bool   `json:"auto_renew"`               // enrollments renew at the end of each billing cycle
+    MaxFreezeDaysPerYear int    `json:"max_freeze_days_per_year"` // 0 means enrollments on the plan cannot be frozen
+    TaxCode              string `json:"tax_code"`                 // TaxRate code; empty for the default rate
+    TaxInclusive         bool   `json:"tax_inclusive"`            // PriceCents already includes tax
+    // Installments split the price of an enrollment into dated parts; empty
+    // when it is paid in full up front.
+    Installments []InstallmentTerm `gorm:"type:jsonb;serializer:json" json:"installments,omitempty"`
+}
+
+// Equal reports whether t and o are the same terms.
+func (t PlanTerms) Equal(o PlanTerms) bool {
+    if !slices.Equal(t.Installments, o.Installments) {
+        return false
+    }
+    t.Installments, o.Installments = nil, nil
+    return reflect.DeepEqual(t, o)
+}
+
+// InstallmentTerm is one part of a plan's price, due some days after an
+// enrollment starts.
+type InstallmentTerm struct {
+    Pct          float64 `json:"pct"`            // share of the price
+    DueAfterDays int     `json:"due_after_days"` // 0 for the start date
+}
+
+// PlanVersion is an immutable snapshot of a plan's terms. Enrollments keep
+// the version they bought.
+type PlanVersion struct {
+    ID      uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id"`
+    PlanID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_plan_version" json:"plan_id"`
+    Version int       `gorm:"not null;uniqueIndex:idx_plan_version" json:"version"`
+    PlanTerms
+    EffectiveFrom time.Time  `gorm:"not null" json:"effective_from"`
+    EffectiveTo   *time.Time `json:"effective_to,omitempty"` // nil for the current version
+    CreatedAt     time.Time  `json:"created_at"`
 }
 
 // Offer applies a discount and can be attached to multiple plans.
+// Eligibility fields left at their zero value do not restrict the offer.
 type Offer struct {
-    ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
-    DiscountPct float64   `json:"discount_pct"`
-    ValidFrom   time.Time `json:"valid_from"`
-    ValidTo     time.Time `json:"valid_to"`
-    Plans       []*Plan   `gorm:"many2many:plan_offers;" json:"plans,omitempty"`
-}
\ No newline at end of file
+    ID              uuid.UUID   `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    Name            string      `json:"name"`
+    DiscountType    string      `gorm:"not null;default:percent" json:"discount_type"` // "percent","fixed"
+    DiscountPct     float64     `json:"discount_pct"`                                  // for percent offers
+    DiscountCents   int         `json:"discount_cents"`                                // for fixed offers
+    Currency        string      `gorm:"size:3" json:"currency,omitempty"`              // of DiscountCents; fixed offers apply only to plans priced in it
+    ValidFrom       time.Time   `json:"valid_from"`
+    ValidTo         time.Time   `json:"valid_to"`
+    NewStudentsOnly bool        `json:"new_students_only"`                           // student has no earlier regular enrollment
+    VenueIDs        []uuid.UUID `gorm:"type:jsonb;serializer:json" json:"venue_ids"` // empty means every venue
+    Sports          []string    `gorm:"type:jsonb;serializer:json" json:"sports"`    // empty means every sport
+    MinDurationDays int         `json:"min_duration_days"`                           // minimum plan duration
+    MinAge          int         `json:"min_age"`
+    MaxAge          int         `json:"max_age"`
+    Stackable       bool        `json:"stackable"` // combines with other stackable offers; otherwise exclusive
+    Priority        int         `json:"priority"`  // higher applies first and wins ties
+    MaxUses         int         `json:"max_uses"`  // 0 means unlimited
+    Plans           []*Plan     `gorm:"many2many:plan_offers;" json:"plans,omitempty"`
+}
+
+// Plan billing intervals.
+const (
+    BillingWeekly  = "weekly"
+    BillingMonthly = "monthly"
+    BillingYearly  = "yearly"
+)
+
+// Offer discount types.
+const (
+    DiscountPercent = "percent"
+    DiscountFixed   = "fixed"
+)
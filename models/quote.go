@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// QuoteUse records a signed price quote being honored. A quote prices one
+// enrollment and one payment, so it can be used once for each.
+type QuoteUse struct {
+    QuoteNonce   uuid.UUID  `gorm:"type:uuid;primaryKey" json:"quote_nonce"`
+    Purpose      string     `gorm:"primaryKey" json:"purpose"` // "enrollment","payment"
+    EnrollmentID uuid.UUID  `gorm:"type:uuid;not null;index" json:"enrollment_id"`
+    FeePaymentID *uuid.UUID `gorm:"type:uuid" json:"fee_payment_id,omitempty"`
+    UsedAt       time.Time  `json:"used_at"`
+}
+
+// What a quote is used for.
+const (
+    QuoteForEnrollment = "enrollment"
+    QuoteForPayment    = "payment"
+)
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Receipt acknowledges a fee payment. It is numbered with the payment and
+// mailed to the student in the background; numbers run without gaps per
+// venue.
+type Receipt struct {
+    ID           uuid.UUID   `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id"`
+    FeePaymentID uuid.UUID   `gorm:"type:uuid;not null;uniqueIndex" json:"fee_payment_id"`
+    FeePayment   *FeePayment `json:"fee_payment,omitempty"`
+    VenueID      uuid.UUID   `gorm:"type:uuid;not null;uniqueIndex:idx_receipt_seq" json:"venue_id"`
+    Venue        *Venue      `json:"venue,omitempty"`
+    Seq          int         `gorm:"not null;uniqueIndex:idx_receipt_seq" json:"seq"`
+    Number       string      `gorm:"not null;index" json:"number"`
+    StudentID    uuid.UUID   `gorm:"type:uuid;not null;index" json:"student_id"`
+    Student      *User       `json:"student,omitempty"`
+    IssuedOn     time.Time   `gorm:"not null" json:"issued_on"`
+    PDF          []byte      `json:"-"` // rendered on first delivery or download
+    Text         string      `json:"-"`
+    Status       string      `gorm:"not null;default:pending;index" json:"status"` // "pending","sent","failed"
+    SentTo       string      `json:"sent_to,omitempty"`
+    SentAt       *time.Time  `json:"sent_at,omitempty"`
+    Attempts     int         `json:"attempts"`
+    LastError    string      `json:"last_error,omitempty"`
+    CreatedAt    time.Time   `json:"created_at"`
+}
+
+// ReceiptSequence holds the last receipt number used at a venue.
+type ReceiptSequence struct {
+    VenueID uuid.UUID `gorm:"type:uuid;primaryKey"`
+    LastSeq int       `gorm:"not null"`
+}
+
+// Receipt delivery statuses.
+const (
+    ReceiptPending = "pending"
+    ReceiptSent    = "sent"
+    ReceiptFailed  = "failed"
+)
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Referral records that one student brought in another. The reward terms
+// are fixed when the referral is recorded and paid out once the referred
+// student's first enrollment on a plan becomes active.
+type Referral struct {
+    ID           uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id"`
+    ReferrerID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"referrer_id"`
+    Referrer     *User      `json:"referrer,omitempty"`
+    ReferredID   uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex" json:"referred_id"` // a student is referred at most once
+    Referred     *User      `json:"referred,omitempty"`
+    Code         string     `gorm:"not null" json:"code"`
+    Status       string     `gorm:"not null;default:pending;index" json:"status"` // "pending","rewarded","rejected"
+    RejectReason string     `json:"reject_reason,omitempty"`
+    RewardType   string     `gorm:"not null" json:"reward_type"` // credit kind granted, see WalletEntry
+    RewardCents  int        `json:"reward_cents"`
+    Currency     string     `gorm:"size:3;not null" json:"currency"`
+    EnrollmentID *uuid.UUID `gorm:"type:uuid" json:"enrollment_id,omitempty"` // enrollment whose activation earned the reward
+    CreatedAt    time.Time  `json:"created_at"`
+    RewardedAt   *time.Time `json:"rewarded_at,omitempty"`
+}
+
+// Referral statuses.
+const (
+    ReferralPending  = "pending"
+    ReferralRewarded = "rewarded"
+    ReferralRejected = "rejected"
+)
+
+// WalletEntry credits (positive) or debits (negative) a user's balance of
+// one kind of credit.
+type WalletEntry struct {
+    ID          uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id"`
+    UserID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+    Kind        string     `gorm:"not null;index" json:"kind"` // "wallet","renewal"
+    AmountCents int        `json:"amount_cents"`
+    Currency    string     `gorm:"size:3;not null" json:"currency"`
+    Reason      string     `json:"reason"`
+    ReferralID  *uuid.UUID `gorm:"type:uuid;index" json:"referral_id,omitempty"`
+    ChargeID    *uuid.UUID `gorm:"type:uuid;index" json:"charge_id,omitempty"`
+    CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Credit kinds. Wallet credit stays until it is spent; renewal credit is
+// taken off the user's next renewal charges automatically.
+const (
+    CreditWallet  = "wallet"
+    CreditRenewal = "renewal"
+)
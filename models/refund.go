@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Refund returns all or part of a fee payment.
+type Refund struct {
+    ID            uuid.UUID   `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    FeePaymentID  uuid.UUID   `gorm:"type:uuid;not null;index" json:"fee_payment_id" binding:"-"`
+    FeePayment    *FeePayment `json:"fee_payment,omitempty" binding:"-"`
+    EnrollmentID  uuid.UUID   `gorm:"type:uuid;not null;index" json:"enrollment_id" binding:"-"`
+    AmountCents   int         `gorm:"not null" json:"amount_cents"`
+    Currency      string      `gorm:"size:3;not null" json:"currency" binding:"-"` // the payment's
+    TaxBreakdown              // tax included in AmountCents, at the payment's rate
+    Reason        string      `gorm:"not null" json:"reason"`
+    ApprovedByID  uuid.UUID   `gorm:"type:uuid;not null" json:"approved_by_id"`
+    Method        string      `gorm:"not null" json:"method" binding:"-"` // "manual", or the gateway that returned the money
+    GatewayRef    string      `gorm:"index" json:"gateway_ref,omitempty" binding:"-"`
+    Status        string      `gorm:"not null;index" json:"status" binding:"-"` // "pending","processed","failed"
+    FailureReason string      `json:"failure_reason,omitempty" binding:"-"`
+    RefundedOn    time.Time   `gorm:"not null" json:"refunded_on"`
+    CreatedAt     time.Time   `json:"created_at"`
+}
+
+// Refund statuses. A pending refund has been accepted by the gateway but
+// not yet paid out; its amount already counts against the payment, but it is
+// posted to the ledger only once processed.
+const (
+    RefundPending   = "pending"
+    RefundProcessed = "processed"
+    RefundFailed    = "failed"
+)
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaxRate is a tax percentage that plans refer to by code, e.g. "coaching"
+// at 18% GST and "merchandise" at another rate.
+type TaxRate struct {
+    ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    Code      string    `gorm:"uniqueIndex;not null;<-:create" json:"code"` // fixed once created
+    Name      string    `json:"name"`
+    Pct       float64   `json:"pct"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TaxBreakdown is the tax included in an amount, kept with the amount so
+// that later rate changes do not alter it.
+type TaxBreakdown struct {
+    TaxCode      string  `json:"tax_code,omitempty" binding:"-"` // empty for the default rate
+    TaxPct       float64 `json:"tax_pct" binding:"-"`
+    TaxableCents int     `json:"taxable_cents" binding:"-"` // amount before tax
+    TaxCents     int     `json:"tax_cents" binding:"-"`
+}
+
+// For returns the breakdown of amount, which includes tax at t's rate.
+func (t TaxBreakdown) For(amount int) TaxBreakdown {
+    t.TaxCents = int(math.Round(float64(amount) * t.TaxPct / (100 + t.TaxPct)))
+    t.TaxableCents = amount - t.TaxCents
+    return t
+}
@@ -22,7 +22,11 @@ type User struct {
     LastName     string     `json:"last_name"`
     Email        string     `gorm:"unique;not null" json:"email"`
     PasswordHash string     `gorm:"not null" json:"-"`
+    DateOfBirth  *time.Time `json:"date_of_birth,omitempty"`
+    Phone        string     `json:"phone,omitempty"`
+    ReferralCode string     `gorm:"uniqueIndex;<-:create" json:"referral_code" binding:"-"` // assigned on create
+    ReferredBy   string     `gorm:"-" json:"referred_by,omitempty"`                         // referral code given at signup
     Roles        []*Role    `gorm:"many2many:user_roles;" json:"roles"`
     CreatedAt    time.Time  `json:"created_at"`
     UpdatedAt    time.Time  `json:"updated_at"`
-}
\ No newline at end of file
+}
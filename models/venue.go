@@ -8,21 +8,38 @@ import (
 
 // Venue where batches run and investments attach.
 type Venue struct {
-    ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
-    Name      string    `gorm:"not null" json:"name"`
-    Location  string    `json:"location"`
-    Capacity  int       `json:"capacity"`
-    Batches   []Batch   `json:"batches,omitempty"`
-    CreatedAt time.Time `json:"created_at"`
-    UpdatedAt time.Time `json:"updated_at"`
+    ID            uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    Name          string    `gorm:"not null" json:"name"`
+    Location      string    `json:"location"`
+    Capacity      int       `json:"capacity"`
+    InvoicePrefix string    `json:"invoice_prefix"`                  // starts the venue's invoice numbers
+    Currency      string    `gorm:"size:3;not null" json:"currency"` // ISO 4217 code of everything charged or spent at the venue
+    Batches       []Batch   `json:"batches,omitempty"`
+    CreatedAt     time.Time `json:"created_at"`
+    UpdatedAt     time.Time `json:"updated_at"`
 }
 
 // Batch groups students at a Venue.
 type Batch struct {
-    ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
-    VenueID   uuid.UUID `gorm:"type:uuid;not null;index" json:"venue_id"`
-    Venue     Venue     `json:"venue"`
-    Name      string    `json:"name"`
-    StartDate time.Time `json:"start_date"`
-    EndDate   time.Time `json:"end_date"`
+    ID           uuid.UUID       `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    VenueID      uuid.UUID       `gorm:"type:uuid;not null;index" json:"venue_id"`
+    Venue        Venue           `json:"venue"`
+    Name         string          `json:"name"`
+    Sport        string          `gorm:"index" json:"sport"`
+    Capacity     int             `json:"capacity"` // 0 means no cap
+    CoachID      *uuid.UUID      `gorm:"type:uuid;index" json:"coach_id,omitempty"`
+    Coach        *User           `json:"coach,omitempty"`
+    StartDate    time.Time       `json:"start_date"`
+    EndDate      time.Time       `json:"end_date"`
+    Schedules    []BatchSchedule `json:"schedules,omitempty"`
+    RolledFromID *uuid.UUID      `gorm:"type:uuid;uniqueIndex" json:"rolled_from_id,omitempty"` // batch of the previous term this one was cloned from
+}
+
+// BatchSchedule is a weekly time slot a batch meets in.
+type BatchSchedule struct {
+    ID        uuid.UUID    `gorm:"type:uuid;default:gen_random_uuid();primaryKey;<-:create" json:"id" binding:"-"`
+    BatchID   uuid.UUID    `gorm:"type:uuid;not null;index" json:"batch_id" binding:"-"`
+    Weekday   time.Weekday `json:"weekday"`                  // 0 = Sunday
+    StartTime string       `gorm:"size:5" json:"start_time"` // "HH:MM", venue local time
+    EndTime   string       `gorm:"size:5" json:"end_time"`
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // AttendanceRepository handles DB operations for Attendance.
@@ -44,14 +45,80 @@ func (r *AttendanceRepository) FindByEnrollment(enrID uuid.UUID) ([]models.Atten
     return recs, nil
 }
 
-// Create inserts a new attendance record.
-func (r *AttendanceRepository) Create(a *models.Attendance) error {
-    return r.db.Create(a).Error
+// CreateTrialSession records attendance of a trial enrollment. check runs
+// first, against the enrollment and the sessions it attended so far, with
+// the enrollment row locked so that concurrent records cannot go over the
+// trial's sessions. It returns the sessions attended, a included.
+func (r *AttendanceRepository) CreateTrialSession(a *models.Attendance, check func(*models.Enrollment, int64) error) (int64, error) {
+    var attended int64
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        var e models.Enrollment
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&e, "id = ?", a.EnrollmentID).Error; err != nil {
+            return err
+        }
+        if err := tx.Model(&models.Attendance{}).
+            Where("enrollment_id = ? AND status = ?", e.ID, "present").Count(&attended).Error; err != nil {
+            return err
+        }
+        if err := check(&e, attended); err != nil {
+            return err
+        }
+        if a.Status == "present" {
+            attended++
+        }
+        return tx.Create(a).Error
+    })
+    return attended, err
 }
 
-// Update saves changes to an existing attendance record.
-func (r *AttendanceRepository) Update(a *models.Attendance) error {
-    return r.db.Save(a).Error
+// AttendanceCounts counts an enrollment's attendance records by status.
+// Records on frozen days are only counted in Frozen.
+type AttendanceCounts struct {
+    Present int64 `json:"present"`
+    Absent  int64 `json:"absent"`
+    Frozen  int64 `json:"frozen"`
+}
+
+// CountStatuses counts the attendance of an enrollment, leaving out records
+// that fall inside one of its freezes.
+func (r *AttendanceRepository) CountStatuses(enrID uuid.UUID) (AttendanceCounts, error) {
+    var c AttendanceCounts
+    err := r.db.Raw(`
+        SELECT COUNT(*) FILTER (WHERE NOT frozen AND status = 'present') AS present,
+               COUNT(*) FILTER (WHERE NOT frozen AND status = 'absent') AS absent,
+               COUNT(*) FILTER (WHERE frozen) AS frozen
+        FROM (
+            SELECT a.status, EXISTS (
+                SELECT 1 FROM enrollment_freezes f
+                WHERE f.enrollment_id = a.enrollment_id
+                  AND a.date::date BETWEEN f.start_date::date AND f.end_date::date
+            ) AS frozen
+            FROM attendances a
+            WHERE a.enrollment_id = ?
+        ) x`, enrID).Scan(&c).Error
+    return c, err
+}
+
+// Create inserts a new attendance record together with the make-up credit
+// it earns, if any.
+func (r *AttendanceRepository) Create(a *models.Attendance, credit *models.MakeupCredit) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Create(a).Error; err != nil {
+            return err
+        }
+        return syncAbsenceCredit(tx, a.ID, credit)
+    })
+}
+
+// Update saves changes to an existing attendance record and issues or
+// revokes its make-up credit to match.
+func (r *AttendanceRepository) Update(a *models.Attendance, credit *models.MakeupCredit) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Save(a).Error; err != nil {
+            return err
+        }
+        return syncAbsenceCredit(tx, a.ID, credit)
+    })
 }
 
 // Delete removes an attendance record by UUID.
@@ -1,12 +1,20 @@
 package repositories
 
 import (
+	"errors"
+	"time"
+
 	"spodemy-backend/models"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrBatchRolledOver is returned when a batch of a rollover was cloned by
+// another rollover in the meantime.
+var ErrBatchRolledOver = errors.New("batch has already been rolled over")
+
 // BatchRepository handles DB operations for Batch.
 type BatchRepository struct {
     db *gorm.DB
@@ -20,7 +28,7 @@ func NewBatchRepository(db *gorm.DB) *BatchRepository {
 // FindAll returns all batches.
 func (r *BatchRepository) FindAll() ([]models.Batch, error) {
     var batches []models.Batch
-    if err := r.db.Preload("Venue").Find(&batches).Error; err != nil {
+    if err := r.db.Preload("Venue").Preload("Coach").Preload("Schedules").Find(&batches).Error; err != nil {
         return nil, err
     }
     return batches, nil
@@ -29,7 +37,7 @@ func (r *BatchRepository) FindAll() ([]models.Batch, error) {
 // FindByID returns a batch by its UUID.
 func (r *BatchRepository) FindByID(id uuid.UUID) (*models.Batch, error) {
     var batch models.Batch
-    if err := r.db.Preload("Venue").First(&batch, "id = ?", id).Error; err != nil {
+    if err := r.db.Preload("Venue").Preload("Coach").Preload("Schedules").First(&batch, "id = ?", id).Error; err != nil {
         return nil, err
     }
     return &batch, nil
@@ -38,7 +46,17 @@ func (r *BatchRepository) FindByID(id uuid.UUID) (*models.Batch, error) {
 // FindByVenue returns batches for a specific venue.
 func (r *BatchRepository) FindByVenue(venueID uuid.UUID) ([]models.Batch, error) {
     var batches []models.Batch
-    if err := r.db.Where("venue_id = ?", venueID).Preload("Venue").Find(&batches).Error; err != nil {
+    if err := r.db.Where("venue_id = ?", venueID).Preload("Venue").Preload("Coach").Preload("Schedules").Find(&batches).Error; err != nil {
+        return nil, err
+    }
+    return batches, nil
+}
+
+// FindRunningOn returns a venue's batches that run on day on.
+func (r *BatchRepository) FindRunningOn(venueID uuid.UUID, on time.Time) ([]models.Batch, error) {
+    var batches []models.Batch
+    if err := r.db.Where("venue_id = ? AND start_date <= ? AND end_date >= ?", venueID, on, on).
+        Preload("Schedules").Find(&batches).Error; err != nil {
         return nil, err
     }
     return batches, nil
@@ -49,12 +67,78 @@ func (r *BatchRepository) Create(b *models.Batch) error {
     return r.db.Create(b).Error
 }
 
-// Update modifies an existing batch.
+// Update modifies an existing batch. Its weekly schedule is replaced only
+// when b.Schedules is set; an empty slice clears it.
 func (r *BatchRepository) Update(b *models.Batch) error {
-    return r.db.Save(b).Error
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        // only a rollover links a batch to the one it was cloned from
+        if err := tx.Omit(clause.Associations, "RolledFromID").Save(b).Error; err != nil {
+            return err
+        }
+        if b.Schedules == nil {
+            return nil
+        }
+        if err := tx.Where("batch_id = ?", b.ID).Delete(&models.BatchSchedule{}).Error; err != nil {
+            return err
+        }
+        if len(b.Schedules) == 0 {
+            return nil
+        }
+        for i := range b.Schedules {
+            b.Schedules[i].ID = uuid.Nil
+            b.Schedules[i].BatchID = b.ID
+        }
+        return tx.Create(&b.Schedules).Error
+    })
 }
 
 // Delete removes a batch by UUID.
 func (r *BatchRepository) Delete(id uuid.UUID) error {
     return r.db.Delete(&models.Batch{}, "id = ?", id).Error
 }
+
+// FindRolledFrom returns batches that were cloned from any of the given batches.
+func (r *BatchRepository) FindRolledFrom(ids []uuid.UUID) ([]models.Batch, error) {
+    var batches []models.Batch
+    if err := r.db.Where("rolled_from_id IN ?", ids).Find(&batches).Error; err != nil {
+        return nil, err
+    }
+    return batches, nil
+}
+
+// CreateRollover inserts next-term batches (with schedules) and their
+// re-enrollments in a single transaction. The source batches are locked
+// first, so two rollovers cannot clone the same batch.
+func (r *BatchRepository) CreateRollover(batches []models.Batch, enrollments []models.Enrollment) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if len(batches) > 0 {
+            sources := make([]uuid.UUID, 0, len(batches))
+            for _, b := range batches {
+                if b.RolledFromID != nil {
+                    sources = append(sources, *b.RolledFromID)
+                }
+            }
+            var locked []models.Batch
+            if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+                Where("id IN ?", sources).Find(&locked).Error; err != nil {
+                return err
+            }
+            var rolled int64
+            if err := tx.Model(&models.Batch{}).Where("rolled_from_id IN ?", sources).Count(&rolled).Error; err != nil {
+                return err
+            }
+            if rolled > 0 {
+                return ErrBatchRolledOver
+            }
+            if err := tx.Omit("Venue", "Coach").Create(&batches).Error; err != nil {
+                return err
+            }
+        }
+        for i := range enrollments {
+            if err := createWithHistory(tx, &enrollments[i], "term rollover", nil); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
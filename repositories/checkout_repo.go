@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"spodemy-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrOrderAmount is returned when a gateway reports a captured amount that
+// differs from the order's.
+var ErrOrderAmount = errors.New("captured amount does not match the payment order")
+
+// CheckoutRepository handles DB operations for online payment orders.
+type CheckoutRepository struct {
+    db *gorm.DB
+}
+
+// NewCheckoutRepository constructs a CheckoutRepository.
+func NewCheckoutRepository(db *gorm.DB) *CheckoutRepository {
+    return &CheckoutRepository{db: db}
+}
+
+// FindByID returns one payment order by UUID.
+func (r *CheckoutRepository) FindByID(id uuid.UUID) (*models.PaymentOrder, error) {
+    var o models.PaymentOrder
+    if err := r.db.First(&o, "id = ?", id).Error; err != nil {
+        return nil, err
+    }
+    return &o, nil
+}
+
+// FindByFeePayment returns the order a fee payment was taken through.
+func (r *CheckoutRepository) FindByFeePayment(feePaymentID uuid.UUID) (*models.PaymentOrder, error) {
+    var o models.PaymentOrder
+    if err := r.db.First(&o, "fee_payment_id = ?", feePaymentID).Error; err != nil {
+        return nil, err
+    }
+    return &o, nil
+}
+
+// Create inserts a new payment order.
+func (r *CheckoutRepository) Create(o *models.PaymentOrder) error {
+    return r.db.Create(o).Error
+}
+
+// Fail marks a payment order that is still open as failed.
+func (r *CheckoutRepository) Fail(provider, orderRef, reason string) error {
+    return r.db.Model(&models.PaymentOrder{}).
+        Where("provider = ? AND order_ref = ? AND status = ?", provider, orderRef, models.OrderCreated).
+        Updates(map[string]interface{}{"status": models.OrderFailed, "failure_reason": reason}).Error
+}
+
+// Record records the fee payment for a captured order exactly once: the
+// order row is locked, and if it already has a payment that payment is
+// returned. The money has been taken by then, so if the charge was settled
+// or the coupon or quote used up since checkout, the payment is still
+// recorded without them, and without the coupon's discount.
+func (r *CheckoutRepository) Record(provider, orderRef, paymentRef string, amountCents int) (*models.PaymentOrder, *models.FeePayment, error) {
+    var o models.PaymentOrder
+    var p models.FeePayment
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+            First(&o, "provider = ? AND order_ref = ?", provider, orderRef).Error; err != nil {
+            return err
+        }
+        if o.FeePaymentID != nil {
+            return tx.First(&p, "id = ?", *o.FeePaymentID).Error
+        }
+        if amountCents != o.AmountCents {
+            return ErrOrderAmount
+        }
+        p = models.FeePayment{
+            EnrollmentID:   o.EnrollmentID,
+            AmountCents:    o.AmountCents,
+            PaidOn:         time.Now(),
+            Method:         provider,
+            TransactionRef: paymentRef,
+            CouponID:       o.CouponID,
+            QuoteNonce:     o.QuoteNonce,
+            DiscountCents:  o.DiscountCents,
+            ChargeID:       o.ChargeID,
+            TaxBreakdown:   o.TaxBreakdown,
+        }
+        err := tx.Transaction(func(tx *gorm.DB) error {
+            return insertPayment(tx, &p, o.StudentID)
+        })
+        if errors.Is(err, ErrChargeSettled) || errors.Is(err, ErrCouponUsedUp) ||
+            errors.Is(err, ErrCouponUserLimit) || errors.Is(err, ErrCouponAlreadyUsed) || errors.Is(err, ErrQuoteUsed) {
+            p.ID, p.ChargeID, p.CouponID, p.QuoteNonce, p.DiscountCents = uuid.Nil, nil, nil, nil, 0
+            err = tx.Transaction(func(tx *gorm.DB) error {
+                return insertPayment(tx, &p, o.StudentID)
+            })
+        }
+        if err != nil {
+            return err
+        }
+        o.Status, o.PaymentRef, o.FeePaymentID, o.FailureReason = models.OrderPaid, paymentRef, &p.ID, ""
+        return tx.Save(&o).Error
+    })
+    if err != nil {
+        return nil, nil, err
+    }
+    return &o, &p, nil
+}
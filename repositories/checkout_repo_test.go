@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+
+	"spodemy-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// checkoutDB returns a database with an open order for 5000 on a new
+// enrollment of the fake gateway.
+func checkoutDB(t *testing.T) (*gorm.DB, *models.PaymentOrder) {
+    t.Helper()
+    db := testDB(t,
+        &models.Venue{}, &models.Batch{}, &models.User{}, &models.Enrollment{},
+        &models.PaymentOrder{}, &models.FeePayment{}, &models.QuoteUse{},
+        &models.Account{}, &models.JournalEntry{}, &models.JournalLine{},
+        &models.Receipt{}, &models.ReceiptSequence{},
+        &models.Invoice{}, &models.InvoiceLine{}, &models.PaymentAllocation{}, &models.Installment{})
+    seedAccounts(t, db)
+    e := seedEnrollment(t, db)
+    o := &models.PaymentOrder{
+        Provider:     "fake",
+        OrderRef:     "order_fake000001",
+        EnrollmentID: e.ID,
+        StudentID:    e.StudentID,
+        AmountCents:  5000,
+        Currency:     "INR",
+        Status:       models.OrderCreated,
+    }
+    if err := db.Create(o).Error; err != nil {
+        t.Fatal(err)
+    }
+    return db, o
+}
+
+// countRows counts the rows of model's table.
+func countRows(t *testing.T, db *gorm.DB, model interface{}) int64 {
+    t.Helper()
+    var n int64
+    if err := db.Model(model).Count(&n).Error; err != nil {
+        t.Fatal(err)
+    }
+    return n
+}
+
+func TestCheckoutRecordOnce(t *testing.T) {
+    db, o := checkoutDB(t)
+    r := NewCheckoutRepository(db)
+
+    paid, p, err := r.Record(o.Provider, o.OrderRef, "pay_fake000002", o.AmountCents)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if paid.Status != models.OrderPaid || paid.FeePaymentID == nil || *paid.FeePaymentID != p.ID {
+        t.Fatalf("order = %+v, want paid with payment %s", paid, p.ID)
+    }
+    if p.AmountCents != 5000 || p.Method != "fake" || p.TransactionRef != "pay_fake000002" {
+        t.Errorf("payment = %+v, want 5000 by fake as pay_fake000002", p)
+    }
+
+    // the gateway retries deliveries; a replay returns the same payment
+    for i := 0; i < 2; i++ {
+        _, again, err := r.Record(o.Provider, o.OrderRef, "pay_fake000002", o.AmountCents)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if again.ID != p.ID {
+            t.Errorf("replay %d recorded payment %s, want %s", i+1, again.ID, p.ID)
+        }
+    }
+    if n := countRows(t, db, &models.FeePayment{}); n != 1 {
+        t.Errorf("%d fee payments recorded, want 1", n)
+    }
+    if n := countRows(t, db, &models.Receipt{}); n != 1 {
+        t.Errorf("%d receipts issued, want 1", n)
+    }
+    if n := countRows(t, db, &models.JournalEntry{}); n != 1 {
+        t.Errorf("%d journal entries posted, want 1", n)
+    }
+}
+
+func TestCheckoutRecordAmountMismatch(t *testing.T) {
+    db, o := checkoutDB(t)
+    r := NewCheckoutRepository(db)
+
+    if _, _, err := r.Record(o.Provider, o.OrderRef, "pay_fake000002", o.AmountCents-1); !errors.Is(err, ErrOrderAmount) {
+        t.Fatalf("err = %v, want %v", err, ErrOrderAmount)
+    }
+    if n := countRows(t, db, &models.FeePayment{}); n != 0 {
+        t.Errorf("%d fee payments recorded, want 0", n)
+    }
+    var got models.PaymentOrder
+    if err := db.First(&got, "id = ?", o.ID).Error; err != nil {
+        t.Fatal(err)
+    }
+    if got.Status != models.OrderCreated || got.FeePaymentID != nil {
+        t.Errorf("order = %+v, want still open", got)
+    }
+
+    if _, _, err := r.Record(o.Provider, "order_unknown", "pay_fake000003", o.AmountCents); !errors.Is(err, gorm.ErrRecordNotFound) {
+        t.Errorf("unknown order: err = %v, want %v", err, gorm.ErrRecordNotFound)
+    }
+}
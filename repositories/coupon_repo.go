@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"spodemy-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Coupon redemption errors, checked while the coupon row is locked.
+var (
+    ErrCouponUsedUp      = errors.New("coupon has been fully redeemed")
+    ErrCouponUserLimit   = errors.New("coupon redemption limit reached for this student")
+    ErrCouponAlreadyUsed = errors.New("coupon has already been used on this enrollment")
+)
+
+// CouponRepository handles DB operations for Coupon.
+type CouponRepository struct {
+    db *gorm.DB
+}
+
+// NewCouponRepository constructs a CouponRepository.
+func NewCouponRepository(db *gorm.DB) *CouponRepository {
+    return &CouponRepository{db: db}
+}
+
+// FindAll returns all coupons with their offer.
+func (r *CouponRepository) FindAll() ([]models.Coupon, error) {
+    var cs []models.Coupon
+    if err := r.db.Preload("Offer").Order("created_at DESC").Find(&cs).Error; err != nil {
+        return nil, err
+    }
+    return cs, nil
+}
+
+// FindByID returns one coupon by UUID.
+func (r *CouponRepository) FindByID(id uuid.UUID) (*models.Coupon, error) {
+    var c models.Coupon
+    if err := r.db.Preload("Offer").First(&c, "id = ?", id).Error; err != nil {
+        return nil, err
+    }
+    return &c, nil
+}
+
+// FindByCode returns the coupon with an exact, already normalised code.
+func (r *CouponRepository) FindByCode(code string) (*models.Coupon, error) {
+    var c models.Coupon
+    if err := r.db.Preload("Offer").First(&c, "code = ?", code).Error; err != nil {
+        return nil, err
+    }
+    return &c, nil
+}
+
+// Create inserts a new coupon.
+func (r *CouponRepository) Create(c *models.Coupon) error {
+    return r.db.Omit(clause.Associations).Create(c).Error
+}
+
+// Update modifies an existing coupon.
+func (r *CouponRepository) Update(c *models.Coupon) error {
+    return r.db.Omit(clause.Associations, "CreatedAt").Save(c).Error
+}
+
+// Delete removes a coupon by UUID.
+func (r *CouponRepository) Delete(id uuid.UUID) error {
+    return r.db.Delete(&models.Coupon{}, "id = ?", id).Error
+}
+
+// FindRedemptions returns a coupon's redemptions, newest first.
+func (r *CouponRepository) FindRedemptions(couponID uuid.UUID) ([]models.CouponRedemption, error) {
+    var rs []models.CouponRedemption
+    if err := r.db.Where("coupon_id = ?", couponID).Order("redeemed_at DESC").Find(&rs).Error; err != nil {
+        return nil, err
+    }
+    return rs, nil
+}
+
+// CountRedemptions returns how often a coupon was redeemed in total and by
+// one student.
+func (r *CouponRepository) CountRedemptions(couponID, userID uuid.UUID) (int64, int64, error) {
+    return countRedemptions(r.db, couponID, userID)
+}
+
+// IsRedeemedOn reports whether a coupon was already used on an enrollment.
+func (r *CouponRepository) IsRedeemedOn(couponID, enrollmentID uuid.UUID) (bool, error) {
+    var n int64
+    err := r.db.Model(&models.CouponRedemption{}).
+        Where("coupon_id = ? AND enrollment_id = ?", couponID, enrollmentID).Count(&n).Error
+    return n > 0, err
+}
+
+func countRedemptions(db *gorm.DB, couponID, userID uuid.UUID) (int64, int64, error) {
+    var total, byUser int64
+    if err := db.Model(&models.CouponRedemption{}).Where("coupon_id = ?", couponID).Count(&total).Error; err != nil {
+        return 0, 0, err
+    }
+    if err := db.Model(&models.CouponRedemption{}).Where("coupon_id = ? AND user_id = ?", couponID, userID).
+        Count(&byUser).Error; err != nil {
+        return 0, 0, err
+    }
+    return total, byUser, nil
+}
+
+// redeemCoupon records red inside tx. The coupon row stays locked until tx
+// ends, so concurrent redemptions are checked against its limits one at a time.
+func redeemCoupon(tx *gorm.DB, red *models.CouponRedemption) error {
+    var c models.Coupon
+    if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&c, "id = ?", red.CouponID).Error; err != nil {
+        return err
+    }
+    total, byUser, err := countRedemptions(tx, c.ID, red.UserID)
+    if err != nil {
+        return err
+    }
+    if c.MaxRedemptions > 0 && total >= int64(c.MaxRedemptions) {
+        return ErrCouponUsedUp
+    }
+    if c.MaxPerUser > 0 && byUser >= int64(c.MaxPerUser) {
+        return ErrCouponUserLimit
+    }
+    var used int64
+    if err := tx.Model(&models.CouponRedemption{}).
+        Where("coupon_id = ? AND enrollment_id = ?", c.ID, red.EnrollmentID).Count(&used).Error; err != nil {
+        return err
+    }
+    if used > 0 {
+        return ErrCouponAlreadyUsed
+    }
+    red.RedeemedAt = time.Now()
+    return tx.Create(red).Error
+}
@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"spodemy-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrCurrencyMismatch is returned for an enrollment on a plan priced in a
+// currency other than its venue's.
+var ErrCurrencyMismatch = errors.New("plan is priced in a different currency from the venue")
+
+// ExchangeRateRepository handles DB operations for exchange rates.
+type ExchangeRateRepository struct {
+    db *gorm.DB
+}
+
+// NewExchangeRateRepository constructs an ExchangeRateRepository.
+func NewExchangeRateRepository(db *gorm.DB) *ExchangeRateRepository {
+    return &ExchangeRateRepository{db: db}
+}
+
+// FindAll returns the rates of a pair, newest first. An empty base or quote
+// does not filter.
+func (r *ExchangeRateRepository) FindAll(base, quote string) ([]models.ExchangeRate, error) {
+    q := r.db
+    if base != "" {
+        q = q.Where("base = ?", base)
+    }
+    if quote != "" {
+        q = q.Where("quote = ?", quote)
+    }
+    var rates []models.ExchangeRate
+    if err := q.Order("base, quote, effective_on DESC").Find(&rates).Error; err != nil {
+        return nil, err
+    }
+    return rates, nil
+}
+
+// FindByID returns one rate by UUID.
+func (r *ExchangeRateRepository) FindByID(id uuid.UUID) (*models.ExchangeRate, error) {
+    var rate models.ExchangeRate
+    if err := r.db.First(&rate, "id = ?", id).Error; err != nil {
+        return nil, err
+    }
+    return &rate, nil
+}
+
+// FindEffective returns the rate of a pair in effect on day on: the one
+// that took effect last on or before it.
+func (r *ExchangeRateRepository) FindEffective(base, quote string, on time.Time) (*models.ExchangeRate, error) {
+    var rate models.ExchangeRate
+    if err := r.db.Where("base = ? AND quote = ? AND effective_on <= ?", base, quote, on).
+        Order("effective_on DESC").First(&rate).Error; err != nil {
+        return nil, err
+    }
+    return &rate, nil
+}
+
+// CountOn counts the rates of a pair taking effect on day on.
+func (r *ExchangeRateRepository) CountOn(base, quote string, on time.Time) (int64, error) {
+    var n int64
+    err := r.db.Model(&models.ExchangeRate{}).
+        Where("base = ? AND quote = ? AND effective_on = ?", base, quote, on).Count(&n).Error
+    return n, err
+}
+
+// Create inserts a new rate.
+func (r *ExchangeRateRepository) Create(rate *models.ExchangeRate) error {
+    return r.db.Create(rate).Error
+}
+
+// Delete removes a rate by UUID.
+func (r *ExchangeRateRepository) Delete(id uuid.UUID) error {
+    return r.db.Delete(&models.ExchangeRate{}, "id = ?", id).Error
+}
+
+// venueCurrency returns the currency of a venue.
+func venueCurrency(tx *gorm.DB, venueID uuid.UUID) (string, error) {
+    var currency string
+    err := tx.Model(&models.Venue{}).Where("id = ?", venueID).Pluck("currency", &currency).Error
+    return currency, err
+}
+
+// batchCurrency returns the currency of the venue a batch runs at.
+func batchCurrency(tx *gorm.DB, batchID uuid.UUID) (string, error) {
+    var currency string
+    err := tx.Raw(`SELECT v.currency FROM batches b JOIN venues v ON v.id = b.venue_id WHERE b.id = ?`,
+        batchID).Scan(&currency).Error
+    return currency, err
+}
+
+// enrollmentCurrency returns the currency an enrollment is billed in.
+func enrollmentCurrency(tx *gorm.DB, enrollmentID uuid.UUID) (string, error) {
+    var currency string
+    err := tx.Model(&models.Enrollment{}).Where("id = ?", enrollmentID).Pluck("currency", &currency).Error
+    return currency, err
+}
@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"spodemy-backend/models"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+// testDB connects to the PostgreSQL database in SPODEMY_TEST_DSN and gives
+// the test a schema of its own, dropped when it ends. Tests needing a
+// database are skipped without one.
+func testDB(t *testing.T, tables ...interface{}) *gorm.DB {
+    t.Helper()
+    dsn := os.Getenv("SPODEMY_TEST_DSN")
+    if dsn == "" {
+        t.Skip("SPODEMY_TEST_DSN is not set")
+    }
+    cfg := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent), DisableForeignKeyConstraintWhenMigrating: true}
+    admin, err := gorm.Open(postgres.Open(dsn), cfg)
+    if err != nil {
+        t.Fatal(err)
+    }
+    schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+    if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() {
+        admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+        if sql, err := admin.DB(); err == nil {
+            sql.Close()
+        }
+    })
+
+    db, err := gorm.Open(postgres.Open(dsn+" search_path="+schema), cfg)
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() {
+        if sql, err := db.DB(); err == nil {
+            sql.Close()
+        }
+    })
+    if err := db.AutoMigrate(tables...); err != nil {
+        t.Fatal(err)
+    }
+    return db
+}
+
+// seedEnrollment inserts a venue, a batch, a student and an enrollment of
+// theirs billed in INR.
+func seedEnrollment(t *testing.T, db *gorm.DB) *models.Enrollment {
+    t.Helper()
+    v := models.Venue{Name: "Ground", Currency: "INR", InvoicePrefix: "GRD"}
+    u := models.User{Email: fmt.Sprintf("student%d@example.com", time.Now().UnixNano())}
+    for _, rec := range []interface{}{&v, &u} {
+        if err := db.Omit(clause.Associations).Create(rec).Error; err != nil {
+            t.Fatal(err)
+        }
+    }
+    b := models.Batch{VenueID: v.ID, Name: "Evening"}
+    if err := db.Omit(clause.Associations).Create(&b).Error; err != nil {
+        t.Fatal(err)
+    }
+    e := models.Enrollment{
+        StudentID:  u.ID,
+        BatchID:    b.ID,
+        EnrolledOn: time.Now(),
+        Status:     models.EnrollmentPendingPayment,
+        Type:       models.EnrollmentRegular,
+        Currency:   "INR",
+    }
+    if err := db.Omit(clause.Associations).Create(&e).Error; err != nil {
+        t.Fatal(err)
+    }
+    return &e
+}
+
+// seedAccounts inserts the system accounts automatic postings use.
+func seedAccounts(t *testing.T, db *gorm.DB) {
+    t.Helper()
+    accts := []models.Account{
+        {Code: models.AccountCodeCash, Name: "Cash", Type: models.AccountAsset, System: true},
+        {Code: models.AccountCodeGSTPayable, Name: "GST payable", Type: models.AccountLiability, System: true},
+        {Code: models.AccountCodeCapital, Name: "Capital", Type: models.AccountEquity, System: true},
+        {Code: models.AccountCodeFeeIncome, Name: "Fee income", Type: models.AccountIncome, System: true},
+        {Code: models.AccountCodeExpenses, Name: "Operating expenses", Type: models.AccountExpense, System: true},
+    }
+    if err := db.Create(&accts).Error; err != nil {
+        t.Fatal(err)
+    }
+}
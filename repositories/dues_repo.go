@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"strings"
+	"time"
+
+	"spodemy-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DuesFilter narrows the balances computed; zero fields do not filter.
+type DuesFilter struct {
+    EnrollmentID *uuid.UUID
+    VenueID      *uuid.UUID
+    BatchID      *uuid.UUID
+    DueBefore    *time.Time // only balances that fell due before this date
+    OwingOnly    bool       // only enrollments with a positive balance
+}
+
+// BalanceRow is what one enrollment has been charged and paid.
+type BalanceRow struct {
+    EnrollmentID uuid.UUID  `json:"enrollment_id"`
+    Status       string     `json:"status"`
+    StudentID    uuid.UUID  `json:"student_id"`
+    StudentName  string     `json:"student_name"`
+    BatchID      uuid.UUID  `json:"batch_id"`
+    BatchName    string     `json:"batch_name"`
+    VenueID      uuid.UUID  `json:"venue_id"`
+    VenueName    string     `json:"venue_name"`
+    Currency     string     `json:"currency"`      // of every amount in the row
+    ChargedCents int64      `json:"charged_cents"` // enrollment price plus renewal charges
+    PaidCents    int64      `json:"paid_cents"`    // payments and coupon discounts, net of refunds
+    BalanceCents int64      `json:"balance_cents"`
+    DueSince     *time.Time `json:"due_since,omitempty"` // when the oldest unpaid amount fell due
+}
+
+// DuesRepository computes balances with aggregate queries.
+type DuesRepository struct {
+    db *gorm.DB
+}
+
+// NewDuesRepository constructs a DuesRepository.
+func NewDuesRepository(db *gorm.DB) *DuesRepository {
+    return &DuesRepository{db: db}
+}
+
+// Balances returns the balance of each enrollment matching f, oldest due
+// first. The enrollment price is due from its start, or installment by
+// installment on a plan paid in installments, and each renewal charge from
+// its due date; waitlisted enrollments owe nothing yet.
+func (r *DuesRepository) Balances(f DuesFilter) ([]BalanceRow, error) {
+    conds := []string{"e.status <> ?"}
+    args := []interface{}{models.EnrollmentWaitlisted}
+    if f.EnrollmentID != nil {
+        conds = append(conds, "e.id = ?")
+        args = append(args, *f.EnrollmentID)
+    }
+    if f.VenueID != nil {
+        conds = append(conds, "b.venue_id = ?")
+        args = append(args, *f.VenueID)
+    }
+    if f.BatchID != nil {
+        conds = append(conds, "e.batch_id = ?")
+        args = append(args, *f.BatchID)
+    }
+    outer := []string{"TRUE"}
+    if f.OwingOnly {
+        outer = append(outer, "balance_cents > 0")
+    }
+    if f.DueBefore != nil {
+        outer = append(outer, "due_since < ?")
+        args = append(args, *f.DueBefore)
+    }
+
+    var rows []BalanceRow
+    err := r.db.Raw(`
+        WITH charged AS (
+            SELECT enrollment_id, SUM(amount_cents) AS cents,
+                   MIN(due_on) FILTER (WHERE status IN ('due', 'overdue')) AS first_due
+            FROM charges WHERE status <> 'void'
+            GROUP BY enrollment_id
+        ), installed AS (
+            SELECT enrollment_id, MIN(due_on) FILTER (WHERE status <> 'paid') AS first_due
+            FROM installments
+            GROUP BY enrollment_id
+        ), paid AS (
+            SELECT enrollment_id, SUM(amount_cents + discount_cents - refunded_cents) AS cents
+            FROM fee_payments
+            GROUP BY enrollment_id
+        )
+        SELECT * FROM (
+            SELECT e.id AS enrollment_id, e.status, e.student_id,
+                   TRIM(u.first_name || ' ' || u.last_name) AS student_name,
+                   e.batch_id, b.name AS batch_name, b.venue_id, v.name AS venue_name, e.currency,
+                   e.price_cents + COALESCE(c.cents, 0) AS charged_cents,
+                   COALESCE(p.cents, 0) AS paid_cents,
+                   e.price_cents + COALESCE(c.cents, 0) - COALESCE(p.cents, 0) AS balance_cents,
+                   CASE WHEN i.enrollment_id IS NOT NULL THEN LEAST(i.first_due, c.first_due)
+                        WHEN COALESCE(p.cents, 0) < e.price_cents THEN e.enrolled_on
+                        ELSE c.first_due END AS due_since
+            FROM enrollments e
+            JOIN users u ON u.id = e.student_id
+            JOIN batches b ON b.id = e.batch_id
+            JOIN venues v ON v.id = b.venue_id
+            LEFT JOIN charged c ON c.enrollment_id = e.id
+            LEFT JOIN installed i ON i.enrollment_id = e.id
+            LEFT JOIN paid p ON p.enrollment_id = e.id
+            WHERE `+strings.Join(conds, " AND ")+`
+        ) d
+        WHERE `+strings.Join(outer, " AND ")+`
+        ORDER BY due_since NULLS LAST, venue_name, batch_name, student_name`, args...).Scan(&rows).Error
+    return rows, err
+}
@@ -1,12 +1,20 @@
 package repositories
 
 import (
+	"errors"
+	"time"
+
 	"spodemy-backend/models"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrOfferUsedUp is returned when saving an enrollment would take an offer
+// past its usage cap.
+var ErrOfferUsedUp = errors.New("offer has reached its usage cap")
+
 // EnrollmentRepository handles DB operations for Enrollment.
 type EnrollmentRepository struct {
     db *gorm.DB
@@ -20,7 +28,7 @@ func NewEnrollmentRepository(db *gorm.DB) *EnrollmentRepository {
 // FindAll returns all enrollments with student and batch preloaded.
 func (r *EnrollmentRepository) FindAll() ([]models.Enrollment, error) {
     var ens []models.Enrollment
-    if err := r.db.Preload("Student").Preload("Batch").Find(&ens).Error; err != nil {
+    if err := r.db.Preload("Student").Preload("Batch").Preload("Plan").Preload("Offer").Find(&ens).Error; err != nil {
         return nil, err
     }
     return ens, nil
@@ -29,7 +37,9 @@ func (r *EnrollmentRepository) FindAll() ([]models.Enrollment, error) {
 // FindByID returns a single enrollment by UUID.
 func (r *EnrollmentRepository) FindByID(id uuid.UUID) (*models.Enrollment, error) {
     var e models.Enrollment
-    if err := r.db.Preload("Student").Preload("Batch").First(&e, "id = ?", id).Error; err != nil {
+    if err := r.db.Preload("Student").Preload("Batch").Preload("Plan").Preload("Offer").Preload("AppliedOffers").Preload("PlanVersion").
+        Preload("Freezes", func(db *gorm.DB) *gorm.DB { return db.Order("start_date") }).
+        First(&e, "id = ?", id).Error; err != nil {
         return nil, err
     }
     return &e, nil
@@ -38,23 +48,358 @@ func (r *EnrollmentRepository) FindByID(id uuid.UUID) (*models.Enrollment, error
 // FindByBatch returns enrollments for a given batch UUID.
 func (r *EnrollmentRepository) FindByBatch(batchID uuid.UUID) ([]models.Enrollment, error) {
     var ens []models.Enrollment
-    if err := r.db.Where("batch_id = ?", batchID).Preload("Student").Preload("Batch").Find(&ens).Error; err != nil {
+    if err := r.db.Where("batch_id = ?", batchID).Preload("Student").Preload("Batch").Preload("Plan").Preload("Offer").Find(&ens).Error; err != nil {
+        return nil, err
+    }
+    return ens, nil
+}
+
+// FindOptedIn returns active enrollments in the given batches whose students
+// opted in to continue next term.
+func (r *EnrollmentRepository) FindOptedIn(batchIDs []uuid.UUID) ([]models.Enrollment, error) {
+    var ens []models.Enrollment
+    if err := r.db.Where("batch_id IN ? AND status = ? AND renewal_opt_in", batchIDs, models.EnrollmentActive).
+        Preload("Student").Preload("Plan").Find(&ens).Error; err != nil {
         return nil, err
     }
     return ens, nil
 }
 
-// Create inserts a new enrollment record.
-func (r *EnrollmentRepository) Create(e *models.Enrollment) error {
-    return r.db.Create(e).Error
+// FindByConvertedFrom returns the paid enrollment a trial was converted into.
+func (r *EnrollmentRepository) FindByConvertedFrom(trialID uuid.UUID) (*models.Enrollment, error) {
+    var e models.Enrollment
+    if err := r.db.First(&e, "converted_from_id = ?", trialID).Error; err != nil {
+        return nil, err
+    }
+    return &e, nil
+}
+
+// CountRegularByStudent counts a student's regular enrollments other than exclude.
+func (r *EnrollmentRepository) CountRegularByStudent(studentID, exclude uuid.UUID) (int64, error) {
+    var n int64
+    err := r.db.Model(&models.Enrollment{}).
+        Where("student_id = ? AND type = ? AND id <> ?", studentID, models.EnrollmentRegular, exclude).
+        Count(&n).Error
+    return n, err
 }
 
-// Update saves changes to an existing enrollment.
-func (r *EnrollmentRepository) Update(e *models.Enrollment) error {
-    return r.db.Save(e).Error
+// CountOfferUses returns how many enrollments other than exclude each offer
+// has been applied to.
+func (r *EnrollmentRepository) CountOfferUses(offerIDs []uuid.UUID, exclude uuid.UUID) (map[uuid.UUID]int64, error) {
+    uses := make(map[uuid.UUID]int64, len(offerIDs))
+    if len(offerIDs) == 0 {
+        return uses, nil
+    }
+    var rows []struct {
+        OfferID uuid.UUID
+        N       int64
+    }
+    if err := r.db.Model(&models.EnrollmentOffer{}).Select("offer_id, COUNT(*) AS n").
+        Where("offer_id IN ? AND enrollment_id <> ?", offerIDs, exclude).
+        Group("offer_id").Scan(&rows).Error; err != nil {
+        return nil, err
+    }
+    for _, row := range rows {
+        uses[row.OfferID] = row.N
+    }
+    return uses, nil
+}
+
+// FindExpiring returns active enrollments whose validity ends in [from, to].
+func (r *EnrollmentRepository) FindExpiring(from, to time.Time) ([]models.Enrollment, error) {
+    var ens []models.Enrollment
+    if err := r.db.Where("status = ? AND valid_until BETWEEN ? AND ?", models.EnrollmentActive, from, to).
+        Preload("Student").Preload("Batch").Preload("Plan").
+        Order("valid_until").Find(&ens).Error; err != nil {
+        return nil, err
+    }
+    return ens, nil
+}
+
+// CompleteExpired completes active enrollments whose validity ended before t.
+// Enrollments that still renew are left to the renewal job.
+func (r *EnrollmentRepository) CompleteExpired(t time.Time) (int64, error) {
+    return r.transitionWhere(models.EnrollmentCompleted, "plan validity ended",
+        "status = ? AND valid_until < ? AND NOT (auto_renew AND NOT cancel_at_period_end)", models.EnrollmentActive, t)
+}
+
+// Create inserts a new enrollment record and its initial status entry.
+func (r *EnrollmentRepository) Create(e *models.Enrollment, actor *uuid.UUID) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        return createWithHistory(tx, e, "enrolled", actor)
+    })
+}
+
+// Update saves changes to an existing enrollment. The status column is left
+// alone; it only changes through Transition, and freezes only through
+// Freeze. A repriced enrollment has its applied offers and installments
+// replaced and its invoice voided and issued again at the new price.
+func (r *EnrollmentRepository) Update(e *models.Enrollment, repriced bool) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if err := setEnrollmentCurrency(tx, e); err != nil {
+            return err
+        }
+        if err := tx.Omit("Status", "AppliedOffers", "Freezes", "PlanVersion").Save(e).Error; err != nil {
+            return err
+        }
+        if err := useEnrollmentQuote(tx, e); err != nil {
+            return err
+        }
+        if !repriced {
+            return nil
+        }
+        if err := tx.Where("enrollment_id = ?", e.ID).Delete(&models.EnrollmentOffer{}).Error; err != nil {
+            return err
+        }
+        if err := saveAppliedOffers(tx, e); err != nil {
+            return err
+        }
+        if err := reissueInstallments(tx, e); err != nil {
+            return err
+        }
+        return reissueEnrollmentInvoice(tx, e)
+    })
 }
 
 // Delete removes an enrollment by UUID.
 func (r *EnrollmentRepository) Delete(id uuid.UUID) error {
     return r.db.Delete(&models.Enrollment{}, "id = ?", id).Error
-}
\ No newline at end of file
+}
+
+// Transition moves an enrollment out of h.FromStatus and records h. It
+// returns false, without changes, if the enrollment is no longer in that status.
+func (r *EnrollmentRepository) Transition(h *models.EnrollmentStatusHistory) (bool, error) {
+    moved := false
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        ok, err := transition(tx, h)
+        moved = ok
+        return err
+    })
+    return moved, err
+}
+
+// Freeze records f and extends the enrollment's validity by f.Days. check
+// runs first, against the enrollment and its existing freezes, with the
+// enrollment row locked so that concurrent freezes are checked one at a time.
+func (r *EnrollmentRepository) Freeze(f *models.EnrollmentFreeze, check func(*models.Enrollment, []models.EnrollmentFreeze) error) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        var e models.Enrollment
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&e, "id = ?", f.EnrollmentID).Error; err != nil {
+            return err
+        }
+        var existing []models.EnrollmentFreeze
+        if err := tx.Where("enrollment_id = ?", e.ID).Order("start_date").Find(&existing).Error; err != nil {
+            return err
+        }
+        if err := check(&e, existing); err != nil {
+            return err
+        }
+        if err := tx.Create(f).Error; err != nil {
+            return err
+        }
+        return tx.Model(&e).Update("valid_until", e.ValidUntil.AddDate(0, 0, f.Days)).Error
+    })
+}
+
+// FindFreezes returns the freezes of an enrollment, earliest first.
+func (r *EnrollmentRepository) FindFreezes(enrID uuid.UUID) ([]models.EnrollmentFreeze, error) {
+    var fs []models.EnrollmentFreeze
+    if err := r.db.Where("enrollment_id = ?", enrID).Order("start_date").Find(&fs).Error; err != nil {
+        return nil, err
+    }
+    return fs, nil
+}
+
+// FindHistory returns the status changes of an enrollment, oldest first.
+func (r *EnrollmentRepository) FindHistory(enrID uuid.UUID) ([]models.EnrollmentStatusHistory, error) {
+    var hs []models.EnrollmentStatusHistory
+    if err := r.db.Where("enrollment_id = ?", enrID).Order("changed_at").Find(&hs).Error; err != nil {
+        return nil, err
+    }
+    return hs, nil
+}
+
+// ExpireTrials completes active trials whose window ended before t.
+func (r *EnrollmentRepository) ExpireTrials(t time.Time) (int64, error) {
+    return r.transitionWhere(models.EnrollmentCompleted, "trial window ended",
+        "type = ? AND status = ? AND trial_ends_on < ?", models.EnrollmentTrial, models.EnrollmentActive, t)
+}
+
+// Convert closes the trial and creates the paid enrollment that replaces it.
+func (r *EnrollmentRepository) Convert(trial *models.Enrollment, paid *models.Enrollment, actor *uuid.UUID) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if trial.Status != models.EnrollmentCompleted {
+            if _, err := transition(tx, &models.EnrollmentStatusHistory{
+                EnrollmentID: trial.ID,
+                FromStatus:   trial.Status,
+                ToStatus:     models.EnrollmentCompleted,
+                Reason:       "converted to paid enrollment",
+                ChangedByID:  actor,
+                ChangedAt:    time.Now(),
+            }); err != nil {
+                return err
+            }
+        }
+        return createWithHistory(tx, paid, "converted from trial", actor)
+    })
+}
+
+// transitionWhere moves every enrollment matching the query to status to,
+// recording a system history entry for each.
+func (r *EnrollmentRepository) transitionWhere(to, reason string, query string, args ...interface{}) (int64, error) {
+    var n int64
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        var ens []models.Enrollment
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(query, args...).Find(&ens).Error; err != nil {
+            return err
+        }
+        now := time.Now()
+        for _, e := range ens {
+            ok, err := transition(tx, &models.EnrollmentStatusHistory{
+                EnrollmentID: e.ID,
+                FromStatus:   e.Status,
+                ToStatus:     to,
+                Reason:       reason,
+                ChangedAt:    now,
+            })
+            if err != nil {
+                return err
+            }
+            if ok {
+                n++
+            }
+        }
+        return nil
+    })
+    return n, err
+}
+
+// transition applies h inside tx. An enrollment becoming active may earn
+// its student's referrer a reward.
+func transition(tx *gorm.DB, h *models.EnrollmentStatusHistory) (bool, error) {
+    res := tx.Model(&models.Enrollment{}).
+        Where("id = ? AND status = ?", h.EnrollmentID, h.FromStatus).
+        Update("status", h.ToStatus)
+    if res.Error != nil || res.RowsAffected == 0 {
+        return false, res.Error
+    }
+    if err := tx.Create(h).Error; err != nil {
+        return false, err
+    }
+    if h.ToStatus == models.EnrollmentActive {
+        if err := rewardReferral(tx, h.EnrollmentID); err != nil {
+            return false, err
+        }
+    }
+    return true, nil
+}
+
+// createWithHistory inserts an enrollment together with its applied offers,
+// coupon redemption, initial status entry, installments and invoice.
+// Enrollments created active may earn a referral reward.
+func createWithHistory(tx *gorm.DB, e *models.Enrollment, reason string, actor *uuid.UUID) error {
+    if err := setEnrollmentCurrency(tx, e); err != nil {
+        return err
+    }
+    if err := tx.Omit(clause.Associations).Create(e).Error; err != nil {
+        return err
+    }
+    if err := saveAppliedOffers(tx, e); err != nil {
+        return err
+    }
+    if err := useEnrollmentQuote(tx, e); err != nil {
+        return err
+    }
+    if e.CouponID != nil {
+        if err := redeemCoupon(tx, &models.CouponRedemption{
+            CouponID:     *e.CouponID,
+            UserID:       e.StudentID,
+            EnrollmentID: e.ID,
+        }); err != nil {
+            return err
+        }
+    }
+    if err := tx.Create(&models.EnrollmentStatusHistory{
+        EnrollmentID: e.ID,
+        ToStatus:     e.Status,
+        Reason:       reason,
+        ChangedByID:  actor,
+        ChangedAt:    time.Now(),
+    }).Error; err != nil {
+        return err
+    }
+    if err := issueInstallments(tx, e); err != nil {
+        return err
+    }
+    if err := issueEnrollmentInvoice(tx, e); err != nil {
+        return err
+    }
+    if e.Status == models.EnrollmentActive {
+        return rewardReferral(tx, e.ID)
+    }
+    return nil
+}
+
+// useEnrollmentQuote records the quote e was priced with, if any, as used
+// for an enrollment.
+func useEnrollmentQuote(tx *gorm.DB, e *models.Enrollment) error {
+    if e.QuoteNonce == nil {
+        return nil
+    }
+    return useQuote(tx, &models.QuoteUse{
+        QuoteNonce:   *e.QuoteNonce,
+        Purpose:      models.QuoteForEnrollment,
+        EnrollmentID: e.ID,
+    })
+}
+
+// setEnrollmentCurrency bills e in the currency of its batch's venue, which
+// its plan must be priced in.
+func setEnrollmentCurrency(tx *gorm.DB, e *models.Enrollment) error {
+    currency, err := batchCurrency(tx, e.BatchID)
+    if err != nil {
+        return err
+    }
+    e.Currency = currency
+    var planCurrency string
+    switch {
+    case e.PlanVersionID != nil:
+        err = tx.Model(&models.PlanVersion{}).Where("id = ?", *e.PlanVersionID).Pluck("currency", &planCurrency).Error
+    case e.PlanID != nil:
+        err = tx.Model(&models.Plan{}).Where("id = ?", *e.PlanID).Pluck("currency", &planCurrency).Error
+    }
+    if err != nil {
+        return err
+    }
+    if planCurrency != "" && planCurrency != currency {
+        return ErrCurrencyMismatch
+    }
+    return nil
+}
+
+// saveAppliedOffers inserts e's applied offers, locking each capped offer so
+// that concurrent enrollments cannot take it past MaxUses.
+func saveAppliedOffers(tx *gorm.DB, e *models.Enrollment) error {
+    for i := range e.AppliedOffers {
+        ao := &e.AppliedOffers[i]
+        ao.EnrollmentID = e.ID
+        var o models.Offer
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "max_uses").
+            First(&o, "id = ?", ao.OfferID).Error; err != nil {
+            return err
+        }
+        if o.MaxUses > 0 {
+            var n int64
+            if err := tx.Model(&models.EnrollmentOffer{}).Where("offer_id = ?", o.ID).Count(&n).Error; err != nil {
+                return err
+            }
+            if n >= int64(o.MaxUses) {
+                return ErrOfferUsedUp
+            }
+        }
+        if err := tx.Create(ao).Error; err != nil {
+            return err
+        }
+    }
+    return nil
+}
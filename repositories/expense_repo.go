@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"time"
+
 	"spodemy-backend/models"
 
 	"github.com/google/uuid"
@@ -35,17 +37,59 @@ func (r *ExpenseRepository) FindByID(id uuid.UUID) (*models.Expense, error) {
     return &e, nil
 }
 
-// Create inserts a new expense.
+// Create inserts a new expense and posts it to the ledger.
 func (r *ExpenseRepository) Create(e *models.Expense) error {
-    return r.db.Create(e).Error
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if err := setExpenseCurrency(tx, e); err != nil {
+            return err
+        }
+        if err := tx.Create(e).Error; err != nil {
+            return err
+        }
+        return postExpense(tx, e)
+    })
 }
 
-// Update modifies an existing expense.
+// Update modifies an existing expense. Its earlier ledger postings are
+// cancelled and it is posted again as it now stands.
 func (r *ExpenseRepository) Update(e *models.Expense) error {
-    return r.db.Save(e).Error
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if err := setExpenseCurrency(tx, e); err != nil {
+            return err
+        }
+        if err := tx.Save(e).Error; err != nil {
+            return err
+        }
+        if err := unpost(tx, models.SourceExpense, e.ID, time.Now(), "Expense changed: "+e.Description); err != nil {
+            return err
+        }
+        return postExpense(tx, e)
+    })
 }
 
-// Delete removes an expense by UUID.
+// Delete removes an expense by UUID and cancels its ledger postings.
 func (r *ExpenseRepository) Delete(id uuid.UUID) error {
-    return r.db.Delete(&models.Expense{}, "id = ?", id).Error
-}
\ No newline at end of file
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        var e models.Expense
+        if err := tx.First(&e, "id = ?", id).Error; err != nil {
+            return err
+        }
+        if err := tx.Delete(&e).Error; err != nil {
+            return err
+        }
+        return unpost(tx, models.SourceExpense, e.ID, time.Now(), "Expense deleted: "+e.Description)
+    })
+}
+
+// setExpenseCurrency puts an expense at a venue in the venue's currency.
+func setExpenseCurrency(tx *gorm.DB, e *models.Expense) error {
+    if e.VenueID == nil {
+        return nil
+    }
+    currency, err := venueCurrency(tx, *e.VenueID)
+    if err != nil {
+        return err
+    }
+    e.Currency = currency
+    return nil
+}
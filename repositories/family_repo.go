@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"time"
+
+	"spodemy-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FamilyRepository handles DB operations for Family.
+type FamilyRepository struct {
+    db *gorm.DB
+}
+
+// NewFamilyRepository constructs a FamilyRepository.
+func NewFamilyRepository(db *gorm.DB) *FamilyRepository {
+    return &FamilyRepository{db: db}
+}
+
+// FindAll returns all families with their members.
+func (r *FamilyRepository) FindAll() ([]models.Family, error) {
+    var fs []models.Family
+    if err := r.db.Preload("Members.User").Order("name").Find(&fs).Error; err != nil {
+        return nil, err
+    }
+    return fs, nil
+}
+
+// FindByID returns one family with its members.
+func (r *FamilyRepository) FindByID(id uuid.UUID) (*models.Family, error) {
+    var f models.Family
+    if err := r.db.Preload("Members.User").First(&f, "id = ?", id).Error; err != nil {
+        return nil, err
+    }
+    return &f, nil
+}
+
+// Create inserts a new family.
+func (r *FamilyRepository) Create(f *models.Family) error {
+    return r.db.Omit(clause.Associations).Create(f).Error
+}
+
+// Update renames a family.
+func (r *FamilyRepository) Update(f *models.Family) error {
+    return r.db.Model(&models.Family{}).Where("id = ?", f.ID).Update("name", f.Name).Error
+}
+
+// Delete removes a family and its memberships.
+func (r *FamilyRepository) Delete(id uuid.UUID) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Where("family_id = ?", id).Delete(&models.FamilyMember{}).Error; err != nil {
+            return err
+        }
+        return tx.Delete(&models.Family{}, "id = ?", id).Error
+    })
+}
+
+// FindMembership returns the family membership of a user.
+func (r *FamilyRepository) FindMembership(userID uuid.UUID) (*models.FamilyMember, error) {
+    var m models.FamilyMember
+    if err := r.db.First(&m, "user_id = ?", userID).Error; err != nil {
+        return nil, err
+    }
+    return &m, nil
+}
+
+// AddMember inserts a membership.
+func (r *FamilyRepository) AddMember(m *models.FamilyMember) error {
+    return r.db.Omit(clause.Associations).Create(m).Error
+}
+
+// RemoveMember deletes a user's membership of a family.
+func (r *FamilyRepository) RemoveMember(familyID, userID uuid.UUID) (bool, error) {
+    res := r.db.Where("family_id = ? AND user_id = ?", familyID, userID).Delete(&models.FamilyMember{})
+    return res.RowsAffected > 0, res.Error
+}
+
+// CountEnrolledSiblings counts the other children in studentID's family who
+// have a current regular enrollment, ignoring the enrollment exclude.
+func (r *FamilyRepository) CountEnrolledSiblings(studentID, exclude uuid.UUID) (int64, error) {
+    var n int64
+    err := r.db.Raw(`
+        SELECT COUNT(DISTINCT e.student_id)
+        FROM family_members me
+        JOIN family_members sib ON sib.family_id = me.family_id AND sib.user_id <> me.user_id AND sib.role = ?
+        JOIN enrollments e ON e.student_id = sib.user_id
+        WHERE me.user_id = ? AND e.id <> ? AND e.type = ? AND e.status IN ?`,
+        models.FamilyStudent, studentID, exclude, models.EnrollmentRegular,
+        []string{models.EnrollmentPendingPayment, models.EnrollmentActive, models.EnrollmentPaused}).
+        Scan(&n).Error
+    return n, err
+}
+
+// FindEnrollmentsStarting returns the regular enrollments of the students
+// that start in [from, to).
+func (r *FamilyRepository) FindEnrollmentsStarting(studentIDs []uuid.UUID, from, to time.Time) ([]models.Enrollment, error) {
+    var ens []models.Enrollment
+    if err := r.db.Where("student_id IN ? AND type = ? AND enrolled_on >= ? AND enrolled_on < ?",
+        studentIDs, models.EnrollmentRegular, from, to).
+        Preload("Batch").Preload("Plan").Order("enrolled_on").Find(&ens).Error; err != nil {
+        return nil, err
+    }
+    return ens, nil
+}
+
+// FindPaymentsBetween returns the payments made in [from, to) on the
+// students' enrollments.
+func (r *FamilyRepository) FindPaymentsBetween(studentIDs []uuid.UUID, from, to time.Time) ([]models.FeePayment, error) {
+    var ps []models.FeePayment
+    if err := r.db.Joins("JOIN enrollments e ON e.id = fee_payments.enrollment_id").
+        Where("e.student_id IN ? AND fee_payments.paid_on >= ? AND fee_payments.paid_on < ?", studentIDs, from, to).
+        Preload("Enrollment").Order("fee_payments.paid_on").Find(&ps).Error; err != nil {
+        return nil, err
+    }
+    return ps, nil
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"time"
+
+	"spodemy-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyRepository handles DB operations for idempotency keys.
+type IdempotencyRepository struct {
+    db *gorm.DB
+}
+
+// NewIdempotencyRepository constructs an IdempotencyRepository.
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+    return &IdempotencyRepository{db: db}
+}
+
+// Reserve claims k for a new request. If the key is already held and not
+// expired, the stored key is returned instead and nothing is claimed.
+func (r *IdempotencyRepository) Reserve(k *models.IdempotencyKey) (*models.IdempotencyKey, error) {
+    var existing *models.IdempotencyKey
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Where("scope = ? AND key = ? AND expires_at <= ?", k.Scope, k.Key, time.Now()).
+            Delete(&models.IdempotencyKey{}).Error; err != nil {
+            return err
+        }
+        res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(k)
+        if res.Error != nil || res.RowsAffected > 0 {
+            return res.Error
+        }
+        existing = &models.IdempotencyKey{}
+        return tx.First(existing, "scope = ? AND key = ?", k.Scope, k.Key).Error
+    })
+    return existing, err
+}
+
+// Complete stores the response to the request that holds a key.
+func (r *IdempotencyRepository) Complete(scope, key string, status int, contentType string, body []byte) error {
+    return r.db.Model(&models.IdempotencyKey{}).Where("scope = ? AND key = ?", scope, key).
+        Updates(map[string]interface{}{"status": status, "content_type": contentType, "body": body}).Error
+}
+
+// Release gives up a key so that the request can be tried again.
+func (r *IdempotencyRepository) Release(scope, key string) error {
+    return r.db.Where("scope = ? AND key = ?", scope, key).Delete(&models.IdempotencyKey{}).Error
+}
+
+// DeleteExpired removes the keys that expired by now.
+func (r *IdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+    res := r.db.Where("expires_at <= ?", now).Delete(&models.IdempotencyKey{})
+    return res.RowsAffected, res.Error
+}
@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"strings"
+
+	"spodemy-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ImportEntry is one enrollment to import, with its student and opening
+// payment. Entries may share a Student; it is created once if it has no ID.
+type ImportEntry struct {
+    Student    *models.User
+    Enrollment *models.Enrollment
+    Payment    *models.FeePayment // nil if nothing was paid
+}
+
+// ImportRepository handles DB operations for bulk imports.
+type ImportRepository struct {
+    db *gorm.DB
+}
+
+// NewImportRepository constructs an ImportRepository.
+func NewImportRepository(db *gorm.DB) *ImportRepository {
+    return &ImportRepository{db: db}
+}
+
+// FindJobs returns import jobs without their row details, newest first.
+func (r *ImportRepository) FindJobs() ([]models.ImportJob, error) {
+    var js []models.ImportJob
+    if err := r.db.Omit("rows").Order("created_at DESC").Find(&js).Error; err != nil {
+        return nil, err
+    }
+    return js, nil
+}
+
+// FindJob returns one import job by UUID.
+func (r *ImportRepository) FindJob(id uuid.UUID) (*models.ImportJob, error) {
+    var j models.ImportJob
+    if err := r.db.First(&j, "id = ?", id).Error; err != nil {
+        return nil, err
+    }
+    return &j, nil
+}
+
+// CreateJob inserts a new import job.
+func (r *ImportRepository) CreateJob(j *models.ImportJob) error {
+    return r.db.Create(j).Error
+}
+
+// SaveJob stores the state of an import job.
+func (r *ImportRepository) SaveJob(j *models.ImportJob) error {
+    return r.db.Save(j).Error
+}
+
+// SaveProgress stores how many rows of a job have been processed.
+func (r *ImportRepository) SaveProgress(id uuid.UUID, processed int) error {
+    return r.db.Model(&models.ImportJob{}).Where("id = ?", id).Update("processed_rows", processed).Error
+}
+
+// FindUsersByEmail returns the users with the given emails, keyed by lower
+// case email.
+func (r *ImportRepository) FindUsersByEmail(emails []string) (map[string]models.User, error) {
+    users := make(map[string]models.User)
+    if len(emails) == 0 {
+        return users, nil
+    }
+    var us []models.User
+    if err := r.db.Where("LOWER(email) IN ?", emails).Find(&us).Error; err != nil {
+        return nil, err
+    }
+    for _, u := range us {
+        users[strings.ToLower(u.Email)] = u
+    }
+    return users, nil
+}
+
+// FindBatches returns the batches of a venue, or every batch if venueID is nil.
+func (r *ImportRepository) FindBatches(venueID *uuid.UUID) ([]models.Batch, error) {
+    var bs []models.Batch
+    q := r.db
+    if venueID != nil {
+        q = q.Where("venue_id = ?", *venueID)
+    }
+    if err := q.Find(&bs).Error; err != nil {
+        return nil, err
+    }
+    return bs, nil
+}
+
+// Import creates every entry's student, enrollment and payment in one
+// transaction, so either all rows are imported or none. progress is called
+// with the number of entries done so far.
+func (r *ImportRepository) Import(entries []ImportEntry, actor *uuid.UUID, progress func(done int)) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        for i, en := range entries {
+            if en.Student.ID == uuid.Nil {
+                en.Student.ReferralCode = newReferralCode()
+                if err := tx.Omit(clause.Associations).Create(en.Student).Error; err != nil {
+                    return err
+                }
+            }
+            en.Enrollment.StudentID = en.Student.ID
+            if err := createWithHistory(tx, en.Enrollment, "imported", actor); err != nil {
+                return err
+            }
+            if en.Payment != nil {
+                en.Payment.EnrollmentID = en.Enrollment.ID
+                en.Payment.Currency = en.Enrollment.Currency
+                en.Payment.UnallocatedCents = en.Payment.AmountCents
+                if err := tx.Omit(clause.Associations).Create(en.Payment).Error; err != nil {
+                    return err
+                }
+                if err := postPayment(tx, en.Payment); err != nil {
+                    return err
+                }
+                if err := allocatePayments(tx, en.Enrollment.ID); err != nil {
+                    return err
+                }
+            }
+            progress(i + 1)
+        }
+        return nil
+    })
+}
@@ -0,0 +1,207 @@
+package repositories
+
+import (
+	"math"
+	"time"
+
+	"spodemy-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Status change reasons used by installment billing.
+const (
+    ReasonInstallmentOverdue = "installment overdue"
+    ReasonInstallmentPaid    = "overdue installment paid"
+)
+
+// InstallmentRepository handles DB operations for enrollment installments.
+type InstallmentRepository struct {
+    db *gorm.DB
+}
+
+// NewInstallmentRepository constructs an InstallmentRepository.
+func NewInstallmentRepository(db *gorm.DB) *InstallmentRepository {
+    return &InstallmentRepository{db: db}
+}
+
+// FindByEnrollment returns an enrollment's installments in order.
+func (r *InstallmentRepository) FindByEnrollment(enrID uuid.UUID) ([]models.Installment, error) {
+    var is []models.Installment
+    if err := r.db.Where("enrollment_id = ?", enrID).Order("seq").Find(&is).Error; err != nil {
+        return nil, err
+    }
+    return is, nil
+}
+
+// FindByStatus returns installments in a status with their enrollment and
+// student, oldest due date first. An empty status returns every installment.
+func (r *InstallmentRepository) FindByStatus(status string) ([]models.Installment, error) {
+    var is []models.Installment
+    q := r.db.Preload("Enrollment").Preload("Enrollment.Student").Order("due_on, seq")
+    if status != "" {
+        q = q.Where("status = ?", status)
+    }
+    if err := q.Find(&is).Error; err != nil {
+        return nil, err
+    }
+    return is, nil
+}
+
+// MarkOverdue turns installments still due before t overdue and pauses their
+// active enrollments until they are paid.
+func (r *InstallmentRepository) MarkOverdue(t time.Time) (int64, error) {
+    var n int64
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        var is []models.Installment
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+            Where("status = ? AND due_on < ?", models.InstallmentDue, t).Find(&is).Error; err != nil {
+            return err
+        }
+        now := time.Now()
+        paused := make(map[uuid.UUID]bool)
+        for _, in := range is {
+            if err := tx.Model(&in).Update("status", models.InstallmentOverdue).Error; err != nil {
+                return err
+            }
+            n++
+            if paused[in.EnrollmentID] {
+                continue
+            }
+            paused[in.EnrollmentID] = true
+            if _, err := transition(tx, &models.EnrollmentStatusHistory{
+                EnrollmentID: in.EnrollmentID,
+                FromStatus:   models.EnrollmentActive,
+                ToStatus:     models.EnrollmentPaused,
+                Reason:       ReasonInstallmentOverdue,
+                ChangedAt:    now,
+            }); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+    return n, err
+}
+
+// issueInstallments splits the price of a new enrollment on a plan paid in
+// installments into its dated parts. Rounding goes to the last part.
+func issueInstallments(tx *gorm.DB, e *models.Enrollment) error {
+    if e.PlanID == nil || e.PriceCents <= 0 {
+        return nil
+    }
+    terms, err := invoicedTerms(tx, *e.PlanID, e.PlanVersionID)
+    if err != nil {
+        return err
+    }
+    if len(terms.Installments) == 0 {
+        return nil
+    }
+    is := make([]models.Installment, len(terms.Installments))
+    left := e.PriceCents
+    for i, t := range terms.Installments {
+        amount := left
+        if i < len(terms.Installments)-1 {
+            amount = min(int(math.Round(float64(e.PriceCents)*t.Pct/100)), left)
+        }
+        left -= amount
+        is[i] = models.Installment{
+            EnrollmentID: e.ID,
+            Seq:          i + 1,
+            DueOn:        e.EnrolledOn.AddDate(0, 0, t.DueAfterDays),
+            AmountCents:  amount,
+            Currency:     e.Currency,
+            Status:       models.InstallmentDue,
+        }
+    }
+    return tx.Create(&is).Error
+}
+
+// reissueInstallments replaces the installments of a repriced enrollment
+// with the schedule for its new price; payments are allocated to them
+// afresh. If the old schedule was overdue, new installments already past
+// due start overdue, so a pause for it holds until they are paid.
+func reissueInstallments(tx *gorm.DB, e *models.Enrollment) error {
+    var old []models.Installment
+    if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+        Where("enrollment_id = ?", e.ID).Find(&old).Error; err != nil {
+        return err
+    }
+    overdue := false
+    for _, in := range old {
+        overdue = overdue || in.Status == models.InstallmentOverdue
+    }
+    if err := tx.Where("enrollment_id = ?", e.ID).Delete(&models.Installment{}).Error; err != nil {
+        return err
+    }
+    if err := issueInstallments(tx, e); err != nil {
+        return err
+    }
+    if !overdue {
+        return nil
+    }
+    return tx.Model(&models.Installment{}).
+        Where("enrollment_id = ? AND status = ? AND due_on < ?", e.ID, models.InstallmentDue, time.Now()).
+        Update("status", models.InstallmentOverdue).Error
+}
+
+// allocateInstallments puts what has been paid towards an enrollment's
+// price, net of refunds and reversals, on its installments oldest first. A
+// coupon discount counts as paid, as it settles that much of the price.
+// Payments for renewal charges do not count. Once no installment is
+// overdue any more, an enrollment paused for one is resumed.
+func allocateInstallments(tx *gorm.DB, enrollmentID uuid.UUID) error {
+    var is []models.Installment
+    if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+        Where("enrollment_id = ?", enrollmentID).Order("seq").Find(&is).Error; err != nil {
+        return err
+    }
+    if len(is) == 0 {
+        return nil
+    }
+    var paid int
+    if err := tx.Raw(`
+        SELECT COALESCE(SUM(p.amount_cents + p.discount_cents - p.refunded_cents), 0)
+        FROM fee_payments p
+        LEFT JOIN fee_payments o ON o.id = p.reversal_of_id
+        WHERE p.enrollment_id = ? AND p.charge_id IS NULL AND o.charge_id IS NULL`, enrollmentID).
+        Scan(&paid).Error; err != nil {
+        return err
+    }
+
+    now := time.Now()
+    wasOverdue, overdue := false, false
+    for _, in := range is {
+        wasOverdue = wasOverdue || in.Status == models.InstallmentOverdue
+        cents := max(min(in.AmountCents, paid), 0)
+        paid -= cents
+        status, paidAt := in.Status, in.PaidAt
+        switch {
+        case cents >= in.AmountCents:
+            status = models.InstallmentPaid
+            if paidAt == nil {
+                paidAt = &now
+            }
+        case in.Status == models.InstallmentPaid:
+            // a refund or reversal took the payment back
+            status, paidAt = models.InstallmentDue, nil
+        }
+        overdue = overdue || status == models.InstallmentOverdue
+        if cents == in.PaidCents && status == in.Status {
+            continue
+        }
+        if err := tx.Model(&in).Updates(map[string]interface{}{
+            "paid_cents": cents,
+            "status":     status,
+            "paid_at":    paidAt,
+        }).Error; err != nil {
+            return err
+        }
+    }
+    if !wasOverdue || overdue {
+        return nil
+    }
+    return resumePaused(tx, enrollmentID, ReasonInstallmentOverdue, ReasonInstallmentPaid, now)
+}
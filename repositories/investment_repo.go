@@ -1,12 +1,19 @@
 package repositories
 
 import (
+	"errors"
+	"time"
+
 	"spodemy-backend/models"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrTransactionReversed is returned when reversing a transaction twice.
+var ErrTransactionReversed = errors.New("transaction has been reversed")
+
 // InvestmentRepository handles DB operations for investments & transactions.
 type InvestmentRepository struct {
     db *gorm.DB
@@ -35,14 +42,20 @@ func (r *InvestmentRepository) FindByID(id uuid.UUID) (*models.Investment, error
     return &inv, nil
 }
 
-// Create inserts a new investment.
+// Create inserts a new investment in its venue's currency.
 func (r *InvestmentRepository) Create(inv *models.Investment) error {
+    currency, err := venueCurrency(r.db, inv.VenueID)
+    if err != nil {
+        return err
+    }
+    inv.Currency = currency
     return r.db.Create(inv).Error
 }
 
-// Update modifies an existing investment.
+// Update modifies an existing investment. Its currency stays the one it
+// was made in, as its transactions are priced in it.
 func (r *InvestmentRepository) Update(inv *models.Investment) error {
-    return r.db.Save(inv).Error
+    return r.db.Omit("currency").Save(inv).Error
 }
 
 // Delete removes an investment by UUID.
@@ -59,7 +72,60 @@ func (r *InvestmentRepository) FindTransactions(invID uuid.UUID) ([]models.Inves
     return txns, nil
 }
 
-// CreateTransaction adds a new transaction linked to an investment.
+// CreateTransaction adds a new transaction linked to an investment and
+// posts it to the ledger.
 func (r *InvestmentRepository) CreateTransaction(txn *models.InvestmentTransaction) error {
-    return r.db.Create(txn).Error
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        var inv models.Investment
+        if err := tx.Select("currency").First(&inv, "id = ?", txn.InvestmentID).Error; err != nil {
+            return err
+        }
+        txn.Currency = inv.Currency
+        if err := tx.Create(txn).Error; err != nil {
+            return err
+        }
+        return postInvestment(tx, txn)
+    })
+}
+
+// ReverseTransaction cancels a transaction of an investment with a reversal
+// of the negated units. Reversals cannot themselves be reversed.
+func (r *InvestmentRepository) ReverseTransaction(invID, txnID uuid.UUID, reason string) (*models.InvestmentTransaction, error) {
+    var rev models.InvestmentTransaction
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        var txn models.InvestmentTransaction
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+            First(&txn, "id = ? AND investment_id = ?", txnID, invID).Error; err != nil {
+            return err
+        }
+        if txn.ReversalOfID != nil {
+            return ErrReverseReversal
+        }
+        var n int64
+        if err := tx.Model(&models.InvestmentTransaction{}).Where("reversal_of_id = ?", txn.ID).Count(&n).Error; err != nil {
+            return err
+        }
+        if n > 0 {
+            return ErrTransactionReversed
+        }
+        rev = models.InvestmentTransaction{
+            InvestmentID:   txn.InvestmentID,
+            Type:           txn.Type,
+            Units:          -txn.Units,
+            PriceCents:     txn.PriceCents,
+            Currency:       txn.Currency,
+            TransactionRef: txn.TransactionRef,
+            TxnDate:        time.Now(),
+            ReversalOfID:   &txn.ID,
+            Reason:         reason,
+        }
+        if err := tx.Create(&rev).Error; err != nil {
+            return err
+        }
+        return postInvestment(tx, &rev)
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &rev, nil
 }
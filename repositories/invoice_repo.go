@@ -0,0 +1,362 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"spodemy-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// InvoiceFilter narrows the invoices listed; zero fields do not filter.
+type InvoiceFilter struct {
+    EnrollmentID *uuid.UUID
+    StudentID    *uuid.UUID
+    VenueID      *uuid.UUID
+    Status       string
+}
+
+// InvoiceRepository handles DB operations for invoices.
+type InvoiceRepository struct {
+    db *gorm.DB
+}
+
+// NewInvoiceRepository constructs an InvoiceRepository.
+func NewInvoiceRepository(db *gorm.DB) *InvoiceRepository {
+    return &InvoiceRepository{db: db}
+}
+
+// FindAll returns the invoices matching f with their lines, newest first.
+func (r *InvoiceRepository) FindAll(f InvoiceFilter) ([]models.Invoice, error) {
+    q := r.db.Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+        Order("issued_on DESC, seq DESC")
+    if f.EnrollmentID != nil {
+        q = q.Where("enrollment_id = ?", *f.EnrollmentID)
+    }
+    if f.StudentID != nil {
+        q = q.Where("student_id = ?", *f.StudentID)
+    }
+    if f.VenueID != nil {
+        q = q.Where("venue_id = ?", *f.VenueID)
+    }
+    if f.Status != "" {
+        q = q.Where("status = ?", f.Status)
+    }
+    var invs []models.Invoice
+    if err := q.Find(&invs).Error; err != nil {
+        return nil, err
+    }
+    return invs, nil
+}
+
+// FindByID returns one invoice with its venue, student, lines and allocations.
+func (r *InvoiceRepository) FindByID(id uuid.UUID) (*models.Invoice, error) {
+    var inv models.Invoice
+    if err := r.db.Preload("Venue").Preload("Student").
+        Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+        Preload("Allocations", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+        First(&inv, "id = ?", id).Error; err != nil {
+        return nil, err
+    }
+    return &inv, nil
+}
+
+// issueEnrollmentInvoice invoices a new enrollment on a plan: the plan price,
+// less each offer and the sibling discount.
+func issueEnrollmentInvoice(tx *gorm.DB, e *models.Enrollment) error {
+    if e.PlanID == nil || e.PriceCents <= 0 {
+        return nil
+    }
+    terms, err := invoicedTerms(tx, *e.PlanID, e.PlanVersionID)
+    if err != nil {
+        return err
+    }
+    lines := []models.InvoiceLine{{Description: periodLine(terms.Name, e.EnrolledOn, e.ValidUntil), AmountCents: terms.PriceCents}}
+    if len(e.AppliedOffers) > 0 {
+        ids := make([]uuid.UUID, len(e.AppliedOffers))
+        for i, ao := range e.AppliedOffers {
+            ids[i] = ao.OfferID
+        }
+        var offers []models.Offer
+        if err := tx.Select("id", "name").Find(&offers, "id IN ?", ids).Error; err != nil {
+            return err
+        }
+        names := make(map[uuid.UUID]string, len(offers))
+        for _, o := range offers {
+            names[o.ID] = o.Name
+        }
+        for _, ao := range e.AppliedOffers {
+            lines = append(lines, models.InvoiceLine{Description: "Offer: " + names[ao.OfferID], AmountCents: -ao.AmountCents})
+        }
+    }
+    if e.SiblingDiscountCents > 0 {
+        lines = append(lines, models.InvoiceLine{Description: "Sibling discount", AmountCents: -e.SiblingDiscountCents})
+    }
+    return issueInvoice(tx, &models.Invoice{
+        StudentID:    e.StudentID,
+        EnrollmentID: e.ID,
+        Currency:     e.Currency,
+        DueOn:        e.EnrolledOn,
+        Lines:        lines,
+        TaxInclusive: terms.TaxInclusive,
+        TaxBreakdown: e.TaxBreakdown,
+        TotalCents:   e.PriceCents,
+    }, e.BatchID)
+}
+
+// reissueEnrollmentInvoice voids the invoice of a repriced enrollment and
+// invoices it again at its new price. Payments allocated to the old invoice
+// go towards the new one and the enrollment's installments.
+func reissueEnrollmentInvoice(tx *gorm.DB, e *models.Enrollment) error {
+    var invs []models.Invoice
+    if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+        Where("enrollment_id = ? AND charge_id IS NULL AND status <> ?", e.ID, models.InvoiceVoid).
+        Find(&invs).Error; err != nil {
+        return err
+    }
+    for i := range invs {
+        if err := voidInvoice(tx, &invs[i]); err != nil {
+            return err
+        }
+    }
+    if err := issueEnrollmentInvoice(tx, e); err != nil {
+        return err
+    }
+    return allocatePayments(tx, e.ID)
+}
+
+// voidInvoice cancels inv and marks what was allocated to it unallocated on
+// its payments again.
+func voidInvoice(tx *gorm.DB, inv *models.Invoice) error {
+    var allocs []models.PaymentAllocation
+    if err := tx.Where("invoice_id = ?", inv.ID).Find(&allocs).Error; err != nil {
+        return err
+    }
+    for _, a := range allocs {
+        if err := tx.Model(&models.FeePayment{}).Where("id = ?", a.FeePaymentID).
+            Update("unallocated_cents", gorm.Expr("unallocated_cents + ?", a.AmountCents)).Error; err != nil {
+            return err
+        }
+    }
+    if err := tx.Where("invoice_id = ?", inv.ID).Delete(&models.PaymentAllocation{}).Error; err != nil {
+        return err
+    }
+    return tx.Model(inv).Updates(map[string]interface{}{"paid_cents": 0, "status": models.InvoiceVoid}).Error
+}
+
+// issueChargeInvoice invoices renewal charge c of e, less renewal credit.
+func issueChargeInvoice(tx *gorm.DB, e *models.Enrollment, c *models.Charge) error {
+    if e.PlanID == nil || c.AmountCents+c.CreditCents <= 0 {
+        return nil
+    }
+    terms, err := invoicedTerms(tx, *e.PlanID, e.PlanVersionID)
+    if err != nil {
+        return err
+    }
+    amount := c.AmountCents + c.CreditCents
+    if !terms.TaxInclusive {
+        amount -= c.TaxCents
+    }
+    end := c.PeriodEnd.AddDate(0, 0, -1)
+    lines := []models.InvoiceLine{{Description: periodLine(terms.Name+" renewal", c.PeriodStart, &end), AmountCents: amount}}
+    if c.CreditCents > 0 {
+        lines = append(lines, models.InvoiceLine{Description: "Renewal credit", AmountCents: -c.CreditCents})
+    }
+    return issueInvoice(tx, &models.Invoice{
+        StudentID:    e.StudentID,
+        EnrollmentID: e.ID,
+        ChargeID:     &c.ID,
+        Currency:     e.Currency,
+        DueOn:        c.DueOn,
+        Lines:        lines,
+        TaxInclusive: terms.TaxInclusive,
+        TaxBreakdown: c.TaxBreakdown,
+        TotalCents:   c.AmountCents,
+    }, e.BatchID)
+}
+
+// issueInvoice numbers inv at the venue of batchID and inserts it, then puts
+// any unallocated payments of its enrollment towards it. The venue's
+// sequence row stays locked until tx ends, so concurrent invoices queue for
+// the next number and a rolled back invoice gives its number back.
+func issueInvoice(tx *gorm.DB, inv *models.Invoice, batchID uuid.UUID) error {
+    var v models.Venue
+    if err := tx.Select("venues.id", "venues.invoice_prefix").Joins("JOIN batches b ON b.venue_id = venues.id").
+        First(&v, "b.id = ?", batchID).Error; err != nil {
+        return err
+    }
+    if err := tx.Raw(`
+        INSERT INTO invoice_sequences (venue_id, last_seq) VALUES (?, 1)
+        ON CONFLICT (venue_id) DO UPDATE SET last_seq = invoice_sequences.last_seq + 1
+        RETURNING last_seq`, v.ID).Scan(&inv.Seq).Error; err != nil {
+        return err
+    }
+    prefix := v.InvoicePrefix
+    if prefix == "" {
+        prefix = strings.ToUpper(v.ID.String()[:8])
+    }
+    inv.VenueID = v.ID
+    inv.Number = fmt.Sprintf("%s-%06d", prefix, inv.Seq)
+    inv.IssuedOn = time.Now()
+    inv.Status = models.InvoiceOpen
+
+    inv.SubtotalCents = 0
+    for i := range inv.Lines {
+        inv.Lines[i].Position = i + 1
+        inv.SubtotalCents += inv.Lines[i].AmountCents
+    }
+    expected := inv.SubtotalCents
+    if !inv.TaxInclusive {
+        expected += inv.TaxCents
+    }
+    if diff := inv.TotalCents - expected; diff != 0 {
+        inv.Lines = append(inv.Lines, models.InvoiceLine{Position: len(inv.Lines) + 1, Description: "Rounding adjustment", AmountCents: diff})
+        inv.SubtotalCents += diff
+    }
+    if err := tx.Omit("Venue", "Student", "Allocations").Create(inv).Error; err != nil {
+        return err
+    }
+    return allocatePayments(tx, inv.EnrollmentID)
+}
+
+// invoicedTerms returns the plan terms an enrollment bought.
+func invoicedTerms(tx *gorm.DB, planID uuid.UUID, versionID *uuid.UUID) (models.PlanTerms, error) {
+    if versionID != nil {
+        var v models.PlanVersion
+        err := tx.First(&v, "id = ?", *versionID).Error
+        return v.PlanTerms, err
+    }
+    var p models.Plan
+    err := tx.First(&p, "id = ?", planID).Error
+    return p.PlanTerms, err
+}
+
+// periodLine describes a plan bought for a period, both days included.
+func periodLine(name string, from time.Time, to *time.Time) string {
+    if to == nil {
+        return fmt.Sprintf("%s, from %s", name, from.Format("2 Jan 2006"))
+    }
+    return fmt.Sprintf("%s, %s to %s", name, from.Format("2 Jan 2006"), to.Format("2 Jan 2006"))
+}
+
+// allocatePayments puts an enrollment's payments towards its invoices and
+// its installments.
+func allocatePayments(tx *gorm.DB, enrollmentID uuid.UUID) error {
+    if err := allocateInvoices(tx, enrollmentID); err != nil {
+        return err
+    }
+    return allocateInstallments(tx, enrollmentID)
+}
+
+// allocateInvoices puts the unallocated part of an enrollment's payments
+// towards its unpaid invoices. A payment for a renewal charge goes to that
+// charge's invoice first; everything else is allocated oldest first. What
+// is left over stays unallocated for later invoices.
+func allocateInvoices(tx *gorm.DB, enrollmentID uuid.UUID) error {
+    var invs []models.Invoice
+    if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+        Where("enrollment_id = ? AND status NOT IN ?", enrollmentID, []string{models.InvoicePaid, models.InvoiceVoid}).
+        Order("issued_on, seq").Find(&invs).Error; err != nil {
+        return err
+    }
+    if len(invs) == 0 {
+        return nil
+    }
+    var ps []models.FeePayment
+    if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+        Where("enrollment_id = ? AND unallocated_cents > 0", enrollmentID).
+        Order("paid_on, id").Find(&ps).Error; err != nil {
+        return err
+    }
+    if len(ps) == 0 {
+        return nil
+    }
+
+    var allocs []models.PaymentAllocation
+    allocate := func(inv *models.Invoice, p *models.FeePayment) {
+        amount := min(inv.TotalCents-inv.PaidCents, p.UnallocatedCents)
+        if amount <= 0 {
+            return
+        }
+        allocs = append(allocs, models.PaymentAllocation{InvoiceID: inv.ID, FeePaymentID: p.ID, AmountCents: amount})
+        inv.PaidCents += amount
+        p.UnallocatedCents -= amount
+    }
+    for i := range ps {
+        if ps[i].ChargeID == nil {
+            continue
+        }
+        for j := range invs {
+            if invs[j].ChargeID != nil && *invs[j].ChargeID == *ps[i].ChargeID {
+                allocate(&invs[j], &ps[i])
+            }
+        }
+    }
+    for j := range invs {
+        for i := range ps {
+            allocate(&invs[j], &ps[i])
+        }
+    }
+    if len(allocs) == 0 {
+        return nil
+    }
+
+    if err := tx.Create(&allocs).Error; err != nil {
+        return err
+    }
+    for _, inv := range invs {
+        if err := tx.Model(&inv).Updates(map[string]interface{}{
+            "paid_cents": inv.PaidCents,
+            "status":     invoiceStatus(inv.PaidCents, inv.TotalCents),
+        }).Error; err != nil {
+            return err
+        }
+    }
+    for _, p := range ps {
+        if err := tx.Model(&p).Update("unallocated_cents", p.UnallocatedCents).Error; err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// releaseAllocations takes a payment's allocations back off their invoices
+// and marks what was not refunded of the payment unallocated.
+func releaseAllocations(tx *gorm.DB, p *models.FeePayment) error {
+    var allocs []models.PaymentAllocation
+    if err := tx.Where("fee_payment_id = ?", p.ID).Find(&allocs).Error; err != nil {
+        return err
+    }
+    for _, a := range allocs {
+        var inv models.Invoice
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&inv, "id = ?", a.InvoiceID).Error; err != nil {
+            return err
+        }
+        paid := inv.PaidCents - a.AmountCents
+        if err := tx.Model(&inv).Updates(map[string]interface{}{
+            "paid_cents": paid,
+            "status":     invoiceStatus(paid, inv.TotalCents),
+        }).Error; err != nil {
+            return err
+        }
+    }
+    if err := tx.Where("fee_payment_id = ?", p.ID).Delete(&models.PaymentAllocation{}).Error; err != nil {
+        return err
+    }
+    p.UnallocatedCents = p.AmountCents - p.RefundedCents
+    return nil
+}
+
+func invoiceStatus(paid, total int) string {
+    switch {
+    case paid >= total:
+        return models.InvoicePaid
+    case paid > 0:
+        return models.InvoicePartiallyPaid
+    }
+    return models.InvoiceOpen
+}
@@ -0,0 +1,390 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"spodemy-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrUnbalanced is returned for a journal entry whose debits and credits
+// differ.
+var ErrUnbalanced = errors.New("journal entry debits and credits must be equal")
+
+// LedgerFilter narrows journal entries and balances; zero fields do not
+// filter. From and To bound the entry date, To exclusive.
+type LedgerFilter struct {
+    VenueID    *uuid.UUID
+    Currency   string
+    From       *time.Time
+    To         *time.Time
+    SourceType string
+}
+
+// TrialBalanceRow is the total debited and credited to one account in one
+// currency.
+type TrialBalanceRow struct {
+    AccountID    uuid.UUID `json:"account_id"`
+    Code         string    `json:"code"`
+    Name         string    `json:"name"`
+    Type         string    `json:"type"`
+    Currency     string    `json:"currency"`
+    DebitCents   int64     `json:"debit_cents"`
+    CreditCents  int64     `json:"credit_cents"`
+    BalanceCents int64     `json:"balance_cents"` // on the account's normal side
+}
+
+// StatementLine is one posting to an account.
+type StatementLine struct {
+    JournalEntryID uuid.UUID  `json:"journal_entry_id"`
+    EntryDate      time.Time  `json:"entry_date"`
+    Description    string     `json:"description"`
+    SourceType     string     `json:"source_type"`
+    SourceID       *uuid.UUID `json:"source_id,omitempty"`
+    VenueID        *uuid.UUID `json:"venue_id,omitempty"`
+    Memo           string     `json:"memo,omitempty"`
+    DebitCents     int64      `json:"debit_cents"`
+    CreditCents    int64      `json:"credit_cents"`
+}
+
+// LedgerRepository handles DB operations for the general ledger.
+type LedgerRepository struct {
+    db *gorm.DB
+}
+
+// NewLedgerRepository constructs a LedgerRepository.
+func NewLedgerRepository(db *gorm.DB) *LedgerRepository {
+    return &LedgerRepository{db: db}
+}
+
+// FindAccounts returns the chart of accounts by code.
+func (r *LedgerRepository) FindAccounts() ([]models.Account, error) {
+    var accts []models.Account
+    if err := r.db.Order("code").Find(&accts).Error; err != nil {
+        return nil, err
+    }
+    return accts, nil
+}
+
+// FindAccount returns one account by UUID.
+func (r *LedgerRepository) FindAccount(id uuid.UUID) (*models.Account, error) {
+    var a models.Account
+    if err := r.db.First(&a, "id = ?", id).Error; err != nil {
+        return nil, err
+    }
+    return &a, nil
+}
+
+// CountAccountsByCode counts the accounts with the given code.
+func (r *LedgerRepository) CountAccountsByCode(code string) (int64, error) {
+    var n int64
+    err := r.db.Model(&models.Account{}).Where("code = ?", code).Count(&n).Error
+    return n, err
+}
+
+// CountAccounts counts the accounts among ids.
+func (r *LedgerRepository) CountAccounts(ids []uuid.UUID) (int64, error) {
+    var n int64
+    err := r.db.Model(&models.Account{}).Where("id IN ?", ids).Count(&n).Error
+    return n, err
+}
+
+// CreateAccount inserts a new account.
+func (r *LedgerRepository) CreateAccount(a *models.Account) error {
+    return r.db.Create(a).Error
+}
+
+// FindEntries returns the journal entries matching f with their lines,
+// newest first.
+func (r *LedgerRepository) FindEntries(f LedgerFilter) ([]models.JournalEntry, error) {
+    q := r.db.Preload("Lines.Account")
+    if f.VenueID != nil {
+        q = q.Where("venue_id = ?", *f.VenueID)
+    }
+    if f.Currency != "" {
+        q = q.Where("currency = ?", f.Currency)
+    }
+    if f.From != nil {
+        q = q.Where("entry_date >= ?", *f.From)
+    }
+    if f.To != nil {
+        q = q.Where("entry_date < ?", *f.To)
+    }
+    if f.SourceType != "" {
+        q = q.Where("source_type = ?", f.SourceType)
+    }
+    var es []models.JournalEntry
+    if err := q.Order("entry_date DESC, created_at DESC").Find(&es).Error; err != nil {
+        return nil, err
+    }
+    return es, nil
+}
+
+// FindEntry returns one journal entry by UUID with its lines.
+func (r *LedgerRepository) FindEntry(id uuid.UUID) (*models.JournalEntry, error) {
+    var e models.JournalEntry
+    if err := r.db.Preload("Lines.Account").First(&e, "id = ?", id).Error; err != nil {
+        return nil, err
+    }
+    return &e, nil
+}
+
+// CreateEntry inserts a balanced journal entry with its lines.
+func (r *LedgerRepository) CreateEntry(e *models.JournalEntry) error {
+    if !balanced(e.Lines) {
+        return ErrUnbalanced
+    }
+    return r.db.Create(e).Error
+}
+
+// TrialBalance totals the postings matching f by currency and account.
+// Accounts without postings are left out.
+func (r *LedgerRepository) TrialBalance(f LedgerFilter) ([]TrialBalanceRow, error) {
+    where, args := f.where()
+    var rows []TrialBalanceRow
+    err := r.db.Raw(`
+        SELECT a.id AS account_id, a.code, a.name, a.type, j.currency,
+               SUM(l.debit_cents) AS debit_cents, SUM(l.credit_cents) AS credit_cents,
+               CASE WHEN a.type IN ('asset', 'expense') THEN SUM(l.debit_cents) - SUM(l.credit_cents)
+                    ELSE SUM(l.credit_cents) - SUM(l.debit_cents) END AS balance_cents
+        FROM journal_lines l
+        JOIN journal_entries j ON j.id = l.journal_entry_id
+        JOIN accounts a ON a.id = l.account_id
+        WHERE `+where+`
+        GROUP BY j.currency, a.id, a.code, a.name, a.type
+        ORDER BY j.currency, a.code`, args...).Scan(&rows).Error
+    return rows, err
+}
+
+// Statement returns the postings to an account matching f, oldest first,
+// and the net debit of the postings before f.From.
+func (r *LedgerRepository) Statement(accountID uuid.UUID, f LedgerFilter) (int64, []StatementLine, error) {
+    var opening int64
+    if f.From != nil {
+        before := LedgerFilter{VenueID: f.VenueID, Currency: f.Currency, To: f.From}
+        where, args := before.where()
+        if err := r.db.Raw(`
+            SELECT COALESCE(SUM(l.debit_cents - l.credit_cents), 0)
+            FROM journal_lines l JOIN journal_entries j ON j.id = l.journal_entry_id
+            WHERE l.account_id = ? AND `+where, append([]interface{}{accountID}, args...)...).
+            Scan(&opening).Error; err != nil {
+            return 0, nil, err
+        }
+    }
+    where, args := f.where()
+    var lines []StatementLine
+    err := r.db.Raw(`
+        SELECT j.id AS journal_entry_id, j.entry_date, j.description, j.source_type, j.source_id, j.venue_id,
+               l.memo, l.debit_cents, l.credit_cents
+        FROM journal_lines l JOIN journal_entries j ON j.id = l.journal_entry_id
+        WHERE l.account_id = ? AND `+where+`
+        ORDER BY j.entry_date, j.created_at, l.id`, append([]interface{}{accountID}, args...)...).Scan(&lines).Error
+    return opening, lines, err
+}
+
+func (f LedgerFilter) where() (string, []interface{}) {
+    conds := []string{"TRUE"}
+    var args []interface{}
+    if f.VenueID != nil {
+        conds = append(conds, "j.venue_id = ?")
+        args = append(args, *f.VenueID)
+    }
+    if f.Currency != "" {
+        conds = append(conds, "j.currency = ?")
+        args = append(args, f.Currency)
+    }
+    if f.From != nil {
+        conds = append(conds, "j.entry_date >= ?")
+        args = append(args, *f.From)
+    }
+    if f.To != nil {
+        conds = append(conds, "j.entry_date < ?")
+        args = append(args, *f.To)
+    }
+    if f.SourceType != "" {
+        conds = append(conds, "j.source_type = ?")
+        args = append(args, f.SourceType)
+    }
+    return strings.Join(conds, " AND "), args
+}
+
+func balanced(lines []models.JournalLine) bool {
+    debit, credit := 0, 0
+    for _, l := range lines {
+        debit += l.DebitCents
+        credit += l.CreditCents
+    }
+    return debit == credit
+}
+
+// posting is an amount for a system account: positive to debit it,
+// negative to credit it.
+type posting struct {
+    code  string
+    cents int
+}
+
+// post records a journal entry in currency for a source record from
+// postings. Zero postings are left out, and nothing is posted if all are zero.
+func post(tx *gorm.DB, source string, sourceID uuid.UUID, venueID *uuid.UUID, currency string, date time.Time, desc string, postings ...posting) error {
+    codes := make([]string, len(postings))
+    for i, p := range postings {
+        codes[i] = p.code
+    }
+    var accts []models.Account
+    if err := tx.Where("code IN ?", codes).Find(&accts).Error; err != nil {
+        return err
+    }
+    ids := make(map[string]uuid.UUID, len(accts))
+    for _, a := range accts {
+        ids[a.Code] = a.ID
+    }
+
+    e := models.JournalEntry{
+        VenueID:     venueID,
+        Currency:    currency,
+        EntryDate:   time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC),
+        Description: desc,
+        SourceType:  source,
+        SourceID:    &sourceID,
+    }
+    for _, p := range postings {
+        if p.cents == 0 {
+            continue
+        }
+        id, ok := ids[p.code]
+        if !ok {
+            return fmt.Errorf("ledger account %s is missing", p.code)
+        }
+        l := models.JournalLine{AccountID: id, DebitCents: p.cents}
+        if p.cents < 0 {
+            l = models.JournalLine{AccountID: id, CreditCents: -p.cents}
+        }
+        e.Lines = append(e.Lines, l)
+    }
+    if len(e.Lines) == 0 {
+        return nil
+    }
+    if !balanced(e.Lines) {
+        return ErrUnbalanced
+    }
+    return tx.Create(&e).Error
+}
+
+// unpost cancels everything posted so far for a source record with one
+// entry of the opposite postings.
+func unpost(tx *gorm.DB, source string, sourceID uuid.UUID, date time.Time, desc string) error {
+    var nets []struct {
+        Code     string
+        VenueID  *uuid.UUID
+        Currency string
+        Cents    int
+    }
+    if err := tx.Raw(`
+        SELECT a.code, j.venue_id, j.currency, SUM(l.debit_cents - l.credit_cents) AS cents
+        FROM journal_lines l
+        JOIN journal_entries j ON j.id = l.journal_entry_id
+        JOIN accounts a ON a.id = l.account_id
+        WHERE j.source_type = ? AND j.source_id = ?
+        GROUP BY a.code, j.venue_id, j.currency`, source, sourceID).Scan(&nets).Error; err != nil {
+        return err
+    }
+    // a source is posted at one venue in one currency unless it was edited;
+    // cancel the postings of each separately
+    type key struct {
+        venue    uuid.UUID
+        currency string
+    }
+    byKey := map[key][]posting{}
+    venues := map[key]*uuid.UUID{}
+    for _, n := range nets {
+        k := key{currency: n.Currency}
+        if n.VenueID != nil {
+            k.venue = *n.VenueID
+        }
+        venues[k] = n.VenueID
+        byKey[k] = append(byKey[k], posting{n.Code, -n.Cents})
+    }
+    for k, ps := range byKey {
+        if err := post(tx, source, sourceID, venues[k], k.currency, date, desc, ps...); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// postPayment posts a fee payment, or with a negative amount its reversal:
+// cash is debited and fee income and GST payable are credited.
+func postPayment(tx *gorm.DB, p *models.FeePayment) error {
+    venueID, err := enrollmentVenue(tx, p.EnrollmentID)
+    if err != nil {
+        return err
+    }
+    desc := "Fee payment"
+    if p.ReversalOfID != nil {
+        desc = "Fee payment reversal: " + p.Reason
+    }
+    return post(tx, models.SourceFeePayment, p.ID, venueID, p.Currency, p.PaidOn, desc,
+        posting{models.AccountCodeCash, p.AmountCents},
+        posting{models.AccountCodeFeeIncome, -(p.AmountCents - p.TaxCents)},
+        posting{models.AccountCodeGSTPayable, -p.TaxCents})
+}
+
+// postRefund posts a refund: fee income and GST payable are debited and
+// cash is credited.
+func postRefund(tx *gorm.DB, rf *models.Refund) error {
+    venueID, err := enrollmentVenue(tx, rf.EnrollmentID)
+    if err != nil {
+        return err
+    }
+    return post(tx, models.SourceRefund, rf.ID, venueID, rf.Currency, rf.RefundedOn, "Refund: "+rf.Reason,
+        posting{models.AccountCodeFeeIncome, rf.AmountCents - rf.TaxCents},
+        posting{models.AccountCodeGSTPayable, rf.TaxCents},
+        posting{models.AccountCodeCash, -rf.AmountCents})
+}
+
+// postExpense posts an expense: operating expenses are debited and cash is
+// credited.
+func postExpense(tx *gorm.DB, e *models.Expense) error {
+    return post(tx, models.SourceExpense, e.ID, e.VenueID, e.Currency, e.IncurredOn, "Expense: "+e.Description,
+        posting{models.AccountCodeExpenses, e.AmountCents},
+        posting{models.AccountCodeCash, -e.AmountCents})
+}
+
+// postInvestment posts an investment transaction at the investment's venue:
+// a buy brings capital in as cash, a sell pays it out.
+func postInvestment(tx *gorm.DB, t *models.InvestmentTransaction) error {
+    var inv models.Investment
+    if err := tx.Select("venue_id").First(&inv, "id = ?", t.InvestmentID).Error; err != nil {
+        return err
+    }
+    cents := t.Units * t.PriceCents
+    if strings.EqualFold(t.Type, "sell") {
+        cents = -cents
+    }
+    desc := "Investment " + strings.ToLower(t.Type)
+    if t.ReversalOfID != nil {
+        desc += " reversal: " + t.Reason
+    }
+    return post(tx, models.SourceInvestment, t.ID, &inv.VenueID, t.Currency, t.TxnDate, desc,
+        posting{models.AccountCodeCash, cents},
+        posting{models.AccountCodeCapital, -cents})
+}
+
+func enrollmentVenue(tx *gorm.DB, enrollmentID uuid.UUID) (*uuid.UUID, error) {
+    var venueID uuid.UUID
+    res := tx.Raw(`SELECT b.venue_id FROM enrollments e JOIN batches b ON b.id = e.batch_id WHERE e.id = ?`,
+        enrollmentID).Scan(&venueID)
+    if res.Error != nil {
+        return nil, res.Error
+    }
+    if res.RowsAffected == 0 {
+        return nil, gorm.ErrRecordNotFound
+    }
+    return &venueID, nil
+}
@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"spodemy-backend/models"
+
+	"github.com/google/uuid"
+)
+
+func TestBalanced(t *testing.T) {
+    tests := []struct {
+        name  string
+        lines []models.JournalLine
+        want  bool
+    }{
+        {name: "no lines", want: true},
+        {name: "debit equals credit", lines: []models.JournalLine{{DebitCents: 500}, {CreditCents: 500}}, want: true},
+        {name: "split credit", lines: []models.JournalLine{{DebitCents: 500}, {CreditCents: 300}, {CreditCents: 200}}, want: true},
+        {name: "debits only", lines: []models.JournalLine{{DebitCents: 500}, {DebitCents: 500}}},
+        {name: "credits only", lines: []models.JournalLine{{CreditCents: 500}, {CreditCents: 500}}},
+        {name: "unbalanced totals", lines: []models.JournalLine{{DebitCents: 500}, {CreditCents: 499}}},
+        {name: "line on both sides", lines: []models.JournalLine{{DebitCents: 500, CreditCents: 500}}, want: true},
+        {name: "line on both sides unbalanced", lines: []models.JournalLine{{DebitCents: 500, CreditCents: 200}, {CreditCents: 200}}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := balanced(tt.lines); got != tt.want {
+                t.Errorf("balanced = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestCreateEntryUnbalanced(t *testing.T) {
+    // checked before the database is touched
+    e := &models.JournalEntry{Lines: []models.JournalLine{{DebitCents: 500}, {CreditCents: 400}}}
+    if err := NewLedgerRepository(nil).CreateEntry(e); !errors.Is(err, ErrUnbalanced) {
+        t.Errorf("err = %v, want %v", err, ErrUnbalanced)
+    }
+}
+
+func TestPost(t *testing.T) {
+    db := testDB(t, &models.Account{}, &models.JournalEntry{}, &models.JournalLine{})
+    seedAccounts(t, db)
+    date := time.Date(2026, 10, 19, 15, 0, 0, 0, time.UTC)
+
+    tests := []struct {
+        name     string
+        postings []posting
+        err      error
+        lines    int
+    }{
+        {name: "balanced", postings: []posting{{models.AccountCodeCash, 1180}, {models.AccountCodeFeeIncome, -1000}, {models.AccountCodeGSTPayable, -180}}, lines: 3},
+        {name: "zero postings left out", postings: []posting{{models.AccountCodeCash, 1000}, {models.AccountCodeFeeIncome, -1000}, {models.AccountCodeGSTPayable, 0}}, lines: 2},
+        {name: "all zero", postings: []posting{{models.AccountCodeCash, 0}, {models.AccountCodeFeeIncome, 0}}},
+        {name: "one-sided", postings: []posting{{models.AccountCodeCash, 1000}, {models.AccountCodeExpenses, 1000}}, err: ErrUnbalanced},
+        {name: "unbalanced totals", postings: []posting{{models.AccountCodeCash, 1000}, {models.AccountCodeFeeIncome, -900}}, err: ErrUnbalanced},
+        {name: "unknown account", postings: []posting{{models.AccountCodeCash, 1000}, {"9999", -1000}}, err: errors.New("ledger account 9999 is missing")},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            source := uuid.New()
+            err := post(db, models.SourceManual, source, nil, "INR", date, tt.name, tt.postings...)
+            if tt.err == nil && err != nil || tt.err != nil && (err == nil || err.Error() != tt.err.Error()) {
+                t.Fatalf("err = %v, want %v", err, tt.err)
+            }
+            var entries []models.JournalEntry
+            if err := db.Preload("Lines").Find(&entries, "source_id = ?", source).Error; err != nil {
+                t.Fatal(err)
+            }
+            if tt.lines == 0 {
+                if len(entries) != 0 {
+                    t.Errorf("%d entries posted, want none", len(entries))
+                }
+                return
+            }
+            if len(entries) != 1 {
+                t.Fatalf("%d entries posted, want 1", len(entries))
+            }
+            e := entries[0]
+            if len(e.Lines) != tt.lines || !balanced(e.Lines) {
+                t.Errorf("lines = %+v, want %d balanced", e.Lines, tt.lines)
+            }
+            if !e.EntryDate.Equal(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)) {
+                t.Errorf("entry date = %v, want the day of %v", e.EntryDate, date)
+            }
+            for _, l := range e.Lines {
+                if (l.DebitCents > 0) == (l.CreditCents > 0) {
+                    t.Errorf("line %+v should debit or credit, not both", l)
+                }
+            }
+        })
+    }
+}
@@ -0,0 +1,200 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"spodemy-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errCreditChanged rolls back a redemption when the credit was no longer booked.
+var errCreditChanged = errors.New("make-up credit changed concurrently")
+
+// ErrMakeupCreditUsed is returned when an absence whose credit was already
+// redeemed is changed so that it no longer earns one.
+var ErrMakeupCreditUsed = errors.New("the make-up credit for this absence has already been used")
+
+// MakeupCreditRepository handles DB operations for MakeupCredit.
+type MakeupCreditRepository struct {
+    db *gorm.DB
+}
+
+// NewMakeupCreditRepository constructs a MakeupCreditRepository.
+func NewMakeupCreditRepository(db *gorm.DB) *MakeupCreditRepository {
+    return &MakeupCreditRepository{db: db}
+}
+
+// FindByID returns one credit by UUID with its enrollment and booking preloaded.
+func (r *MakeupCreditRepository) FindByID(id uuid.UUID) (*models.MakeupCredit, error) {
+    var mc models.MakeupCredit
+    if err := r.db.Preload("Enrollment.Batch").Preload("BookedBatch").First(&mc, "id = ?", id).Error; err != nil {
+        return nil, err
+    }
+    return &mc, nil
+}
+
+// FindByEnrollment returns all credits issued to an enrollment, newest first.
+func (r *MakeupCreditRepository) FindByEnrollment(enrID uuid.UUID) ([]models.MakeupCredit, error) {
+    var mcs []models.MakeupCredit
+    if err := r.db.Where("enrollment_id = ?", enrID).Preload("BookedBatch").Order("issued_on desc").Find(&mcs).Error; err != nil {
+        return nil, err
+    }
+    return mcs, nil
+}
+
+// Book reserves a seat in the batch session for the credit. The batch row is
+// locked so concurrent bookings cannot exceed capacity; it returns false when
+// the session is full or the credit is no longer available.
+func (r *MakeupCreditRepository) Book(creditID, batchID uuid.UUID, date time.Time) (bool, error) {
+    booked := false
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        var b models.Batch
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&b, "id = ?", batchID).Error; err != nil {
+            return err
+        }
+        if b.Capacity > 0 {
+            load, err := sessionLoad(tx, b.ID, date)
+            if err != nil {
+                return err
+            }
+            if load >= int64(b.Capacity) {
+                return nil
+            }
+        }
+        res := tx.Model(&models.MakeupCredit{}).
+            Where("id = ? AND status = ?", creditID, models.MakeupAvailable).
+            Updates(map[string]interface{}{
+                "status":          models.MakeupBooked,
+                "booked_batch_id": b.ID,
+                "booked_date":     date,
+            })
+        if res.Error != nil {
+            return res.Error
+        }
+        booked = res.RowsAffected == 1
+        return nil
+    })
+    return booked, err
+}
+
+// CancelBooking releases a booked seat and makes the credit available again.
+func (r *MakeupCreditRepository) CancelBooking(creditID uuid.UUID) (bool, error) {
+    res := r.db.Model(&models.MakeupCredit{}).
+        Where("id = ? AND status = ?", creditID, models.MakeupBooked).
+        Updates(map[string]interface{}{
+            "status":          models.MakeupAvailable,
+            "booked_batch_id": nil,
+            "booked_date":     nil,
+        })
+    return res.RowsAffected == 1, res.Error
+}
+
+// Redeem records the make-up attendance and marks the credit used in one transaction.
+func (r *MakeupCreditRepository) Redeem(creditID uuid.UUID, a *models.Attendance) (bool, error) {
+    redeemed := false
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Create(a).Error; err != nil {
+            return err
+        }
+        res := tx.Model(&models.MakeupCredit{}).
+            Where("id = ? AND status = ?", creditID, models.MakeupBooked).
+            Updates(map[string]interface{}{
+                "status":               models.MakeupUsed,
+                "makeup_attendance_id": a.ID,
+            })
+        if res.Error != nil {
+            return res.Error
+        }
+        if res.RowsAffected != 1 {
+            return errCreditChanged
+        }
+        redeemed = true
+        return nil
+    })
+    if errors.Is(err, errCreditChanged) {
+        return false, nil
+    }
+    return redeemed, err
+}
+
+// ExpireBefore releases bookings for sessions before t that were not
+// attended, then marks unbooked credits that expired before t, and returns
+// how many changed.
+func (r *MakeupCreditRepository) ExpireBefore(t time.Time) (int64, error) {
+    var n int64
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        res := tx.Model(&models.MakeupCredit{}).
+            Where("status = ? AND booked_date < ?", models.MakeupBooked, t).
+            Updates(map[string]interface{}{
+                "status":          models.MakeupAvailable,
+                "booked_batch_id": nil,
+                "booked_date":     nil,
+            })
+        if res.Error != nil {
+            return res.Error
+        }
+        n = res.RowsAffected
+        res = tx.Model(&models.MakeupCredit{}).
+            Where("status = ? AND expires_on < ?", models.MakeupAvailable, t).
+            Update("status", models.MakeupExpired)
+        n += res.RowsAffected
+        return res.Error
+    })
+    return n, err
+}
+
+// syncAbsenceCredit makes the credit of an attendance record match what it
+// earns now: credit is issued, or given back if it was revoked before, or
+// with credit nil an unused credit is revoked.
+func syncAbsenceCredit(tx *gorm.DB, absenceID uuid.UUID, credit *models.MakeupCredit) error {
+    if credit != nil {
+        credit.AbsenceID = absenceID
+        return tx.Clauses(clause.OnConflict{
+            Columns: []clause.Column{{Name: "absence_id"}},
+            DoUpdates: clause.Assignments(map[string]interface{}{
+                "status":     models.MakeupAvailable,
+                "reason":     credit.Reason,
+                "issued_on":  credit.IssuedOn,
+                "expires_on": credit.ExpiresOn,
+            }),
+            Where: clause.Where{Exprs: []clause.Expression{
+                clause.Eq{Column: clause.Column{Table: "makeup_credits", Name: "status"}, Value: models.MakeupRevoked},
+            }},
+        }).Create(credit).Error
+    }
+    var used int64
+    if err := tx.Model(&models.MakeupCredit{}).
+        Where("absence_id = ? AND status = ?", absenceID, models.MakeupUsed).Count(&used).Error; err != nil {
+        return err
+    }
+    if used > 0 {
+        return ErrMakeupCreditUsed
+    }
+    return tx.Model(&models.MakeupCredit{}).
+        Where("absence_id = ? AND status IN ?", absenceID, []string{models.MakeupAvailable, models.MakeupBooked, models.MakeupExpired}).
+        Updates(map[string]interface{}{
+            "status":          models.MakeupRevoked,
+            "booked_batch_id": nil,
+            "booked_date":     nil,
+        }).Error
+}
+
+// sessionLoad counts active enrollments in the batch plus make-up seats booked for the date.
+func sessionLoad(tx *gorm.DB, batchID uuid.UUID, date time.Time) (int64, error) {
+    var enrolled, makeups int64
+    if err := tx.Model(&models.Enrollment{}).
+        Where("batch_id = ? AND status = ?", batchID, models.EnrollmentActive).
+        Count(&enrolled).Error; err != nil {
+        return 0, err
+    }
+    if err := tx.Model(&models.MakeupCredit{}).
+        Where("booked_batch_id = ? AND booked_date = ? AND status IN ?", batchID, date, []string{models.MakeupBooked, models.MakeupUsed}).
+        Count(&makeups).Error; err != nil {
+        return 0, err
+    }
+    return enrolled + makeups, nil
+}
@@ -1,10 +1,23 @@
 package repositories
 
 import (
+	"errors"
+	"time"
+
 	"spodemy-backend/models"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Refund and reversal errors.
+var (
+    ErrRefundExceeds   = errors.New("refunds would exceed the amount paid")
+    ErrPaymentRefunded = errors.New("payment has refunds")
+    ErrPaymentReversed = errors.New("payment has been reversed")
+    ErrReverseReversal = errors.New("a reversal cannot itself be refunded or reversed")
+    ErrPaymentOnline   = errors.New("payment was taken online; refund it instead")
 )
 
 // PaymentRepository handles DB operations for fee payments.
@@ -44,17 +57,144 @@ func (r *PaymentRepository) FindByEnrollment(enrID uuid.UUID) ([]models.FeePayme
     return payments, nil
 }
 
-// Create inserts a new payment.
-func (r *PaymentRepository) Create(p *models.FeePayment) error {
-    return r.db.Create(p).Error
+// Create inserts a new payment and allocates it to the enrollment's unpaid
+// invoices. If it settles a charge, the charge is marked paid, and if it
+// carries a coupon, the coupon is redeemed by studentID, all in the same
+// transaction.
+func (r *PaymentRepository) Create(p *models.FeePayment, studentID uuid.UUID) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        return insertPayment(tx, p, studentID)
+    })
 }
 
-// Update modifies an existing payment.
-func (r *PaymentRepository) Update(p *models.FeePayment) error {
-    return r.db.Save(p).Error
+// insertPayment does the work of Create inside tx, posting the payment to
+// the ledger and queueing its receipt.
+func insertPayment(tx *gorm.DB, p *models.FeePayment, studentID uuid.UUID) error {
+    currency, err := enrollmentCurrency(tx, p.EnrollmentID)
+    if err != nil {
+        return err
+    }
+    p.Currency = currency
+    p.UnallocatedCents = p.AmountCents
+    if err := tx.Create(p).Error; err != nil {
+        return err
+    }
+    if p.QuoteNonce != nil {
+        if err := useQuote(tx, &models.QuoteUse{
+            QuoteNonce:   *p.QuoteNonce,
+            Purpose:      models.QuoteForPayment,
+            EnrollmentID: p.EnrollmentID,
+            FeePaymentID: &p.ID,
+        }); err != nil {
+            return err
+        }
+    }
+    if err := postPayment(tx, p); err != nil {
+        return err
+    }
+    if err := issueReceipt(tx, p, studentID); err != nil {
+        return err
+    }
+    if p.ChargeID != nil {
+        if err := settleCharge(tx, p); err != nil {
+            return err
+        }
+    }
+    if p.CouponID != nil {
+        if err := redeemCoupon(tx, &models.CouponRedemption{
+            CouponID:     *p.CouponID,
+            UserID:       studentID,
+            EnrollmentID: p.EnrollmentID,
+            FeePaymentID: &p.ID,
+        }); err != nil {
+            return err
+        }
+    }
+    return allocatePayments(tx, p.EnrollmentID)
+}
+
+// Reverse cancels a payment with a reversal entry of the negated amount,
+// taking it off the invoices it was allocated to. A charge it settled is
+// due again and a coupon it redeemed is given back. Refunded payments,
+// payments taken online and reversals themselves cannot be reversed.
+func (r *PaymentRepository) Reverse(id uuid.UUID, reason string) (*models.FeePayment, error) {
+    var rev models.FeePayment
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        var p models.FeePayment
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&p, "id = ?", id).Error; err != nil {
+            return err
+        }
+        if p.ReversalOfID != nil {
+            return ErrReverseReversal
+        }
+        if p.RefundedCents > 0 {
+            return ErrPaymentRefunded
+        }
+        if err := checkNotReversed(tx, p.ID); err != nil {
+            return err
+        }
+        var online int64
+        if err := tx.Model(&models.PaymentOrder{}).Where("fee_payment_id = ?", p.ID).Count(&online).Error; err != nil {
+            return err
+        }
+        if online > 0 {
+            return ErrPaymentOnline
+        }
+
+        if err := releaseAllocations(tx, &p); err != nil {
+            return err
+        }
+        if err := tx.Model(&p).Update("unallocated_cents", 0).Error; err != nil {
+            return err
+        }
+        rev = models.FeePayment{
+            EnrollmentID:   p.EnrollmentID,
+            AmountCents:    -p.AmountCents,
+            Currency:       p.Currency,
+            PaidOn:         time.Now(),
+            Method:         p.Method,
+            TransactionRef: p.TransactionRef,
+            DiscountCents:  -p.DiscountCents,
+            TaxBreakdown: models.TaxBreakdown{
+                TaxCode:      p.TaxCode,
+                TaxPct:       p.TaxPct,
+                TaxableCents: -p.TaxableCents,
+                TaxCents:     -p.TaxCents,
+            },
+            ReversalOfID: &p.ID,
+            Reason:       reason,
+        }
+        if err := tx.Create(&rev).Error; err != nil {
+            return err
+        }
+        if err := postPayment(tx, &rev); err != nil {
+            return err
+        }
+        if p.ChargeID != nil {
+            if err := tx.Model(&models.Charge{}).Where("id = ? AND fee_payment_id = ?", *p.ChargeID, p.ID).
+                Updates(map[string]interface{}{"status": models.ChargeDue, "fee_payment_id": nil, "paid_at": nil}).Error; err != nil {
+                return err
+            }
+        }
+        if err := tx.Where("fee_payment_id = ?", p.ID).Delete(&models.CouponRedemption{}).Error; err != nil {
+            return err
+        }
+        return allocatePayments(tx, p.EnrollmentID)
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &rev, nil
 }
 
-// Delete removes a payment by UUID.
-func (r *PaymentRepository) Delete(id uuid.UUID) error {
-    return r.db.Delete(&models.FeePayment{}, "id = ?", id).Error
+// checkNotReversed returns ErrPaymentReversed if the payment has a reversal.
+func checkNotReversed(tx *gorm.DB, id uuid.UUID) error {
+    var n int64
+    if err := tx.Model(&models.FeePayment{}).Where("reversal_of_id = ?", id).Count(&n).Error; err != nil {
+        return err
+    }
+    if n > 0 {
+        return ErrPaymentReversed
+    }
+    return nil
 }
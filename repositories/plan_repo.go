@@ -1,10 +1,13 @@
 package repositories
 
 import (
+	"time"
+
 	"spodemy-backend/models"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // PlanRepository handles DB operations for Plan.
@@ -35,14 +38,73 @@ func (r *PlanRepository) FindByID(id uuid.UUID) (*models.Plan, error) {
     return &plan, nil
 }
 
-// Create inserts a new plan.
+// Create inserts a new plan together with its first version.
 func (r *PlanRepository) Create(p *models.Plan) error {
-    return r.db.Create(p).Error
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Create(p).Error; err != nil {
+            return err
+        }
+        return addVersion(tx, p, 1, time.Now())
+    })
 }
 
-// Update modifies an existing plan.
+// Update modifies an existing plan. If its terms change, the current version
+// is closed and a new one takes effect, so enrollments on earlier versions
+// keep their terms. Offers are managed through AttachOffer and DetachOffer.
 func (r *PlanRepository) Update(p *models.Plan) error {
-    return r.db.Save(p).Error
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        var cur models.Plan
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&cur, "id = ?", p.ID).Error; err != nil {
+            return err
+        }
+        p.Version = cur.Version
+        p.CurrentVersionID = cur.CurrentVersionID
+        if p.PlanTerms.Equal(cur.PlanTerms) && cur.CurrentVersionID != nil {
+            return nil
+        }
+        now := time.Now()
+        if cur.CurrentVersionID != nil {
+            if err := tx.Model(&models.PlanVersion{}).Where("id = ?", *cur.CurrentVersionID).
+                Update("effective_to", now).Error; err != nil {
+                return err
+            }
+        }
+        if err := tx.Omit(clause.Associations).Save(p).Error; err != nil {
+            return err
+        }
+        return addVersion(tx, p, cur.Version+1, now)
+    })
+}
+
+// FindVersions returns a plan's versions, newest first.
+func (r *PlanRepository) FindVersions(planID uuid.UUID) ([]models.PlanVersion, error) {
+    var vs []models.PlanVersion
+    if err := r.db.Where("plan_id = ?", planID).Order("version DESC").Find(&vs).Error; err != nil {
+        return nil, err
+    }
+    return vs, nil
+}
+
+// FindVersion returns one plan version by UUID.
+func (r *PlanRepository) FindVersion(id uuid.UUID) (*models.PlanVersion, error) {
+    var v models.PlanVersion
+    if err := r.db.First(&v, "id = ?", id).Error; err != nil {
+        return nil, err
+    }
+    return &v, nil
+}
+
+// addVersion records p's terms as version n, effective from, and makes it the
+// plan's current version.
+func addVersion(tx *gorm.DB, p *models.Plan, n int, from time.Time) error {
+    v := models.PlanVersion{PlanID: p.ID, Version: n, PlanTerms: p.PlanTerms, EffectiveFrom: from}
+    if err := tx.Create(&v).Error; err != nil {
+        return err
+    }
+    p.Version = n
+    p.CurrentVersionID = &v.ID
+    return tx.Model(&models.Plan{}).Where("id = ?", p.ID).
+        Updates(map[string]interface{}{"version": n, "current_version_id": v.ID}).Error
 }
 
 // Delete removes a plan by UUID.
@@ -66,4 +128,4 @@ func (r *PlanRepository) DetachOffer(planID, offerID uuid.UUID) error {
         return err
     }
     return r.db.Model(plan).Association("Offers").Delete(&models.Offer{ID: offerID})
-}
\ No newline at end of file
+}
@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"spodemy-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrQuoteUsed is returned for a quote already honored for the same purpose.
+var ErrQuoteUsed = errors.New("quote has already been used")
+
+// QuoteRepository handles DB operations for quote uses.
+type QuoteRepository struct {
+    db *gorm.DB
+}
+
+// NewQuoteRepository constructs a QuoteRepository.
+func NewQuoteRepository(db *gorm.DB) *QuoteRepository {
+    return &QuoteRepository{db: db}
+}
+
+// IsUsed reports whether the quote with the given nonce was used for purpose.
+func (r *QuoteRepository) IsUsed(nonce uuid.UUID, purpose string) (bool, error) {
+    var n int64
+    err := r.db.Model(&models.QuoteUse{}).
+        Where("quote_nonce = ? AND purpose = ?", nonce, purpose).Count(&n).Error
+    return n > 0, err
+}
+
+// useQuote records use inside tx, or returns ErrQuoteUsed if the quote was
+// used for the same purpose before, concurrently or not.
+func useQuote(tx *gorm.DB, use *models.QuoteUse) error {
+    use.UsedAt = time.Now()
+    res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(use)
+    if res.Error != nil {
+        return res.Error
+    }
+    if res.RowsAffected == 0 {
+        return ErrQuoteUsed
+    }
+    return nil
+}
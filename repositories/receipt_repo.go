@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"spodemy-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReceiptRepository handles DB operations for payment receipts.
+type ReceiptRepository struct {
+    db *gorm.DB
+}
+
+// NewReceiptRepository constructs a ReceiptRepository.
+func NewReceiptRepository(db *gorm.DB) *ReceiptRepository {
+    return &ReceiptRepository{db: db}
+}
+
+// FindByPayment returns the receipt of a payment with the payment, venue
+// and student it is rendered from.
+func (r *ReceiptRepository) FindByPayment(paymentID uuid.UUID) (*models.Receipt, error) {
+    return r.find("fee_payment_id = ?", paymentID)
+}
+
+// FindByID returns one receipt like FindByPayment.
+func (r *ReceiptRepository) FindByID(id uuid.UUID) (*models.Receipt, error) {
+    return r.find("id = ?", id)
+}
+
+func (r *ReceiptRepository) find(cond string, arg uuid.UUID) (*models.Receipt, error) {
+    var rc models.Receipt
+    if err := r.db.Preload("FeePayment").Preload("FeePayment.Enrollment.Batch").
+        Preload("Venue").Preload("Student").
+        First(&rc, cond, arg).Error; err != nil {
+        return nil, err
+    }
+    return &rc, nil
+}
+
+// FindPending returns the IDs of receipts still to be mailed, oldest first.
+func (r *ReceiptRepository) FindPending(limit int) ([]uuid.UUID, error) {
+    var ids []uuid.UUID
+    err := r.db.Model(&models.Receipt{}).Where("status = ?", models.ReceiptPending).
+        Order("created_at").Limit(limit).Pluck("id", &ids).Error
+    return ids, err
+}
+
+// SaveDocument stores the rendered receipt.
+func (r *ReceiptRepository) SaveDocument(rc *models.Receipt) error {
+    return r.db.Model(rc).Updates(map[string]interface{}{"pdf": rc.PDF, "text": rc.Text}).Error
+}
+
+// MarkSent records that the receipt was mailed to addr.
+func (r *ReceiptRepository) MarkSent(rc *models.Receipt, addr string, at time.Time) error {
+    rc.Status, rc.SentTo, rc.SentAt, rc.LastError = models.ReceiptSent, addr, &at, ""
+    rc.Attempts++
+    return r.db.Model(rc).Updates(map[string]interface{}{
+        "status":     rc.Status,
+        "sent_to":    addr,
+        "sent_at":    at,
+        "attempts":   rc.Attempts,
+        "last_error": "",
+    }).Error
+}
+
+// MarkAttemptFailed records a failed delivery; the receipt stays pending for
+// another try unless giveUp is set.
+func (r *ReceiptRepository) MarkAttemptFailed(rc *models.Receipt, reason string, giveUp bool) error {
+    rc.Attempts++
+    rc.LastError = reason
+    if giveUp {
+        rc.Status = models.ReceiptFailed
+    }
+    return r.db.Model(rc).Updates(map[string]interface{}{
+        "status":     rc.Status,
+        "attempts":   rc.Attempts,
+        "last_error": reason,
+    }).Error
+}
+
+// issueReceipt numbers a receipt for payment p at its enrollment's venue,
+// queued for mailing. Like invoices, the venue's sequence row stays locked
+// until tx ends so numbers are not skipped.
+func issueReceipt(tx *gorm.DB, p *models.FeePayment, studentID uuid.UUID) error {
+    var v models.Venue
+    if err := tx.Select("venues.id", "venues.invoice_prefix").
+        Joins("JOIN batches b ON b.venue_id = venues.id").
+        Joins("JOIN enrollments e ON e.batch_id = b.id").
+        First(&v, "e.id = ?", p.EnrollmentID).Error; err != nil {
+        return err
+    }
+    rc := models.Receipt{
+        FeePaymentID: p.ID,
+        VenueID:      v.ID,
+        StudentID:    studentID,
+        IssuedOn:     time.Now(),
+        Status:       models.ReceiptPending,
+    }
+    if err := tx.Raw(`
+        INSERT INTO receipt_sequences (venue_id, last_seq) VALUES (?, 1)
+        ON CONFLICT (venue_id) DO UPDATE SET last_seq = receipt_sequences.last_seq + 1
+        RETURNING last_seq`, v.ID).Scan(&rc.Seq).Error; err != nil {
+        return err
+    }
+    prefix := v.InvoicePrefix
+    if prefix == "" {
+        prefix = strings.ToUpper(v.ID.String()[:8])
+    }
+    rc.Number = fmt.Sprintf("%s-R%06d", prefix, rc.Seq)
+    return tx.Omit("FeePayment", "Venue", "Student").Create(&rc).Error
+}
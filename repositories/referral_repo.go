@@ -0,0 +1,174 @@
+package repositories
+
+import (
+	"crypto/rand"
+	"errors"
+	"time"
+
+	"spodemy-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// referralAlphabet leaves out characters that are easily confused.
+const referralAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// ReferralRepository handles DB operations for referrals and wallet credit.
+type ReferralRepository struct {
+    db *gorm.DB
+}
+
+// NewReferralRepository constructs a ReferralRepository.
+func NewReferralRepository(db *gorm.DB) *ReferralRepository {
+    return &ReferralRepository{db: db}
+}
+
+// FindAll returns referrals with both students, newest first, optionally
+// only those of one referrer.
+func (r *ReferralRepository) FindAll(referrerID *uuid.UUID) ([]models.Referral, error) {
+    var rs []models.Referral
+    q := r.db.Preload("Referrer").Preload("Referred").Order("created_at DESC")
+    if referrerID != nil {
+        q = q.Where("referrer_id = ?", *referrerID)
+    }
+    if err := q.Find(&rs).Error; err != nil {
+        return nil, err
+    }
+    return rs, nil
+}
+
+// FindByID returns one referral by UUID.
+func (r *ReferralRepository) FindByID(id uuid.UUID) (*models.Referral, error) {
+    var ref models.Referral
+    if err := r.db.Preload("Referrer").Preload("Referred").First(&ref, "id = ?", id).Error; err != nil {
+        return nil, err
+    }
+    return &ref, nil
+}
+
+// FindReferrer returns the user owning a referral code.
+func (r *ReferralRepository) FindReferrer(code string) (*models.User, error) {
+    var u models.User
+    if err := r.db.First(&u, "referral_code = ?", code).Error; err != nil {
+        return nil, err
+    }
+    return &u, nil
+}
+
+// IsReferred reports whether a student already has a referral.
+func (r *ReferralRepository) IsReferred(userID uuid.UUID) (bool, error) {
+    var n int64
+    err := r.db.Model(&models.Referral{}).Where("referred_id = ?", userID).Count(&n).Error
+    return n > 0, err
+}
+
+// Create inserts a new referral.
+func (r *ReferralRepository) Create(ref *models.Referral) error {
+    return r.db.Omit(clause.Associations).Create(ref).Error
+}
+
+// FindWallet returns a user's credit and debit entries, newest first.
+func (r *ReferralRepository) FindWallet(userID uuid.UUID) ([]models.WalletEntry, error) {
+    var es []models.WalletEntry
+    if err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&es).Error; err != nil {
+        return nil, err
+    }
+    return es, nil
+}
+
+// WalletBalance is a user's balance of each kind of credit in one currency.
+type WalletBalance struct {
+    Currency     string `json:"currency"`
+    WalletCents  int    `json:"wallet_cents"`
+    RenewalCents int    `json:"renewal_cents"`
+}
+
+// Balances returns a user's balances per currency.
+func (r *ReferralRepository) Balances(userID uuid.UUID) ([]WalletBalance, error) {
+    var bs []WalletBalance
+    if err := r.db.Raw(`
+        SELECT currency,
+               COALESCE(SUM(amount_cents) FILTER (WHERE kind = ?), 0) AS wallet_cents,
+               COALESCE(SUM(amount_cents) FILTER (WHERE kind = ?), 0) AS renewal_cents
+        FROM wallet_entries
+        WHERE user_id = ?
+        GROUP BY currency
+        ORDER BY currency`, models.CreditWallet, models.CreditRenewal, userID).Scan(&bs).Error; err != nil {
+        return nil, err
+    }
+    return bs, nil
+}
+
+// newReferralCode returns a random 8 character referral code.
+func newReferralCode() string {
+    b := make([]byte, 8)
+    if _, err := rand.Read(b); err != nil {
+        panic(err)
+    }
+    for i := range b {
+        b[i] = referralAlphabet[int(b[i])%len(referralAlphabet)]
+    }
+    return string(b)
+}
+
+// rewardReferral pays out the pending referral of the student of an
+// enrollment that just became active, if it is a regular enrollment on a plan.
+func rewardReferral(tx *gorm.DB, enrollmentID uuid.UUID) error {
+    var e models.Enrollment
+    if err := tx.Select("id", "student_id", "type", "plan_id").First(&e, "id = ?", enrollmentID).Error; err != nil {
+        return err
+    }
+    if e.Type != models.EnrollmentRegular || e.PlanID == nil {
+        return nil
+    }
+    var ref models.Referral
+    err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+        Where("referred_id = ? AND status = ?", e.StudentID, models.ReferralPending).First(&ref).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    now := time.Now()
+    if err := tx.Model(&ref).Updates(map[string]interface{}{
+        "status":        models.ReferralRewarded,
+        "enrollment_id": e.ID,
+        "rewarded_at":   now,
+    }).Error; err != nil {
+        return err
+    }
+    return tx.Create(&models.WalletEntry{
+        UserID:      ref.ReferrerID,
+        Kind:        ref.RewardType,
+        AmountCents: ref.RewardCents,
+        Currency:    ref.Currency,
+        Reason:      "referral reward",
+        ReferralID:  &ref.ID,
+    }).Error
+}
+
+// takeRenewalCredit takes the student's renewal credit in c's currency, up
+// to the charge amount, off c before it is created. The student row is
+// locked so that credit is not spent twice.
+func takeRenewalCredit(tx *gorm.DB, studentID uuid.UUID, c *models.Charge) error {
+    if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+        First(&models.User{}, "id = ?", studentID).Error; err != nil {
+        return err
+    }
+    var balance int
+    if err := tx.Model(&models.WalletEntry{}).Select("COALESCE(SUM(amount_cents), 0)").
+        Where("user_id = ? AND kind = ? AND currency = ?", studentID, models.CreditRenewal, c.Currency).
+        Scan(&balance).Error; err != nil {
+        return err
+    }
+    if balance <= 0 || c.AmountCents <= 0 {
+        return nil
+    }
+    c.CreditCents = min(balance, c.AmountCents)
+    c.AmountCents -= c.CreditCents
+    c.TaxBreakdown = c.TaxBreakdown.For(c.AmountCents)
+    return nil
+}
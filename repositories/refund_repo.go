@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"spodemy-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RefundFilter narrows the refunds listed.
+type RefundFilter struct {
+    FeePaymentID *uuid.UUID
+    EnrollmentID *uuid.UUID
+    Status       string
+}
+
+// RefundRepository handles DB operations for refunds.
+type RefundRepository struct {
+    db *gorm.DB
+}
+
+// NewRefundRepository constructs a RefundRepository.
+func NewRefundRepository(db *gorm.DB) *RefundRepository {
+    return &RefundRepository{db: db}
+}
+
+// FindAll returns the refunds matching f, newest first.
+func (r *RefundRepository) FindAll(f RefundFilter) ([]models.Refund, error) {
+    q := r.db.Model(&models.Refund{})
+    if f.FeePaymentID != nil {
+        q = q.Where("fee_payment_id = ?", *f.FeePaymentID)
+    }
+    if f.EnrollmentID != nil {
+        q = q.Where("enrollment_id = ?", *f.EnrollmentID)
+    }
+    if f.Status != "" {
+        q = q.Where("status = ?", f.Status)
+    }
+    var refunds []models.Refund
+    if err := q.Order("created_at DESC").Find(&refunds).Error; err != nil {
+        return nil, err
+    }
+    return refunds, nil
+}
+
+// FindByID returns one refund by UUID with its payment.
+func (r *RefundRepository) FindByID(id uuid.UUID) (*models.Refund, error) {
+    var rf models.Refund
+    if err := r.db.Preload("FeePayment").First(&rf, "id = ?", id).Error; err != nil {
+        return nil, err
+    }
+    return &rf, nil
+}
+
+// FindByGatewayRef returns the refund a gateway knows by ref.
+func (r *RefundRepository) FindByGatewayRef(method, ref string) (*models.Refund, error) {
+    var rf models.Refund
+    if err := r.db.First(&rf, "method = ? AND gateway_ref = ?", method, ref).Error; err != nil {
+        return nil, err
+    }
+    return &rf, nil
+}
+
+// Create inserts a refund of a payment. The payment is locked so that
+// concurrent refunds cannot together exceed it, and what is left of it is
+// allocated to the enrollment's invoices again. Processed refunds are posted
+// to the ledger; pending ones wait for the gateway in Complete.
+func (r *RefundRepository) Create(rf *models.Refund) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        var p models.FeePayment
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&p, "id = ?", rf.FeePaymentID).Error; err != nil {
+            return err
+        }
+        if p.ReversalOfID != nil {
+            return ErrReverseReversal
+        }
+        if err := checkNotReversed(tx, p.ID); err != nil {
+            return err
+        }
+        if rf.AmountCents == 0 {
+            rf.AmountCents = p.AmountCents - p.RefundedCents
+        }
+        if rf.AmountCents <= 0 || p.RefundedCents+rf.AmountCents > p.AmountCents {
+            return ErrRefundExceeds
+        }
+        rf.EnrollmentID = p.EnrollmentID
+        rf.Currency = p.Currency
+        rf.TaxBreakdown = p.TaxBreakdown.For(rf.AmountCents)
+        if err := tx.Create(rf).Error; err != nil {
+            return err
+        }
+        if rf.Status == models.RefundProcessed {
+            if err := postRefund(tx, rf); err != nil {
+                return err
+            }
+        }
+        p.RefundedCents += rf.AmountCents
+        return reallocatePayment(tx, &p)
+    })
+}
+
+// Complete records the gateway's reference and status for a pending refund
+// the gateway accepted. A refund it has paid out is posted to the ledger.
+func (r *RefundRepository) Complete(id uuid.UUID, gatewayRef, status string) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        var rf models.Refund
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&rf, "id = ?", id).Error; err != nil {
+            return err
+        }
+        if rf.Status != models.RefundPending {
+            return nil
+        }
+        if err := tx.Model(&rf).Updates(map[string]interface{}{
+            "gateway_ref": gatewayRef,
+            "status":      status,
+        }).Error; err != nil {
+            return err
+        }
+        if status != models.RefundProcessed {
+            return nil
+        }
+        return postRefund(tx, &rf)
+    })
+}
+
+// Fail marks a pending refund the gateway refused or failed to pay out as
+// failed and gives its amount back to the payment. Nothing of it was posted
+// to the ledger yet.
+func (r *RefundRepository) Fail(id uuid.UUID, reason string) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        var rf models.Refund
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&rf, "id = ?", id).Error; err != nil {
+            return err
+        }
+        if rf.Status != models.RefundPending {
+            return nil
+        }
+        var p models.FeePayment
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&p, "id = ?", rf.FeePaymentID).Error; err != nil {
+            return err
+        }
+        if err := tx.Model(&rf).Updates(map[string]interface{}{
+            "status":         models.RefundFailed,
+            "failure_reason": reason,
+        }).Error; err != nil {
+            return err
+        }
+        p.RefundedCents -= rf.AmountCents
+        return reallocatePayment(tx, &p)
+    })
+}
+
+// reallocatePayment saves a payment's refunded amount and allocates what is
+// left of it from scratch.
+func reallocatePayment(tx *gorm.DB, p *models.FeePayment) error {
+    if err := releaseAllocations(tx, p); err != nil {
+        return err
+    }
+    if err := tx.Model(p).Updates(map[string]interface{}{
+        "refunded_cents":    p.RefundedCents,
+        "unallocated_cents": p.UnallocatedCents,
+    }).Error; err != nil {
+        return err
+    }
+    return allocatePayments(tx, p.EnrollmentID)
+}
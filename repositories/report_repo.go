@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReportRepository runs aggregate queries for reports.
+type ReportRepository struct {
+    db *gorm.DB
+}
+
+// NewReportRepository constructs a ReportRepository.
+func NewReportRepository(db *gorm.DB) *ReportRepository {
+    return &ReportRepository{db: db}
+}
+
+// TrialConversionRow counts trials and conversions for one venue/sport/coach.
+type TrialConversionRow struct {
+    VenueID   uuid.UUID  `json:"venue_id"`
+    VenueName string     `json:"venue_name"`
+    Sport     string     `json:"sport"`
+    CoachID   *uuid.UUID `json:"coach_id,omitempty"`
+    CoachName string     `json:"coach_name"`
+    Trials    int64      `json:"trials"`
+    Converted int64      `json:"converted"`
+}
+
+// TrialConversions groups trials started in [from, to) by venue, sport and
+// coach, counting those that were converted to a paid enrollment.
+func (r *ReportRepository) TrialConversions(from, to time.Time) ([]TrialConversionRow, error) {
+    var rows []TrialConversionRow
+    err := r.db.Raw(`
+        SELECT b.venue_id, v.name AS venue_name, b.sport, b.coach_id,
+               COALESCE(TRIM(u.first_name || ' ' || u.last_name), '') AS coach_name,
+               COUNT(t.id) AS trials, COUNT(p.id) AS converted
+        FROM enrollments t
+        JOIN batches b ON b.id = t.batch_id
+        JOIN venues v ON v.id = b.venue_id
+        LEFT JOIN users u ON u.id = b.coach_id
+        LEFT JOIN enrollments p ON p.converted_from_id = t.id
+        WHERE t.type = 'trial' AND t.enrolled_on >= ? AND t.enrolled_on < ?
+        GROUP BY b.venue_id, v.name, b.sport, b.coach_id, u.first_name, u.last_name
+        ORDER BY v.name, b.sport, coach_name`, from, to).Scan(&rows).Error
+    return rows, err
+}
+
+// ReferralLeaderboardRow counts one referrer's referrals and rewards.
+type ReferralLeaderboardRow struct {
+    ReferrerID   uuid.UUID `json:"referrer_id"`
+    ReferrerName string    `json:"referrer_name"`
+    Referred     int64     `json:"referred"`
+    Rewarded     int64     `json:"rewarded"`
+    RewardCents  int64     `json:"reward_cents"`
+}
+
+// ReferralLeaderboard groups referrals made in [from, to) by referrer, most
+// rewarded first. Rejected referrals are left out.
+func (r *ReportRepository) ReferralLeaderboard(from, to time.Time) ([]ReferralLeaderboardRow, error) {
+    var rows []ReferralLeaderboardRow
+    err := r.db.Raw(`
+        SELECT f.referrer_id, TRIM(u.first_name || ' ' || u.last_name) AS referrer_name,
+               COUNT(*) AS referred,
+               COUNT(*) FILTER (WHERE f.status = 'rewarded') AS rewarded,
+               COALESCE(SUM(f.reward_cents) FILTER (WHERE f.status = 'rewarded'), 0) AS reward_cents
+        FROM referrals f
+        JOIN users u ON u.id = f.referrer_id
+        WHERE f.status <> 'rejected' AND f.created_at >= ? AND f.created_at < ?
+        GROUP BY f.referrer_id, u.first_name, u.last_name
+        ORDER BY rewarded DESC, referred DESC, referrer_name`, from, to).Scan(&rows).Error
+    return rows, err
+}
+
+// GSTRow totals the payments received at one venue in one currency at one
+// tax rate, less the refunds and reversals made in the same period.
+type GSTRow struct {
+    VenueID      uuid.UUID `json:"venue_id"`
+    VenueName    string    `json:"venue_name"`
+    Currency     string    `json:"currency"`
+    TaxCode      string    `json:"tax_code"`
+    TaxPct       float64   `json:"tax_pct"`
+    Payments     int64     `json:"payments"`
+    Refunds      int64     `json:"refunds"`
+    Reversals    int64     `json:"reversals"`
+    AmountCents  int64     `json:"amount_cents"`
+    TaxableCents int64     `json:"taxable_cents"`
+    TaxCents     int64     `json:"tax_cents"`
+}
+
+// GSTSummary groups the payments, refunds and reversals of [from, to) by
+// venue, currency and tax rate. Refunds and reversals take off the tax of
+// the payment they return or cancel.
+func (r *ReportRepository) GSTSummary(from, to time.Time) ([]GSTRow, error) {
+    var rows []GSTRow
+    err := r.db.Raw(`
+        WITH moves AS (
+            SELECT enrollment_id, currency, tax_code, tax_pct,
+                   CASE WHEN reversal_of_id IS NULL THEN 1 ELSE 0 END AS payment, 0 AS refund,
+                   CASE WHEN reversal_of_id IS NULL THEN 0 ELSE 1 END AS reversal,
+                   amount_cents, taxable_cents, tax_cents
+            FROM fee_payments WHERE paid_on >= ? AND paid_on < ?
+            UNION ALL
+            SELECT enrollment_id, currency, tax_code, tax_pct, 0, 1, 0,
+                   -amount_cents, -taxable_cents, -tax_cents
+            FROM refunds WHERE status <> 'failed' AND refunded_on >= ? AND refunded_on < ?
+        )
+        SELECT b.venue_id, v.name AS venue_name, m.currency, m.tax_code, m.tax_pct,
+               SUM(m.payment) AS payments, SUM(m.refund) AS refunds, SUM(m.reversal) AS reversals,
+               SUM(m.amount_cents) AS amount_cents,
+               SUM(m.taxable_cents) AS taxable_cents, SUM(m.tax_cents) AS tax_cents
+        FROM moves m
+        JOIN enrollments e ON e.id = m.enrollment_id
+        JOIN batches b ON b.id = e.batch_id
+        JOIN venues v ON v.id = b.venue_id
+        GROUP BY b.venue_id, v.name, m.currency, m.tax_code, m.tax_pct
+        ORDER BY v.name, m.currency, m.tax_pct, m.tax_code`, from, to, from, to).Scan(&rows).Error
+    return rows, err
+}
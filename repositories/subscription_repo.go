@@ -0,0 +1,219 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"spodemy-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrChargeSettled is returned when paying a charge that is already paid or void.
+var ErrChargeSettled = errors.New("charge has already been paid or voided")
+
+// Status change reasons used by subscription billing.
+const (
+    ReasonRenewalOverdue = "renewal payment overdue"
+    ReasonRenewalPaid    = "overdue renewal paid"
+)
+
+// SubscriptionRepository handles DB operations for renewing enrollments and
+// their charges.
+type SubscriptionRepository struct {
+    db *gorm.DB
+}
+
+// NewSubscriptionRepository constructs a SubscriptionRepository.
+func NewSubscriptionRepository(db *gorm.DB) *SubscriptionRepository {
+    return &SubscriptionRepository{db: db}
+}
+
+// FindDueRenewals returns active renewing enrollments whose cycle ends on or
+// before t.
+func (r *SubscriptionRepository) FindDueRenewals(t time.Time) ([]models.Enrollment, error) {
+    var ens []models.Enrollment
+    if err := r.db.Where("status = ? AND auto_renew AND NOT cancel_at_period_end AND valid_until <= ?",
+        models.EnrollmentActive, t).
+        Preload("Plan").Preload("PlanVersion").Preload("Batch").Order("valid_until").Find(&ens).Error; err != nil {
+        return nil, err
+    }
+    return ens, nil
+}
+
+// Renew records and invoices c, less any renewal credit of the student, and
+// extends its enrollment to c.PeriodEnd. It returns false, without changes,
+// if the enrollment was renewed, canceled or left the active status in the
+// meantime.
+func (r *SubscriptionRepository) Renew(c *models.Charge) (bool, error) {
+    renewed := false
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        var e models.Enrollment
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&e, "id = ?", c.EnrollmentID).Error; err != nil {
+            return err
+        }
+        if e.Status != models.EnrollmentActive || !e.AutoRenew || e.CancelAtPeriodEnd ||
+            e.ValidUntil == nil || !e.ValidUntil.Equal(c.PeriodStart) {
+            return nil
+        }
+        c.Currency = e.Currency
+        if err := takeRenewalCredit(tx, e.StudentID, c); err != nil {
+            return err
+        }
+        if err := tx.Omit(clause.Associations).Create(c).Error; err != nil {
+            return err
+        }
+        if c.CreditCents > 0 {
+            if err := tx.Create(&models.WalletEntry{
+                UserID:      e.StudentID,
+                Kind:        models.CreditRenewal,
+                AmountCents: -c.CreditCents,
+                Currency:    c.Currency,
+                Reason:      "taken off renewal charge",
+                ChargeID:    &c.ID,
+            }).Error; err != nil {
+                return err
+            }
+        }
+        if err := issueChargeInvoice(tx, &e, c); err != nil {
+            return err
+        }
+        renewed = true
+        return tx.Model(&e).Update("valid_until", c.PeriodEnd).Error
+    })
+    return renewed, err
+}
+
+// SetCancelAtPeriodEnd turns renewal of an enrollment off or back on.
+func (r *SubscriptionRepository) SetCancelAtPeriodEnd(id uuid.UUID, cancel bool, at *time.Time) error {
+    return r.db.Model(&models.Enrollment{}).Where("id = ?", id).
+        Updates(map[string]interface{}{"cancel_at_period_end": cancel, "canceled_at": at}).Error
+}
+
+// FindCharge returns one charge by UUID.
+func (r *SubscriptionRepository) FindCharge(id uuid.UUID) (*models.Charge, error) {
+    var c models.Charge
+    if err := r.db.First(&c, "id = ?", id).Error; err != nil {
+        return nil, err
+    }
+    return &c, nil
+}
+
+// FindChargesByEnrollment returns an enrollment's charges, newest cycle first.
+func (r *SubscriptionRepository) FindChargesByEnrollment(enrID uuid.UUID) ([]models.Charge, error) {
+    var cs []models.Charge
+    if err := r.db.Where("enrollment_id = ?", enrID).Order("period_start DESC").Find(&cs).Error; err != nil {
+        return nil, err
+    }
+    return cs, nil
+}
+
+// FindChargesByStatus returns charges in a status with their enrollment and
+// student, oldest due date first. An empty status returns every charge.
+func (r *SubscriptionRepository) FindChargesByStatus(status string) ([]models.Charge, error) {
+    var cs []models.Charge
+    q := r.db.Preload("Enrollment").Preload("Enrollment.Student").Order("due_on")
+    if status != "" {
+        q = q.Where("status = ?", status)
+    }
+    if err := q.Find(&cs).Error; err != nil {
+        return nil, err
+    }
+    return cs, nil
+}
+
+// MarkOverdue turns charges still due before t overdue and pauses their
+// active enrollments until they are paid.
+func (r *SubscriptionRepository) MarkOverdue(t time.Time) (int64, error) {
+    var n int64
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        var cs []models.Charge
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+            Where("status = ? AND due_on < ?", models.ChargeDue, t).Find(&cs).Error; err != nil {
+            return err
+        }
+        now := time.Now()
+        paused := make(map[uuid.UUID]bool)
+        for _, c := range cs {
+            if err := tx.Model(&c).Update("status", models.ChargeOverdue).Error; err != nil {
+                return err
+            }
+            n++
+            if paused[c.EnrollmentID] {
+                continue
+            }
+            paused[c.EnrollmentID] = true
+            if _, err := transition(tx, &models.EnrollmentStatusHistory{
+                EnrollmentID: c.EnrollmentID,
+                FromStatus:   models.EnrollmentActive,
+                ToStatus:     models.EnrollmentPaused,
+                Reason:       ReasonRenewalOverdue,
+                ChangedAt:    now,
+            }); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+    return n, err
+}
+
+// settleCharge marks a charge paid by payment p inside tx. Once the last
+// overdue charge of an enrollment is paid, an enrollment paused for it is
+// resumed.
+func settleCharge(tx *gorm.DB, p *models.FeePayment) error {
+    var c models.Charge
+    if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&c, "id = ?", *p.ChargeID).Error; err != nil {
+        return err
+    }
+    if c.Status != models.ChargeDue && c.Status != models.ChargeOverdue {
+        return ErrChargeSettled
+    }
+    now := time.Now()
+    if err := tx.Model(&c).Updates(map[string]interface{}{
+        "status":         models.ChargePaid,
+        "fee_payment_id": p.ID,
+        "paid_at":        now,
+    }).Error; err != nil {
+        return err
+    }
+    if c.Status != models.ChargeOverdue {
+        return nil
+    }
+
+    var overdue int64
+    if err := tx.Model(&models.Charge{}).Where("enrollment_id = ? AND status = ?", c.EnrollmentID, models.ChargeOverdue).
+        Count(&overdue).Error; err != nil {
+        return err
+    }
+    if overdue > 0 {
+        return nil
+    }
+    return resumePaused(tx, c.EnrollmentID, ReasonRenewalOverdue, ReasonRenewalPaid, now)
+}
+
+// resumePaused makes an enrollment active again if its latest status change
+// paused it for pausedFor.
+func resumePaused(tx *gorm.DB, enrollmentID uuid.UUID, pausedFor, reason string, at time.Time) error {
+    var last models.EnrollmentStatusHistory
+    err := tx.Where("enrollment_id = ?", enrollmentID).Order("changed_at DESC").First(&last).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    if last.ToStatus != models.EnrollmentPaused || last.Reason != pausedFor {
+        return nil
+    }
+    _, err = transition(tx, &models.EnrollmentStatusHistory{
+        EnrollmentID: enrollmentID,
+        FromStatus:   models.EnrollmentPaused,
+        ToStatus:     models.EnrollmentActive,
+        Reason:       reason,
+        ChangedAt:    at,
+    })
+    return err
+}
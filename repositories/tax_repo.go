@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"spodemy-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TaxRepository handles DB operations for TaxRate.
+type TaxRepository struct {
+    db *gorm.DB
+}
+
+// NewTaxRepository constructs a TaxRepository.
+func NewTaxRepository(db *gorm.DB) *TaxRepository {
+    return &TaxRepository{db: db}
+}
+
+// FindAll returns all tax rates ordered by code.
+func (r *TaxRepository) FindAll() ([]models.TaxRate, error) {
+    var rates []models.TaxRate
+    if err := r.db.Order("code").Find(&rates).Error; err != nil {
+        return nil, err
+    }
+    return rates, nil
+}
+
+// FindByID returns one tax rate by UUID.
+func (r *TaxRepository) FindByID(id uuid.UUID) (*models.TaxRate, error) {
+    var t models.TaxRate
+    if err := r.db.First(&t, "id = ?", id).Error; err != nil {
+        return nil, err
+    }
+    return &t, nil
+}
+
+// FindByCode returns the tax rate with the given code.
+func (r *TaxRepository) FindByCode(code string) (*models.TaxRate, error) {
+    var t models.TaxRate
+    if err := r.db.First(&t, "code = ?", code).Error; err != nil {
+        return nil, err
+    }
+    return &t, nil
+}
+
+// CountPlans counts the plans priced with a tax code.
+func (r *TaxRepository) CountPlans(code string) (int64, error) {
+    var n int64
+    err := r.db.Model(&models.Plan{}).Where("tax_code = ?", code).Count(&n).Error
+    return n, err
+}
+
+// Create inserts a new tax rate.
+func (r *TaxRepository) Create(t *models.TaxRate) error {
+    return r.db.Create(t).Error
+}
+
+// Update modifies an existing tax rate.
+func (r *TaxRepository) Update(t *models.TaxRate) error {
+    return r.db.Save(t).Error
+}
+
+// Delete removes a tax rate by UUID.
+func (r *TaxRepository) Delete(id uuid.UUID) error {
+    return r.db.Delete(&models.TaxRate{}, "id = ?", id).Error
+}
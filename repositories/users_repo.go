@@ -5,6 +5,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // UserRepository handles DB operations for User.
@@ -35,9 +36,20 @@ func (r *UserRepository) FindByID(id uuid.UUID) (*models.User, error) {
     return &user, nil
 }
 
-// Create inserts a new user record.
-func (r *UserRepository) Create(u *models.User) error {
-    return r.db.Create(u).Error
+// Create inserts a new user record with a fresh referral code and, if ref
+// is not nil, the referral of the new user in the same transaction.
+func (r *UserRepository) Create(u *models.User, ref *models.Referral) error {
+    u.ReferralCode = newReferralCode()
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Create(u).Error; err != nil {
+            return err
+        }
+        if ref == nil {
+            return nil
+        }
+        ref.ReferredID = u.ID
+        return tx.Omit(clause.Associations).Create(ref).Error
+    })
 }
 
 // Update modifies an existing user.
@@ -48,4 +60,4 @@ func (r *UserRepository) Update(u *models.User) error {
 // Delete removes a user by UUID.
 func (r *UserRepository) Delete(id uuid.UUID) error {
     return r.db.Delete(&models.User{}, "id = ?", id).Error
-}
\ No newline at end of file
+}
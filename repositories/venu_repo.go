@@ -39,6 +39,19 @@ func (r *VenueRepository) Create(venue *models.Venue) error {
     return r.db.Create(venue).Error;
 }
 
+// HasMoneyRecords reports whether anything priced in the venue's currency
+// has been recorded at it: enrollments, investments, expenses or ledger
+// entries.
+func (r *VenueRepository) HasMoneyRecords(id uuid.UUID) (bool, error) {
+    var found bool
+    err := r.db.Raw(`
+        SELECT EXISTS (SELECT 1 FROM enrollments e JOIN batches b ON b.id = e.batch_id WHERE b.venue_id = ?)
+            OR EXISTS (SELECT 1 FROM investments WHERE venue_id = ?)
+            OR EXISTS (SELECT 1 FROM expenses WHERE venue_id = ?)
+            OR EXISTS (SELECT 1 FROM journal_entries WHERE venue_id = ?)`, id, id, id, id).Scan(&found).Error
+    return found, err
+}
+
 // Update modifies an existing venue.
 func (r *VenueRepository) Update(v *models.Venue) error {
     return r.db.Save(v).Error
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"spodemy-backend/config"
 	"spodemy-backend/controllers"
 	"spodemy-backend/repositories"
 	"spodemy-backend/services"
@@ -10,9 +11,10 @@ import (
 )
 
 // RegisterAttendanceRoutes sets up attendance endpoints.
-func RegisterAttendanceRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+func RegisterAttendanceRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
     repo := repositories.NewAttendanceRepository(db)
-    svc := services.NewAttendanceService(repo)
+    makeups := services.NewMakeupCreditService(repositories.NewMakeupCreditRepository(db), repositories.NewBatchRepository(db), cfg.Makeup)
+    svc := services.NewAttendanceService(repo, newEnrollmentService(db, cfg), makeups)
     ctrl := controllers.NewAttendanceController(svc)
 
     att := rg.Group("/attendance")
@@ -25,5 +27,6 @@ func RegisterAttendanceRoutes(rg *gin.RouterGroup, db *gorm.DB) {
     }
 
     // nested under enrollments
-    rg.GET("/enrollments/:enrollmentId/attendance", ctrl.ListByEnrollment)
-}
\ No newline at end of file
+    rg.GET("/enrollments/:id/attendance", ctrl.ListByEnrollment)
+    rg.GET("/enrollments/:id/attendance/summary", ctrl.Summary)
+}
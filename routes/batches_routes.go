@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"spodemy-backend/config"
 	"spodemy-backend/controllers"
 	"spodemy-backend/repositories"
 	"spodemy-backend/services"
@@ -10,7 +11,7 @@ import (
 )
 
 // RegisterBatchRoutes wires up batch endpoints.
-func RegisterBatchRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+func RegisterBatchRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
     repo := repositories.NewBatchRepository(db)
     svc := services.NewBatchService(repo)
     ctrl := controllers.NewBatchController(svc)
@@ -22,6 +23,10 @@ func RegisterBatchRoutes(rg *gin.RouterGroup, db *gorm.DB) {
     rg.DELETE("/batches/:id", ctrl.Delete)
 
     // Nested under venues
-    rg.GET("/venues/:venueId/batches", ctrl.ListByVenue)
-    rg.POST("/venues/:venueId/batches", ctrl.Create)
+    rg.GET("/venues/:id/batches", ctrl.ListByVenue)
+    rg.POST("/venues/:id/batches", ctrl.Create)
+
+    // Term rollover
+    rollover := controllers.NewRolloverController(services.NewRolloverService(repo, repositories.NewEnrollmentRepository(db), newPricingService(db, cfg)))
+    rg.POST("/venues/:id/rollover", rollover.Rollover)
 }
\ No newline at end of file
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"spodemy-backend/controllers"
+	"spodemy-backend/repositories"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterCouponRoutes sets up coupon code endpoints.
+func RegisterCouponRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+    svc := services.NewCouponService(repositories.NewCouponRepository(db), repositories.NewOfferRepository(db))
+    ctrl := controllers.NewCouponController(svc)
+
+    coupons := rg.Group("/coupons")
+    {
+        coupons.GET("", ctrl.List)
+        coupons.POST("", ctrl.Create)
+        coupons.GET("/:id", ctrl.Get)
+        coupons.PUT("/:id", ctrl.Update)
+        coupons.DELETE("/:id", ctrl.Delete)
+        coupons.GET("/:id/redemptions", ctrl.Redemptions)
+    }
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"spodemy-backend/config"
+	"spodemy-backend/controllers"
+	"spodemy-backend/repositories"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterCurrencyRoutes sets up exchange rate endpoints.
+func RegisterCurrencyRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
+    ctrl := controllers.NewCurrencyController(newCurrencyService(db, cfg))
+
+    rates := rg.Group("/exchange-rates")
+    {
+        rates.GET("", ctrl.Rates)
+        rates.POST("", ctrl.CreateRate)
+        rates.DELETE("/:id", ctrl.DeleteRate)
+    }
+}
+
+// newCurrencyService builds the currency service shared with the reports.
+func newCurrencyService(db *gorm.DB, cfg *config.Config) *services.CurrencyService {
+    return services.NewCurrencyService(repositories.NewExchangeRateRepository(db), cfg.Currency)
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"spodemy-backend/config"
+	"spodemy-backend/controllers"
+	"spodemy-backend/repositories"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterDuesRoutes sets up outstanding balance endpoints.
+func RegisterDuesRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
+    repo := repositories.NewDuesRepository(db)
+    svc := services.NewDuesService(repo, cfg.Dues)
+    ctrl := controllers.NewDuesController(svc)
+
+    rg.GET("/dues", ctrl.List)
+
+    // nested under enrollments
+    rg.GET("/enrollments/:id/balance", ctrl.Balance)
+}
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"spodemy-backend/config"
 	"spodemy-backend/controllers"
 	"spodemy-backend/repositories"
 	"spodemy-backend/services"
@@ -10,20 +11,38 @@ import (
 )
 
 // RegisterEnrollmentRoutes sets up enrollment endpoints.
-func RegisterEnrollmentRoutes(rg *gin.RouterGroup, db *gorm.DB) {
-    repo := repositories.NewEnrollmentRepository(db)
-    svc := services.NewEnrollmentService(repo)
+func RegisterEnrollmentRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
+    svc := newEnrollmentService(db, cfg)
     ctrl := controllers.NewEnrollmentController(svc)
 
     ens := rg.Group("/enrollments")
     {
         ens.GET("", ctrl.List)
+        ens.GET("/expiring", ctrl.ListExpiring)
         ens.GET("/:id", ctrl.Get)
         ens.POST("", ctrl.Create)
         ens.PUT("/:id", ctrl.Update)
         ens.DELETE("/:id", ctrl.Delete)
+        ens.POST("/:id/convert", ctrl.ConvertTrial)
+        ens.GET("/:id/history", ctrl.History)
+        ens.POST("/:id/freeze", ctrl.Freeze)
+        ens.GET("/:id/freezes", ctrl.Freezes)
+
+        // status transitions
+        ens.POST("/:id/activate", ctrl.Activate)
+        ens.POST("/:id/pause", ctrl.Pause)
+        ens.POST("/:id/resume", ctrl.Resume)
+        ens.POST("/:id/complete", ctrl.Complete)
+        ens.POST("/:id/drop", ctrl.Drop)
+        ens.POST("/:id/waitlist", ctrl.Waitlist)
     }
 
     // nested under batches
-    rg.GET("/batches/:batchId/enrollments", ctrl.ListByBatch)
-}
\ No newline at end of file
+    rg.GET("/batches/:id/enrollments", ctrl.ListByBatch)
+}
+
+// newEnrollmentService builds the enrollment service shared by several route groups.
+func newEnrollmentService(db *gorm.DB, cfg *config.Config) *services.EnrollmentService {
+    return services.NewEnrollmentService(repositories.NewEnrollmentRepository(db), repositories.NewPlanRepository(db),
+        repositories.NewBatchRepository(db), newPricingService(db, cfg), cfg.Trial)
+}
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"spodemy-backend/config"
 	"spodemy-backend/controllers"
 	"spodemy-backend/repositories"
 	"spodemy-backend/services"
@@ -10,9 +11,9 @@ import (
 )
 
 // RegisterExpenseRoutes wires up expense endpoints.
-func RegisterExpenseRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+func RegisterExpenseRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
     repo := repositories.NewExpenseRepository(db)
-    svc := services.NewExpenseService(repo)
+    svc := services.NewExpenseService(repo, cfg.Currency)
     ctrl := controllers.NewExpenseController(svc)
 
     rg.GET("/expenses", ctrl.List)
@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"spodemy-backend/config"
+	"spodemy-backend/controllers"
+	"spodemy-backend/repositories"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterFamilyRoutes sets up family endpoints.
+func RegisterFamilyRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
+    svc := services.NewFamilyService(repositories.NewFamilyRepository(db), repositories.NewUserRepository(db),
+        newCurrencyService(db, cfg))
+    ctrl := controllers.NewFamilyController(svc)
+
+    families := rg.Group("/families")
+    {
+        families.GET("", ctrl.List)
+        families.POST("", ctrl.Create)
+        families.GET("/:id", ctrl.Get)
+        families.PUT("/:id", ctrl.Update)
+        families.DELETE("/:id", ctrl.Delete)
+        families.POST("/:id/members", ctrl.AddMember)
+        families.DELETE("/:id/members/:userId", ctrl.RemoveMember)
+        families.GET("/:id/statement", ctrl.Statement)
+    }
+}
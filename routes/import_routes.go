@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"spodemy-backend/config"
+	"spodemy-backend/controllers"
+	"spodemy-backend/repositories"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterImportRoutes sets up bulk import endpoints.
+func RegisterImportRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
+    svc := services.NewImportService(repositories.NewImportRepository(db), repositories.NewPlanRepository(db),
+        newPricingService(db, cfg), cfg.Import)
+    ctrl := controllers.NewImportController(svc)
+
+    imports := rg.Group("/imports")
+    {
+        imports.GET("", ctrl.List)
+        imports.POST("/enrollments", ctrl.Enrollments)
+        imports.GET("/:id", ctrl.Get)
+    }
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"spodemy-backend/config"
+	"spodemy-backend/controllers"
+	"spodemy-backend/repositories"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterInstallmentRoutes sets up installment endpoints.
+func RegisterInstallmentRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
+    svc := services.NewInstallmentService(repositories.NewInstallmentRepository(db),
+        repositories.NewEnrollmentRepository(db), cfg.Installment)
+    ctrl := controllers.NewInstallmentController(svc)
+
+    rg.GET("/installments", ctrl.List)
+
+    // nested under enrollments
+    rg.GET("/enrollments/:id/installments", ctrl.ListByEnrollment)
+}
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"spodemy-backend/config"
 	"spodemy-backend/controllers"
 	"spodemy-backend/repositories"
 	"spodemy-backend/services"
@@ -10,7 +11,7 @@ import (
 )
 
 // RegisterInvestmentRoutes wires up investment endpoints.
-func RegisterInvestmentRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+func RegisterInvestmentRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
     repo := repositories.NewInvestmentRepository(db)
     svc := services.NewInvestmentService(repo)
     ctrl := controllers.NewInvestmentController(svc)
@@ -22,5 +23,6 @@ func RegisterInvestmentRoutes(rg *gin.RouterGroup, db *gorm.DB) {
     rg.DELETE("/investments/:id", ctrl.Delete)
 
     rg.GET("/investments/:id/transactions", ctrl.ListTransactions)
-    rg.POST("/investments/:id/transactions", ctrl.CreateTransaction)
+    rg.POST("/investments/:id/transactions", idempotent(db, cfg), ctrl.CreateTransaction)
+    rg.POST("/investments/:id/transactions/:txnId/reverse", idempotent(db, cfg), ctrl.ReverseTransaction)
 }
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"spodemy-backend/config"
+	"spodemy-backend/controllers"
+	"spodemy-backend/repositories"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterInvoiceRoutes sets up invoice endpoints.
+func RegisterInvoiceRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
+    repo := repositories.NewInvoiceRepository(db)
+    svc := services.NewInvoiceService(repo, cfg.Invoice)
+    ctrl := controllers.NewInvoiceController(svc)
+
+    invoices := rg.Group("/invoices")
+    {
+        invoices.GET("", ctrl.List)
+        invoices.GET("/:id", ctrl.Get) // also /invoices/{id}.pdf
+    }
+
+    // nested under enrollments
+    rg.GET("/enrollments/:id/invoices", ctrl.ListByEnrollment)
+}
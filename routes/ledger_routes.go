@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"spodemy-backend/config"
+	"spodemy-backend/controllers"
+	"spodemy-backend/repositories"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterLedgerRoutes sets up general ledger endpoints.
+func RegisterLedgerRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
+    repo := repositories.NewLedgerRepository(db)
+    svc := services.NewLedgerService(repo, cfg.Currency)
+    ctrl := controllers.NewLedgerController(svc)
+
+    ledger := rg.Group("/ledger")
+    {
+        ledger.GET("/accounts", ctrl.Accounts)
+        ledger.POST("/accounts", ctrl.CreateAccount)
+        ledger.GET("/accounts/:id/statement", ctrl.Statement)
+        ledger.GET("/journal-entries", ctrl.Entries)
+        ledger.POST("/journal-entries", ctrl.CreateEntry)
+        ledger.GET("/journal-entries/:id", ctrl.Entry)
+        ledger.GET("/trial-balance", ctrl.TrialBalance)
+    }
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"spodemy-backend/config"
+	"spodemy-backend/controllers"
+	"spodemy-backend/repositories"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterMakeupCreditRoutes sets up make-up credit endpoints.
+func RegisterMakeupCreditRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
+    repo := repositories.NewMakeupCreditRepository(db)
+    svc := services.NewMakeupCreditService(repo, repositories.NewBatchRepository(db), cfg.Makeup)
+    ctrl := controllers.NewMakeupCreditController(svc)
+
+    mcs := rg.Group("/makeup-credits")
+    {
+        mcs.GET("/:id", ctrl.Get)
+        mcs.POST("/:id/book", ctrl.Book)
+        mcs.DELETE("/:id/book", ctrl.CancelBooking)
+    }
+
+    // nested under enrollments
+    rg.GET("/enrollments/:id/makeup-credits", ctrl.ListByEnrollment)
+}
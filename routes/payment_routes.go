@@ -1,7 +1,11 @@
 package routes
 
 import (
+	"log"
+
+	"spodemy-backend/config"
 	"spodemy-backend/controllers"
+	"spodemy-backend/middlewares"
 	"spodemy-backend/repositories"
 	"spodemy-backend/services"
 
@@ -10,15 +14,51 @@ import (
 )
 
 // RegisterPaymentRoutes wires up payment endpoints.
-func RegisterPaymentRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+func RegisterPaymentRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
     repo := repositories.NewPaymentRepository(db)
-    svc := services.NewPaymentService(repo)
+    svc := services.NewPaymentService(repo, newEnrollmentService(db, cfg), newPricingService(db, cfg), newSubscriptionService(db, cfg))
     ctrl := controllers.NewPaymentController(svc)
+    gateway, err := services.NewPaymentGateway(cfg.Gateway)
+    if err != nil {
+        log.Fatalf("payment gateway: %v", err)
+    }
+    refundSvc := services.NewRefundService(repositories.NewRefundRepository(db), repositories.NewCheckoutRepository(db), gateway)
+    checkout := controllers.NewCheckoutController(services.NewCheckoutService(
+        repositories.NewCheckoutRepository(db), svc, refundSvc, gateway))
 
     rg.GET("/payments", ctrl.List)
-    rg.POST("/payments", ctrl.Create)
+    rg.POST("/payments", idempotent(db, cfg), ctrl.Create)
     rg.GET("/payments/:id", ctrl.Get)
-    rg.PUT("/payments/:id", ctrl.Update)
-    rg.DELETE("/payments/:id", ctrl.Delete)
-    rg.GET("/enrollments/:enrollmentId/payments", ctrl.ListByEnrollment)
+    rg.POST("/payments/:id/reverse", idempotent(db, cfg), ctrl.Reverse)
+    rg.GET("/enrollments/:id/payments", ctrl.ListByEnrollment)
+
+    // online payments
+    rg.POST("/payments/checkout", idempotent(db, cfg), checkout.Start)
+    rg.GET("/payments/checkout/:id", checkout.Get)
+    rg.POST("/payments/webhook", checkout.Webhook)
+    if gateway.Name() == "fake" {
+        rg.POST("/payments/checkout/:id/simulate", checkout.Simulate)
+    }
+
+    // refunds go back through the gateway online payments were taken with
+    refunds := controllers.NewRefundController(refundSvc)
+    rg.GET("/refunds", refunds.List)
+    rg.GET("/refunds/:id", refunds.Get)
+    rg.GET("/payments/:id/refunds", refunds.ListByPayment)
+    rg.POST("/payments/:id/refunds", idempotent(db, cfg), refunds.Create)
+
+    // receipts are mailed by a background job; these re-download and resend
+    mailer, err := services.NewMailer(cfg.Mail)
+    if err != nil {
+        log.Fatalf("mailer: %v", err)
+    }
+    receipts := controllers.NewReceiptController(services.NewReceiptService(
+        repositories.NewReceiptRepository(db), mailer, cfg.Invoice, cfg.Mail))
+    rg.GET("/payments/:id/receipt", receipts.Get)
+    rg.POST("/payments/:id/receipt/resend", receipts.Resend)
+}
+
+// idempotent lets clients retry a money-moving POST with an Idempotency-Key.
+func idempotent(db *gorm.DB, cfg *config.Config) gin.HandlerFunc {
+    return middlewares.Idempotency(services.NewIdempotencyService(repositories.NewIdempotencyRepository(db), cfg.Idempotency))
 }
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"spodemy-backend/config"
 	"spodemy-backend/controllers"
 	"spodemy-backend/repositories"
 	"spodemy-backend/services"
@@ -10,10 +11,11 @@ import (
 )
 
 // RegisterPlanRoutes sets up plan-related routes
-func RegisterPlanRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+func RegisterPlanRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
     repo := repositories.NewPlanRepository(db)
-    svc := services.NewPlanService(repo)
+    svc := services.NewPlanService(repo, repositories.NewOfferRepository(db), repositories.NewTaxRepository(db), cfg.Currency)
     ctrl := controllers.NewPlanController(svc)
+    pricing := controllers.NewPricingController(newPricingService(db, cfg))
 
     plans := rg.Group("/plans")
     {
@@ -22,13 +24,25 @@ func RegisterPlanRoutes(rg *gin.RouterGroup, db *gorm.DB) {
         plans.GET("/:id", ctrl.Get)
         plans.PUT("/:id", ctrl.Update)
         plans.DELETE("/:id", ctrl.Delete)
+        plans.GET("/:id/versions", ctrl.Versions)
+        plans.POST("/:id/quote", pricing.Quote)
+        plans.POST("/:id/offers/:offerId", ctrl.AttachOffer)
+        plans.DELETE("/:id/offers/:offerId", ctrl.DetachOffer)
     }
 }
 
+// newPricingService builds the pricing service shared by several route groups.
+func newPricingService(db *gorm.DB, cfg *config.Config) *services.PricingService {
+    return services.NewPricingService(repositories.NewPlanRepository(db), repositories.NewVenueRepository(db),
+        repositories.NewUserRepository(db), repositories.NewBatchRepository(db), repositories.NewEnrollmentRepository(db),
+        repositories.NewCouponRepository(db), repositories.NewFamilyRepository(db), repositories.NewTaxRepository(db),
+        repositories.NewQuoteRepository(db), cfg.Pricing, cfg.Family)
+}
+
 // RegisterOfferRoutes sets up offer-related routes
-func RegisterOfferRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+func RegisterOfferRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
     repo := repositories.NewOfferRepository(db)
-    svc := services.NewOfferService(repo)
+    svc := services.NewOfferService(repo, cfg.Currency)
     ctrl := controllers.NewOfferController(svc)
 
     offers := rg.Group("/offers")
@@ -39,4 +53,4 @@ func RegisterOfferRoutes(rg *gin.RouterGroup, db *gorm.DB) {
         offers.PUT("/:id", ctrl.Update)
         offers.DELETE("/:id", ctrl.Delete)
     }
-}
\ No newline at end of file
+}
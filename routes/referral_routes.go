@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"spodemy-backend/config"
+	"spodemy-backend/controllers"
+	"spodemy-backend/repositories"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterReferralRoutes sets up referral and wallet endpoints.
+func RegisterReferralRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
+    ctrl := controllers.NewReferralController(newReferralService(db, cfg))
+
+    referrals := rg.Group("/referrals")
+    {
+        referrals.GET("", ctrl.List)
+        referrals.POST("", ctrl.Create)
+    }
+
+    // nested under users
+    rg.GET("/users/:id/wallet", ctrl.Wallet)
+}
+
+// newReferralService builds the referral service shared with user signup.
+func newReferralService(db *gorm.DB, cfg *config.Config) *services.ReferralService {
+    return services.NewReferralService(repositories.NewReferralRepository(db),
+        repositories.NewUserRepository(db), repositories.NewEnrollmentRepository(db), cfg.Referral, cfg.Currency)
+}
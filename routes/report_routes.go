@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"spodemy-backend/config"
+	"spodemy-backend/controllers"
+	"spodemy-backend/repositories"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterReportRoutes sets up reporting endpoints.
+func RegisterReportRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
+    repo := repositories.NewReportRepository(db)
+    svc := services.NewReportService(repo, newCurrencyService(db, cfg))
+    ctrl := controllers.NewReportController(svc)
+
+    reports := rg.Group("/reports")
+    {
+        reports.GET("/trial-conversions", ctrl.TrialConversions)
+        reports.GET("/referrals", ctrl.ReferralLeaderboard)
+        reports.GET("/gst", ctrl.GSTSummary)
+    }
+}
@@ -1,27 +1,42 @@
 package routes
 
 import (
+	"spodemy-backend/config"
+
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 // SetupRoutes registers all API v1 routes on the Gin engine.
-func SetupRoutes(r *gin.Engine, db *gorm.DB) {
+func SetupRoutes(r *gin.Engine, db *gorm.DB, cfg *config.Config) {
     api := r.Group("/api/v1")
 
     // venue endpoints
-    RegisterVenueRoutes(api, db)
+    RegisterVenueRoutes(api, db, cfg)
 
     // user endpoints
-    RegisterUserRoutes(api, db)
+    RegisterUserRoutes(api, db, cfg)
 
     RegisterRoleRoutes(api, db)
-    RegisterBatchRoutes(api, db)
-    RegisterPaymentRoutes(api, db)
-    RegisterInvestmentRoutes(api, db)
-    RegisterOfferRoutes(api, db)
-    RegisterPlanRoutes(api, db)
-    RegisterExpenseRoutes(api, db)
-    RegisterEnrollmentRoutes(api, db)
-    RegisterAttendanceRoutes(api, db)
+    RegisterBatchRoutes(api, db, cfg)
+    RegisterPaymentRoutes(api, db, cfg)
+    RegisterInvestmentRoutes(api, db, cfg)
+    RegisterOfferRoutes(api, db, cfg)
+    RegisterPlanRoutes(api, db, cfg)
+    RegisterTaxRoutes(api, db)
+    RegisterCouponRoutes(api, db)
+    RegisterFamilyRoutes(api, db, cfg)
+    RegisterExpenseRoutes(api, db, cfg)
+    RegisterLedgerRoutes(api, db, cfg)
+    RegisterCurrencyRoutes(api, db, cfg)
+    RegisterEnrollmentRoutes(api, db, cfg)
+    RegisterSubscriptionRoutes(api, db, cfg)
+    RegisterInstallmentRoutes(api, db, cfg)
+    RegisterInvoiceRoutes(api, db, cfg)
+    RegisterDuesRoutes(api, db, cfg)
+    RegisterAttendanceRoutes(api, db, cfg)
+    RegisterMakeupCreditRoutes(api, db, cfg)
+    RegisterReportRoutes(api, db, cfg)
+    RegisterImportRoutes(api, db, cfg)
+    RegisterReferralRoutes(api, db, cfg)
 }
@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"spodemy-backend/config"
+	"spodemy-backend/controllers"
+	"spodemy-backend/repositories"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterSubscriptionRoutes sets up renewal and charge endpoints.
+func RegisterSubscriptionRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
+    ctrl := controllers.NewSubscriptionController(newSubscriptionService(db, cfg))
+
+    rg.GET("/charges", ctrl.List)
+    rg.GET("/charges/:id", ctrl.Get)
+
+    // nested under enrollments
+    rg.GET("/enrollments/:id/charges", ctrl.ListByEnrollment)
+    rg.POST("/enrollments/:id/cancel-renewal", ctrl.Cancel)
+    rg.POST("/enrollments/:id/reinstate-renewal", ctrl.Reinstate)
+}
+
+// newSubscriptionService builds the subscription service shared with payments.
+func newSubscriptionService(db *gorm.DB, cfg *config.Config) *services.SubscriptionService {
+    return services.NewSubscriptionService(repositories.NewSubscriptionRepository(db),
+        repositories.NewEnrollmentRepository(db), newPricingService(db, cfg), cfg.Subscription)
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"spodemy-backend/controllers"
+	"spodemy-backend/repositories"
+	"spodemy-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterTaxRoutes sets up tax rate endpoints.
+func RegisterTaxRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+    repo := repositories.NewTaxRepository(db)
+    svc := services.NewTaxService(repo)
+    ctrl := controllers.NewTaxController(svc)
+
+    rates := rg.Group("/tax-rates")
+    {
+        rates.GET("", ctrl.List)
+        rates.POST("", ctrl.Create)
+        rates.GET("/:id", ctrl.Get)
+        rates.PUT("/:id", ctrl.Update)
+        rates.DELETE("/:id", ctrl.Delete)
+    }
+}
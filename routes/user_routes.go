@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"spodemy-backend/config"
 	"spodemy-backend/controllers"
 	"spodemy-backend/repositories"
 	"spodemy-backend/services"
@@ -10,9 +11,9 @@ import (
 )
 
 // RegisterUserRoutes wires up the /users endpoints under the given router group.
-func RegisterUserRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+func RegisterUserRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
     repo := repositories.NewUserRepository(db)
-    svc  := services.NewUserService(repo)
+    svc  := services.NewUserService(repo, newReferralService(db, cfg))
     ctrl := controllers.NewUserController(svc)
 
     users := rg.Group("/users")
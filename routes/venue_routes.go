@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"spodemy-backend/config"
 	"spodemy-backend/controllers"
 	"spodemy-backend/repositories"
 	"spodemy-backend/services"
@@ -10,9 +11,9 @@ import (
 )
 
 // RegisterVenueRoutes wires up the /venues endpoints under the given router group.
-func RegisterVenueRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+func RegisterVenueRoutes(rg *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
     repo := repositories.NewVenueRepository(db)
-    svc  := services.NewVenueService(repo)
+    svc  := services.NewVenueService(repo, cfg.Currency)
     ctrl := controllers.NewVenueController(svc)
 
     venues := rg.Group("/venues")
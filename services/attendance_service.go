@@ -7,14 +7,24 @@ import (
 	"github.com/google/uuid"
 )
 
+// AttendanceSummary is an enrollment's attendance with frozen days left out.
+type AttendanceSummary struct {
+    EnrollmentID uuid.UUID `json:"enrollment_id"`
+    repositories.AttendanceCounts
+    FrozenDays     int     `json:"frozen_days"`
+    AttendanceRate float64 `json:"attendance_rate"` // present / (present + absent), 0..1
+}
+
 // AttendanceService encapsulates business logic for attendance.
 type AttendanceService struct {
-    repo *repositories.AttendanceRepository
+    repo        *repositories.AttendanceRepository
+    enrollments *EnrollmentService
+    makeups     *MakeupCreditService
 }
 
 // NewAttendanceService creates a new AttendanceService.
-func NewAttendanceService(r *repositories.AttendanceRepository) *AttendanceService {
-    return &AttendanceService{repo: r}
+func NewAttendanceService(r *repositories.AttendanceRepository, enrollments *EnrollmentService, makeups *MakeupCreditService) *AttendanceService {
+    return &AttendanceService{repo: r, enrollments: enrollments, makeups: makeups}
 }
 
 // List returns all attendance records.
@@ -27,22 +37,61 @@ func (s *AttendanceService) ListByEnrollment(enrID uuid.UUID) ([]models.Attendan
     return s.repo.FindByEnrollment(enrID)
 }
 
+// Summary reports how often an enrollment's student attended. Sessions on
+// frozen days do not count towards the attendance rate.
+func (s *AttendanceService) Summary(enrID uuid.UUID) (*AttendanceSummary, error) {
+    e, err := s.enrollments.Get(enrID)
+    if err != nil {
+        return nil, err
+    }
+    counts, err := s.repo.CountStatuses(enrID)
+    if err != nil {
+        return nil, err
+    }
+    sum := &AttendanceSummary{EnrollmentID: enrID, AttendanceCounts: counts, FrozenDays: frozenDays(e.Freezes)}
+    if n := counts.Present + counts.Absent; n > 0 {
+        sum.AttendanceRate = float64(counts.Present) / float64(n)
+    }
+    return sum, nil
+}
+
 // Get retrieves a single attendance record by UUID.
 func (s *AttendanceService) Get(id uuid.UUID) (*models.Attendance, error) {
     return s.repo.FindByID(id)
 }
 
-// Create adds a new attendance record.
+// Create adds a new attendance record. Records carrying a make-up credit
+// redeem it; excused absences earn one, saved together with the record.
+// Trial enrollments may only attend their allowed sessions and close after
+// the last one.
 func (s *AttendanceService) Create(a *models.Attendance) error {
-    return s.repo.Create(a)
+    if a.MakeupCreditID != nil {
+        return s.makeups.Redeem(a)
+    }
+    enr, err := s.enrollments.Get(a.EnrollmentID)
+    if err != nil {
+        return err
+    }
+    if enr.Type != models.EnrollmentTrial {
+        return s.repo.Create(a, s.makeups.creditFor(a))
+    }
+
+    attended, err := s.repo.CreateTrialSession(a, func(e *models.Enrollment, attended int64) error {
+        return s.enrollments.CheckTrialSession(e, a.Date, attended)
+    })
+    if err != nil {
+        return err
+    }
+    return s.enrollments.CompleteTrialIfUsed(enr, attended)
 }
 
-// Update modifies an attendance record.
+// Update modifies an attendance record, issuing a make-up credit if it now
+// records an excused absence and revoking an unused one if it no longer does.
 func (s *AttendanceService) Update(a *models.Attendance) error {
-    return s.repo.Update(a)
+    return fromRepo(s.repo.Update(a, s.makeups.creditFor(a)))
 }
 
 // Delete removes an attendance record by UUID.
 func (s *AttendanceService) Delete(id uuid.UUID) error {
     return s.repo.Delete(id)
-}
\ No newline at end of file
+}
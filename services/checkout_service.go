@@ -0,0 +1,170 @@
+package services
+
+import (
+	"errors"
+
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrCheckoutAmount is returned for an online payment of nothing.
+var ErrCheckoutAmount = invalid("amount_cents must be positive for an online payment")
+
+// CheckoutRequest starts an online payment. The fields mean what they do
+// on a fee payment: a quote or charge fixes the amount, a coupon comes off
+// it.
+type CheckoutRequest struct {
+    EnrollmentID uuid.UUID  `json:"enrollment_id" binding:"required"`
+    AmountCents  int        `json:"amount_cents"`
+    QuoteID      string     `json:"quote_id"`
+    CouponCode   string     `json:"coupon_code"`
+    ChargeID     *uuid.UUID `json:"charge_id"`
+}
+
+// Checkout is a payment order with what the payer's checkout needs.
+type Checkout struct {
+    models.PaymentOrder
+    KeyID string `json:"key_id,omitempty"` // the gateway's public key
+}
+
+// CheckoutService takes fee payments through a payment gateway.
+type CheckoutService struct {
+    repo     *repositories.CheckoutRepository
+    payments *PaymentService
+    refunds  *RefundService
+    gateway  PaymentGateway
+}
+
+// NewCheckoutService creates a new CheckoutService. Orders are taken in
+// the currency of the enrollment paid for; refunds hears back from the
+// gateway through the same webhook.
+func NewCheckoutService(r *repositories.CheckoutRepository, payments *PaymentService, refunds *RefundService, gateway PaymentGateway) *CheckoutService {
+    return &CheckoutService{repo: r, payments: payments, refunds: refunds, gateway: gateway}
+}
+
+// Start checks the payment as Create would and opens a gateway order for
+// it. Nothing is recorded as paid until the gateway confirms the payment.
+func (s *CheckoutService) Start(req CheckoutRequest) (*Checkout, error) {
+    p := models.FeePayment{
+        EnrollmentID: req.EnrollmentID,
+        AmountCents:  req.AmountCents,
+        QuoteID:      req.QuoteID,
+        CouponCode:   req.CouponCode,
+        ChargeID:     req.ChargeID,
+    }
+    e, err := s.payments.prepare(&p)
+    if err != nil {
+        return nil, err
+    }
+    if p.AmountCents <= 0 {
+        return nil, ErrCheckoutAmount
+    }
+    o := models.PaymentOrder{
+        ID:            uuid.New(),
+        Provider:      s.gateway.Name(),
+        EnrollmentID:  e.ID,
+        StudentID:     e.StudentID,
+        ChargeID:      p.ChargeID,
+        CouponID:      p.CouponID,
+        QuoteNonce:    p.QuoteNonce,
+        DiscountCents: p.DiscountCents,
+        AmountCents:   p.AmountCents,
+        Currency:      e.Currency,
+        TaxBreakdown:  p.TaxBreakdown,
+        Status:        models.OrderCreated,
+    }
+    g, err := s.gateway.CreateOrder(o.AmountCents, o.Currency, o.ID.String())
+    if err != nil {
+        return nil, err
+    }
+    o.OrderRef = g.Ref
+    if err := s.repo.Create(&o); err != nil {
+        return nil, err
+    }
+    return &Checkout{PaymentOrder: o, KeyID: s.gateway.KeyID()}, nil
+}
+
+// Get returns a payment order. An order still open is checked with the
+// gateway first, in case its webhook was missed.
+func (s *CheckoutService) Get(id uuid.UUID) (*models.PaymentOrder, error) {
+    o, err := s.repo.FindByID(id)
+    if err != nil || o.Status != models.OrderCreated || o.Provider != s.gateway.Name() {
+        return o, err
+    }
+    gp, err := s.gateway.FetchStatus(o.OrderRef)
+    if err != nil {
+        return nil, err
+    }
+    if gp.Status == GatewayPending {
+        return o, nil
+    }
+    if _, err := s.apply(gp); err != nil {
+        return nil, err
+    }
+    return s.repo.FindByID(id)
+}
+
+// Webhook verifies a gateway webhook and applies the payment or refund it
+// reports. Deliveries are retried by the gateway, so a payment seen before
+// returns the fee payment already recorded for it. Events for orders not
+// started here are ignored, and refund events return no fee payment.
+func (s *CheckoutService) Webhook(body []byte, signature string) (*models.FeePayment, error) {
+    ev, err := s.gateway.VerifyWebhook(body, signature)
+    if err != nil {
+        return nil, err
+    }
+    if ev.Refund != nil {
+        return nil, s.refunds.Settle(ev.Refund)
+    }
+    if ev.Payment == nil {
+        return nil, nil
+    }
+    p, err := s.apply(ev.Payment)
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, nil
+    }
+    return p, err
+}
+
+// apply records a captured payment, or marks the order of a failed one.
+func (s *CheckoutService) apply(gp *GatewayPayment) (*models.FeePayment, error) {
+    switch gp.Status {
+    case GatewayCaptured:
+        o, p, err := s.repo.Record(s.gateway.Name(), gp.OrderRef, gp.PaymentRef, gp.AmountCents)
+        if err != nil {
+            return nil, fromRepo(err)
+        }
+        return p, s.payments.enrollments.ActivateOnPayment(o.EnrollmentID)
+    case GatewayFailed:
+        return nil, s.repo.Fail(s.gateway.Name(), gp.OrderRef, gp.Reason)
+    }
+    return nil, nil
+}
+
+// Simulate pays, or with fail declines, an open order on the fake gateway
+// and delivers the webhook it sends.
+func (s *CheckoutService) Simulate(id uuid.UUID, fail bool) (*models.PaymentOrder, error) {
+    fake, ok := s.gateway.(*FakeGateway)
+    if !ok {
+        return nil, invalid("payments can only be simulated on the fake gateway")
+    }
+    o, err := s.repo.FindByID(id)
+    if err != nil {
+        return nil, err
+    }
+    settle := fake.Pay
+    if fail {
+        settle = func(ref string) ([]byte, string, error) { return fake.Decline(ref, "declined by simulation") }
+    }
+    body, sig, err := settle(o.OrderRef)
+    if err != nil {
+        return nil, invalid(err.Error())
+    }
+    if _, err := s.Webhook(body, sig); err != nil {
+        return nil, err
+    }
+    return s.repo.FindByID(id)
+}
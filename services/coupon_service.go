@@ -0,0 +1,80 @@
+package services
+
+import (
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+
+	"github.com/google/uuid"
+)
+
+// Coupon validation errors.
+var (
+    ErrCouponCode   = invalid("code is required")
+    ErrCouponLimits = invalid("max_redemptions and max_per_user must not be negative")
+)
+
+// CouponService manages promo codes.
+type CouponService struct {
+    repo   *repositories.CouponRepository
+    offers *repositories.OfferRepository
+}
+
+// NewCouponService creates a new CouponService.
+func NewCouponService(r *repositories.CouponRepository, offers *repositories.OfferRepository) *CouponService {
+    return &CouponService{repo: r, offers: offers}
+}
+
+// List returns all coupons.
+func (s *CouponService) List() ([]models.Coupon, error) {
+    return s.repo.FindAll()
+}
+
+// Get retrieves a single coupon.
+func (s *CouponService) Get(id uuid.UUID) (*models.Coupon, error) {
+    return s.repo.FindByID(id)
+}
+
+// Create adds a new coupon for an existing offer.
+func (s *CouponService) Create(c *models.Coupon) error {
+    if err := s.validate(c); err != nil {
+        return err
+    }
+    return s.repo.Create(c)
+}
+
+// Update modifies a coupon.
+func (s *CouponService) Update(c *models.Coupon) error {
+    if _, err := s.repo.FindByID(c.ID); err != nil {
+        return err
+    }
+    if err := s.validate(c); err != nil {
+        return err
+    }
+    return s.repo.Update(c)
+}
+
+// Delete removes a coupon.
+func (s *CouponService) Delete(id uuid.UUID) error {
+    return s.repo.Delete(id)
+}
+
+// Redemptions returns where a coupon has been used, newest first.
+func (s *CouponService) Redemptions(id uuid.UUID) ([]models.CouponRedemption, error) {
+    if _, err := s.repo.FindByID(id); err != nil {
+        return nil, err
+    }
+    return s.repo.FindRedemptions(id)
+}
+
+// validate normalises the code and checks the limits and offer.
+func (s *CouponService) validate(c *models.Coupon) error {
+    c.Code = normalizeCode(c.Code)
+    if c.Code == "" {
+        return ErrCouponCode
+    }
+    if c.MaxRedemptions < 0 || c.MaxPerUser < 0 {
+        return ErrCouponLimits
+    }
+    _, err := s.offers.FindByID(c.OfferID)
+    return err
+}
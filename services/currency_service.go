@@ -0,0 +1,175 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"spodemy-backend/config"
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Currency errors.
+var (
+    ErrCurrencyCode = invalid("currency must be a three letter ISO 4217 code")
+    ErrRatePair     = invalid("base and quote must be different currencies")
+    ErrRateValue    = invalid("rate must be positive")
+    ErrRateExists   = conflict("a rate for this pair already takes effect on this day")
+)
+
+// CurrencyService keeps exchange rates and converts amounts between
+// currencies with them.
+type CurrencyService struct {
+    repo *repositories.ExchangeRateRepository
+    base string
+}
+
+// NewCurrencyService creates a new CurrencyService.
+func NewCurrencyService(r *repositories.ExchangeRateRepository, cfg config.CurrencyConfig) *CurrencyService {
+    return &CurrencyService{repo: r, base: cfg.Base}
+}
+
+// Base returns the business's own currency.
+func (s *CurrencyService) Base() string {
+    return s.base
+}
+
+// Reporting checks a requested reporting currency, defaulting it to the base
+// currency.
+func (s *CurrencyService) Reporting(code string) (string, error) {
+    return normalizeCurrency(code, s.base)
+}
+
+// Rates returns the rates of a pair, newest first. Empty codes do not filter.
+func (s *CurrencyService) Rates(base, quote string) ([]models.ExchangeRate, error) {
+    var err error
+    if base, err = normalizeCurrency(base, ""); err != nil {
+        return nil, err
+    }
+    if quote, err = normalizeCurrency(quote, ""); err != nil {
+        return nil, err
+    }
+    return s.repo.FindAll(base, quote)
+}
+
+// CreateRate adds a rate taking effect on rate.EffectiveOn, or today if it
+// is not set. A pair has at most one rate per day.
+func (s *CurrencyService) CreateRate(rate *models.ExchangeRate) error {
+    var err error
+    if rate.Base, err = normalizeCurrency(rate.Base, ""); err != nil || rate.Base == "" {
+        return ErrCurrencyCode
+    }
+    if rate.Quote, err = normalizeCurrency(rate.Quote, ""); err != nil || rate.Quote == "" {
+        return ErrCurrencyCode
+    }
+    if rate.Base == rate.Quote {
+        return ErrRatePair
+    }
+    if rate.Rate <= 0 {
+        return ErrRateValue
+    }
+    if rate.EffectiveOn.IsZero() {
+        rate.EffectiveOn = time.Now()
+    }
+    rate.EffectiveOn = dateOnly(rate.EffectiveOn)
+    n, err := s.repo.CountOn(rate.Base, rate.Quote, rate.EffectiveOn)
+    if err != nil {
+        return err
+    }
+    if n > 0 {
+        return ErrRateExists
+    }
+    return s.repo.Create(rate)
+}
+
+// DeleteRate removes a rate.
+func (s *CurrencyService) DeleteRate(id uuid.UUID) error {
+    if _, err := s.repo.FindByID(id); err != nil {
+        return err
+    }
+    return s.repo.Delete(id)
+}
+
+// Rate returns how many units of to one unit of from is worth on day on.
+// A pair without a rate of its own is converted with the inverse of the
+// opposite pair's rate, or else through the base currency.
+func (s *CurrencyService) Rate(from, to string, on time.Time) (float64, error) {
+    if from == to {
+        return 1, nil
+    }
+    on = dateOnly(on)
+    if r, ok, err := s.direct(from, to, on); ok || err != nil {
+        return r, err
+    }
+    if from != s.base && to != s.base {
+        fromBase, ok1, err := s.direct(from, s.base, on)
+        if err != nil {
+            return 0, err
+        }
+        baseTo, ok2, err := s.direct(s.base, to, on)
+        if err != nil {
+            return 0, err
+        }
+        if ok1 && ok2 {
+            return fromBase * baseTo, nil
+        }
+    }
+    return 0, invalid("no exchange rate from " + from + " to " + to + " on " + on.Format("2006-01-02"))
+}
+
+// Convert converts cents of from into to at the rate of day on, rounded to
+// the nearest cent.
+func (s *CurrencyService) Convert(cents int64, from, to string, on time.Time) (int64, error) {
+    rate, err := s.Rate(from, to, on)
+    if err != nil {
+        return 0, err
+    }
+    return convertCents(cents, rate), nil
+}
+
+// direct looks for a rate of the pair or of the opposite pair.
+func (s *CurrencyService) direct(from, to string, on time.Time) (float64, bool, error) {
+    r, err := s.repo.FindEffective(from, to, on)
+    if err == nil {
+        return r.Rate, true, nil
+    }
+    if !errors.Is(err, gorm.ErrRecordNotFound) {
+        return 0, false, err
+    }
+    r, err = s.repo.FindEffective(to, from, on)
+    if err == nil {
+        return 1 / r.Rate, true, nil
+    }
+    if !errors.Is(err, gorm.ErrRecordNotFound) {
+        return 0, false, err
+    }
+    return 0, false, nil
+}
+
+// convertCents multiplies cents by rate, rounded to the nearest cent.
+func convertCents(cents int64, rate float64) int64 {
+    return int64(math.Round(float64(cents) * rate))
+}
+
+// normalizeCurrency upper-cases a currency code, defaulting an empty one to
+// def, and checks that it is three letters.
+func normalizeCurrency(code, def string) (string, error) {
+    code = strings.ToUpper(strings.TrimSpace(code))
+    if code == "" {
+        return def, nil
+    }
+    if len(code) != 3 {
+        return "", ErrCurrencyCode
+    }
+    for _, r := range code {
+        if r < 'A' || r > 'Z' {
+            return "", ErrCurrencyCode
+        }
+    }
+    return code, nil
+}
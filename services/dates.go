@@ -0,0 +1,27 @@
+package services
+
+import (
+	"time"
+
+	"spodemy-backend/models"
+)
+
+// dateOnly truncates t to midnight UTC so calendar days compare reliably.
+func dateOnly(t time.Time) time.Time {
+    y, m, d := t.UTC().Date()
+    return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// addInterval returns t moved forward by one billing interval, or t itself
+// for an unknown interval.
+func addInterval(t time.Time, interval string) time.Time {
+    switch interval {
+    case models.BillingWeekly:
+        return t.AddDate(0, 0, 7)
+    case models.BillingMonthly:
+        return t.AddDate(0, 1, 0)
+    case models.BillingYearly:
+        return t.AddDate(1, 0, 0)
+    }
+    return t
+}
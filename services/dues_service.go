@@ -0,0 +1,85 @@
+package services
+
+import (
+	"time"
+
+	"spodemy-backend/config"
+	"spodemy-backend/repositories"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrOverdueDays is returned for a negative overdue_days filter.
+var ErrOverdueDays = invalid("overdue_days must not be negative")
+
+// DuesQuery selects the enrollments listed as owing.
+type DuesQuery struct {
+    VenueID     *uuid.UUID
+    BatchID     *uuid.UUID
+    OverdueDays *int // only balances unpaid for at least this many days
+}
+
+// Balance is an enrollment's balance with how long it has been unpaid.
+type Balance struct {
+    repositories.BalanceRow
+    DaysOverdue int  `json:"days_overdue"` // days since DueSince; 0 if nothing is owed
+    Overdue     bool `json:"overdue"`      // unpaid for longer than the grace period
+}
+
+// DuesService reports what students owe.
+type DuesService struct {
+    repo *repositories.DuesRepository
+    cfg  config.DuesConfig
+}
+
+// NewDuesService creates a new DuesService.
+func NewDuesService(r *repositories.DuesRepository, cfg config.DuesConfig) *DuesService {
+    return &DuesService{repo: r, cfg: cfg}
+}
+
+// List returns the enrollments with a balance, oldest due first.
+func (s *DuesService) List(q DuesQuery) ([]Balance, error) {
+    today := dateOnly(time.Now())
+    f := repositories.DuesFilter{VenueID: q.VenueID, BatchID: q.BatchID, OwingOnly: true}
+    if q.OverdueDays != nil {
+        if *q.OverdueDays < 0 {
+            return nil, ErrOverdueDays
+        }
+        cutoff := today.AddDate(0, 0, 1-*q.OverdueDays)
+        f.DueBefore = &cutoff
+    }
+    rows, err := s.repo.Balances(f)
+    if err != nil {
+        return nil, err
+    }
+    out := make([]Balance, len(rows))
+    for i, row := range rows {
+        out[i] = s.balance(row, today)
+    }
+    return out, nil
+}
+
+// Get returns the balance of one enrollment.
+func (s *DuesService) Get(enrollmentID uuid.UUID) (*Balance, error) {
+    rows, err := s.repo.Balances(repositories.DuesFilter{EnrollmentID: &enrollmentID})
+    if err != nil {
+        return nil, err
+    }
+    if len(rows) == 0 {
+        return nil, gorm.ErrRecordNotFound
+    }
+    b := s.balance(rows[0], dateOnly(time.Now()))
+    return &b, nil
+}
+
+func (s *DuesService) balance(row repositories.BalanceRow, today time.Time) Balance {
+    b := Balance{BalanceRow: row}
+    if row.BalanceCents > 0 && row.DueSince != nil {
+        if days := int(today.Sub(dateOnly(*row.DueSince)).Hours() / 24); days > 0 {
+            b.DaysOverdue = days
+        }
+        b.Overdue = b.DaysOverdue > s.cfg.GraceDays
+    }
+    return b
+}
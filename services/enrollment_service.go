@@ -1,20 +1,90 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"spodemy-backend/config"
 	"spodemy-backend/models"
 	"spodemy-backend/repositories"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Enrollment errors.
+var (
+    ErrEnrollmentType   = invalid("enrollment type must be \"regular\" or \"trial\"")
+    ErrNotTrial         = invalid("enrollment is not a trial")
+    ErrTrialConverted   = conflict("trial has already been converted")
+    ErrTrialClosed      = conflict("trial is no longer open")
+    ErrTrialExhausted   = conflict("trial has used all of its sessions")
+    ErrConvertVenue     = invalid("a trial can only be converted into a batch at its venue")
+    ErrOfferWithoutPlan = invalid("an offer can only be applied together with a plan")
+    ErrOfferNotOnPlan   = invalid("offer is not attached to the plan")
+    ErrExpiringDays     = invalid("days must be between 0 and 365")
+    ErrReasonRequired   = invalid("a reason is required to change an enrollment's status")
+    ErrStatusChanged    = conflict("enrollment status changed concurrently, retry")
+    ErrCouponOnUpdate   = invalid("coupons can only be redeemed when enrolling or paying")
+    ErrLifecycleUpdate  = invalid("status, type and trial fields change only through transitions and trial conversion")
+    ErrFreezeNotAllowed = invalid("the enrollment's plan does not allow freezing")
+    ErrFreezePeriod     = invalid("end_date must not be before start_date")
+    ErrFreezeOutside    = invalid("a freeze must start within the enrollment's validity")
+    ErrFreezeStatus     = conflict("only active or paused enrollments can be frozen")
+    ErrFreezeOverlap    = conflict("freeze overlaps an existing freeze")
 )
 
+// enrollmentTransitions lists the statuses reachable from each status.
+// Completed and dropped enrollments are final.
+var enrollmentTransitions = map[string][]string{
+    models.EnrollmentWaitlisted:     {models.EnrollmentPendingPayment, models.EnrollmentActive, models.EnrollmentDropped},
+    models.EnrollmentPendingPayment: {models.EnrollmentActive, models.EnrollmentWaitlisted, models.EnrollmentDropped},
+    models.EnrollmentActive:         {models.EnrollmentPaused, models.EnrollmentCompleted, models.EnrollmentDropped},
+    models.EnrollmentPaused:         {models.EnrollmentActive, models.EnrollmentCompleted, models.EnrollmentDropped},
+}
+
+// CanTransition reports whether an enrollment may move from one status to another.
+func CanTransition(from, to string) bool {
+    for _, s := range enrollmentTransitions[from] {
+        if s == to {
+            return true
+        }
+    }
+    return false
+}
+
+// ConvertTrialRequest describes the paid enrollment a trial becomes.
+type ConvertTrialRequest struct {
+    PlanID     uuid.UUID  `json:"plan_id" binding:"required"`
+    OfferID    *uuid.UUID `json:"offer_id"`
+    BatchID    *uuid.UUID `json:"batch_id"`    // defaults to the trial's batch
+    EnrolledOn *time.Time `json:"enrolled_on"` // defaults to today
+    QuoteID    string     `json:"quote_id"`    // signed quote to honor for the price
+    CouponCode string     `json:"coupon_code"`
+}
+
+// FreezeRequest puts an enrollment on hold from StartDate to EndDate, both
+// days included.
+type FreezeRequest struct {
+    StartDate time.Time `json:"start_date" binding:"required"`
+    EndDate   time.Time `json:"end_date" binding:"required"`
+    Reason    string    `json:"reason"`
+}
+
 // EnrollmentService provides business logic for enrollments.
 type EnrollmentService struct {
-    repo *repositories.EnrollmentRepository
+    repo    *repositories.EnrollmentRepository
+    plans   *repositories.PlanRepository
+    batches *repositories.BatchRepository
+    pricing *PricingService
+    trial   config.TrialConfig
 }
 
 // NewEnrollmentService creates a new service instance.
-func NewEnrollmentService(r *repositories.EnrollmentRepository) *EnrollmentService {
-    return &EnrollmentService{repo: r}
+func NewEnrollmentService(r *repositories.EnrollmentRepository, plans *repositories.PlanRepository, batches *repositories.BatchRepository, pricing *PricingService, trial config.TrialConfig) *EnrollmentService {
+    return &EnrollmentService{repo: r, plans: plans, batches: batches, pricing: pricing, trial: trial}
 }
 
 // List returns all enrollments.
@@ -32,17 +102,558 @@ func (s *EnrollmentService) Get(id uuid.UUID) (*models.Enrollment, error) {
     return s.repo.FindByID(id)
 }
 
-// Create adds a new enrollment.
-func (s *EnrollmentService) Create(e *models.Enrollment) error {
-    return s.repo.Create(e)
+// Create adds a new enrollment. Regular enrollments start pending payment,
+// or waitlisted if asked to, and become active only once paid; trials start
+// active, are limited to the configured number of sessions and close after
+// the trial window.
+func (s *EnrollmentService) Create(e *models.Enrollment, actor *uuid.UUID) error {
+    e.CouponID = nil
+    e.Freezes = nil
+    e.PlanVersionID = nil
+    if e.Type == "" {
+        e.Type = models.EnrollmentRegular
+    }
+    switch e.Type {
+    case models.EnrollmentRegular:
+        if err := s.applyPlan(e); err != nil {
+            return err
+        }
+        if e.Status != models.EnrollmentWaitlisted {
+            e.Status = models.EnrollmentPendingPayment
+        }
+    case models.EnrollmentTrial:
+        s.startTrial(e)
+    default:
+        return ErrEnrollmentType
+    }
+    return fromRepo(s.repo.Create(e, actor))
 }
 
-// Update modifies an existing enrollment.
+// Update modifies an existing enrollment, recomputing its validity from the
+// plan and its freezes. The status, type and trial fields may be left out
+// but not changed; use Transition and ConvertTrial for that. The price is
+// kept unless the plan or offer changes or a new quote is given, in which
+// case the enrollment is invoiced again. Renewal is cancelled through the
+// subscription endpoints.
 func (s *EnrollmentService) Update(e *models.Enrollment) error {
-    return s.repo.Update(e)
+    current, err := s.repo.FindByID(e.ID)
+    if err != nil {
+        return err
+    }
+    if lifecycleChanged(e, current) {
+        return ErrLifecycleUpdate
+    }
+    e.Status = current.Status
+    e.Type = current.Type
+    e.TrialSessions = current.TrialSessions
+    e.TrialEndsOn = current.TrialEndsOn
+    e.ConvertedFromID = current.ConvertedFromID
+    e.PriceCents = current.PriceCents
+    e.TaxBreakdown = current.TaxBreakdown
+    e.SiblingDiscountCents = current.SiblingDiscountCents
+    e.AppliedOffers = nil
+    e.CouponID = current.CouponID
+    e.AutoRenew = current.AutoRenew
+    e.CancelAtPeriodEnd = current.CancelAtPeriodEnd
+    e.CanceledAt = current.CanceledAt
+    e.Freezes = current.Freezes
+    e.PlanVersion = nil
+    e.PlanVersionID = nil
+    if sameID(e.PlanID, current.PlanID) {
+        e.PlanVersionID = current.PlanVersionID
+    }
+    repriced := false
+    if current.Type != models.EnrollmentTrial {
+        repriced = e.QuoteID != "" || !sameID(e.PlanID, current.PlanID) || !sameID(e.OfferID, current.OfferID)
+        if err := s.applyPlan(e); err != nil {
+            return err
+        }
+        if !sameID(e.CouponID, current.CouponID) {
+            return ErrCouponOnUpdate
+        }
+        if !repriced {
+            e.PriceCents = current.PriceCents
+            e.TaxBreakdown = current.TaxBreakdown
+            e.SiblingDiscountCents = current.SiblingDiscountCents
+            e.AppliedOffers = nil
+            if current.AutoRenew {
+                e.ValidUntil = current.ValidUntil // moved on by renewals
+            }
+        }
+    }
+    return fromRepo(s.repo.Update(e, repriced))
+}
+
+// Transition moves an enrollment to status to if the state machine allows
+// it, recording the reason and who made the change.
+func (s *EnrollmentService) Transition(id uuid.UUID, to, reason string, actor *uuid.UUID) (*models.Enrollment, error) {
+    reason = strings.TrimSpace(reason)
+    if reason == "" {
+        return nil, ErrReasonRequired
+    }
+    e, err := s.repo.FindByID(id)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.transition(e, to, reason, actor); err != nil {
+        return nil, err
+    }
+    return s.repo.FindByID(id)
+}
+
+// History returns the status changes of an enrollment, oldest first.
+func (s *EnrollmentService) History(id uuid.UUID) ([]models.EnrollmentStatusHistory, error) {
+    if _, err := s.repo.FindByID(id); err != nil {
+        return nil, err
+    }
+    return s.repo.FindHistory(id)
 }
 
 // Delete removes an enrollment by UUID.
 func (s *EnrollmentService) Delete(id uuid.UUID) error {
     return s.repo.Delete(id)
 }
+
+// ConvertTrial upgrades a trial to a paid enrollment on a plan, pending its
+// first payment. The trial is completed and the new enrollment records which
+// trial it came from.
+func (s *EnrollmentService) ConvertTrial(id uuid.UUID, req ConvertTrialRequest, actor *uuid.UUID) (*models.Enrollment, error) {
+    trial, err := s.repo.FindByID(id)
+    if err != nil {
+        return nil, err
+    }
+    if trial.Type != models.EnrollmentTrial {
+        return nil, ErrNotTrial
+    }
+    if _, err := s.repo.FindByConvertedFrom(trial.ID); err == nil {
+        return nil, ErrTrialConverted
+    } else if !errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, err
+    }
+    paid := &models.Enrollment{
+        StudentID:       trial.StudentID,
+        BatchID:         trial.BatchID,
+        EnrolledOn:      dateOnly(time.Now()),
+        Status:          models.EnrollmentPendingPayment,
+        Type:            models.EnrollmentRegular,
+        PlanID:          &req.PlanID,
+        OfferID:         req.OfferID,
+        ConvertedFromID: &trial.ID,
+        QuoteID:         req.QuoteID,
+        CouponCode:      req.CouponCode,
+    }
+    if req.BatchID != nil {
+        batch, err := s.batches.FindByID(*req.BatchID)
+        if err != nil {
+            return nil, err
+        }
+        if batch.VenueID != trial.Batch.VenueID {
+            return nil, ErrConvertVenue
+        }
+        paid.BatchID = batch.ID
+    }
+    if req.EnrolledOn != nil {
+        paid.EnrolledOn = *req.EnrolledOn
+    }
+    if err := s.applyPlan(paid); err != nil {
+        return nil, err
+    }
+    if err := s.repo.Convert(trial, paid, actor); err != nil {
+        return nil, fromRepo(err)
+    }
+    return s.repo.FindByID(paid.ID)
+}
+
+// Freeze puts an active or paused enrollment on hold for the requested days
+// and extends its validity by as many days. The plan caps the frozen days per
+// calendar year; freezes may not overlap.
+func (s *EnrollmentService) Freeze(id uuid.UUID, req FreezeRequest, actor *uuid.UUID) (*models.Enrollment, error) {
+    current, err := s.repo.FindByID(id)
+    if err != nil {
+        return nil, err
+    }
+    plan := pinnedPlan(current.Plan, current.PlanVersion)
+    if plan == nil || plan.MaxFreezeDaysPerYear <= 0 {
+        return nil, ErrFreezeNotAllowed
+    }
+    start, end := dateOnly(req.StartDate), dateOnly(req.EndDate)
+    if end.Before(start) {
+        return nil, ErrFreezePeriod
+    }
+    f := &models.EnrollmentFreeze{
+        EnrollmentID: id,
+        StartDate:    start,
+        EndDate:      end,
+        Days:         int(end.Sub(start).Hours()/24) + 1,
+        Reason:       strings.TrimSpace(req.Reason),
+        CreatedByID:  actor,
+    }
+    err = s.repo.Freeze(f, func(e *models.Enrollment, existing []models.EnrollmentFreeze) error {
+        if !sameID(e.PlanID, &plan.ID) {
+            return ErrStatusChanged
+        }
+        if e.Status != models.EnrollmentActive && e.Status != models.EnrollmentPaused {
+            return ErrFreezeStatus
+        }
+        if e.ValidUntil == nil || start.Before(dateOnly(e.EnrolledOn)) || start.After(*e.ValidUntil) {
+            return ErrFreezeOutside
+        }
+        for _, x := range existing {
+            if !start.After(x.EndDate) && !end.Before(x.StartDate) {
+                return ErrFreezeOverlap
+            }
+        }
+        for year := start.Year(); year <= end.Year(); year++ {
+            days := freezeDaysIn(*f, year)
+            for _, x := range existing {
+                days += freezeDaysIn(x, year)
+            }
+            if days > plan.MaxFreezeDaysPerYear {
+                return invalid(fmt.Sprintf("freeze would take %d past the plan's %d frozen days per year", year, plan.MaxFreezeDaysPerYear))
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return s.repo.FindByID(id)
+}
+
+// Freezes returns the freezes of an enrollment, earliest first.
+func (s *EnrollmentService) Freezes(id uuid.UUID) ([]models.EnrollmentFreeze, error) {
+    if _, err := s.repo.FindByID(id); err != nil {
+        return nil, err
+    }
+    return s.repo.FindFreezes(id)
+}
+
+// ListExpiring returns active enrollments whose validity ends within the next
+// days days, soonest first, for renewal calls.
+func (s *EnrollmentService) ListExpiring(days int) ([]models.Enrollment, error) {
+    if days < 0 || days > 365 {
+        return nil, ErrExpiringDays
+    }
+    today := dateOnly(time.Now())
+    return s.repo.FindExpiring(today, today.AddDate(0, 0, days))
+}
+
+// CompleteExpired completes active enrollments whose plan validity has ended.
+func (s *EnrollmentService) CompleteExpired(now time.Time) (int64, error) {
+    return s.repo.CompleteExpired(dateOnly(now))
+}
+
+// CheckTrialSession reports whether a trial may record one more session on date.
+func (s *EnrollmentService) CheckTrialSession(e *models.Enrollment, date time.Time, attended int64) error {
+    if e.Status != models.EnrollmentActive || (e.TrialEndsOn != nil && dateOnly(date).After(*e.TrialEndsOn)) {
+        return ErrTrialClosed
+    }
+    if attended >= int64(e.TrialSessions) {
+        return ErrTrialExhausted
+    }
+    return nil
+}
+
+// CompleteTrialIfUsed closes a trial once it has used all of its sessions.
+func (s *EnrollmentService) CompleteTrialIfUsed(e *models.Enrollment, attended int64) error {
+    if attended < int64(e.TrialSessions) {
+        return nil
+    }
+    return s.transition(e, models.EnrollmentCompleted, "all trial sessions used", nil)
+}
+
+// ActivateOnPayment activates an enrollment that was waiting for payment.
+// Enrollments in any other status are left as they are.
+func (s *EnrollmentService) ActivateOnPayment(id uuid.UUID) error {
+    e, err := s.repo.FindByID(id)
+    if err != nil {
+        return err
+    }
+    if e.Status != models.EnrollmentPendingPayment {
+        return nil
+    }
+    return s.transition(e, models.EnrollmentActive, "payment received", nil)
+}
+
+// ExpireTrials completes trials whose window has passed.
+func (s *EnrollmentService) ExpireTrials(now time.Time) (int64, error) {
+    return s.repo.ExpireTrials(dateOnly(now))
+}
+
+// transition validates and applies a status change of e.
+func (s *EnrollmentService) transition(e *models.Enrollment, to, reason string, actor *uuid.UUID) error {
+    if !CanTransition(e.Status, to) {
+        return conflict(fmt.Sprintf("cannot move enrollment from %q to %q", e.Status, to))
+    }
+    ok, err := s.repo.Transition(&models.EnrollmentStatusHistory{
+        EnrollmentID: e.ID,
+        FromStatus:   e.Status,
+        ToStatus:     to,
+        Reason:       reason,
+        ChangedByID:  actor,
+        ChangedAt:    time.Now(),
+    })
+    if err != nil {
+        return err
+    }
+    if !ok {
+        return ErrStatusChanged
+    }
+    e.Status = to
+    return nil
+}
+
+// applyPlan validates the plan, offer and coupon of a regular enrollment and
+// derives its validity from the plan duration. The price, and the offers
+// that make it up, are the quoted ones if a quote was given, otherwise the
+// plan price less the chosen offer and coupon, which must both apply to the
+// student.
+func (s *EnrollmentService) applyPlan(e *models.Enrollment) error {
+    var quote *quoteClaims
+    if e.QuoteID != "" {
+        q, err := s.applyQuote(e)
+        if err != nil {
+            return err
+        }
+        quote = q
+    }
+    var coupon *models.Coupon
+    if quote == nil && e.CouponCode != "" {
+        c, err := s.pricing.resolveCoupon(e.CouponCode, e.StudentID, time.Now())
+        if err != nil {
+            return err
+        }
+        coupon = c
+        e.CouponID = &c.ID
+    } else if quote == nil && e.CouponID != nil {
+        c, err := s.pricing.coupons.FindByID(*e.CouponID)
+        if err != nil {
+            return err
+        }
+        coupon = c
+    }
+    if e.PlanID == nil {
+        if e.OfferID != nil || e.CouponID != nil {
+            return ErrOfferWithoutPlan
+        }
+        e.PriceCents = 0
+        e.SiblingDiscountCents = 0
+        e.TaxBreakdown = models.TaxBreakdown{}
+        e.AutoRenew = false
+        e.PlanVersionID = nil
+        e.AppliedOffers = []models.EnrollmentOffer{}
+        return nil
+    }
+    plan, err := s.plans.FindByID(*e.PlanID)
+    if err != nil {
+        return err
+    }
+    if e.PlanVersionID != nil {
+        v, err := s.plans.FindVersion(*e.PlanVersionID)
+        if err != nil {
+            return err
+        }
+        plan = pinnedPlan(plan, v)
+    } else {
+        e.PlanVersionID = plan.CurrentVersionID
+    }
+    if e.EnrolledOn.IsZero() {
+        e.EnrolledOn = dateOnly(time.Now())
+    }
+
+    var price PriceBreakdown
+    if quote != nil {
+        if _, err := s.pricing.quotedOffers(plan, quote); err != nil {
+            return err
+        }
+        price = quote.breakdown()
+    } else {
+        var offers []*models.Offer
+        if e.OfferID != nil {
+            offer := planOffer(plan, *e.OfferID)
+            if offer == nil {
+                return ErrOfferNotOnPlan
+            }
+            offers = append(offers, offer)
+        }
+        if coupon != nil && (e.OfferID == nil || *e.OfferID != coupon.OfferID) {
+            offers = append(offers, coupon.Offer)
+        }
+        if price, err = s.priceOffers(e, plan, offers); err != nil {
+            return err
+        }
+    }
+
+    e.ValidUntil = planValidUntil(e.EnrolledOn, plan)
+    if e.ValidUntil != nil {
+        t := e.ValidUntil.AddDate(0, 0, frozenDays(e.Freezes))
+        e.ValidUntil = &t
+    }
+    e.AutoRenew = plan.AutoRenew && plan.BillingInterval != ""
+    e.PriceCents = price.TotalCents
+    e.SiblingDiscountCents = price.SiblingDiscountCents
+    e.TaxBreakdown = price.taxBreakdown()
+    e.AppliedOffers = make([]models.EnrollmentOffer, len(price.Discounts))
+    for i, d := range price.Discounts {
+        e.AppliedOffers[i] = models.EnrollmentOffer{OfferID: d.OfferID, AmountCents: d.AmountCents}
+    }
+    return nil
+}
+
+// priceOffers prices plan for e with offers, which must all apply, and any
+// sibling discount.
+func (s *EnrollmentService) priceOffers(e *models.Enrollment, plan *models.Plan, offers []*models.Offer) (PriceBreakdown, error) {
+    batch, err := s.batches.FindByID(e.BatchID)
+    if err != nil {
+        return PriceBreakdown{}, err
+    }
+    ctx, err := s.pricing.offerContext(plan.DurationDays, offers, e.StudentID, batch.VenueID, batch, e.EnrolledOn, e.ID)
+    if err != nil {
+        return PriceBreakdown{}, err
+    }
+    ctx.Currency = plan.Currency
+    if len(offers) == 0 {
+        return s.pricing.priceFor(plan, offers, ctx)
+    }
+    tax, err := s.pricing.taxFor(plan)
+    if err != nil {
+        return PriceBreakdown{}, err
+    }
+    ev := EvaluateOffers(plan.PriceCents, offers, ctx, tax)
+    if len(ev.Rejected) > 0 {
+        return PriceBreakdown{}, invalid("offer does not apply: " + ev.Rejected[0].Reason)
+    }
+    return ev.Breakdown, nil
+}
+
+// applyQuote checks that a quote was issued for this student, plan, offers,
+// coupon and batch and fills in the plan, offer, coupon and start date it was
+// priced for.
+func (s *EnrollmentService) applyQuote(e *models.Enrollment) (*quoteClaims, error) {
+    q, err := s.pricing.verify(e.QuoteID, models.QuoteForEnrollment, time.Now())
+    if err != nil {
+        return nil, err
+    }
+    e.QuoteNonce = &q.Nonce
+    if e.PlanID == nil {
+        e.PlanID = &q.PlanID
+    }
+    if q.StudentID != e.StudentID || q.PlanID != *e.PlanID ||
+        (e.OfferID != nil && !containsID(q.OfferIDs, *e.OfferID)) ||
+        (q.BatchID != nil && *q.BatchID != e.BatchID) {
+        return nil, ErrQuoteMismatch
+    }
+    if e.CouponCode != "" {
+        c, err := s.pricing.coupons.FindByCode(normalizeCode(e.CouponCode))
+        if err != nil || q.CouponID == nil || c.ID != *q.CouponID {
+            return nil, ErrQuoteMismatch
+        }
+    }
+    e.OfferID = q.offerID()
+    e.CouponID = q.CouponID
+    batch, err := s.batches.FindByID(e.BatchID)
+    if err != nil {
+        return nil, err
+    }
+    if batch.VenueID != q.VenueID {
+        return nil, ErrQuoteMismatch
+    }
+    if e.EnrolledOn.IsZero() {
+        e.EnrolledOn = q.Date
+    }
+    return q, nil
+}
+
+// planOffer returns the offer with the given ID attached to plan, or nil.
+func planOffer(plan *models.Plan, id uuid.UUID) *models.Offer {
+    for _, o := range plan.Offers {
+        if o.ID == id {
+            return o
+        }
+    }
+    return nil
+}
+
+// lifecycleChanged reports whether e sets a status, type or trial field to
+// something other than current has. Fields left out do not count.
+func lifecycleChanged(e, current *models.Enrollment) bool {
+    switch {
+    case e.Status != "" && e.Status != current.Status,
+        e.Type != "" && e.Type != current.Type,
+        e.TrialSessions != 0 && e.TrialSessions != current.TrialSessions,
+        e.TrialEndsOn != nil && (current.TrialEndsOn == nil || !e.TrialEndsOn.Equal(*current.TrialEndsOn)),
+        e.ConvertedFromID != nil && !sameID(e.ConvertedFromID, current.ConvertedFromID):
+        return true
+    }
+    return false
+}
+
+// sameID reports whether two optional IDs are equal.
+func sameID(a, b *uuid.UUID) bool {
+    if a == nil || b == nil {
+        return a == b
+    }
+    return *a == *b
+}
+
+// planValidUntil is the last day an enrollment starting on start is covered
+// by plan, or nil for plans without a duration. Billed plans cover one cycle.
+func planValidUntil(start time.Time, plan *models.Plan) *time.Time {
+    if plan != nil && plan.BillingInterval != "" {
+        t := addInterval(dateOnly(start), plan.BillingInterval)
+        return &t
+    }
+    if plan == nil || plan.DurationDays <= 0 {
+        return nil
+    }
+    t := dateOnly(start).AddDate(0, 0, plan.DurationDays)
+    return &t
+}
+
+// frozenDays is the total length of fs.
+func frozenDays(fs []models.EnrollmentFreeze) int {
+    n := 0
+    for _, f := range fs {
+        n += f.Days
+    }
+    return n
+}
+
+// freezeDaysIn counts the days of f that fall in a calendar year.
+func freezeDaysIn(f models.EnrollmentFreeze, year int) int {
+    from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+    to := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC)
+    start, end := dateOnly(f.StartDate), dateOnly(f.EndDate)
+    if start.Before(from) {
+        start = from
+    }
+    if end.After(to) {
+        end = to
+    }
+    if end.Before(start) {
+        return 0
+    }
+    return int(end.Sub(start).Hours()/24) + 1
+}
+
+func (s *EnrollmentService) startTrial(e *models.Enrollment) {
+    if e.EnrolledOn.IsZero() {
+        e.EnrolledOn = time.Now()
+    }
+    if e.TrialSessions <= 0 || e.TrialSessions > s.trial.MaxSessions {
+        e.TrialSessions = s.trial.MaxSessions
+    }
+    ends := dateOnly(e.EnrolledOn).AddDate(0, 0, s.trial.ValidityDays)
+    e.TrialEndsOn = &ends
+    e.PlanID = nil
+    e.OfferID = nil
+    e.TaxBreakdown = models.TaxBreakdown{}
+    e.ValidUntil = nil
+    e.PriceCents = 0
+    e.SiblingDiscountCents = 0
+    e.AppliedOffers = nil
+    e.CouponID = nil
+    if e.Status == "" {
+        e.Status = models.EnrollmentActive
+    }
+}
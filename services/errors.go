@@ -0,0 +1,89 @@
+package services
+
+import (
+	"errors"
+
+	"spodemy-backend/repositories"
+)
+
+// Error kinds returned by services so that controllers can pick a status code
+// without knowing every individual error.
+var (
+    // ErrInvalidInput marks requests that can never succeed as sent.
+    ErrInvalidInput = errors.New("invalid input")
+    // ErrConflict marks requests that clash with the current state of a record.
+    ErrConflict = errors.New("conflict")
+)
+
+// serviceError is a business-rule error of a given kind.
+type serviceError struct {
+    kind error
+    msg  string
+}
+
+func (e *serviceError) Error() string { return e.msg }
+
+func (e *serviceError) Unwrap() error { return e.kind }
+
+func invalid(msg string) error { return &serviceError{kind: ErrInvalidInput, msg: msg} }
+
+func conflict(msg string) error { return &serviceError{kind: ErrConflict, msg: msg} }
+
+// Limit errors detected by repositories while rows are locked.
+var (
+    ErrOfferUsedUp         = conflict(repositories.ErrOfferUsedUp.Error())
+    ErrCouponUsedUp        = conflict(repositories.ErrCouponUsedUp.Error())
+    ErrCouponUserLimit     = conflict(repositories.ErrCouponUserLimit.Error())
+    ErrCouponAlreadyUsed   = conflict(repositories.ErrCouponAlreadyUsed.Error())
+    ErrChargeSettled       = conflict(repositories.ErrChargeSettled.Error())
+    ErrRefundExceeds       = conflict(repositories.ErrRefundExceeds.Error())
+    ErrPaymentRefunded     = conflict(repositories.ErrPaymentRefunded.Error())
+    ErrPaymentReversed     = conflict(repositories.ErrPaymentReversed.Error())
+    ErrReverseReversal     = conflict(repositories.ErrReverseReversal.Error())
+    ErrPaymentOnline       = conflict(repositories.ErrPaymentOnline.Error())
+    ErrTransactionReversed = conflict(repositories.ErrTransactionReversed.Error())
+    ErrOrderAmount         = conflict(repositories.ErrOrderAmount.Error())
+    ErrCurrencyMismatch    = invalid(repositories.ErrCurrencyMismatch.Error())
+    ErrRolloverAlreadyDone = conflict(repositories.ErrBatchRolledOver.Error())
+    ErrQuoteUsed           = conflict(repositories.ErrQuoteUsed.Error())
+    ErrMakeupCreditUsed    = conflict(repositories.ErrMakeupCreditUsed.Error())
+)
+
+// fromRepo gives repository limit errors their service error kind.
+func fromRepo(err error) error {
+    switch {
+    case errors.Is(err, repositories.ErrOfferUsedUp):
+        return ErrOfferUsedUp
+    case errors.Is(err, repositories.ErrCouponUsedUp):
+        return ErrCouponUsedUp
+    case errors.Is(err, repositories.ErrCouponUserLimit):
+        return ErrCouponUserLimit
+    case errors.Is(err, repositories.ErrCouponAlreadyUsed):
+        return ErrCouponAlreadyUsed
+    case errors.Is(err, repositories.ErrChargeSettled):
+        return ErrChargeSettled
+    case errors.Is(err, repositories.ErrRefundExceeds):
+        return ErrRefundExceeds
+    case errors.Is(err, repositories.ErrPaymentRefunded):
+        return ErrPaymentRefunded
+    case errors.Is(err, repositories.ErrPaymentReversed):
+        return ErrPaymentReversed
+    case errors.Is(err, repositories.ErrReverseReversal):
+        return ErrReverseReversal
+    case errors.Is(err, repositories.ErrPaymentOnline):
+        return ErrPaymentOnline
+    case errors.Is(err, repositories.ErrTransactionReversed):
+        return ErrTransactionReversed
+    case errors.Is(err, repositories.ErrOrderAmount):
+        return ErrOrderAmount
+    case errors.Is(err, repositories.ErrCurrencyMismatch):
+        return ErrCurrencyMismatch
+    case errors.Is(err, repositories.ErrBatchRolledOver):
+        return ErrRolloverAlreadyDone
+    case errors.Is(err, repositories.ErrQuoteUsed):
+        return ErrQuoteUsed
+    case errors.Is(err, repositories.ErrMakeupCreditUsed):
+        return ErrMakeupCreditUsed
+    }
+    return err
+}
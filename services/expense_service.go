@@ -1,6 +1,7 @@
 package services
 
 import (
+	"spodemy-backend/config"
 	"spodemy-backend/models"
 	"spodemy-backend/repositories"
 
@@ -10,11 +11,14 @@ import (
 // ExpenseService encapsulates business logic for expenses.
 type ExpenseService struct {
     repo *repositories.ExpenseRepository
+    base string
 }
 
-// NewExpenseService creates a new ExpenseService.
-func NewExpenseService(r *repositories.ExpenseRepository) *ExpenseService {
-    return &ExpenseService{repo: r}
+// NewExpenseService creates a new ExpenseService. Academy-wide expenses are
+// in the base currency of cfg unless they name another; expenses at a venue
+// are always in the venue's.
+func NewExpenseService(r *repositories.ExpenseRepository, cfg config.CurrencyConfig) *ExpenseService {
+    return &ExpenseService{repo: r, base: cfg.Base}
 }
 
 // List returns all expenses.
@@ -29,11 +33,19 @@ func (s *ExpenseService) Get(id uuid.UUID) (*models.Expense, error) {
 
 // Create adds a new expense.
 func (s *ExpenseService) Create(e *models.Expense) error {
+    var err error
+    if e.Currency, err = normalizeCurrency(e.Currency, s.base); err != nil {
+        return err
+    }
     return s.repo.Create(e)
 }
 
 // Update modifies an expense.
 func (s *ExpenseService) Update(e *models.Expense) error {
+    var err error
+    if e.Currency, err = normalizeCurrency(e.Currency, s.base); err != nil {
+        return err
+    }
     return s.repo.Update(e)
 }
 
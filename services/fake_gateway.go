@@ -0,0 +1,118 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// FakeGateway is an in-memory PaymentGateway for local development and
+// tests. Its webhooks are signed and shaped like Razorpay's; Pay and Decline
+// settle an order and return the webhook the gateway would send. Refunds are
+// processed at once.
+type FakeGateway struct {
+    secret  string
+    mu      sync.Mutex
+    seq     int
+    orders  map[string]*GatewayPayment
+    refunds map[string]*GatewayRefund
+}
+
+// NewFakeGateway returns a FakeGateway signing webhooks with secret.
+func NewFakeGateway(secret string) *FakeGateway {
+    return &FakeGateway{secret: secret, orders: map[string]*GatewayPayment{}, refunds: map[string]*GatewayRefund{}}
+}
+
+func (g *FakeGateway) Name() string { return "fake" }
+
+func (g *FakeGateway) KeyID() string { return "" }
+
+func (g *FakeGateway) CreateOrder(amountCents int, currency, receipt string) (*GatewayOrder, error) {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    g.seq++
+    ref := fmt.Sprintf("order_fake%06d", g.seq)
+    g.orders[ref] = &GatewayPayment{OrderRef: ref, Status: GatewayPending, AmountCents: amountCents}
+    return &GatewayOrder{Ref: ref, AmountCents: amountCents, Currency: currency}, nil
+}
+
+func (g *FakeGateway) VerifyWebhook(body []byte, signature string) (*GatewayEvent, error) {
+    if !validHMAC(g.secret, body, signature) {
+        return nil, ErrWebhookSignature
+    }
+    return parseRazorpayWebhook(body)
+}
+
+func (g *FakeGateway) FetchStatus(orderRef string) (*GatewayPayment, error) {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    o, ok := g.orders[orderRef]
+    if !ok {
+        return nil, fmt.Errorf("fake gateway: no order %s", orderRef)
+    }
+    p := *o
+    return &p, nil
+}
+
+func (g *FakeGateway) Refund(paymentRef string, amountCents int) (*GatewayRefund, error) {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    for _, o := range g.orders {
+        if o.PaymentRef == paymentRef && o.Status == GatewayCaptured {
+            g.seq++
+            rf := &GatewayRefund{Ref: fmt.Sprintf("rfnd_fake%06d", g.seq), AmountCents: amountCents, Status: "processed"}
+            g.refunds[rf.Ref] = rf
+            r := *rf
+            return &r, nil
+        }
+    }
+    return nil, fmt.Errorf("fake gateway: no captured payment %s", paymentRef)
+}
+
+func (g *FakeGateway) FetchRefund(ref string) (*GatewayRefund, error) {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    rf, ok := g.refunds[ref]
+    if !ok {
+        return nil, fmt.Errorf("fake gateway: no refund %s", ref)
+    }
+    r := *rf
+    return &r, nil
+}
+
+// Pay captures an order's full amount and returns the signed webhook.
+func (g *FakeGateway) Pay(orderRef string) (body []byte, signature string, err error) {
+    return g.settle(orderRef, GatewayCaptured, "")
+}
+
+// Decline fails an order's payment and returns the signed webhook.
+func (g *FakeGateway) Decline(orderRef, reason string) (body []byte, signature string, err error) {
+    return g.settle(orderRef, GatewayFailed, reason)
+}
+
+func (g *FakeGateway) settle(orderRef, status, reason string) ([]byte, string, error) {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    o, ok := g.orders[orderRef]
+    if !ok {
+        return nil, "", fmt.Errorf("fake gateway: no order %s", orderRef)
+    }
+    g.seq++
+    o.PaymentRef, o.Status, o.Reason = fmt.Sprintf("pay_fake%06d", g.seq), status, reason
+
+    var w razorpayWebhook
+    w.Event = "payment." + status
+    w.Payload.Payment.Entity = razorpayPayment{
+        ID: o.PaymentRef, OrderID: o.OrderRef, Amount: o.AmountCents, Status: status, ErrorDescription: reason,
+    }
+    body, err := json.Marshal(w)
+    if err != nil {
+        return nil, "", err
+    }
+    mac := hmac.New(sha256.New, []byte(g.secret))
+    mac.Write(body)
+    return body, hex.EncodeToString(mac.Sum(nil)), nil
+}
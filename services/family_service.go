@@ -0,0 +1,204 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Family errors.
+var (
+    ErrFamilyRole      = invalid("role must be \"student\" or \"guardian\"")
+    ErrAlreadyInFamily = conflict("user already belongs to a family")
+    ErrNotFamilyMember = invalid("user is not a member of this family")
+)
+
+// ChildStatement is one child's share of a family statement. Its totals are
+// in the statement's currency.
+type ChildStatement struct {
+    StudentID    uuid.UUID           `json:"student_id"`
+    Name         string              `json:"name"`
+    Enrollments  []models.Enrollment `json:"enrollments"`
+    Payments     []models.FeePayment `json:"payments"`
+    ChargedCents int                 `json:"charged_cents"`
+    PaidCents    int                 `json:"paid_cents"`
+}
+
+// FamilyStatement consolidates a month of enrollments and payments across all
+// children of a family. Enrollments and payments keep their own currency;
+// the totals are converted to Currency at the rates of the month's last day.
+type FamilyStatement struct {
+    FamilyID     uuid.UUID        `json:"family_id"`
+    FamilyName   string           `json:"family_name"`
+    Month        string           `json:"month"` // YYYY-MM
+    Currency     string           `json:"currency"`
+    RateDate     time.Time        `json:"rate_date"`
+    Guardians    []models.User    `json:"guardians"`
+    Children     []ChildStatement `json:"children"`
+    ChargedCents int              `json:"charged_cents"`
+    PaidCents    int              `json:"paid_cents"`
+    BalanceCents int              `json:"balance_cents"`
+}
+
+// FamilyService manages families and their statements.
+type FamilyService struct {
+    repo       *repositories.FamilyRepository
+    users      *repositories.UserRepository
+    currencies *CurrencyService
+}
+
+// NewFamilyService creates a new FamilyService.
+func NewFamilyService(r *repositories.FamilyRepository, users *repositories.UserRepository, currencies *CurrencyService) *FamilyService {
+    return &FamilyService{repo: r, users: users, currencies: currencies}
+}
+
+// List returns all families.
+func (s *FamilyService) List() ([]models.Family, error) {
+    return s.repo.FindAll()
+}
+
+// Get retrieves a family with its members.
+func (s *FamilyService) Get(id uuid.UUID) (*models.Family, error) {
+    return s.repo.FindByID(id)
+}
+
+// Create adds a new family.
+func (s *FamilyService) Create(f *models.Family) error {
+    return s.repo.Create(f)
+}
+
+// Update renames a family.
+func (s *FamilyService) Update(f *models.Family) error {
+    if _, err := s.repo.FindByID(f.ID); err != nil {
+        return err
+    }
+    return s.repo.Update(f)
+}
+
+// Delete removes a family; its members become individual customers again.
+func (s *FamilyService) Delete(id uuid.UUID) error {
+    return s.repo.Delete(id)
+}
+
+// AddMember puts a user into a family as a student or guardian.
+func (s *FamilyService) AddMember(familyID uuid.UUID, m *models.FamilyMember) (*models.Family, error) {
+    if m.Role == "" {
+        m.Role = models.FamilyStudent
+    }
+    if m.Role != models.FamilyStudent && m.Role != models.FamilyGuardian {
+        return nil, ErrFamilyRole
+    }
+    if _, err := s.repo.FindByID(familyID); err != nil {
+        return nil, err
+    }
+    if _, err := s.users.FindByID(m.UserID); err != nil {
+        return nil, err
+    }
+    if _, err := s.repo.FindMembership(m.UserID); err == nil {
+        return nil, ErrAlreadyInFamily
+    } else if !errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, err
+    }
+    m.FamilyID = familyID
+    if err := s.repo.AddMember(m); err != nil {
+        return nil, err
+    }
+    return s.repo.FindByID(familyID)
+}
+
+// RemoveMember takes a user out of a family.
+func (s *FamilyService) RemoveMember(familyID, userID uuid.UUID) error {
+    ok, err := s.repo.RemoveMember(familyID, userID)
+    if err != nil {
+        return err
+    }
+    if !ok {
+        return ErrNotFamilyMember
+    }
+    return nil
+}
+
+// Statement lists what each child of the family was charged, by enrollments
+// starting in the month, and paid in the month, totalled in currency, or the
+// base currency if it is empty.
+func (s *FamilyService) Statement(id uuid.UUID, month time.Time, currency string) (*FamilyStatement, error) {
+    currency, err := s.currencies.Reporting(currency)
+    if err != nil {
+        return nil, err
+    }
+    f, err := s.repo.FindByID(id)
+    if err != nil {
+        return nil, err
+    }
+    from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+    to := from.AddDate(0, 1, 0)
+    st := &FamilyStatement{
+        FamilyID:   f.ID,
+        FamilyName: f.Name,
+        Month:      from.Format("2006-01"),
+        Currency:   currency,
+        RateDate:   to.AddDate(0, 0, -1),
+        Guardians:  []models.User{},
+        Children:   []ChildStatement{},
+    }
+
+    var studentIDs []uuid.UUID
+    children := make(map[uuid.UUID]*ChildStatement)
+    for _, m := range f.Members {
+        if m.Role == models.FamilyGuardian {
+            if m.User != nil {
+                st.Guardians = append(st.Guardians, *m.User)
+            }
+            continue
+        }
+        child := ChildStatement{StudentID: m.UserID, Enrollments: []models.Enrollment{}, Payments: []models.FeePayment{}}
+        if m.User != nil {
+            child.Name = strings.TrimSpace(m.User.FirstName + " " + m.User.LastName)
+        }
+        st.Children = append(st.Children, child)
+        studentIDs = append(studentIDs, m.UserID)
+    }
+    for i := range st.Children {
+        children[st.Children[i].StudentID] = &st.Children[i]
+    }
+    if len(studentIDs) == 0 {
+        return st, nil
+    }
+
+    ens, err := s.repo.FindEnrollmentsStarting(studentIDs, from, to)
+    if err != nil {
+        return nil, err
+    }
+    for _, e := range ens {
+        cents, err := s.currencies.Convert(int64(e.PriceCents), e.Currency, currency, st.RateDate)
+        if err != nil {
+            return nil, err
+        }
+        child := children[e.StudentID]
+        child.Enrollments = append(child.Enrollments, e)
+        child.ChargedCents += int(cents)
+        st.ChargedCents += int(cents)
+    }
+    pays, err := s.repo.FindPaymentsBetween(studentIDs, from, to)
+    if err != nil {
+        return nil, err
+    }
+    for _, p := range pays {
+        cents, err := s.currencies.Convert(int64(p.AmountCents), p.Currency, currency, st.RateDate)
+        if err != nil {
+            return nil, err
+        }
+        child := children[p.Enrollment.StudentID]
+        child.Payments = append(child.Payments, p)
+        child.PaidCents += int(cents)
+        st.PaidCents += int(cents)
+    }
+    st.BalanceCents = st.ChargedCents - st.PaidCents
+    return st, nil
+}
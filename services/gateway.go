@@ -0,0 +1,264 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"spodemy-backend/config"
+)
+
+// ErrWebhookSignature is returned for a webhook whose signature does not
+// match its body.
+var ErrWebhookSignature = invalid("invalid webhook signature")
+
+// Gateway payment statuses.
+const (
+    GatewayPending  = "pending"
+    GatewayCaptured = "captured"
+    GatewayFailed   = "failed"
+)
+
+// GatewayOrder is an order created with a payment gateway, which the payer
+// completes in the gateway's checkout.
+type GatewayOrder struct {
+    Ref         string
+    AmountCents int
+    Currency    string
+}
+
+// GatewayPayment is the state of a payment made against an order.
+type GatewayPayment struct {
+    OrderRef    string
+    PaymentRef  string // empty while no payment has been attempted
+    Status      string // GatewayPending, GatewayCaptured or GatewayFailed
+    AmountCents int
+    Reason      string // why the payment failed
+}
+
+// GatewayRefund is a refund issued through a payment gateway.
+type GatewayRefund struct {
+    Ref         string
+    AmountCents int
+    Status      string // models.RefundPending, RefundProcessed or RefundFailed
+}
+
+// GatewayEvent is what a webhook reports: a payment, a refund, or neither
+// for events not handled here.
+type GatewayEvent struct {
+    Payment *GatewayPayment
+    Refund  *GatewayRefund
+}
+
+// PaymentGateway takes online payments.
+type PaymentGateway interface {
+    // Name is recorded as the method of the payments it takes.
+    Name() string
+    // KeyID is the public key the payer's checkout needs, if any.
+    KeyID() string
+    // CreateOrder opens an order for amountCents; receipt is our reference.
+    CreateOrder(amountCents int, currency, receipt string) (*GatewayOrder, error)
+    // VerifyWebhook checks a webhook's signature and returns the event it
+    // reports.
+    VerifyWebhook(body []byte, signature string) (*GatewayEvent, error)
+    // FetchStatus asks the gateway for the state of an order's payment.
+    FetchStatus(orderRef string) (*GatewayPayment, error)
+    // Refund returns amountCents of a captured payment.
+    Refund(paymentRef string, amountCents int) (*GatewayRefund, error)
+    // FetchRefund asks the gateway for the state of a refund.
+    FetchRefund(ref string) (*GatewayRefund, error)
+}
+
+// NewPaymentGateway returns the gateway configured in cfg.
+func NewPaymentGateway(cfg config.GatewayConfig) (PaymentGateway, error) {
+    switch cfg.Provider {
+    case "razorpay":
+        return &razorpayGateway{cfg: cfg, client: &http.Client{Timeout: 15 * time.Second}}, nil
+    case "fake":
+        return NewFakeGateway(cfg.WebhookSecret), nil
+    case "":
+        return nil, errors.New(`gateway.provider must be set to "razorpay" or "fake"`)
+    }
+    return nil, fmt.Errorf("unknown payment gateway %q", cfg.Provider)
+}
+
+// razorpayGateway talks to the Razorpay API. Amounts are in the currency's
+// smallest unit, as they are here.
+type razorpayGateway struct {
+    cfg    config.GatewayConfig
+    client *http.Client
+}
+
+func (g *razorpayGateway) Name() string { return "razorpay" }
+
+func (g *razorpayGateway) KeyID() string { return g.cfg.KeyID }
+
+func (g *razorpayGateway) CreateOrder(amountCents int, currency, receipt string) (*GatewayOrder, error) {
+    var res struct {
+        ID       string `json:"id"`
+        Amount   int    `json:"amount"`
+        Currency string `json:"currency"`
+    }
+    body := map[string]interface{}{"amount": amountCents, "currency": currency, "receipt": receipt}
+    if err := g.call(http.MethodPost, "/orders", body, &res); err != nil {
+        return nil, err
+    }
+    return &GatewayOrder{Ref: res.ID, AmountCents: res.Amount, Currency: res.Currency}, nil
+}
+
+func (g *razorpayGateway) VerifyWebhook(body []byte, signature string) (*GatewayEvent, error) {
+    if !validHMAC(g.cfg.WebhookSecret, body, signature) {
+        return nil, ErrWebhookSignature
+    }
+    return parseRazorpayWebhook(body)
+}
+
+func (g *razorpayGateway) FetchStatus(orderRef string) (*GatewayPayment, error) {
+    var res struct {
+        Items []razorpayPayment `json:"items"`
+    }
+    if err := g.call(http.MethodGet, "/orders/"+orderRef+"/payments", nil, &res); err != nil {
+        return nil, err
+    }
+    // A captured payment wins over failed attempts; otherwise the latest
+    // attempt, which Razorpay lists first, tells the state.
+    out := &GatewayPayment{OrderRef: orderRef, Status: GatewayPending}
+    for i := len(res.Items) - 1; i >= 0; i-- {
+        p := res.Items[i].payment()
+        if p.Status == GatewayCaptured {
+            return p, nil
+        }
+        if i == 0 {
+            out = p
+        }
+    }
+    return out, nil
+}
+
+func (g *razorpayGateway) Refund(paymentRef string, amountCents int) (*GatewayRefund, error) {
+    var res razorpayRefund
+    body := map[string]interface{}{"amount": amountCents}
+    if err := g.call(http.MethodPost, "/payments/"+paymentRef+"/refund", body, &res); err != nil {
+        return nil, err
+    }
+    return res.refund(), nil
+}
+
+func (g *razorpayGateway) FetchRefund(ref string) (*GatewayRefund, error) {
+    var res razorpayRefund
+    if err := g.call(http.MethodGet, "/refunds/"+ref, nil, &res); err != nil {
+        return nil, err
+    }
+    return res.refund(), nil
+}
+
+// call sends an authenticated API request and decodes the JSON response
+// into out.
+func (g *razorpayGateway) call(method, path string, body, out interface{}) error {
+    var buf bytes.Buffer
+    if body != nil {
+        if err := json.NewEncoder(&buf).Encode(body); err != nil {
+            return err
+        }
+    }
+    req, err := http.NewRequest(method, strings.TrimSuffix(g.cfg.BaseURL, "/")+path, &buf)
+    if err != nil {
+        return err
+    }
+    req.SetBasicAuth(g.cfg.KeyID, g.cfg.KeySecret)
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := g.client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode >= 300 {
+        var e struct {
+            Error struct {
+                Description string `json:"description"`
+            } `json:"error"`
+        }
+        json.NewDecoder(resp.Body).Decode(&e)
+        return fmt.Errorf("razorpay %s %s: %s %s", method, path, resp.Status, e.Error.Description)
+    }
+    return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// razorpayPayment is a Razorpay payment entity.
+type razorpayPayment struct {
+    ID               string `json:"id"`
+    OrderID          string `json:"order_id"`
+    Amount           int    `json:"amount"`
+    Status           string `json:"status"` // "created","authorized","captured","refunded","failed"
+    ErrorDescription string `json:"error_description"`
+}
+
+func (p razorpayPayment) payment() *GatewayPayment {
+    out := &GatewayPayment{OrderRef: p.OrderID, PaymentRef: p.ID, AmountCents: p.Amount, Status: GatewayPending}
+    switch p.Status {
+    case "captured", "refunded":
+        out.Status = GatewayCaptured
+    case "failed":
+        out.Status, out.Reason = GatewayFailed, p.ErrorDescription
+    }
+    return out
+}
+
+// razorpayRefund is a Razorpay refund entity.
+type razorpayRefund struct {
+    ID     string `json:"id"`
+    Amount int    `json:"amount"`
+    Status string `json:"status"` // "pending","processed","failed"
+}
+
+func (r razorpayRefund) refund() *GatewayRefund {
+    return &GatewayRefund{Ref: r.ID, AmountCents: r.Amount, Status: r.Status}
+}
+
+// razorpayWebhook is the body of a Razorpay webhook.
+type razorpayWebhook struct {
+    Event   string `json:"event"`
+    Payload struct {
+        Payment struct {
+            Entity razorpayPayment `json:"entity"`
+        } `json:"payment"`
+        Refund struct {
+            Entity razorpayRefund `json:"entity"`
+        } `json:"refund"`
+    } `json:"payload"`
+}
+
+// parseRazorpayWebhook returns the payment in a payment.captured,
+// payment.failed or order.paid event, or the refund in a refund.processed
+// or refund.failed event.
+func parseRazorpayWebhook(body []byte) (*GatewayEvent, error) {
+    var w razorpayWebhook
+    if err := json.Unmarshal(body, &w); err != nil {
+        return nil, invalid("malformed webhook: " + err.Error())
+    }
+    switch w.Event {
+    case "payment.captured", "payment.failed", "order.paid":
+        return &GatewayEvent{Payment: w.Payload.Payment.Entity.payment()}, nil
+    case "refund.processed", "refund.failed":
+        return &GatewayEvent{Refund: w.Payload.Refund.Entity.refund()}, nil
+    }
+    return &GatewayEvent{}, nil
+}
+
+// validHMAC reports whether signature is the hex HMAC-SHA256 of body.
+func validHMAC(secret string, body []byte, signature string) bool {
+    got, err := hex.DecodeString(signature)
+    if err != nil || secret == "" {
+        return false
+    }
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write(body)
+    return hmac.Equal(got, mac.Sum(nil))
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"spodemy-backend/models"
+)
+
+// sign returns the hex HMAC-SHA256 of body under secret.
+func sign(secret string, body []byte) string {
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write(body)
+    return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestValidHMAC(t *testing.T) {
+    body := []byte(`{"event":"payment.captured"}`)
+    tests := []struct {
+        name      string
+        secret    string
+        signature string
+        want      bool
+    }{
+        {name: "good signature", secret: "s3cret", signature: sign("s3cret", body), want: true},
+        {name: "other secret", secret: "s3cret", signature: sign("other", body)},
+        {name: "other body", secret: "s3cret", signature: sign("s3cret", []byte(`{}`))},
+        {name: "not hex", secret: "s3cret", signature: "zz"},
+        {name: "empty signature", secret: "s3cret"},
+        {name: "empty secret", signature: sign("", body)},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := validHMAC(tt.secret, body, tt.signature); got != tt.want {
+                t.Errorf("validHMAC = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestFakeGatewayWebhook(t *testing.T) {
+    g := NewFakeGateway("s3cret")
+    o, err := g.CreateOrder(5000, "INR", "receipt")
+    if err != nil {
+        t.Fatal(err)
+    }
+    body, sig, err := g.Pay(o.Ref)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    ev, err := g.VerifyWebhook(body, sig)
+    if err != nil {
+        t.Fatal(err)
+    }
+    p := ev.Payment
+    if p == nil || p.Status != GatewayCaptured || p.OrderRef != o.Ref || p.AmountCents != 5000 || p.PaymentRef == "" {
+        t.Fatalf("payment = %+v, want order %s captured for 5000", p, o.Ref)
+    }
+
+    // a replayed delivery reports the same payment, which Record keys on
+    again, err := g.VerifyWebhook(body, sig)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if *again.Payment != *p {
+        t.Errorf("replayed payment = %+v, want %+v", again.Payment, p)
+    }
+
+    tampered := append([]byte{}, body...)
+    tampered[len(tampered)-2] ^= 1
+    if _, err := g.VerifyWebhook(tampered, sig); !errors.Is(err, ErrWebhookSignature) {
+        t.Errorf("tampered body: err = %v, want %v", err, ErrWebhookSignature)
+    }
+    if _, err := NewFakeGateway("other").VerifyWebhook(body, sig); !errors.Is(err, ErrWebhookSignature) {
+        t.Errorf("other secret: err = %v, want %v", err, ErrWebhookSignature)
+    }
+    if _, err := NewFakeGateway("").VerifyWebhook(body, sign("", body)); !errors.Is(err, ErrWebhookSignature) {
+        t.Errorf("empty secret: err = %v, want %v", err, ErrWebhookSignature)
+    }
+}
+
+func TestFakeGatewayDecline(t *testing.T) {
+    g := NewFakeGateway("s3cret")
+    o, err := g.CreateOrder(5000, "INR", "receipt")
+    if err != nil {
+        t.Fatal(err)
+    }
+    body, sig, err := g.Decline(o.Ref, "card declined")
+    if err != nil {
+        t.Fatal(err)
+    }
+    ev, err := g.VerifyWebhook(body, sig)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if p := ev.Payment; p == nil || p.Status != GatewayFailed || p.Reason != "card declined" {
+        t.Fatalf("payment = %+v, want failed with the reason", p)
+    }
+    st, err := g.FetchStatus(o.Ref)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if st.Status != GatewayFailed {
+        t.Errorf("FetchStatus = %s, want %s", st.Status, GatewayFailed)
+    }
+}
+
+func TestFakeGatewayRefund(t *testing.T) {
+    g := NewFakeGateway("s3cret")
+    o, err := g.CreateOrder(5000, "INR", "receipt")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := g.Refund("pay_unknown", 1000); err == nil {
+        t.Error("refund of an unknown payment succeeded")
+    }
+    if _, _, err := g.Pay(o.Ref); err != nil {
+        t.Fatal(err)
+    }
+    st, err := g.FetchStatus(o.Ref)
+    if err != nil {
+        t.Fatal(err)
+    }
+    rf, err := g.Refund(st.PaymentRef, 1000)
+    if err != nil {
+        t.Fatal(err)
+    }
+    got, err := g.FetchRefund(rf.Ref)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if *got != *rf || got.Status != models.RefundProcessed || got.AmountCents != 1000 {
+        t.Errorf("FetchRefund = %+v, want %+v processed", got, rf)
+    }
+}
+
+func TestParseRazorpayWebhook(t *testing.T) {
+    tests := []struct {
+        name    string
+        body    string
+        payment *GatewayPayment
+        refund  *GatewayRefund
+    }{
+        {
+            name:    "payment captured",
+            body:    `{"event":"payment.captured","payload":{"payment":{"entity":{"id":"pay_1","order_id":"order_1","amount":5000,"status":"captured"}}}}`,
+            payment: &GatewayPayment{OrderRef: "order_1", PaymentRef: "pay_1", AmountCents: 5000, Status: GatewayCaptured},
+        },
+        {
+            name:    "order paid",
+            body:    `{"event":"order.paid","payload":{"payment":{"entity":{"id":"pay_1","order_id":"order_1","amount":5000,"status":"captured"}}}}`,
+            payment: &GatewayPayment{OrderRef: "order_1", PaymentRef: "pay_1", AmountCents: 5000, Status: GatewayCaptured},
+        },
+        {
+            name:    "payment failed",
+            body:    `{"event":"payment.failed","payload":{"payment":{"entity":{"id":"pay_1","order_id":"order_1","amount":5000,"status":"failed","error_description":"declined"}}}}`,
+            payment: &GatewayPayment{OrderRef: "order_1", PaymentRef: "pay_1", AmountCents: 5000, Status: GatewayFailed, Reason: "declined"},
+        },
+        {
+            name:    "payment authorized only",
+            body:    `{"event":"payment.captured","payload":{"payment":{"entity":{"id":"pay_1","order_id":"order_1","amount":5000,"status":"authorized"}}}}`,
+            payment: &GatewayPayment{OrderRef: "order_1", PaymentRef: "pay_1", AmountCents: 5000, Status: GatewayPending},
+        },
+        {
+            name:   "refund processed",
+            body:   `{"event":"refund.processed","payload":{"refund":{"entity":{"id":"rfnd_1","amount":1000,"status":"processed"}}}}`,
+            refund: &GatewayRefund{Ref: "rfnd_1", AmountCents: 1000, Status: models.RefundProcessed},
+        },
+        {
+            name:   "refund failed",
+            body:   `{"event":"refund.failed","payload":{"refund":{"entity":{"id":"rfnd_1","amount":1000,"status":"failed"}}}}`,
+            refund: &GatewayRefund{Ref: "rfnd_1", AmountCents: 1000, Status: models.RefundFailed},
+        },
+        {name: "other event", body: `{"event":"refund.created","payload":{}}`},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            ev, err := parseRazorpayWebhook([]byte(tt.body))
+            if err != nil {
+                t.Fatal(err)
+            }
+            if (ev.Payment == nil) != (tt.payment == nil) || ev.Payment != nil && *ev.Payment != *tt.payment {
+                t.Errorf("payment = %+v, want %+v", ev.Payment, tt.payment)
+            }
+            if (ev.Refund == nil) != (tt.refund == nil) || ev.Refund != nil && *ev.Refund != *tt.refund {
+                t.Errorf("refund = %+v, want %+v", ev.Refund, tt.refund)
+            }
+        })
+    }
+
+    if _, err := parseRazorpayWebhook([]byte(`{`)); err == nil {
+        t.Error("malformed webhook parsed")
+    }
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"time"
+
+	"spodemy-backend/config"
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+)
+
+// Idempotency errors.
+var (
+    ErrIdempotencyMismatch   = invalid("Idempotency-Key was already used with a different request")
+    ErrIdempotencyInProgress = conflict("a request with this Idempotency-Key is still in progress")
+)
+
+// IdempotencyService lets clients retry requests safely: the first request
+// with a key claims it and stores its response, and retries are answered
+// with that response.
+type IdempotencyService struct {
+    repo *repositories.IdempotencyRepository
+    ttl  time.Duration
+}
+
+// NewIdempotencyService creates a new IdempotencyService.
+func NewIdempotencyService(r *repositories.IdempotencyRepository, cfg config.IdempotencyConfig) *IdempotencyService {
+    return &IdempotencyService{repo: r, ttl: time.Duration(cfg.TTLHours) * time.Hour}
+}
+
+// Begin claims key for a request whose method, path and body hash to hash.
+// It returns nil if the request should go ahead, or the stored response
+// if the same request was already answered.
+func (s *IdempotencyService) Begin(scope, key, hash string) (*models.IdempotencyKey, error) {
+    now := time.Now()
+    stored, err := s.repo.Reserve(&models.IdempotencyKey{
+        Scope:       scope,
+        Key:         key,
+        RequestHash: hash,
+        CreatedAt:   now,
+        ExpiresAt:   now.Add(s.ttl),
+    })
+    if err != nil {
+        return nil, err
+    }
+    return replay(stored, hash)
+}
+
+// replay decides what a request hashing to hash gets when its key is
+// already held by stored: the stored response if it is the same request and
+// was answered, an error otherwise. A nil stored key was just claimed.
+func replay(stored *models.IdempotencyKey, hash string) (*models.IdempotencyKey, error) {
+    switch {
+    case stored == nil:
+        return nil, nil
+    case stored.RequestHash != hash:
+        return nil, ErrIdempotencyMismatch
+    case stored.Status == 0:
+        return nil, ErrIdempotencyInProgress
+    }
+    return stored, nil
+}
+
+// Finish stores the response to a claimed request. Server errors are not
+// stored, so that the request can be retried with the same key.
+func (s *IdempotencyService) Finish(scope, key string, status int, contentType string, body []byte) error {
+    if status >= 500 {
+        return s.repo.Release(scope, key)
+    }
+    return s.repo.Complete(scope, key, status, contentType, body)
+}
+
+// Abandon releases a claimed key whose request never produced a response.
+func (s *IdempotencyService) Abandon(scope, key string) error {
+    return s.repo.Release(scope, key)
+}
+
+// ExpireDue removes keys past the retention window.
+func (s *IdempotencyService) ExpireDue(now time.Time) (int64, error) {
+    return s.repo.DeleteExpired(now)
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"spodemy-backend/models"
+)
+
+func TestIdempotencyReplay(t *testing.T) {
+    answered := &models.IdempotencyKey{Key: "k", RequestHash: "abc", Status: 201, Body: []byte(`{"id":1}`)}
+    tests := []struct {
+        name   string
+        stored *models.IdempotencyKey
+        hash   string
+        want   *models.IdempotencyKey
+        err    error
+    }{
+        {name: "key just claimed", hash: "abc"},
+        {name: "same request answered", stored: answered, hash: "abc", want: answered},
+        {name: "client error answered", stored: &models.IdempotencyKey{RequestHash: "abc", Status: 400}, hash: "abc", want: &models.IdempotencyKey{RequestHash: "abc", Status: 400}},
+        {name: "different body", stored: answered, hash: "abd", err: ErrIdempotencyMismatch},
+        {name: "still in progress", stored: &models.IdempotencyKey{RequestHash: "abc"}, hash: "abc", err: ErrIdempotencyInProgress},
+        {name: "different body in progress", stored: &models.IdempotencyKey{RequestHash: "abc"}, hash: "abd", err: ErrIdempotencyMismatch},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := replay(tt.stored, tt.hash)
+            if !errors.Is(err, tt.err) {
+                t.Fatalf("err = %v, want %v", err, tt.err)
+            }
+            if (got == nil) != (tt.want == nil) || got != nil && (got.Status != tt.want.Status || string(got.Body) != string(tt.want.Body)) {
+                t.Errorf("replay = %+v, want %+v", got, tt.want)
+            }
+        })
+    }
+}
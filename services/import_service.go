@@ -0,0 +1,420 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"spodemy-backend/config"
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+
+	"github.com/google/uuid"
+)
+
+// Import errors about the file as a whole.
+var (
+    ErrImportEmpty = invalid("the CSV has no data rows")
+)
+
+// importColumns are the columns of an enrollment import. Only name, email
+// and batch are required; batch and plan take an ID or a name.
+var importColumns = []string{"name", "email", "phone", "dob", "batch", "plan", "start_date", "amount_paid"}
+
+// importColumnAliases maps other accepted header names to importColumns.
+var importColumnAliases = map[string]string{
+    "date_of_birth": "dob",
+    "start":         "start_date",
+    "paid":          "amount_paid",
+}
+
+// importProgressEvery is how many imported rows pass between progress saves.
+const importProgressEvery = 50
+
+// ImportOptions control an enrollment import.
+type ImportOptions struct {
+    DryRun  bool       // validate and preview without saving
+    VenueID *uuid.UUID // resolve batch names within this venue only
+}
+
+// ImportService imports students and enrollments from CSV files.
+type ImportService struct {
+    repo    *repositories.ImportRepository
+    plans   *repositories.PlanRepository
+    pricing *PricingService
+    cfg     config.ImportConfig
+}
+
+// NewImportService creates a new ImportService.
+func NewImportService(r *repositories.ImportRepository, plans *repositories.PlanRepository, pricing *PricingService, cfg config.ImportConfig) *ImportService {
+    return &ImportService{repo: r, plans: plans, pricing: pricing, cfg: cfg}
+}
+
+// List returns all import jobs, newest first.
+func (s *ImportService) List() ([]models.ImportJob, error) {
+    return s.repo.FindJobs()
+}
+
+// Get returns an import job with its per-row results.
+func (s *ImportService) Get(id uuid.UUID) (*models.ImportJob, error) {
+    return s.repo.FindJob(id)
+}
+
+// ImportEnrollments validates every row of a CSV and, unless it is a dry
+// run, creates the students, enrollments and opening payments in a single
+// transaction. Any row error fails the whole import. Files of up to
+// SyncMaxRows rows are imported before returning; larger ones return a
+// queued job that runs in the background.
+func (s *ImportService) ImportEnrollments(r io.Reader, opts ImportOptions, actor *uuid.UUID) (*models.ImportJob, error) {
+    cols, rows, err := readImportCSV(r)
+    if err != nil {
+        return nil, err
+    }
+    job := &models.ImportJob{
+        Kind:        models.ImportEnrollments,
+        Status:      models.ImportQueued,
+        DryRun:      opts.DryRun,
+        TotalRows:   len(rows),
+        RowErrors:   []models.ImportRowError{},
+        CreatedByID: actor,
+    }
+    if err := s.repo.CreateJob(job); err != nil {
+        return nil, err
+    }
+    if len(rows) > s.cfg.SyncMaxRows {
+        bg := *job
+        go func() {
+            if err := s.run(&bg, cols, rows, opts); err != nil {
+                log.Printf("import %s: %v", bg.ID, err)
+            }
+        }()
+        return job, nil
+    }
+    if err := s.run(job, cols, rows, opts); err != nil {
+        return nil, err
+    }
+    return job, nil
+}
+
+// run validates and imports rows, recording the outcome on job.
+func (s *ImportService) run(job *models.ImportJob, cols map[string]int, rows [][]string, opts ImportOptions) error {
+    job.Status = models.ImportRunning
+    if err := s.repo.SaveJob(job); err != nil {
+        return err
+    }
+    entries, results, rowErrs, err := s.prepare(job.ID, cols, rows, opts)
+    if err != nil {
+        return s.finish(job, err)
+    }
+    job.Rows = results
+    job.RowErrors = rowErrs
+    if len(rowErrs) > 0 {
+        return s.finish(job, fmt.Errorf("%d rows have errors; nothing was imported", countRows(rowErrs)))
+    }
+    if job.DryRun {
+        job.ProcessedRows = len(rows)
+        return s.finish(job, nil)
+    }
+
+    err = s.repo.Import(entries, job.CreatedByID, func(done int) {
+        if done%importProgressEvery == 0 || done == len(entries) {
+            if err := s.repo.SaveProgress(job.ID, done); err != nil {
+                log.Printf("import %s: saving progress: %v", job.ID, err)
+            }
+        }
+    })
+    if err != nil {
+        return s.finish(job, fromRepo(err))
+    }
+    for i, en := range entries {
+        studentID, enrollmentID := en.Student.ID, en.Enrollment.ID
+        job.Rows[i].StudentID = &studentID
+        job.Rows[i].EnrollmentID = &enrollmentID
+    }
+    job.ProcessedRows = len(entries)
+    job.CreatedRows = len(entries)
+    return s.finish(job, nil)
+}
+
+// finish marks job completed, or failed with cause, and saves it.
+func (s *ImportService) finish(job *models.ImportJob, cause error) error {
+    now := time.Now()
+    job.FinishedAt = &now
+    job.Status = models.ImportCompleted
+    if cause != nil {
+        job.Status = models.ImportFailed
+        job.Error = cause.Error()
+    }
+    return s.repo.SaveJob(job)
+}
+
+// prepare checks every row and builds what it would create. Rows with errors
+// produce no entry.
+func (s *ImportService) prepare(jobID uuid.UUID, cols map[string]int, rows [][]string, opts ImportOptions) ([]repositories.ImportEntry, []models.ImportRowResult, []models.ImportRowError, error) {
+    var emails []string
+    for _, row := range rows {
+        if e := strings.ToLower(importField(cols, row, "email")); e != "" {
+            emails = append(emails, e)
+        }
+    }
+    existing, err := s.repo.FindUsersByEmail(emails)
+    if err != nil {
+        return nil, nil, nil, err
+    }
+    batches, err := s.repo.FindBatches(opts.VenueID)
+    if err != nil {
+        return nil, nil, nil, err
+    }
+    plans, err := s.plans.FindAll()
+    if err != nil {
+        return nil, nil, nil, err
+    }
+
+    var (
+        entries  []repositories.ImportEntry
+        results  []models.ImportRowResult
+        rowErrs  = []models.ImportRowError{}
+        students = make(map[string]*models.User)
+        seen     = make(map[string]int)
+        today    = dateOnly(time.Now())
+    )
+    for i, row := range rows {
+        n := i + 1
+        fail := func(field, msg string) {
+            rowErrs = append(rowErrs, models.ImportRowError{Row: n, Field: field, Message: msg})
+        }
+        field := func(col string) string { return importField(cols, row, col) }
+
+        names := strings.Fields(field("name"))
+        if len(names) == 0 {
+            fail("name", "name is required")
+        }
+        email := strings.ToLower(field("email"))
+        if email == "" {
+            fail("email", "email is required")
+        } else if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+            fail("email", "not a valid email address")
+        }
+        var dob *time.Time
+        if v := field("dob"); v != "" {
+            t, err := time.Parse("2006-01-02", v)
+            if err != nil || !t.Before(today) {
+                fail("dob", "expected a past date as YYYY-MM-DD")
+            } else {
+                dob = &t
+            }
+        }
+        batch, msg := resolveBatch(batches, field("batch"), opts.VenueID != nil)
+        if msg != "" {
+            fail("batch", msg)
+        }
+        var plan *models.Plan
+        if v := field("plan"); v != "" {
+            if plan, msg = resolvePlan(plans, v); msg != "" {
+                fail("plan", msg)
+            }
+        }
+        var start time.Time
+        if v := field("start_date"); v != "" {
+            t, err := time.Parse("2006-01-02", v)
+            if err != nil {
+                fail("start_date", "expected a date as YYYY-MM-DD")
+            }
+            start = t
+        } else if batch != nil {
+            start = dateOnly(batch.StartDate)
+        }
+        paid := 0
+        if v := field("amount_paid"); v != "" {
+            c, err := parseCents(v)
+            if err != nil {
+                fail("amount_paid", "expected a non-negative amount such as 1500 or 1500.50")
+            }
+            paid = c
+        }
+        if batch != nil && email != "" {
+            key := email + "|" + batch.ID.String()
+            if first, ok := seen[key]; ok {
+                fail("batch", fmt.Sprintf("student is already enrolled in this batch by row %d", first))
+            } else {
+                seen[key] = n
+            }
+        }
+        if len(rowErrs) > 0 && rowErrs[len(rowErrs)-1].Row == n {
+            continue
+        }
+
+        student, ok := students[email]
+        if !ok {
+            if u, found := existing[email]; found {
+                student = &u
+            } else {
+                student = &models.User{
+                    FirstName:   names[0],
+                    LastName:    strings.Join(names[1:], " "),
+                    Email:       email,
+                    Phone:       field("phone"),
+                    DateOfBirth: dob,
+                }
+            }
+            students[email] = student
+        }
+
+        e := &models.Enrollment{
+            BatchID:    batch.ID,
+            EnrolledOn: start,
+            Type:       models.EnrollmentRegular,
+        }
+        if plan != nil {
+            e.PlanID = &plan.ID
+            e.PlanVersionID = plan.CurrentVersionID
+            e.ValidUntil = planValidUntil(start, plan)
+            e.AutoRenew = plan.AutoRenew && plan.BillingInterval != ""
+            price, err := s.pricing.priceFor(plan, nil, OfferContext{StudentAge: -1})
+            if err != nil {
+                return nil, nil, nil, err
+            }
+            e.PriceCents = price.TotalCents
+            e.TaxBreakdown = price.taxBreakdown()
+        }
+        e.Status = models.EnrollmentActive
+        if paid < e.PriceCents {
+            e.Status = models.EnrollmentPendingPayment
+        }
+        entry := repositories.ImportEntry{Student: student, Enrollment: e}
+        if paid > 0 {
+            entry.Payment = &models.FeePayment{
+                AmountCents:    paid,
+                PaidOn:         start,
+                Method:         "import",
+                TransactionRef: fmt.Sprintf("import:%s:%d", jobID, n),
+                TaxBreakdown:   e.TaxBreakdown.For(paid),
+            }
+        }
+        entries = append(entries, entry)
+
+        res := models.ImportRowResult{
+            Row:             n,
+            Email:           email,
+            NewStudent:      student.ID == uuid.Nil,
+            BatchID:         batch.ID,
+            PlanID:          e.PlanID,
+            EnrolledOn:      start,
+            PriceCents:      e.PriceCents,
+            AmountPaidCents: paid,
+            Status:          e.Status,
+        }
+        if !res.NewStudent {
+            id := student.ID
+            res.StudentID = &id
+        }
+        results = append(results, res)
+    }
+    return entries, results, rowErrs, nil
+}
+
+// readImportCSV reads the header and data rows of a CSV and maps each known
+// column to its index.
+func readImportCSV(r io.Reader) (map[string]int, [][]string, error) {
+    cr := csv.NewReader(r)
+    cr.FieldsPerRecord = -1
+    cr.TrimLeadingSpace = true
+    records, err := cr.ReadAll()
+    if err != nil {
+        return nil, nil, invalid("invalid CSV: " + err.Error())
+    }
+    if len(records) < 2 {
+        return nil, nil, ErrImportEmpty
+    }
+    cols := make(map[string]int)
+    for i, h := range records[0] {
+        h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+        h = strings.NewReplacer(" ", "_", "-", "_").Replace(h)
+        if alias, ok := importColumnAliases[h]; ok {
+            h = alias
+        }
+        cols[h] = i
+    }
+    for _, c := range []string{"name", "email", "batch"} {
+        if _, ok := cols[c]; !ok {
+            return nil, nil, invalid(fmt.Sprintf("missing column %q; expected %s", c, strings.Join(importColumns, ", ")))
+        }
+    }
+    return cols, records[1:], nil
+}
+
+// importField returns the trimmed value of a column in row, or "".
+func importField(cols map[string]int, row []string, col string) string {
+    i, ok := cols[col]
+    if !ok || i >= len(row) {
+        return ""
+    }
+    return strings.TrimSpace(row[i])
+}
+
+// resolveBatch finds a batch by ID or by case-insensitive name. It returns
+// a message instead if there is no single match.
+func resolveBatch(batches []models.Batch, v string, inVenue bool) (*models.Batch, string) {
+    if v == "" {
+        return nil, "batch is required"
+    }
+    id, err := uuid.Parse(v)
+    var found []*models.Batch
+    for i := range batches {
+        if (err == nil && batches[i].ID == id) || strings.EqualFold(strings.TrimSpace(batches[i].Name), v) {
+            found = append(found, &batches[i])
+        }
+    }
+    switch {
+    case len(found) == 1:
+        return found[0], ""
+    case len(found) == 0:
+        return nil, "unknown batch"
+    case inVenue:
+        return nil, "batch name is ambiguous; use the batch ID"
+    }
+    return nil, "batch name is ambiguous; use the batch ID or pass venue_id"
+}
+
+// resolvePlan finds a plan by ID or by case-insensitive name.
+func resolvePlan(plans []models.Plan, v string) (*models.Plan, string) {
+    id, err := uuid.Parse(v)
+    var found []*models.Plan
+    for i := range plans {
+        if (err == nil && plans[i].ID == id) || strings.EqualFold(strings.TrimSpace(plans[i].Name), v) {
+            found = append(found, &plans[i])
+        }
+    }
+    switch len(found) {
+    case 1:
+        return found[0], ""
+    case 0:
+        return nil, "unknown plan"
+    }
+    return nil, "plan name is ambiguous; use the plan ID"
+}
+
+// parseCents parses an amount in currency units, such as "1500.50", into cents.
+func parseCents(v string) (int, error) {
+    f, err := strconv.ParseFloat(v, 64)
+    if err != nil || f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+        return 0, errors.New("invalid amount")
+    }
+    return int(math.Round(f * 100)), nil
+}
+
+// countRows counts the distinct rows in errs.
+func countRows(errs []models.ImportRowError) int {
+    rows := make(map[int]bool)
+    for _, e := range errs {
+        rows[e.Row] = true
+    }
+    return len(rows)
+}
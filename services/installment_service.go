@@ -0,0 +1,52 @@
+package services
+
+import (
+	"time"
+
+	"spodemy-backend/config"
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+
+	"github.com/google/uuid"
+)
+
+// ErrInstallmentStatus is returned for an unknown installment status filter.
+var ErrInstallmentStatus = invalid("status must be empty, \"due\", \"paid\" or \"overdue\"")
+
+// InstallmentService tracks the installments of enrollments on plans paid
+// in installments. Installments are issued with the enrollment and paid
+// through ordinary fee payments.
+type InstallmentService struct {
+    repo        *repositories.InstallmentRepository
+    enrollments *repositories.EnrollmentRepository
+    cfg         config.InstallmentConfig
+}
+
+// NewInstallmentService creates a new InstallmentService.
+func NewInstallmentService(r *repositories.InstallmentRepository, enrollments *repositories.EnrollmentRepository, cfg config.InstallmentConfig) *InstallmentService {
+    return &InstallmentService{repo: r, enrollments: enrollments, cfg: cfg}
+}
+
+// ListByEnrollment returns an enrollment's installments in order.
+func (s *InstallmentService) ListByEnrollment(enrID uuid.UUID) ([]models.Installment, error) {
+    if _, err := s.enrollments.FindByID(enrID); err != nil {
+        return nil, err
+    }
+    return s.repo.FindByEnrollment(enrID)
+}
+
+// List returns installments in a status, or all of them, oldest due first.
+func (s *InstallmentService) List(status string) ([]models.Installment, error) {
+    switch status {
+    case "", models.InstallmentDue, models.InstallmentPaid, models.InstallmentOverdue:
+    default:
+        return nil, ErrInstallmentStatus
+    }
+    return s.repo.FindByStatus(status)
+}
+
+// SuspendOverdue turns installments unpaid for longer than the configured
+// threshold overdue and pauses their enrollments until they are paid.
+func (s *InstallmentService) SuspendOverdue(now time.Time) (int64, error) {
+    return s.repo.MarkOverdue(dateOnly(now).AddDate(0, 0, -s.cfg.SuspendAfterDays))
+}
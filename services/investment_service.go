@@ -1,6 +1,8 @@
 package services
 
 import (
+	"strings"
+
 	"spodemy-backend/models"
 	"spodemy-backend/repositories"
 
@@ -51,3 +53,17 @@ func (s *InvestmentService) ListTransactions(invID uuid.UUID) ([]models.Investme
 func (s *InvestmentService) CreateTransaction(txn *models.InvestmentTransaction) error {
     return s.repo.CreateTransaction(txn)
 }
+
+// ReverseTransaction cancels a transaction recorded by mistake with a
+// reversal entry and returns the reversal.
+func (s *InvestmentService) ReverseTransaction(invID, txnID uuid.UUID, reason string) (*models.InvestmentTransaction, error) {
+    reason = strings.TrimSpace(reason)
+    if reason == "" {
+        return nil, ErrReversalReason
+    }
+    rev, err := s.repo.ReverseTransaction(invID, txnID, reason)
+    if err != nil {
+        return nil, fromRepo(err)
+    }
+    return rev, nil
+}
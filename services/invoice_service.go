@@ -0,0 +1,175 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"spodemy-backend/config"
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+
+	"github.com/google/uuid"
+)
+
+// InvoiceService reads invoices and renders them as PDFs. Invoices are
+// issued by the repositories together with the enrollment or renewal they
+// bill.
+type InvoiceService struct {
+    repo *repositories.InvoiceRepository
+    cfg  config.InvoiceConfig
+}
+
+// NewInvoiceService creates a new InvoiceService.
+func NewInvoiceService(r *repositories.InvoiceRepository, cfg config.InvoiceConfig) *InvoiceService {
+    return &InvoiceService{repo: r, cfg: cfg}
+}
+
+// List returns the invoices matching f.
+func (s *InvoiceService) List(f repositories.InvoiceFilter) ([]models.Invoice, error) {
+    return s.repo.FindAll(f)
+}
+
+// Get retrieves a single invoice.
+func (s *InvoiceService) Get(id uuid.UUID) (*models.Invoice, error) {
+    return s.repo.FindByID(id)
+}
+
+// PDF renders an invoice with the configured branding.
+func (s *InvoiceService) PDF(id uuid.UUID) (*models.Invoice, []byte, error) {
+    inv, err := s.repo.FindByID(id)
+    if err != nil {
+        return nil, nil, err
+    }
+    return inv, renderInvoice(inv, s.cfg), nil
+}
+
+// renderInvoice lays out inv on A4 pages: a header band in the accent
+// color, the seller and invoice details, the lines and the totals.
+func renderInvoice(inv *models.Invoice, cfg config.InvoiceConfig) []byte {
+    const left, right = 40.0, pdfPageWidth - 40
+    white, black, gray := [3]float64{1, 1, 1}, [3]float64{0, 0, 0}, [3]float64{0.4, 0.4, 0.4}
+    d := newPDFDoc("Invoice " + inv.Number)
+
+    d.fillColor(hexColor(cfg.AccentColor))
+    d.rect(0, pdfPageHeight-70, pdfPageWidth, 70)
+    d.fillColor(white)
+    d.text(left, pdfPageHeight-45, 20, true, cfg.BusinessName)
+    title := "INVOICE"
+    if inv.TaxCents > 0 {
+        title = "TAX INVOICE"
+    }
+    d.textRight(right, pdfPageHeight-45, 16, true, title)
+
+    y := pdfPageHeight - 95
+    d.fillColor(gray)
+    for _, s := range strings.Split(cfg.Address, "\n") {
+        if s != "" {
+            d.text(left, y, 9, false, s)
+            y -= 12
+        }
+    }
+    if cfg.TaxID != "" {
+        d.text(left, y, 9, false, "Tax ID: "+cfg.TaxID)
+    }
+    y = pdfPageHeight - 95
+    details := [][2]string{
+        {"Invoice no.", inv.Number},
+        {"Issued", inv.IssuedOn.Format("2 Jan 2006")},
+        {"Due", inv.DueOn.Format("2 Jan 2006")},
+        {"Status", strings.ReplaceAll(inv.Status, "_", " ")},
+        {"Currency", inv.Currency},
+    }
+    for _, kv := range details {
+        d.fillColor(gray)
+        d.textRight(right-110, y, 9, false, kv[0])
+        d.fillColor(black)
+        d.textRight(right, y, 9, true, kv[1])
+        y -= 12
+    }
+
+    y = pdfPageHeight - 170
+    d.fillColor(gray)
+    d.text(left, y, 9, true, "BILL TO")
+    d.fillColor(black)
+    y -= 14
+    if st := inv.Student; st != nil {
+        d.text(left, y, 11, true, strings.TrimSpace(st.FirstName+" "+st.LastName))
+        y -= 13
+        for _, s := range []string{st.Email, st.Phone} {
+            if s != "" {
+                d.text(left, y, 9, false, s)
+                y -= 12
+            }
+        }
+    }
+    if inv.Venue != nil {
+        d.fillColor(gray)
+        d.text(left, y, 9, false, "Venue: "+inv.Venue.Name)
+        d.fillColor(black)
+        y -= 12
+    }
+
+    y -= 20
+    d.fillColor(gray)
+    d.text(left, y, 9, true, "DESCRIPTION")
+    d.textRight(right, y, 9, true, "AMOUNT ("+inv.Currency+")")
+    d.fillColor(black)
+    y -= 6
+    d.line(left, y, right, y)
+    for _, l := range inv.Lines {
+        if y < 160 {
+            d.addPage()
+            y = pdfPageHeight - 60
+        }
+        y -= 16
+        d.text(left, y, 10, false, l.Description)
+        d.textRight(right, y, 10, false, formatCents(l.AmountCents))
+        y -= 6
+        d.line(left, y, right, y)
+    }
+
+    y -= 20
+    totals := [][2]string{{"Subtotal", formatCents(inv.SubtotalCents)}}
+    if inv.TaxPct > 0 || inv.TaxCents > 0 {
+        label := fmt.Sprintf("Tax %s%%", pdfNum(inv.TaxPct))
+        if inv.TaxInclusive {
+            label += " (included)"
+        }
+        totals = append(totals, [2]string{label, formatCents(inv.TaxCents)})
+    }
+    balance := inv.TotalCents - inv.PaidCents
+    if inv.Status == models.InvoiceVoid {
+        balance = 0
+    }
+    totals = append(totals,
+        [2]string{"Total", formatCents(inv.TotalCents)},
+        [2]string{"Paid", formatCents(inv.PaidCents)},
+        [2]string{"Balance due", formatCents(balance)},
+    )
+    for i, kv := range totals {
+        bold := i >= len(totals)-1 || kv[0] == "Total"
+        d.text(right-200, y, 10, bold, kv[0])
+        d.textRight(right, y, 10, bold, kv[1])
+        y -= 15
+    }
+
+    if cfg.Footer != "" {
+        d.fillColor(gray)
+        d.text(left, 40, 8, false, cfg.Footer)
+    }
+    return d.bytes()
+}
+
+// formatCents formats an amount in cents with thousands separators, e.g.
+// "-1,234.50".
+func formatCents(cents int) string {
+    sign := ""
+    if cents < 0 {
+        sign, cents = "-", -cents
+    }
+    units := fmt.Sprint(cents / 100)
+    for i := len(units) - 3; i > 0; i -= 3 {
+        units = units[:i] + "," + units[i:]
+    }
+    return fmt.Sprintf("%s%s.%02d", sign, units, cents%100)
+}
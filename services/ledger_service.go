@@ -0,0 +1,236 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"spodemy-backend/config"
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+
+	"github.com/google/uuid"
+)
+
+// Ledger errors.
+var (
+    ErrAccountCode     = invalid("code and name are required")
+    ErrAccountType     = invalid("type must be asset, liability, equity, income or expense")
+    ErrAccountExists   = conflict("an account with this code already exists")
+    ErrEntryLines      = invalid("a journal entry needs at least two lines, each either debiting or crediting a positive amount")
+    ErrEntryAccount    = invalid("journal entry refers to an unknown account")
+    ErrEntryUnbalanced = invalid(repositories.ErrUnbalanced.Error())
+    ErrEntryDesc       = invalid("description is required")
+)
+
+// TrialBalance lists the debit and credit totals of every account posted
+// to in a period, per currency. The totals of each currency are equal when
+// the ledger balances.
+type TrialBalance struct {
+    VenueID *uuid.UUID                     `json:"venue_id,omitempty"`
+    From    *time.Time                     `json:"from,omitempty"`
+    To      *time.Time                     `json:"to,omitempty"` // exclusive
+    Rows    []repositories.TrialBalanceRow `json:"rows"`
+    Totals  []TrialBalanceTotal            `json:"totals"`
+}
+
+// TrialBalanceTotal is the debits and credits of a trial balance in one
+// currency.
+type TrialBalanceTotal struct {
+    Currency    string `json:"currency"`
+    DebitCents  int64  `json:"debit_cents"`
+    CreditCents int64  `json:"credit_cents"`
+}
+
+// StatementEntry is a posting with the account's balance after it.
+type StatementEntry struct {
+    repositories.StatementLine
+    BalanceCents int64 `json:"balance_cents"`
+}
+
+// AccountStatement lists the postings to an account in a period. Balances
+// are on the account's normal side: debits less credits for assets and
+// expenses, credits less debits otherwise.
+type AccountStatement struct {
+    Account      models.Account   `json:"account"`
+    VenueID      *uuid.UUID       `json:"venue_id,omitempty"`
+    Currency     string           `json:"currency"`
+    From         *time.Time       `json:"from,omitempty"`
+    To           *time.Time       `json:"to,omitempty"` // exclusive
+    OpeningCents int64            `json:"opening_cents"`
+    Entries      []StatementEntry `json:"entries"`
+    ClosingCents int64            `json:"closing_cents"`
+}
+
+// LedgerService keeps the general ledger. Payments, refunds, expenses and
+// investment transactions are posted by their repositories as they are
+// recorded; this service adds manual entries and reports on the ledger.
+// Every entry is in one currency, and amounts in different currencies are
+// never added up.
+type LedgerService struct {
+    repo *repositories.LedgerRepository
+    base string
+}
+
+// NewLedgerService creates a new LedgerService.
+func NewLedgerService(r *repositories.LedgerRepository, cfg config.CurrencyConfig) *LedgerService {
+    return &LedgerService{repo: r, base: cfg.Base}
+}
+
+// Accounts returns the chart of accounts.
+func (s *LedgerService) Accounts() ([]models.Account, error) {
+    return s.repo.FindAccounts()
+}
+
+// CreateAccount adds an account to the chart of accounts.
+func (s *LedgerService) CreateAccount(a *models.Account) error {
+    a.Code, a.Name = strings.TrimSpace(a.Code), strings.TrimSpace(a.Name)
+    if a.Code == "" || a.Name == "" {
+        return ErrAccountCode
+    }
+    switch a.Type {
+    case models.AccountAsset, models.AccountLiability, models.AccountEquity, models.AccountIncome, models.AccountExpense:
+    default:
+        return ErrAccountType
+    }
+    n, err := s.repo.CountAccountsByCode(a.Code)
+    if err != nil {
+        return err
+    }
+    if n > 0 {
+        return ErrAccountExists
+    }
+    a.System = false
+    return s.repo.CreateAccount(a)
+}
+
+// Entries returns the journal entries matching f.
+func (s *LedgerService) Entries(f repositories.LedgerFilter) ([]models.JournalEntry, error) {
+    if err := checkPeriod(f); err != nil {
+        return nil, err
+    }
+    return s.repo.FindEntries(f)
+}
+
+// Entry retrieves a single journal entry.
+func (s *LedgerService) Entry(id uuid.UUID) (*models.JournalEntry, error) {
+    return s.repo.FindEntry(id)
+}
+
+// CreateEntry posts a manual journal entry, e.g. an adjustment, in the base
+// currency unless it names another. Its lines must balance.
+func (s *LedgerService) CreateEntry(e *models.JournalEntry) error {
+    e.Description = strings.TrimSpace(e.Description)
+    if e.Description == "" {
+        return ErrEntryDesc
+    }
+    var err error
+    if e.Currency, err = normalizeCurrency(e.Currency, s.base); err != nil {
+        return err
+    }
+    if len(e.Lines) < 2 {
+        return ErrEntryLines
+    }
+    seen := map[uuid.UUID]bool{}
+    var ids []uuid.UUID
+    for i := range e.Lines {
+        l := &e.Lines[i]
+        if l.DebitCents < 0 || l.CreditCents < 0 || (l.DebitCents > 0) == (l.CreditCents > 0) {
+            return ErrEntryLines
+        }
+        if !seen[l.AccountID] {
+            seen[l.AccountID] = true
+            ids = append(ids, l.AccountID)
+        }
+        l.ID, l.JournalEntryID, l.Account = uuid.Nil, uuid.Nil, nil
+    }
+    n, err := s.repo.CountAccounts(ids)
+    if err != nil {
+        return err
+    }
+    if n != int64(len(ids)) {
+        return ErrEntryAccount
+    }
+    if e.EntryDate.IsZero() {
+        e.EntryDate = time.Now()
+    }
+    e.EntryDate = dateOnly(e.EntryDate)
+    e.SourceType, e.SourceID = models.SourceManual, nil
+    if err := s.repo.CreateEntry(e); err != nil {
+        if errors.Is(err, repositories.ErrUnbalanced) {
+            return ErrEntryUnbalanced
+        }
+        return err
+    }
+    return nil
+}
+
+// TrialBalance totals the postings matching f by currency and account.
+func (s *LedgerService) TrialBalance(f repositories.LedgerFilter) (*TrialBalance, error) {
+    if err := checkPeriod(f); err != nil {
+        return nil, err
+    }
+    rows, err := s.repo.TrialBalance(f)
+    if err != nil {
+        return nil, err
+    }
+    tb := &TrialBalance{VenueID: f.VenueID, From: f.From, To: f.To, Rows: rows, Totals: []TrialBalanceTotal{}}
+    for _, row := range rows {
+        // rows come ordered by currency
+        if n := len(tb.Totals); n == 0 || tb.Totals[n-1].Currency != row.Currency {
+            tb.Totals = append(tb.Totals, TrialBalanceTotal{Currency: row.Currency})
+        }
+        t := &tb.Totals[len(tb.Totals)-1]
+        t.DebitCents += row.DebitCents
+        t.CreditCents += row.CreditCents
+    }
+    return tb, nil
+}
+
+// Statement lists the postings to an account matching f with a running
+// balance carried forward from before f.From. It covers one currency, the
+// base currency unless f names another.
+func (s *LedgerService) Statement(accountID uuid.UUID, f repositories.LedgerFilter) (*AccountStatement, error) {
+    if err := checkPeriod(f); err != nil {
+        return nil, err
+    }
+    if f.Currency == "" {
+        f.Currency = s.base
+    }
+    a, err := s.repo.FindAccount(accountID)
+    if err != nil {
+        return nil, err
+    }
+    sign := int64(1)
+    if !models.DebitNormal(a.Type) {
+        sign = -1
+    }
+    f.SourceType = ""
+    opening, lines, err := s.repo.Statement(a.ID, f)
+    if err != nil {
+        return nil, err
+    }
+    st := &AccountStatement{
+        Account:      *a,
+        VenueID:      f.VenueID,
+        Currency:     f.Currency,
+        From:         f.From,
+        To:           f.To,
+        OpeningCents: sign * opening,
+        Entries:      make([]StatementEntry, len(lines)),
+    }
+    balance := st.OpeningCents
+    for i, l := range lines {
+        balance += sign * (l.DebitCents - l.CreditCents)
+        st.Entries[i] = StatementEntry{StatementLine: l, BalanceCents: balance}
+    }
+    st.ClosingCents = balance
+    return st, nil
+}
+
+func checkPeriod(f repositories.LedgerFilter) error {
+    if f.From != nil && f.To != nil && !f.To.After(*f.From) {
+        return ErrReportPeriod
+    }
+    return nil
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"spodemy-backend/config"
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// entryLines is a journal entry of lines over accounts a and b.
+func entryLines(a, b uuid.UUID, lines ...[2]int) *models.JournalEntry {
+    e := &models.JournalEntry{Description: "Adjustment"}
+    for i, l := range lines {
+        id := a
+        if i%2 == 1 {
+            id = b
+        }
+        e.Lines = append(e.Lines, models.JournalLine{AccountID: id, DebitCents: l[0], CreditCents: l[1]})
+    }
+    return e
+}
+
+func TestCreateEntryLines(t *testing.T) {
+    a, b := uuid.New(), uuid.New()
+    tests := []struct {
+        name  string
+        entry *models.JournalEntry
+        err   error
+    }{
+        {name: "no description", entry: &models.JournalEntry{Lines: entryLines(a, b, [2]int{500, 0}, [2]int{0, 500}).Lines}, err: ErrEntryDesc},
+        {name: "bad currency", entry: func() *models.JournalEntry {
+            e := entryLines(a, b, [2]int{500, 0}, [2]int{0, 500})
+            e.Currency = "RUPEES"
+            return e
+        }(), err: ErrCurrencyCode},
+        {name: "no lines", entry: entryLines(a, b), err: ErrEntryLines},
+        {name: "one line", entry: entryLines(a, b, [2]int{500, 0}), err: ErrEntryLines},
+        {name: "empty line", entry: entryLines(a, b, [2]int{500, 0}, [2]int{0, 0}), err: ErrEntryLines},
+        {name: "debit and credit on one line", entry: entryLines(a, b, [2]int{500, 0}, [2]int{500, 1000}), err: ErrEntryLines},
+        {name: "negative debit", entry: entryLines(a, b, [2]int{-500, 0}, [2]int{0, -500}), err: ErrEntryLines},
+        {name: "negative credit", entry: entryLines(a, b, [2]int{0, -500}, [2]int{-500, 0}), err: ErrEntryLines},
+    }
+    // each is rejected before the accounts are looked up
+    s := NewLedgerService(nil, config.CurrencyConfig{Base: "INR"})
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if err := s.CreateEntry(tt.entry); !errors.Is(err, tt.err) {
+                t.Errorf("err = %v, want %v", err, tt.err)
+            }
+        })
+    }
+}
+
+func TestCreateEntryAccounts(t *testing.T) {
+    dsn := os.Getenv("SPODEMY_TEST_DSN")
+    if dsn == "" {
+        t.Skip("SPODEMY_TEST_DSN is not set")
+    }
+    db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+    if err != nil {
+        t.Fatal(err)
+    }
+    tx := db.Begin()
+    defer tx.Rollback()
+    if err := tx.AutoMigrate(&models.Account{}, &models.JournalEntry{}, &models.JournalLine{}); err != nil {
+        t.Fatal(err)
+    }
+    cash := models.Account{Code: "T" + uuid.NewString()[:8], Name: "Test cash", Type: models.AccountAsset}
+    income := models.Account{Code: "T" + uuid.NewString()[:8], Name: "Test income", Type: models.AccountIncome}
+    for _, a := range []*models.Account{&cash, &income} {
+        if err := tx.Create(a).Error; err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    tests := []struct {
+        name  string
+        entry *models.JournalEntry
+        err   error
+    }{
+        {name: "balanced", entry: entryLines(cash.ID, income.ID, [2]int{500, 0}, [2]int{0, 500})},
+        {name: "unknown account", entry: entryLines(cash.ID, uuid.New(), [2]int{500, 0}, [2]int{0, 500}), err: ErrEntryAccount},
+        {name: "debits only", entry: entryLines(cash.ID, income.ID, [2]int{500, 0}, [2]int{500, 0}), err: ErrEntryUnbalanced},
+        {name: "unbalanced totals", entry: entryLines(cash.ID, income.ID, [2]int{500, 0}, [2]int{0, 499}), err: ErrEntryUnbalanced},
+    }
+    s := NewLedgerService(repositories.NewLedgerRepository(tx), config.CurrencyConfig{Base: "INR"})
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            err := s.CreateEntry(tt.entry)
+            if !errors.Is(err, tt.err) {
+                t.Fatalf("err = %v, want %v", err, tt.err)
+            }
+            if err == nil && (tt.entry.Currency != "INR" || tt.entry.SourceType != models.SourceManual) {
+                t.Errorf("entry = %+v, want a manual entry in INR", tt.entry)
+            }
+        })
+    }
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"spodemy-backend/config"
+
+	"github.com/google/uuid"
+)
+
+// Mail is a plain-text message with optional attachments.
+type Mail struct {
+    To          string
+    Subject     string
+    Text        string
+    Attachments []Attachment
+}
+
+// Attachment is a file sent with a Mail.
+type Attachment struct {
+    Name        string
+    ContentType string
+    Data        []byte
+}
+
+// Mailer sends mail.
+type Mailer interface {
+    Send(m Mail) error
+}
+
+// NewMailer returns the mailer configured in cfg.
+func NewMailer(cfg config.MailConfig) (Mailer, error) {
+    switch cfg.Provider {
+    case "smtp":
+        if cfg.Host == "" {
+            return nil, fmt.Errorf("smtp mailer needs a host")
+        }
+        return &smtpMailer{cfg: cfg}, nil
+    case "file":
+        return NewFileMailer(cfg.Dir, cfg.From), nil
+    }
+    return nil, fmt.Errorf("unknown mail provider %q", cfg.Provider)
+}
+
+// smtpMailer sends through an SMTP relay, authenticating when a username
+// is configured. net/smtp upgrades to TLS when the server offers STARTTLS.
+type smtpMailer struct {
+    cfg config.MailConfig
+}
+
+func (s *smtpMailer) Send(m Mail) error {
+    var auth smtp.Auth
+    if s.cfg.Username != "" {
+        auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+    }
+    addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+    return smtp.SendMail(addr, auth, s.cfg.From, []string{m.To}, buildMessage(s.cfg.From, m))
+}
+
+// FileMailer writes each message to a directory as an .eml file instead of
+// sending it.
+type FileMailer struct {
+    dir  string
+    from string
+}
+
+// NewFileMailer returns a FileMailer writing to dir, which is created when
+// the first message is written.
+func NewFileMailer(dir, from string) *FileMailer {
+    return &FileMailer{dir: dir, from: from}
+}
+
+func (f *FileMailer) Send(m Mail) error {
+    if err := os.MkdirAll(f.dir, 0o755); err != nil {
+        return err
+    }
+    name := time.Now().UTC().Format("20060102T150405") + "-" + uuid.NewString()[:8] + ".eml"
+    return os.WriteFile(filepath.Join(f.dir, name), buildMessage(f.from, m), 0o644)
+}
+
+// buildMessage encodes m as a MIME message: the text alone, or a
+// multipart/mixed body with the text first and each attachment in base64.
+func buildMessage(from string, m Mail) []byte {
+    var b bytes.Buffer
+    fmt.Fprintf(&b, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\n",
+        from, m.To, mime.QEncoding.Encode("utf-8", m.Subject), time.Now().Format(time.RFC1123Z))
+    text := strings.ReplaceAll(strings.ReplaceAll(m.Text, "\r\n", "\n"), "\n", "\r\n")
+    if len(m.Attachments) == 0 {
+        b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+        b.WriteString(text)
+        return b.Bytes()
+    }
+
+    w := multipart.NewWriter(&b)
+    fmt.Fprintf(&b, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", w.Boundary())
+    part, _ := w.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+    part.Write([]byte(text))
+    for _, a := range m.Attachments {
+        part, _ = w.CreatePart(textproto.MIMEHeader{
+            "Content-Type":              {a.ContentType},
+            "Content-Transfer-Encoding": {"base64"},
+            "Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Name})},
+        })
+        enc := base64.StdEncoding.EncodeToString(a.Data)
+        for len(enc) > 76 {
+            part.Write([]byte(enc[:76] + "\r\n"))
+            enc = enc[76:]
+        }
+        part.Write([]byte(enc + "\r\n"))
+    }
+    w.Close()
+    return b.Bytes()
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"spodemy-backend/config"
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+
+	"github.com/google/uuid"
+)
+
+// Make-up credit errors.
+var (
+    ErrMakeupCreditUnavailable = conflict("make-up credit is not available")
+    ErrMakeupCreditExpired     = conflict("make-up credit has expired")
+    ErrMakeupSessionFull       = conflict("make-up session has no free capacity")
+    ErrMakeupSameBatch         = invalid("make-up session must be in a different batch")
+    ErrMakeupSportMismatch     = invalid("make-up batch must be of the same sport")
+    ErrMakeupOutsideTerm       = invalid("make-up date is outside the batch term or the credit window")
+    ErrMakeupWrongEnrollment   = invalid("make-up credit belongs to another enrollment")
+)
+
+// MakeupCreditService issues, books and redeems make-up credits.
+type MakeupCreditService struct {
+    repo    *repositories.MakeupCreditRepository
+    batches *repositories.BatchRepository
+    cfg     config.MakeupConfig
+}
+
+// NewMakeupCreditService creates a new MakeupCreditService.
+func NewMakeupCreditService(r *repositories.MakeupCreditRepository, batches *repositories.BatchRepository, cfg config.MakeupConfig) *MakeupCreditService {
+    return &MakeupCreditService{repo: r, batches: batches, cfg: cfg}
+}
+
+// ListByEnrollment returns the credits issued to an enrollment.
+func (s *MakeupCreditService) ListByEnrollment(enrID uuid.UUID) ([]models.MakeupCredit, error) {
+    return s.repo.FindByEnrollment(enrID)
+}
+
+// Get retrieves a single credit by UUID.
+func (s *MakeupCreditService) Get(id uuid.UUID) (*models.MakeupCredit, error) {
+    return s.repo.FindByID(id)
+}
+
+// creditFor returns the credit an attendance record earns: one when a
+// regular session was missed for a valid reason, otherwise nil. It is saved
+// with the record.
+func (s *MakeupCreditService) creditFor(a *models.Attendance) *models.MakeupCredit {
+    if a.Status != "absent" || a.MakeupCreditID != nil || !s.validReason(a.Reason) {
+        return nil
+    }
+    absent := dateOnly(a.Date)
+    return &models.MakeupCredit{
+        EnrollmentID: a.EnrollmentID,
+        AbsenceID:    a.ID,
+        Reason:       a.Reason,
+        IssuedOn:     time.Now(),
+        ExpiresOn:    absent.AddDate(0, 0, s.cfg.CreditValidityDays),
+        Status:       models.MakeupAvailable,
+    }
+}
+
+// Book reserves a session of another batch of the same sport for the credit.
+func (s *MakeupCreditService) Book(id, batchID uuid.UUID, date time.Time) (*models.MakeupCredit, error) {
+    mc, err := s.repo.FindByID(id)
+    if err != nil {
+        return nil, err
+    }
+    today := dateOnly(time.Now())
+    if mc.Status == models.MakeupExpired || (mc.Status == models.MakeupAvailable && mc.ExpiresOn.Before(today)) {
+        return nil, ErrMakeupCreditExpired
+    }
+    if mc.Status != models.MakeupAvailable {
+        return nil, ErrMakeupCreditUnavailable
+    }
+    batch, err := s.batches.FindByID(batchID)
+    if err != nil {
+        return nil, err
+    }
+    home := mc.Enrollment.Batch
+    if batch.ID == home.ID {
+        return nil, ErrMakeupSameBatch
+    }
+    if batch.Sport == "" || !strings.EqualFold(batch.Sport, home.Sport) {
+        return nil, ErrMakeupSportMismatch
+    }
+    date = dateOnly(date)
+    if date.Before(today) || date.After(mc.ExpiresOn) ||
+        date.Before(dateOnly(batch.StartDate)) || date.After(dateOnly(batch.EndDate)) {
+        return nil, ErrMakeupOutsideTerm
+    }
+    ok, err := s.repo.Book(id, batch.ID, date)
+    if err != nil {
+        return nil, err
+    }
+    if !ok {
+        current, err := s.repo.FindByID(id)
+        if err != nil {
+            return nil, err
+        }
+        if current.Status != models.MakeupAvailable {
+            return nil, ErrMakeupCreditUnavailable
+        }
+        return nil, ErrMakeupSessionFull
+    }
+    return s.repo.FindByID(id)
+}
+
+// CancelBooking gives the seat back and makes the credit available again.
+func (s *MakeupCreditService) CancelBooking(id uuid.UUID) (*models.MakeupCredit, error) {
+    ok, err := s.repo.CancelBooking(id)
+    if err != nil {
+        return nil, err
+    }
+    if !ok {
+        if _, err := s.repo.FindByID(id); err != nil {
+            return nil, err
+        }
+        return nil, ErrMakeupCreditUnavailable
+    }
+    return s.repo.FindByID(id)
+}
+
+// Redeem records attendance for a booked make-up session against the
+// original enrollment and marks the credit used.
+func (s *MakeupCreditService) Redeem(a *models.Attendance) error {
+    mc, err := s.repo.FindByID(*a.MakeupCreditID)
+    if err != nil {
+        return err
+    }
+    if mc.EnrollmentID != a.EnrollmentID {
+        return ErrMakeupWrongEnrollment
+    }
+    if mc.Status != models.MakeupBooked {
+        return ErrMakeupCreditUnavailable
+    }
+    a.Date = *mc.BookedDate
+    a.BatchID = mc.BookedBatchID
+    ok, err := s.repo.Redeem(mc.ID, a)
+    if err != nil {
+        return err
+    }
+    if !ok {
+        return ErrMakeupCreditUnavailable
+    }
+    return nil
+}
+
+// ExpireDue makes credits booked for a session before today that was not
+// attended available again, and marks credits whose window closed before now
+// as expired.
+func (s *MakeupCreditService) ExpireDue(now time.Time) (int64, error) {
+    return s.repo.ExpireBefore(dateOnly(now))
+}
+
+func (s *MakeupCreditService) validReason(reason string) bool {
+    reason = strings.TrimSpace(reason)
+    for _, r := range s.cfg.ValidReasons {
+        if strings.EqualFold(r, reason) {
+            return true
+        }
+    }
+    return false
+}
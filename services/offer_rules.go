@@ -0,0 +1,230 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"spodemy-backend/models"
+
+	"github.com/google/uuid"
+)
+
+// reasonBetterOffer rejects an eligible offer that lost to a larger discount.
+const reasonBetterOffer = "a better offer applies"
+
+// OfferContext describes the purchase that offer eligibility is judged against.
+type OfferContext struct {
+    Date             time.Time
+    VenueID          uuid.UUID
+    Currency         string // the plan's; fixed offers in another do not apply
+    Sport            string // empty when no batch is known yet
+    PlanDurationDays int
+    StudentAge       int // whole years on Date, -1 if unknown
+    NewStudent       bool
+    Uses             map[uuid.UUID]int64 // redemptions so far per offer
+    SiblingPct       float64             // family discount taken off after the offers
+}
+
+// TaxTerms is the tax a price is subject to.
+type TaxTerms struct {
+    Code      string // TaxRate code, empty for the default rate
+    Pct       float64
+    Inclusive bool // the price already includes tax
+}
+
+// RejectedOffer is an attached offer that was not applied, and why.
+type RejectedOffer struct {
+    OfferID uuid.UUID `json:"offer_id"`
+    Reason  string    `json:"reason"`
+}
+
+// OfferEvaluation is the outcome of EvaluateOffers.
+type OfferEvaluation struct {
+    Applied   []*models.Offer
+    Breakdown PriceBreakdown
+    Rejected  []RejectedOffer
+}
+
+// EvaluateOffers picks the offers to apply to a base price. Ineligible offers
+// are rejected with a reason. Of the rest, every exclusive offer is tried on
+// its own and all stackable offers are tried together; the combination with
+// the largest discount wins, higher priority breaking ties. Offers are
+// applied in priority order, each on what is left after the previous one,
+// and the sibling discount, if any, comes off last.
+func EvaluateOffers(base int, offers []*models.Offer, ctx OfferContext, tax TaxTerms) OfferEvaluation {
+    ev := OfferEvaluation{Rejected: []RejectedOffer{}}
+    var eligible []*models.Offer
+    for _, o := range offers {
+        if reason := offerIneligibility(o, ctx); reason != "" {
+            ev.Rejected = append(ev.Rejected, RejectedOffer{OfferID: o.ID, Reason: reason})
+            continue
+        }
+        eligible = append(eligible, o)
+    }
+    sortOffers(eligible)
+
+    candidates := [][]*models.Offer{}
+    var stack []*models.Offer
+    for _, o := range eligible {
+        if o.Stackable {
+            stack = append(stack, o)
+        } else {
+            candidates = append(candidates, []*models.Offer{o})
+        }
+    }
+    if len(stack) > 0 {
+        candidates = append(candidates, stack)
+    }
+
+    ev.Breakdown = priceBreakdown(base, nil, tax)
+    for _, c := range candidates {
+        b := priceBreakdown(base, c, tax)
+        if b.DiscountCents > ev.Breakdown.DiscountCents ||
+            (b.DiscountCents == ev.Breakdown.DiscountCents && len(ev.Applied) > 0 && c[0].Priority > ev.Applied[0].Priority) {
+            ev.Breakdown = b
+            ev.Applied = c
+        }
+    }
+
+    ev.Breakdown = withSiblingDiscount(ev.Breakdown, ctx.SiblingPct)
+
+    applied := make(map[uuid.UUID]bool, len(ev.Applied))
+    for _, o := range ev.Applied {
+        applied[o.ID] = true
+    }
+    for _, o := range eligible {
+        if !applied[o.ID] {
+            ev.Rejected = append(ev.Rejected, RejectedOffer{OfferID: o.ID, Reason: reasonBetterOffer})
+        }
+    }
+    return ev
+}
+
+// offerIneligibility returns why o cannot be applied in ctx, or "" if it can.
+func offerIneligibility(o *models.Offer, ctx OfferContext) string {
+    switch {
+    case !offerActive(o, ctx.Date):
+        return "not valid on this date"
+    case o.DiscountType == models.DiscountFixed && o.Currency != ctx.Currency:
+        return "discount is in a different currency"
+    case o.NewStudentsOnly && !ctx.NewStudent:
+        return "for new students only"
+    case len(o.VenueIDs) > 0 && !containsID(o.VenueIDs, ctx.VenueID):
+        return "not valid at this venue"
+    case len(o.Sports) > 0 && !containsFold(o.Sports, ctx.Sport):
+        return "not valid for this sport"
+    case o.MinDurationDays > ctx.PlanDurationDays:
+        return "plan is shorter than the offer requires"
+    case (o.MinAge > 0 || o.MaxAge > 0) && ctx.StudentAge < 0:
+        return "student's date of birth is unknown"
+    case o.MinAge > 0 && ctx.StudentAge < o.MinAge:
+        return "student is too young"
+    case o.MaxAge > 0 && ctx.StudentAge > o.MaxAge:
+        return "student is too old"
+    case o.MaxUses > 0 && ctx.Uses[o.ID] >= int64(o.MaxUses):
+        return "usage cap reached"
+    }
+    return ""
+}
+
+// sortOffers orders offers by descending priority, then by ID so that the
+// result does not depend on input order.
+func sortOffers(offers []*models.Offer) {
+    sort.SliceStable(offers, func(i, j int) bool {
+        if offers[i].Priority != offers[j].Priority {
+            return offers[i].Priority > offers[j].Priority
+        }
+        return offers[i].ID.String() < offers[j].ID.String()
+    })
+}
+
+// priceBreakdown applies offers to base in order, each on the amount left by
+// the previous ones and never below zero, then works out tax on what remains.
+func priceBreakdown(base int, offers []*models.Offer, tax TaxTerms) PriceBreakdown {
+    b := PriceBreakdown{BaseCents: base, Discounts: []AppliedDiscount{}, TaxCode: tax.Code, TaxPct: tax.Pct, TaxInclusive: tax.Inclusive}
+    for _, o := range offers {
+        left := base - b.DiscountCents
+        amount := o.DiscountCents
+        if o.DiscountType != models.DiscountFixed {
+            amount = int(math.Round(float64(left) * o.DiscountPct / 100))
+        }
+        if amount > left {
+            amount = left
+        }
+        b.Discounts = append(b.Discounts, AppliedDiscount{
+            OfferID:      o.ID,
+            Name:         o.Name,
+            DiscountType: o.DiscountType,
+            DiscountPct:  o.DiscountPct,
+            AmountCents:  amount,
+        })
+        b.DiscountCents += amount
+    }
+    b.addTax(base - b.DiscountCents)
+    return b
+}
+
+// withSiblingDiscount takes pct off what is left of b after its offers and
+// works out the tax and total again.
+func withSiblingDiscount(b PriceBreakdown, pct float64) PriceBreakdown {
+    if pct <= 0 {
+        return b
+    }
+    net := b.BaseCents - b.DiscountCents
+    b.SiblingDiscountCents = int(math.Round(float64(net) * pct / 100))
+    b.DiscountCents += b.SiblingDiscountCents
+    b.addTax(net - b.SiblingDiscountCents)
+    return b
+}
+
+// addTax sets the taxable amount, tax and total for a discounted price of
+// net. A tax-inclusive net already contains the tax.
+func (b *PriceBreakdown) addTax(net int) {
+    if b.TaxInclusive {
+        b.TaxCents = int(math.Round(float64(net) * b.TaxPct / (100 + b.TaxPct)))
+        b.TaxableCents = net - b.TaxCents
+        b.TotalCents = net
+        return
+    }
+    b.TaxableCents = net
+    b.TaxCents = int(math.Round(float64(net) * b.TaxPct / 100))
+    b.TotalCents = net + b.TaxCents
+}
+
+// taxBreakdown is the tax part of b, to be kept with the amount charged.
+func (b PriceBreakdown) taxBreakdown() models.TaxBreakdown {
+    return models.TaxBreakdown{TaxCode: b.TaxCode, TaxPct: b.TaxPct, TaxableCents: b.TaxableCents, TaxCents: b.TaxCents}
+}
+
+// ageOn returns the age in whole years on day t of someone born on dob, or -1
+// if dob is unknown.
+func ageOn(dob *time.Time, t time.Time) int {
+    if dob == nil {
+        return -1
+    }
+    age := t.Year() - dob.Year()
+    if t.Month() < dob.Month() || (t.Month() == dob.Month() && t.Day() < dob.Day()) {
+        age--
+    }
+    return age
+}
+
+func containsID(ids []uuid.UUID, id uuid.UUID) bool {
+    for _, v := range ids {
+        if v == id {
+            return true
+        }
+    }
+    return false
+}
+
+func containsFold(list []string, s string) bool {
+    for _, v := range list {
+        if strings.EqualFold(strings.TrimSpace(v), strings.TrimSpace(s)) {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,306 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"spodemy-backend/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+    ruleDay    = time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+    ruleVenue  = uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+    otherVenue = uuid.MustParse("00000000-0000-0000-0000-00000000000b")
+)
+
+// ruleContext is a purchase every offer built by ruleOffer applies to.
+func ruleContext() OfferContext {
+    return OfferContext{
+        Date:             ruleDay,
+        VenueID:          ruleVenue,
+        Currency:         "INR",
+        Sport:            "Football",
+        PlanDurationDays: 90,
+        StudentAge:       12,
+        NewStudent:       true,
+        Uses:             map[uuid.UUID]int64{},
+    }
+}
+
+// ruleOffer is an unrestricted, exclusive offer of pct percent.
+func ruleOffer(id byte, pct float64) *models.Offer {
+    return &models.Offer{
+        ID:           uuid.UUID{15: id},
+        DiscountType: models.DiscountPercent,
+        DiscountPct:  pct,
+    }
+}
+
+// fixedOffer is an unrestricted, exclusive offer of cents in INR.
+func fixedOffer(id byte, cents int) *models.Offer {
+    return &models.Offer{
+        ID:            uuid.UUID{15: id},
+        DiscountType:  models.DiscountFixed,
+        DiscountCents: cents,
+        Currency:      "INR",
+    }
+}
+
+func TestOfferIneligibility(t *testing.T) {
+    tests := []struct {
+        name   string
+        offer  func(o *models.Offer)
+        ctx    func(c *OfferContext)
+        reason string
+    }{
+        {name: "unrestricted"},
+        {name: "starts later", offer: func(o *models.Offer) { o.ValidFrom = ruleDay.AddDate(0, 0, 1) }, reason: "not valid on this date"},
+        {name: "starts today", offer: func(o *models.Offer) { o.ValidFrom = ruleDay.Add(15 * time.Hour) }},
+        {name: "ended", offer: func(o *models.Offer) { o.ValidTo = ruleDay.AddDate(0, 0, -1) }, reason: "not valid on this date"},
+        {name: "ends today", offer: func(o *models.Offer) { o.ValidTo = ruleDay }},
+        {name: "fixed in another currency", offer: func(o *models.Offer) {
+            o.DiscountType, o.DiscountCents, o.Currency = models.DiscountFixed, 500, "USD"
+        }, reason: "discount is in a different currency"},
+        {name: "fixed in the plan's currency", offer: func(o *models.Offer) {
+            o.DiscountType, o.DiscountCents, o.Currency = models.DiscountFixed, 500, "INR"
+        }},
+        {name: "percent ignores currency", ctx: func(c *OfferContext) { c.Currency = "USD" }},
+        {name: "new students only, returning student", offer: func(o *models.Offer) { o.NewStudentsOnly = true },
+            ctx: func(c *OfferContext) { c.NewStudent = false }, reason: "for new students only"},
+        {name: "new students only, new student", offer: func(o *models.Offer) { o.NewStudentsOnly = true }},
+        {name: "other venue", offer: func(o *models.Offer) { o.VenueIDs = []uuid.UUID{otherVenue} }, reason: "not valid at this venue"},
+        {name: "listed venue", offer: func(o *models.Offer) { o.VenueIDs = []uuid.UUID{otherVenue, ruleVenue} }},
+        {name: "other sport", offer: func(o *models.Offer) { o.Sports = []string{"Cricket"} }, reason: "not valid for this sport"},
+        {name: "sport matched loosely", offer: func(o *models.Offer) { o.Sports = []string{" football "} }},
+        {name: "sport not known yet", offer: func(o *models.Offer) { o.Sports = []string{"Football"} },
+            ctx: func(c *OfferContext) { c.Sport = "" }, reason: "not valid for this sport"},
+        {name: "plan too short", offer: func(o *models.Offer) { o.MinDurationDays = 180 }, reason: "plan is shorter than the offer requires"},
+        {name: "plan just long enough", offer: func(o *models.Offer) { o.MinDurationDays = 90 }},
+        {name: "age unknown", offer: func(o *models.Offer) { o.MaxAge = 14 },
+            ctx: func(c *OfferContext) { c.StudentAge = -1 }, reason: "student's date of birth is unknown"},
+        {name: "too young", offer: func(o *models.Offer) { o.MinAge = 13 }, reason: "student is too young"},
+        {name: "too old", offer: func(o *models.Offer) { o.MaxAge = 11 }, reason: "student is too old"},
+        {name: "age at the bounds", offer: func(o *models.Offer) { o.MinAge, o.MaxAge = 12, 12 }},
+        {name: "usage cap reached", offer: func(o *models.Offer) { o.MaxUses = 3 },
+            ctx: func(c *OfferContext) { c.Uses[uuid.UUID{15: 1}] = 3 }, reason: "usage cap reached"},
+        {name: "usage below cap", offer: func(o *models.Offer) { o.MaxUses = 3 },
+            ctx: func(c *OfferContext) { c.Uses[uuid.UUID{15: 1}] = 2 }},
+        {name: "other offer's uses", offer: func(o *models.Offer) { o.MaxUses = 1 },
+            ctx: func(c *OfferContext) { c.Uses[uuid.UUID{15: 2}] = 5 }},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            o, ctx := ruleOffer(1, 10), ruleContext()
+            if tt.offer != nil {
+                tt.offer(o)
+            }
+            if tt.ctx != nil {
+                tt.ctx(&ctx)
+            }
+            if got := offerIneligibility(o, ctx); got != tt.reason {
+                t.Errorf("offerIneligibility() = %q, want %q", got, tt.reason)
+            }
+        })
+    }
+}
+
+func TestEvaluateOffers(t *testing.T) {
+    stackable := func(o *models.Offer) *models.Offer { o.Stackable = true; return o }
+    priority := func(o *models.Offer, p int) *models.Offer { o.Priority = p; return o }
+    usd := fixedOffer(9, 700)
+    usd.Currency = "USD"
+    capped := ruleOffer(8, 50)
+    capped.MaxUses = 1
+
+    type applied struct {
+        id    byte
+        cents int
+    }
+    tests := []struct {
+        name     string
+        base     int
+        offers   []*models.Offer
+        ctx      func(c *OfferContext)
+        tax      TaxTerms
+        applied  []applied
+        total    int
+        rejected map[byte]string
+    }{
+        {
+            name:  "no offers",
+            base:  10000,
+            total: 10000,
+        },
+        {
+            name:    "percent",
+            base:    10000,
+            offers:  []*models.Offer{ruleOffer(1, 10)},
+            applied: []applied{{1, 1000}},
+            total:   9000,
+        },
+        {
+            name:     "fixed beats smaller percent",
+            base:     10000,
+            offers:   []*models.Offer{ruleOffer(1, 10), fixedOffer(2, 1500)},
+            applied:  []applied{{2, 1500}},
+            total:    8500,
+            rejected: map[byte]string{1: reasonBetterOffer},
+        },
+        {
+            name:     "percent beats smaller fixed",
+            base:     10000,
+            offers:   []*models.Offer{fixedOffer(2, 500), ruleOffer(1, 10)},
+            applied:  []applied{{1, 1000}},
+            total:    9000,
+            rejected: map[byte]string{2: reasonBetterOffer},
+        },
+        {
+            name:    "fixed larger than the price",
+            base:    1000,
+            offers:  []*models.Offer{fixedOffer(2, 2500)},
+            applied: []applied{{2, 1000}},
+            total:   0,
+        },
+        {
+            name:     "stack beats exclusive",
+            base:     10000,
+            offers:   []*models.Offer{ruleOffer(1, 15), stackable(ruleOffer(2, 10)), stackable(ruleOffer(3, 10))},
+            applied:  []applied{{2, 1000}, {3, 900}},
+            total:    8100,
+            rejected: map[byte]string{1: reasonBetterOffer},
+        },
+        {
+            name:     "exclusive beats stack",
+            base:     10000,
+            offers:   []*models.Offer{stackable(ruleOffer(2, 5)), ruleOffer(1, 20), stackable(ruleOffer(3, 5))},
+            applied:  []applied{{1, 2000}},
+            total:    8000,
+            rejected: map[byte]string{2: reasonBetterOffer, 3: reasonBetterOffer},
+        },
+        {
+            name:     "exclusive offers are not combined",
+            base:     10000,
+            offers:   []*models.Offer{ruleOffer(1, 10), ruleOffer(2, 12)},
+            applied:  []applied{{2, 1200}},
+            total:    8800,
+            rejected: map[byte]string{1: reasonBetterOffer},
+        },
+        {
+            name: "stack applies in priority order",
+            base: 10000,
+            offers: []*models.Offer{
+                stackable(priority(fixedOffer(1, 1000), 1)),
+                stackable(priority(ruleOffer(2, 50), 2)),
+            },
+            applied: []applied{{2, 5000}, {1, 1000}},
+            total:   4000,
+        },
+        {
+            name:     "tie goes to the higher priority",
+            base:     10000,
+            offers:   []*models.Offer{priority(ruleOffer(1, 10), 1), priority(fixedOffer(2, 1000), 5)},
+            applied:  []applied{{2, 1000}},
+            total:    9000,
+            rejected: map[byte]string{1: reasonBetterOffer},
+        },
+        {
+            name:     "tie at equal priority goes to the lower ID",
+            base:     10000,
+            offers:   []*models.Offer{fixedOffer(2, 1000), ruleOffer(1, 10)},
+            applied:  []applied{{1, 1000}},
+            total:    9000,
+            rejected: map[byte]string{2: reasonBetterOffer},
+        },
+        {
+            name:     "currency mismatch is rejected",
+            base:     10000,
+            offers:   []*models.Offer{usd, ruleOffer(1, 5)},
+            applied:  []applied{{1, 500}},
+            total:    9500,
+            rejected: map[byte]string{9: "discount is in a different currency"},
+        },
+        {
+            name:     "usage cap is rejected",
+            base:     10000,
+            offers:   []*models.Offer{capped, ruleOffer(1, 5)},
+            ctx:      func(c *OfferContext) { c.Uses[capped.ID] = 1 },
+            applied:  []applied{{1, 500}},
+            total:    9500,
+            rejected: map[byte]string{8: "usage cap reached"},
+        },
+        {
+            name:     "nothing eligible",
+            base:     10000,
+            offers:   []*models.Offer{usd},
+            total:    10000,
+            rejected: map[byte]string{9: "discount is in a different currency"},
+        },
+        {
+            name:    "sibling discount comes off last",
+            base:    10000,
+            offers:  []*models.Offer{ruleOffer(1, 10)},
+            ctx:     func(c *OfferContext) { c.SiblingPct = 10 },
+            applied: []applied{{1, 1000}},
+            total:   8100,
+        },
+        {
+            name:    "tax on the discounted price",
+            base:    10000,
+            offers:  []*models.Offer{ruleOffer(1, 10)},
+            tax:     TaxTerms{Pct: 18},
+            applied: []applied{{1, 1000}},
+            total:   10620,
+        },
+        {
+            name:    "tax included in the price",
+            base:    11800,
+            offers:  []*models.Offer{fixedOffer(2, 1180)},
+            tax:     TaxTerms{Pct: 18, Inclusive: true},
+            applied: []applied{{2, 1180}},
+            total:   10620,
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            ctx := ruleContext()
+            if tt.ctx != nil {
+                tt.ctx(&ctx)
+            }
+            ev := EvaluateOffers(tt.base, tt.offers, ctx, tt.tax)
+
+            if len(ev.Applied) != len(tt.applied) || len(ev.Breakdown.Discounts) != len(tt.applied) {
+                t.Fatalf("applied %d offers with %d discounts, want %d", len(ev.Applied), len(ev.Breakdown.Discounts), len(tt.applied))
+            }
+            for i, want := range tt.applied {
+                d := ev.Breakdown.Discounts[i]
+                if ev.Applied[i].ID != (uuid.UUID{15: want.id}) || d.OfferID != ev.Applied[i].ID || d.AmountCents != want.cents {
+                    t.Errorf("discount %d = offer %s of %d cents, want offer %d of %d cents", i, d.OfferID, d.AmountCents, want.id, want.cents)
+                }
+            }
+            if ev.Breakdown.TotalCents != tt.total {
+                t.Errorf("total = %d, want %d", ev.Breakdown.TotalCents, tt.total)
+            }
+            if len(ev.Rejected) != len(tt.rejected) {
+                t.Fatalf("rejected %v, want %v", ev.Rejected, tt.rejected)
+            }
+            for _, r := range ev.Rejected {
+                if want, ok := tt.rejected[r.OfferID[15]]; !ok || r.Reason != want {
+                    t.Errorf("offer %s rejected with %q, want %q", r.OfferID, r.Reason, want)
+                }
+            }
+        })
+    }
+}
+
+// The winner must not depend on the order offers are attached in.
+func TestEvaluateOffersOrderIndependent(t *testing.T) {
+    a, b := ruleOffer(1, 10), fixedOffer(2, 1000)
+    for _, offers := range [][]*models.Offer{{a, b}, {b, a}} {
+        ev := EvaluateOffers(10000, offers, ruleContext(), TaxTerms{})
+        if len(ev.Applied) != 1 || ev.Applied[0].ID != a.ID {
+            t.Errorf("applied %v, want offer %s", ev.Applied, a.ID)
+        }
+    }
+}
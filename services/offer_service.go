@@ -1,20 +1,35 @@
 package services
 
 import (
+	"time"
+
+	"spodemy-backend/config"
 	"spodemy-backend/models"
 	"spodemy-backend/repositories"
 
 	"github.com/google/uuid"
 )
 
+// Offer errors.
+var (
+    ErrOfferDiscountType  = invalid("discount_type must be \"percent\" or \"fixed\"")
+    ErrOfferDiscountPct   = invalid("discount_pct must be greater than 0 and at most 100")
+    ErrOfferDiscountCents = invalid("discount_cents must be positive for a fixed offer")
+    ErrOfferAgeRange      = invalid("min_age and max_age must be non-negative and min_age at most max_age")
+    ErrOfferLimits        = invalid("max_uses and min_duration_days must not be negative")
+    ErrOfferPeriod        = invalid("valid_to must not be before valid_from")
+)
+
 // OfferService encapsulates business logic for offers.
 type OfferService struct {
     repo *repositories.OfferRepository
+    base string
 }
 
-// NewOfferService creates a new OfferService.
-func NewOfferService(repo *repositories.OfferRepository) *OfferService {
-    return &OfferService{repo: repo}
+// NewOfferService creates a new OfferService. Fixed offers are in the base
+// currency of cfg unless they name another.
+func NewOfferService(repo *repositories.OfferRepository, cfg config.CurrencyConfig) *OfferService {
+    return &OfferService{repo: repo, base: cfg.Base}
 }
 
 // List returns all offers.
@@ -29,15 +44,70 @@ func (s *OfferService) Get(id uuid.UUID) (*models.Offer, error) {
 
 // Create adds a new offer.
 func (s *OfferService) Create(offer *models.Offer) error {
+    if err := validateOffer(offer, s.base); err != nil {
+        return err
+    }
     return s.repo.Create(offer)
 }
 
 // Update modifies an offer.
 func (s *OfferService) Update(offer *models.Offer) error {
+    if _, err := s.repo.FindByID(offer.ID); err != nil {
+        return err
+    }
+    if err := validateOffer(offer, s.base); err != nil {
+        return err
+    }
     return s.repo.Update(offer)
 }
 
 // Delete removes an offer.
 func (s *OfferService) Delete(id uuid.UUID) error {
     return s.repo.Delete(id)
-}
\ No newline at end of file
+}
+
+// validateOffer checks an offer's discount and rules, defaulting it to a
+// percentage discount and a fixed discount to currency.
+func validateOffer(o *models.Offer, currency string) error {
+    if o.DiscountType == "" {
+        o.DiscountType = models.DiscountPercent
+    }
+    switch o.DiscountType {
+    case models.DiscountPercent:
+        if o.DiscountPct <= 0 || o.DiscountPct > 100 {
+            return ErrOfferDiscountPct
+        }
+        o.DiscountCents, o.Currency = 0, ""
+    case models.DiscountFixed:
+        if o.DiscountCents <= 0 {
+            return ErrOfferDiscountCents
+        }
+        var err error
+        if o.Currency, err = normalizeCurrency(o.Currency, currency); err != nil {
+            return err
+        }
+        o.DiscountPct = 0
+    default:
+        return ErrOfferDiscountType
+    }
+    if o.MinAge < 0 || o.MaxAge < 0 || (o.MaxAge > 0 && o.MinAge > o.MaxAge) {
+        return ErrOfferAgeRange
+    }
+    if o.MaxUses < 0 || o.MinDurationDays < 0 {
+        return ErrOfferLimits
+    }
+    if !o.ValidFrom.IsZero() && !o.ValidTo.IsZero() && o.ValidTo.Before(o.ValidFrom) {
+        return ErrOfferPeriod
+    }
+    return nil
+}
+
+// offerActive reports whether o can be applied on day t. A zero ValidTo
+// leaves the offer open-ended.
+func offerActive(o *models.Offer, t time.Time) bool {
+    day := dateOnly(t)
+    if !o.ValidFrom.IsZero() && day.Before(dateOnly(o.ValidFrom)) {
+        return false
+    }
+    return o.ValidTo.IsZero() || !day.After(dateOnly(o.ValidTo))
+}
@@ -1,20 +1,36 @@
 package services
 
 import (
+	"strings"
+	"time"
+
 	"spodemy-backend/models"
 	"spodemy-backend/repositories"
 
 	"github.com/google/uuid"
 )
 
+// Payment errors.
+var (
+    ErrCouponWithQuote = invalid("give either a quote_id or a coupon_code; a quote already includes its coupon")
+    ErrPaymentAmount   = invalid("amount_cents must be positive to apply a coupon")
+    ErrChargeWithQuote = invalid("a charge is paid at its own amount; do not give a quote_id")
+    ErrChargeMismatch  = invalid("charge does not belong to this enrollment")
+    ErrChargeAmount    = invalid("amount_cents must equal the charge amount")
+    ErrReversalReason  = invalid("reason is required")
+)
+
 // PaymentService encapsulates logic for fee payments.
 type PaymentService struct {
-    repo *repositories.PaymentRepository
+    repo          *repositories.PaymentRepository
+    enrollments   *EnrollmentService
+    pricing       *PricingService
+    subscriptions *SubscriptionService
 }
 
 // NewPaymentService creates a new PaymentService.
-func NewPaymentService(r *repositories.PaymentRepository) *PaymentService {
-    return &PaymentService{repo: r}
+func NewPaymentService(r *repositories.PaymentRepository, enrollments *EnrollmentService, pricing *PricingService, subscriptions *SubscriptionService) *PaymentService {
+    return &PaymentService{repo: r, enrollments: enrollments, pricing: pricing, subscriptions: subscriptions}
 }
 
 // List returns all payments.
@@ -32,17 +48,157 @@ func (s *PaymentService) Get(id uuid.UUID) (*models.FeePayment, error) {
     return s.repo.FindByID(id)
 }
 
-// Create adds a new payment.
+// Create adds a new payment and activates the enrollment if it was waiting
+// for one. With a quote ID the amount defaults to, and must equal, the quoted
+// total. With a coupon code the coupon's offer is taken off the amount and
+// the coupon is redeemed against this payment. With a charge ID the amount
+// defaults to, and must equal, the charge amount before any coupon, and the
+// charge is marked paid. The tax in the amount is recorded at the rate of
+// the enrollment or charge it pays for.
 func (s *PaymentService) Create(p *models.FeePayment) error {
-    return s.repo.Create(p)
+    e, err := s.prepare(p)
+    if err != nil {
+        return err
+    }
+    if err := s.repo.Create(p, e.StudentID); err != nil {
+        return fromRepo(err)
+    }
+    return s.enrollments.ActivateOnPayment(p.EnrollmentID)
+}
+
+// prepare applies the charge, quote or coupon of a payment about to be
+// recorded and returns its enrollment.
+func (s *PaymentService) prepare(p *models.FeePayment) (*models.Enrollment, error) {
+    p.CouponID = nil
+    p.DiscountCents = 0
+    e, err := s.enrollments.Get(p.EnrollmentID)
+    if err != nil {
+        return nil, err
+    }
+    tax := e.TaxBreakdown
+    if p.ChargeID != nil {
+        c, err := s.applyCharge(p)
+        if err != nil {
+            return nil, err
+        }
+        tax = c.TaxBreakdown
+    }
+    switch {
+    case p.QuoteID != "" && p.CouponCode != "":
+        return nil, ErrCouponWithQuote
+    case p.QuoteID != "":
+        if err := s.applyQuote(p, e); err != nil {
+            return nil, err
+        }
+    case p.CouponCode != "":
+        if err := s.applyCoupon(p, e); err != nil {
+            return nil, err
+        }
+    }
+    p.TaxBreakdown = tax.For(p.AmountCents)
+    return e, nil
 }
 
-// Update modifies a payment.
-func (s *PaymentService) Update(p *models.FeePayment) error {
-    return s.repo.Update(p)
+// Reverse cancels a payment recorded by mistake with a reversal entry and
+// returns the reversal. Payments are never edited or deleted.
+func (s *PaymentService) Reverse(id uuid.UUID, reason string) (*models.FeePayment, error) {
+    reason = strings.TrimSpace(reason)
+    if reason == "" {
+        return nil, ErrReversalReason
+    }
+    rev, err := s.repo.Reverse(id, reason)
+    if err != nil {
+        return nil, fromRepo(err)
+    }
+    return rev, nil
 }
 
-// Delete removes a payment.
-func (s *PaymentService) Delete(id uuid.UUID) error {
-    return s.repo.Delete(id)
-}
\ No newline at end of file
+// applyCharge checks that the charge is owed by the payment's enrollment and
+// that the amount matches it, and returns the charge.
+func (s *PaymentService) applyCharge(p *models.FeePayment) (*models.Charge, error) {
+    if p.QuoteID != "" {
+        return nil, ErrChargeWithQuote
+    }
+    c, err := s.subscriptions.GetCharge(*p.ChargeID)
+    if err != nil {
+        return nil, err
+    }
+    if c.EnrollmentID != p.EnrollmentID {
+        return nil, ErrChargeMismatch
+    }
+    if c.Status != models.ChargeDue && c.Status != models.ChargeOverdue {
+        return nil, ErrChargeSettled
+    }
+    if p.AmountCents == 0 {
+        p.AmountCents = c.AmountCents
+    }
+    if p.AmountCents != c.AmountCents {
+        return nil, ErrChargeAmount
+    }
+    return c, nil
+}
+
+// applyQuote checks that the quote was issued for the enrollment's student
+// and plan and that the amount matches it. A quoted coupon not yet redeemed
+// on the enrollment is redeemed against this payment.
+func (s *PaymentService) applyQuote(p *models.FeePayment, e *models.Enrollment) error {
+    q, err := s.pricing.verify(p.QuoteID, models.QuoteForPayment, time.Now())
+    if err != nil {
+        return err
+    }
+    p.QuoteNonce = &q.Nonce
+    if e.StudentID != q.StudentID || e.PlanID == nil || *e.PlanID != q.PlanID {
+        return ErrQuoteMismatch
+    }
+    if p.AmountCents == 0 {
+        p.AmountCents = q.TotalCents
+    }
+    if p.AmountCents != q.TotalCents {
+        return ErrQuoteAmount
+    }
+    if q.CouponID != nil {
+        used, err := s.pricing.coupons.IsRedeemedOn(*q.CouponID, e.ID)
+        if err != nil {
+            return err
+        }
+        if !used {
+            p.CouponID = q.CouponID
+        }
+    }
+    return nil
+}
+
+// applyCoupon takes the coupon's offer off the payment amount if it applies
+// to the enrollment's student, plan and batch.
+func (s *PaymentService) applyCoupon(p *models.FeePayment, e *models.Enrollment) error {
+    c, err := s.pricing.resolveCoupon(p.CouponCode, e.StudentID, time.Now())
+    if err != nil {
+        return err
+    }
+    if p.AmountCents <= 0 {
+        return ErrPaymentAmount
+    }
+    duration := 0
+    if plan := pinnedPlan(e.Plan, e.PlanVersion); plan != nil {
+        duration = plan.DurationDays
+    }
+    date := p.PaidOn
+    if date.IsZero() {
+        date = time.Now()
+    }
+    offers := []*models.Offer{c.Offer}
+    ctx, err := s.pricing.offerContext(duration, offers, e.StudentID, e.Batch.VenueID, &e.Batch, date, e.ID)
+    if err != nil {
+        return err
+    }
+    ctx.SiblingPct = 0 // already in the enrollment price
+    ctx.Currency = e.Currency
+    ev := EvaluateOffers(p.AmountCents, offers, ctx, TaxTerms{})
+    if len(ev.Rejected) > 0 {
+        return invalid("coupon does not apply: " + ev.Rejected[0].Reason)
+    }
+    p.CouponID = &c.ID
+    p.DiscountCents = ev.Breakdown.DiscountCents
+    p.AmountCents = ev.Breakdown.TotalCents
+    return nil
+}
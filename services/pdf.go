@@ -0,0 +1,182 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A4 page size in points.
+const (
+    pdfPageWidth  = 595.0
+    pdfPageHeight = 842.0
+)
+
+// Widths of the printable ASCII characters, from space to '~', in
+// thousandths of the font size for the standard Helvetica fonts.
+var (
+    helveticaWidths = [95]int{
+        278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+        556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+        1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+        667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+        333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+        556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+    }
+    helveticaBoldWidths = [95]int{
+        278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+        556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+        975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+        667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+        333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+        611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+    }
+)
+
+// pdfDoc builds a PDF of A4 pages using the standard Helvetica fonts, so
+// that nothing needs to be embedded. Coordinates are in points from the
+// bottom-left corner of the page.
+type pdfDoc struct {
+    title string
+    pages []*bytes.Buffer
+}
+
+func newPDFDoc(title string) *pdfDoc {
+    d := &pdfDoc{title: title}
+    d.addPage()
+    return d
+}
+
+// addPage starts a new page; later drawing goes on it.
+func (d *pdfDoc) addPage() {
+    d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+func (d *pdfDoc) page() *bytes.Buffer {
+    return d.pages[len(d.pages)-1]
+}
+
+// text draws s with its baseline starting at x, y.
+func (d *pdfDoc) text(x, y, size float64, bold bool, s string) {
+    font := "F1"
+    if bold {
+        font = "F2"
+    }
+    fmt.Fprintf(d.page(), "BT /%s %s Tf %s %s Td (%s) Tj ET\n", font, pdfNum(size), pdfNum(x), pdfNum(y), pdfEscape(s))
+}
+
+// textRight draws s so that it ends at x.
+func (d *pdfDoc) textRight(x, y, size float64, bold bool, s string) {
+    d.text(x-pdfTextWidth(s, size, bold), y, size, bold, s)
+}
+
+// fillColor sets the color of later text and rectangles.
+func (d *pdfDoc) fillColor(c [3]float64) {
+    fmt.Fprintf(d.page(), "%s %s %s rg\n", pdfNum(c[0]), pdfNum(c[1]), pdfNum(c[2]))
+}
+
+// rect fills a rectangle in the current fill color.
+func (d *pdfDoc) rect(x, y, w, h float64) {
+    fmt.Fprintf(d.page(), "%s %s %s %s re f\n", pdfNum(x), pdfNum(y), pdfNum(w), pdfNum(h))
+}
+
+// line draws a thin gray line.
+func (d *pdfDoc) line(x1, y1, x2, y2 float64) {
+    fmt.Fprintf(d.page(), "0.8 0.8 0.8 RG 0.5 w %s %s m %s %s l S\n", pdfNum(x1), pdfNum(y1), pdfNum(x2), pdfNum(y2))
+}
+
+// bytes lays out the document's objects and cross-reference table.
+func (d *pdfDoc) bytes() []byte {
+    var out bytes.Buffer
+    var offsets []int
+    obj := func(body string) {
+        offsets = append(offsets, out.Len())
+        fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+    }
+
+    out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+    // 1 catalog, 2 page tree, 3 and 4 fonts, 5 info, then a page and its
+    // content stream for each page.
+    kids := make([]string, len(d.pages))
+    for i := range d.pages {
+        kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+    }
+    obj("<< /Type /Catalog /Pages 2 0 R >>")
+    obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+    obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+    obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+    obj(fmt.Sprintf("<< /Title (%s) /CreationDate (D:%s) >>", pdfEscape(d.title), time.Now().UTC().Format("20060102150405Z")))
+    for i, p := range d.pages {
+        obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+            pdfNum(pdfPageWidth), pdfNum(pdfPageHeight), 7+2*i))
+        obj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.Len(), p.String()))
+    }
+
+    xref := out.Len()
+    fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+    for _, off := range offsets {
+        fmt.Fprintf(&out, "%010d 00000 n \n", off)
+    }
+    fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+    return out.Bytes()
+}
+
+// pdfTextWidth is the width of s in points.
+func pdfTextWidth(s string, size float64, bold bool) float64 {
+    widths := &helveticaWidths
+    if bold {
+        widths = &helveticaBoldWidths
+    }
+    total := 0
+    for _, r := range s {
+        if r >= ' ' && r <= '~' {
+            total += widths[r-' ']
+        } else {
+            total += 556
+        }
+    }
+    return float64(total) * size / 1000
+}
+
+// pdfEscape encodes s as the body of a PDF string in WinAnsiEncoding.
+// Characters outside Latin-1 become '?'.
+func pdfEscape(s string) string {
+    var b strings.Builder
+    for _, r := range s {
+        switch {
+        case r == '(' || r == ')' || r == '\\':
+            b.WriteByte('\\')
+            b.WriteRune(r)
+        case r >= ' ' && r <= '~':
+            b.WriteRune(r)
+        case r >= 0xa0 && r <= 0xff:
+            fmt.Fprintf(&b, "\\%03o", r)
+        default:
+            b.WriteByte('?')
+        }
+    }
+    return b.String()
+}
+
+func pdfNum(f float64) string {
+    return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// hexColor parses "#rrggbb" into PDF color components, falling back to black.
+func hexColor(s string) [3]float64 {
+    var c [3]float64
+    s = strings.TrimPrefix(s, "#")
+    if len(s) != 6 {
+        return c
+    }
+    for i := range c {
+        v, err := strconv.ParseUint(s[2*i:2*i+2], 16, 8)
+        if err != nil {
+            return [3]float64{}
+        }
+        c[i] = float64(v) / 255
+    }
+    return c
+}
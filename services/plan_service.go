@@ -1,20 +1,40 @@
 package services
 
 import (
+	"errors"
+	"math"
+	"strings"
+
+	"spodemy-backend/config"
 	"spodemy-backend/models"
 	"spodemy-backend/repositories"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Plan errors.
+var (
+    ErrBillingInterval     = invalid("billing_interval must be empty, \"weekly\", \"monthly\" or \"yearly\"")
+    ErrAutoRenewOneOffPlan = invalid("auto_renew needs a billing_interval")
+    ErrInstallmentCount    = invalid("installments must have between 2 and 12 parts")
+    ErrInstallmentPct      = invalid("installment pct must be positive and add up to 100")
+    ErrInstallmentDays     = invalid("installment due_after_days must start at 0 or later, increase, and fall within duration_days")
+    ErrOfferCurrency       = invalid("fixed offer is in a different currency from the plan")
 )
 
 // PlanService encapsulates business logic for plans.
 type PlanService struct {
-    repo *repositories.PlanRepository
+    repo   *repositories.PlanRepository
+    offers *repositories.OfferRepository
+    taxes  *repositories.TaxRepository
+    base   string
 }
 
-// NewPlanService creates a new PlanService.
-func NewPlanService(repo *repositories.PlanRepository) *PlanService {
-    return &PlanService{repo: repo}
+// NewPlanService creates a new PlanService. Plans are priced in the base
+// currency of cfg unless they name another.
+func NewPlanService(repo *repositories.PlanRepository, offers *repositories.OfferRepository, taxes *repositories.TaxRepository, cfg config.CurrencyConfig) *PlanService {
+    return &PlanService{repo: repo, offers: offers, taxes: taxes, base: cfg.Base}
 }
 
 // List returns all plans.
@@ -29,25 +49,126 @@ func (s *PlanService) Get(id uuid.UUID) (*models.Plan, error) {
 
 // Create adds a new plan.
 func (s *PlanService) Create(plan *models.Plan) error {
+    if err := s.validate(plan, s.base); err != nil {
+        return err
+    }
     return s.repo.Create(plan)
 }
 
-// Update modifies a plan.
+// Update modifies a plan. A plan left without a currency keeps its own.
 func (s *PlanService) Update(plan *models.Plan) error {
+    old, err := s.repo.FindByID(plan.ID)
+    if err != nil {
+        return err
+    }
+    if err := s.validate(plan, old.Currency); err != nil {
+        return err
+    }
     return s.repo.Update(plan)
 }
 
+// Versions returns a plan's price history, newest version first.
+func (s *PlanService) Versions(id uuid.UUID) ([]models.PlanVersion, error) {
+    if _, err := s.repo.FindByID(id); err != nil {
+        return nil, err
+    }
+    return s.repo.FindVersions(id)
+}
+
 // Delete removes a plan.
 func (s *PlanService) Delete(id uuid.UUID) error {
     return s.repo.Delete(id)
 }
 
-// AttachOffer associates an offer with a plan.
+// AttachOffer associates an existing offer with a plan.
 func (s *PlanService) AttachOffer(planID, offerID uuid.UUID) error {
+    plan, err := s.repo.FindByID(planID)
+    if err != nil {
+        return err
+    }
+    o, err := s.offers.FindByID(offerID)
+    if err != nil {
+        return err
+    }
+    if o.DiscountType == models.DiscountFixed && o.Currency != plan.Currency {
+        return ErrOfferCurrency
+    }
     return s.repo.AttachOffer(planID, offerID)
 }
 
 // DetachOffer removes the association.
 func (s *PlanService) DetachOffer(planID, offerID uuid.UUID) error {
     return s.repo.DetachOffer(planID, offerID)
-}
\ No newline at end of file
+}
+
+// validate checks a plan's currency, defaulting it to currency, its billing
+// settings and installments and that its tax code exists.
+func (s *PlanService) validate(plan *models.Plan, currency string) error {
+    var err error
+    if plan.Currency, err = normalizeCurrency(plan.Currency, currency); err != nil {
+        return err
+    }
+    if err := validatePlan(plan); err != nil {
+        return err
+    }
+    if plan.TaxCode == "" {
+        return nil
+    }
+    plan.TaxCode = strings.ToLower(strings.TrimSpace(plan.TaxCode))
+    _, err = s.taxes.FindByCode(plan.TaxCode)
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return ErrTaxUnknown
+    }
+    return err
+}
+
+// validatePlan checks the billing settings and installments of a plan.
+func validatePlan(plan *models.Plan) error {
+    switch plan.BillingInterval {
+    case "", models.BillingWeekly, models.BillingMonthly, models.BillingYearly:
+    default:
+        return ErrBillingInterval
+    }
+    if plan.AutoRenew && plan.BillingInterval == "" {
+        return ErrAutoRenewOneOffPlan
+    }
+    return validateInstallments(plan)
+}
+
+// validateInstallments checks that a plan's installments split its whole
+// price and fall due in order within its duration. Renewals of a plan with
+// installments are still charged in full.
+func validateInstallments(plan *models.Plan) error {
+    if len(plan.Installments) == 0 {
+        return nil
+    }
+    if len(plan.Installments) < 2 || len(plan.Installments) > 12 {
+        return ErrInstallmentCount
+    }
+    total := 0.0
+    for i, in := range plan.Installments {
+        if in.Pct <= 0 {
+            return ErrInstallmentPct
+        }
+        total += in.Pct
+        if in.DueAfterDays < 0 || (i > 0 && in.DueAfterDays <= plan.Installments[i-1].DueAfterDays) ||
+            (plan.DurationDays > 0 && in.DueAfterDays >= plan.DurationDays) {
+            return ErrInstallmentDays
+        }
+    }
+    if math.Abs(total-100) > 0.001 {
+        return ErrInstallmentPct
+    }
+    return nil
+}
+
+// pinnedPlan returns plan with the terms of version v, or plan itself if v
+// is nil or belongs to another plan.
+func pinnedPlan(plan *models.Plan, v *models.PlanVersion) *models.Plan {
+    if plan == nil || v == nil || v.PlanID != plan.ID {
+        return plan
+    }
+    p := *plan
+    p.PlanTerms = v.PlanTerms
+    return &p
+}
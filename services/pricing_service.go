@@ -0,0 +1,431 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"spodemy-backend/config"
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Quote errors.
+var (
+    ErrQuoteInvalid  = invalid("quote ID is invalid")
+    ErrQuoteExpired  = invalid("quote has expired, request a new one")
+    ErrQuoteMismatch = invalid("quote was issued for a different student, plan, offer or venue")
+    ErrQuoteAmount   = invalid("amount does not match the quote")
+)
+
+// Coupon errors.
+var (
+    ErrCouponInvalid = invalid("coupon code is not valid")
+    ErrCouponExpired = invalid("coupon has expired")
+)
+
+// QuoteRequest identifies who is buying a plan, where and from when.
+type QuoteRequest struct {
+    StudentID  uuid.UUID  `json:"student_id" binding:"required"`
+    VenueID    uuid.UUID  `json:"venue_id" binding:"required"`
+    BatchID    *uuid.UUID `json:"batch_id"` // optional; needed for sport-specific offers
+    Date       *time.Time `json:"date"`     // defaults to today
+    CouponCode string     `json:"coupon_code"`
+}
+
+// AppliedDiscount is one offer taken off the base price.
+type AppliedDiscount struct {
+    OfferID      uuid.UUID `json:"offer_id"`
+    Name         string    `json:"name,omitempty"`
+    DiscountType string    `json:"discount_type"`
+    DiscountPct  float64   `json:"discount_pct,omitempty"`
+    AmountCents  int       `json:"amount_cents"`
+}
+
+// PriceBreakdown shows how a final amount is made up, in cents.
+type PriceBreakdown struct {
+    BaseCents            int               `json:"base_cents"`
+    Discounts            []AppliedDiscount `json:"discounts"`
+    DiscountCents        int               `json:"discount_cents"` // offers plus sibling discount
+    SiblingDiscountCents int               `json:"sibling_discount_cents"`
+    TaxCode              string            `json:"tax_code,omitempty"`
+    TaxPct               float64           `json:"tax_pct"`
+    TaxInclusive         bool              `json:"tax_inclusive"`
+    TaxableCents         int               `json:"taxable_cents"` // price before tax, after discounts
+    TaxCents             int               `json:"tax_cents"`
+    TotalCents           int               `json:"total_cents"`
+}
+
+// Quote is a priced plan for a student. Its ID is signed and can be handed
+// to one enrollment creation and one payment recording until ExpiresAt.
+type Quote struct {
+    ID        string     `json:"quote_id"`
+    PlanID    uuid.UUID  `json:"plan_id"`
+    StudentID uuid.UUID  `json:"student_id"`
+    VenueID   uuid.UUID  `json:"venue_id"`
+    BatchID   *uuid.UUID `json:"batch_id,omitempty"`
+    CouponID  *uuid.UUID `json:"coupon_id,omitempty"` // set if the coupon's offer was applied
+    Date      time.Time  `json:"date"`
+    Currency  string     `json:"currency"` // the plan's
+    PriceBreakdown
+    Skipped   []RejectedOffer `json:"skipped_offers"`
+    ExpiresAt time.Time       `json:"expires_at"`
+}
+
+// quoteClaims is the signed content of a quote ID.
+type quoteClaims struct {
+    Nonce      uuid.UUID           `json:"n"` // tells quotes apart when recording their use
+    PlanID     uuid.UUID           `json:"p"`
+    StudentID  uuid.UUID           `json:"s"`
+    VenueID    uuid.UUID           `json:"v"`
+    BatchID    *uuid.UUID          `json:"b,omitempty"`
+    OfferIDs   []uuid.UUID         `json:"o,omitempty"`
+    CouponID   *uuid.UUID          `json:"c,omitempty"`
+    Date       time.Time           `json:"d"`
+    BaseCents  int                 `json:"bc"`
+    Discounts  []int               `json:"a,omitempty"`  // amount taken off by each offer in OfferIDs
+    Sibling    int                 `json:"sd,omitempty"` // sibling discount
+    Tax        models.TaxBreakdown `json:"x"`
+    TotalCents int                 `json:"t"`
+    ExpiresAt  time.Time           `json:"e"`
+}
+
+// offerID is the offer recorded on an enrollment made from the quote.
+func (q *quoteClaims) offerID() *uuid.UUID {
+    if len(q.OfferIDs) == 0 {
+        return nil
+    }
+    id := q.OfferIDs[0]
+    return &id
+}
+
+// breakdown is the price the quote was signed for, with the amount of each
+// offer applied.
+func (q *quoteClaims) breakdown() PriceBreakdown {
+    b := PriceBreakdown{
+        BaseCents:            q.BaseCents,
+        Discounts:            make([]AppliedDiscount, len(q.OfferIDs)),
+        SiblingDiscountCents: q.Sibling,
+        DiscountCents:        q.Sibling,
+        TaxCode:              q.Tax.TaxCode,
+        TaxPct:               q.Tax.TaxPct,
+        TaxableCents:         q.Tax.TaxableCents,
+        TaxCents:             q.Tax.TaxCents,
+        TotalCents:           q.TotalCents,
+    }
+    for i, id := range q.OfferIDs {
+        b.Discounts[i] = AppliedDiscount{OfferID: id}
+        if i < len(q.Discounts) {
+            b.Discounts[i].AmountCents = q.Discounts[i]
+            b.DiscountCents += q.Discounts[i]
+        }
+    }
+    return b
+}
+
+// PricingService prices plans and signs quotes.
+type PricingService struct {
+    plans       *repositories.PlanRepository
+    venues      *repositories.VenueRepository
+    users       *repositories.UserRepository
+    batches     *repositories.BatchRepository
+    enrollments *repositories.EnrollmentRepository
+    coupons     *repositories.CouponRepository
+    families    *repositories.FamilyRepository
+    taxes       *repositories.TaxRepository
+    quotes      *repositories.QuoteRepository
+    cfg         config.PricingConfig
+    family      config.FamilyConfig
+}
+
+// NewPricingService creates a new PricingService.
+func NewPricingService(plans *repositories.PlanRepository, venues *repositories.VenueRepository, users *repositories.UserRepository, batches *repositories.BatchRepository, enrollments *repositories.EnrollmentRepository, coupons *repositories.CouponRepository, families *repositories.FamilyRepository, taxes *repositories.TaxRepository, quotes *repositories.QuoteRepository, cfg config.PricingConfig, family config.FamilyConfig) *PricingService {
+    return &PricingService{plans: plans, venues: venues, users: users, batches: batches, enrollments: enrollments, coupons: coupons, families: families, taxes: taxes, quotes: quotes, cfg: cfg, family: family}
+}
+
+// Quote prices a plan for a student on a date, applying the best combination
+// of the plan's offers, and the coupon's if one is given, that the student is
+// eligible for.
+func (s *PricingService) Quote(planID uuid.UUID, req QuoteRequest) (*Quote, error) {
+    plan, err := s.plans.FindByID(planID)
+    if err != nil {
+        return nil, err
+    }
+    venue, err := s.venues.FindByID(req.VenueID)
+    if err != nil {
+        return nil, err
+    }
+    if venue.Currency != plan.Currency {
+        return nil, ErrCurrencyMismatch
+    }
+    var batch *models.Batch
+    if req.BatchID != nil {
+        if batch, err = s.batches.FindByID(*req.BatchID); err != nil {
+            return nil, err
+        }
+        if batch.VenueID != req.VenueID {
+            return nil, ErrQuoteMismatch
+        }
+    }
+    date := dateOnly(time.Now())
+    if req.Date != nil {
+        date = dateOnly(*req.Date)
+    }
+    offers := plan.Offers
+    var coupon *models.Coupon
+    if req.CouponCode != "" {
+        if coupon, err = s.resolveCoupon(req.CouponCode, req.StudentID, time.Now()); err != nil {
+            return nil, err
+        }
+        if planOffer(plan, coupon.OfferID) == nil {
+            offers = append(append([]*models.Offer{}, offers...), coupon.Offer)
+        }
+    }
+    ctx, err := s.offerContext(plan.DurationDays, offers, req.StudentID, req.VenueID, batch, date, uuid.Nil)
+    if err != nil {
+        return nil, err
+    }
+    ctx.Currency = plan.Currency
+    tax, err := s.taxFor(plan)
+    if err != nil {
+        return nil, err
+    }
+    ev := EvaluateOffers(plan.PriceCents, offers, ctx, tax)
+    if coupon != nil {
+        for _, r := range ev.Rejected {
+            if r.OfferID == coupon.OfferID && r.Reason != reasonBetterOffer {
+                return nil, invalid("coupon does not apply: " + r.Reason)
+            }
+        }
+    }
+
+    q := &Quote{
+        PlanID:         plan.ID,
+        StudentID:      req.StudentID,
+        VenueID:        req.VenueID,
+        BatchID:        req.BatchID,
+        Date:           date,
+        Currency:       plan.Currency,
+        PriceBreakdown: ev.Breakdown,
+        Skipped:        ev.Rejected,
+        ExpiresAt:      time.Now().Add(time.Duration(s.cfg.QuoteTTLMinutes) * time.Minute).Truncate(time.Second),
+    }
+    claims := quoteClaims{
+        Nonce:      uuid.New(),
+        PlanID:     q.PlanID,
+        StudentID:  q.StudentID,
+        VenueID:    q.VenueID,
+        BatchID:    q.BatchID,
+        Date:       q.Date,
+        BaseCents:  q.BaseCents,
+        Sibling:    q.SiblingDiscountCents,
+        Tax:        q.taxBreakdown(),
+        TotalCents: q.TotalCents,
+        ExpiresAt:  q.ExpiresAt,
+    }
+    for _, d := range q.Discounts {
+        claims.OfferIDs = append(claims.OfferIDs, d.OfferID)
+        claims.Discounts = append(claims.Discounts, d.AmountCents)
+        if coupon != nil && d.OfferID == coupon.OfferID {
+            q.CouponID = &coupon.ID
+            claims.CouponID = q.CouponID
+        }
+    }
+    if q.ID, err = s.sign(claims); err != nil {
+        return nil, err
+    }
+    return q, nil
+}
+
+// priceFor applies offers to plan's price in order, then the sibling
+// discount of ctx, and works out tax on the discounted amount.
+func (s *PricingService) priceFor(plan *models.Plan, offers []*models.Offer, ctx OfferContext) (PriceBreakdown, error) {
+    tax, err := s.taxFor(plan)
+    if err != nil {
+        return PriceBreakdown{}, err
+    }
+    return withSiblingDiscount(priceBreakdown(plan.PriceCents, offers, tax), ctx.SiblingPct), nil
+}
+
+// taxFor returns the tax plan is priced with: the rate of its tax code, or
+// the configured default rate if it has none.
+func (s *PricingService) taxFor(plan *models.Plan) (TaxTerms, error) {
+    tax := TaxTerms{Code: plan.TaxCode, Pct: s.cfg.TaxPct, Inclusive: plan.TaxInclusive}
+    if plan.TaxCode == "" {
+        return tax, nil
+    }
+    rate, err := s.taxes.FindByCode(plan.TaxCode)
+    if err != nil {
+        return tax, err
+    }
+    tax.Pct = rate.Pct
+    return tax, nil
+}
+
+// siblingPct is the family discount for a student with the given number of
+// siblings already enrolled.
+func (s *PricingService) siblingPct(siblings int64) float64 {
+    switch {
+    case siblings == 1:
+        return s.family.SecondChildDiscountPct
+    case siblings > 1:
+        return s.family.FurtherChildDiscountPct
+    }
+    return 0
+}
+
+// offerContext gathers what offer eligibility and the sibling discount
+// depend on for a student buying a plan of durationDays. The enrollment
+// exclude, if being repriced, counts neither as an earlier enrollment nor as
+// an offer use.
+func (s *PricingService) offerContext(durationDays int, offers []*models.Offer, studentID, venueID uuid.UUID, batch *models.Batch, date time.Time, exclude uuid.UUID) (OfferContext, error) {
+    student, err := s.users.FindByID(studentID)
+    if err != nil {
+        return OfferContext{}, err
+    }
+    earlier, err := s.enrollments.CountRegularByStudent(studentID, exclude)
+    if err != nil {
+        return OfferContext{}, err
+    }
+    ids := make([]uuid.UUID, len(offers))
+    for i, o := range offers {
+        ids[i] = o.ID
+    }
+    uses, err := s.enrollments.CountOfferUses(ids, exclude)
+    if err != nil {
+        return OfferContext{}, err
+    }
+    siblings, err := s.families.CountEnrolledSiblings(studentID, exclude)
+    if err != nil {
+        return OfferContext{}, err
+    }
+    ctx := OfferContext{
+        Date:             dateOnly(date),
+        VenueID:          venueID,
+        PlanDurationDays: durationDays,
+        StudentAge:       ageOn(student.DateOfBirth, date),
+        NewStudent:       earlier == 0,
+        Uses:             uses,
+        SiblingPct:       s.siblingPct(siblings),
+    }
+    if batch != nil {
+        ctx.Sport = batch.Sport
+    }
+    return ctx, nil
+}
+
+// resolveCoupon looks up a code and checks that studentID may still redeem
+// it. The limits are checked again, under lock, when the redemption is saved.
+func (s *PricingService) resolveCoupon(code string, studentID uuid.UUID, now time.Time) (*models.Coupon, error) {
+    c, err := s.coupons.FindByCode(normalizeCode(code))
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, ErrCouponInvalid
+    }
+    if err != nil {
+        return nil, err
+    }
+    if c.Disabled || c.Offer == nil {
+        return nil, ErrCouponInvalid
+    }
+    if c.ExpiresAt != nil && now.After(*c.ExpiresAt) {
+        return nil, ErrCouponExpired
+    }
+    total, byUser, err := s.coupons.CountRedemptions(c.ID, studentID)
+    if err != nil {
+        return nil, err
+    }
+    if c.MaxRedemptions > 0 && total >= int64(c.MaxRedemptions) {
+        return nil, ErrCouponUsedUp
+    }
+    if c.MaxPerUser > 0 && byUser >= int64(c.MaxPerUser) {
+        return nil, ErrCouponUserLimit
+    }
+    return c, nil
+}
+
+// quotedOffers returns the offers a quote was priced with, in order. They
+// must still be attached to the plan or be the quote's coupon offer; the
+// amounts they took off are the quoted ones, not recomputed.
+func (s *PricingService) quotedOffers(plan *models.Plan, q *quoteClaims) ([]*models.Offer, error) {
+    var coupon *models.Coupon
+    if q.CouponID != nil {
+        c, err := s.coupons.FindByID(*q.CouponID)
+        if err != nil {
+            return nil, err
+        }
+        coupon = c
+    }
+    var offers []*models.Offer
+    for _, id := range q.OfferIDs {
+        o := planOffer(plan, id)
+        if o == nil && coupon != nil && coupon.OfferID == id {
+            o = coupon.Offer
+        }
+        if o == nil {
+            return nil, ErrOfferNotOnPlan
+        }
+        offers = append(offers, o)
+    }
+    return offers, nil
+}
+
+// normalizeCode puts a coupon code in its stored form.
+func normalizeCode(code string) string {
+    return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// sign encodes claims as base64url JSON followed by its HMAC-SHA256.
+func (s *PricingService) sign(claims quoteClaims) (string, error) {
+    payload, err := json.Marshal(claims)
+    if err != nil {
+        return "", err
+    }
+    body := base64.RawURLEncoding.EncodeToString(payload)
+    return body + "." + base64.RawURLEncoding.EncodeToString(s.mac(body)), nil
+}
+
+// verify checks the signature and expiry of a quote ID, and that it was not
+// used for purpose yet, and returns its claims. The use is recorded when the
+// enrollment or payment it prices is saved.
+func (s *PricingService) verify(id, purpose string, now time.Time) (*quoteClaims, error) {
+    body, sig, ok := strings.Cut(id, ".")
+    if !ok {
+        return nil, ErrQuoteInvalid
+    }
+    mac, err := base64.RawURLEncoding.DecodeString(sig)
+    if err != nil || !hmac.Equal(mac, s.mac(body)) {
+        return nil, ErrQuoteInvalid
+    }
+    payload, err := base64.RawURLEncoding.DecodeString(body)
+    if err != nil {
+        return nil, ErrQuoteInvalid
+    }
+    var claims quoteClaims
+    if err := json.Unmarshal(payload, &claims); err != nil || claims.Nonce == uuid.Nil {
+        return nil, ErrQuoteInvalid
+    }
+    if now.After(claims.ExpiresAt) {
+        return nil, ErrQuoteExpired
+    }
+    used, err := s.quotes.IsUsed(claims.Nonce, purpose)
+    if err != nil {
+        return nil, err
+    }
+    if used {
+        return nil, ErrQuoteUsed
+    }
+    return &claims, nil
+}
+
+func (s *PricingService) mac(body string) []byte {
+    h := hmac.New(sha256.New, []byte(s.cfg.QuoteSecret))
+    h.Write([]byte(body))
+    return h.Sum(nil)
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"spodemy-backend/config"
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+
+	"github.com/google/uuid"
+)
+
+// ErrReceiptNoEmail is returned when a receipt has nowhere to be sent.
+var ErrReceiptNoEmail = invalid("the student has no email address; give one to send the receipt to")
+
+// receiptBatch caps how many receipts one delivery run sends.
+const receiptBatch = 100
+
+// ReceiptService renders payment receipts and mails them. Receipts are
+// numbered by the repositories together with the payment.
+type ReceiptService struct {
+    repo        *repositories.ReceiptRepository
+    mailer      Mailer
+    cfg         config.InvoiceConfig
+    maxAttempts int
+}
+
+// NewReceiptService creates a new ReceiptService.
+func NewReceiptService(r *repositories.ReceiptRepository, mailer Mailer, cfg config.InvoiceConfig, mail config.MailConfig) *ReceiptService {
+    return &ReceiptService{repo: r, mailer: mailer, cfg: cfg, maxAttempts: mail.MaxAttempts}
+}
+
+// Get returns the receipt of a payment, rendering it if it has not been yet.
+func (s *ReceiptService) Get(paymentID uuid.UUID) (*models.Receipt, error) {
+    rc, err := s.repo.FindByPayment(paymentID)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.render(rc); err != nil {
+        return nil, err
+    }
+    return rc, nil
+}
+
+// Resend mails the receipt of a payment again, to addr if given or else to
+// the student.
+func (s *ReceiptService) Resend(paymentID uuid.UUID, addr string) (*models.Receipt, error) {
+    rc, err := s.repo.FindByPayment(paymentID)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.deliver(rc, strings.TrimSpace(addr)); err != nil {
+        return nil, err
+    }
+    return rc, nil
+}
+
+// DeliverPending mails the receipts still pending, retrying failed ones
+// until they run out of attempts, and returns how many were sent.
+func (s *ReceiptService) DeliverPending() (int, error) {
+    ids, err := s.repo.FindPending(receiptBatch)
+    if err != nil {
+        return 0, err
+    }
+    sent := 0
+    for _, id := range ids {
+        rc, err := s.repo.FindByID(id)
+        if err != nil {
+            return sent, err
+        }
+        if err := s.deliver(rc, ""); err != nil {
+            log.Printf("receipt %s: %v", rc.Number, err)
+            continue
+        }
+        sent++
+    }
+    return sent, nil
+}
+
+// deliver renders rc if needed and mails it, recording the outcome.
+func (s *ReceiptService) deliver(rc *models.Receipt, addr string) error {
+    if err := s.render(rc); err != nil {
+        return err
+    }
+    if addr == "" && rc.Student != nil {
+        addr = rc.Student.Email
+    }
+    if addr == "" {
+        if err := s.repo.MarkAttemptFailed(rc, ErrReceiptNoEmail.Error(), true); err != nil {
+            return err
+        }
+        return ErrReceiptNoEmail
+    }
+    if err := s.mailer.Send(s.mail(rc, addr)); err != nil {
+        if ferr := s.repo.MarkAttemptFailed(rc, err.Error(), rc.Attempts+1 >= s.maxAttempts); ferr != nil {
+            return ferr
+        }
+        return fmt.Errorf("sending receipt: %w", err)
+    }
+    return s.repo.MarkSent(rc, addr, time.Now())
+}
+
+// mail is the message sending the rendered rc to addr.
+func (s *ReceiptService) mail(rc *models.Receipt, addr string) Mail {
+    return Mail{
+        To:      addr,
+        Subject: fmt.Sprintf("Payment receipt %s from %s", rc.Number, s.cfg.BusinessName),
+        Text:    rc.Text,
+        Attachments: []Attachment{
+            {Name: rc.Number + ".pdf", ContentType: "application/pdf", Data: rc.PDF},
+        },
+    }
+}
+
+// render fills in and stores the PDF and text of rc once.
+func (s *ReceiptService) render(rc *models.Receipt) error {
+    if len(rc.PDF) > 0 {
+        return nil
+    }
+    rc.Text = receiptText(rc, s.cfg)
+    rc.PDF = renderReceipt(rc, s.cfg)
+    return s.repo.SaveDocument(rc)
+}
+
+// receiptLines are the label and value rows shown on a receipt.
+func receiptLines(rc *models.Receipt) [][2]string {
+    p := rc.FeePayment
+    lines := [][2]string{
+        {"Receipt no.", rc.Number},
+        {"Date", p.PaidOn.Format("2 Jan 2006")},
+    }
+    if rc.Student != nil {
+        lines = append(lines, [2]string{"Received from", strings.TrimSpace(rc.Student.FirstName + " " + rc.Student.LastName)})
+    }
+    if b := p.Enrollment.Batch; b.Name != "" {
+        lines = append(lines, [2]string{"Batch", b.Name})
+    }
+    if rc.Venue != nil {
+        lines = append(lines, [2]string{"Venue", rc.Venue.Name})
+    }
+    if p.Method != "" {
+        lines = append(lines, [2]string{"Payment method", p.Method})
+    }
+    if p.TransactionRef != "" {
+        lines = append(lines, [2]string{"Reference", p.TransactionRef})
+    }
+    if p.DiscountCents > 0 {
+        lines = append(lines, [2]string{"Discount", formatCents(p.DiscountCents)})
+    }
+    if p.TaxCents > 0 {
+        lines = append(lines, [2]string{fmt.Sprintf("Tax %s%% (included)", pdfNum(p.TaxPct)), formatCents(p.TaxCents)})
+    }
+    return append(lines, [2]string{"Amount received", p.Currency + " " + formatCents(p.AmountCents)})
+}
+
+// receiptText is the plain-text receipt sent as the body of the mail.
+func receiptText(rc *models.Receipt, cfg config.InvoiceConfig) string {
+    var b strings.Builder
+    fmt.Fprintf(&b, "%s\nPAYMENT RECEIPT\n\n", cfg.BusinessName)
+    lines := receiptLines(rc)
+    width := 0
+    for _, kv := range lines {
+        width = max(width, len(kv[0])+1)
+    }
+    for _, kv := range lines {
+        fmt.Fprintf(&b, "%-*s %s\n", width, kv[0]+":", kv[1])
+    }
+    b.WriteString("\nThank you for your payment. The receipt is attached as a PDF.\n")
+    if cfg.Address != "" {
+        fmt.Fprintf(&b, "\n%s\n", cfg.Address)
+    }
+    if cfg.TaxID != "" {
+        fmt.Fprintf(&b, "Tax ID: %s\n", cfg.TaxID)
+    }
+    return b.String()
+}
+
+// renderReceipt lays out rc on one A4 page in the style of the invoices.
+func renderReceipt(rc *models.Receipt, cfg config.InvoiceConfig) []byte {
+    const left, right = 40.0, pdfPageWidth - 40
+    white, black, gray := [3]float64{1, 1, 1}, [3]float64{0, 0, 0}, [3]float64{0.4, 0.4, 0.4}
+    d := newPDFDoc("Receipt " + rc.Number)
+
+    d.fillColor(hexColor(cfg.AccentColor))
+    d.rect(0, pdfPageHeight-70, pdfPageWidth, 70)
+    d.fillColor(white)
+    d.text(left, pdfPageHeight-45, 20, true, cfg.BusinessName)
+    d.textRight(right, pdfPageHeight-45, 16, true, "RECEIPT")
+
+    y := pdfPageHeight - 95
+    d.fillColor(gray)
+    for _, s := range strings.Split(cfg.Address, "\n") {
+        if s != "" {
+            d.text(left, y, 9, false, s)
+            y -= 12
+        }
+    }
+    if cfg.TaxID != "" {
+        d.text(left, y, 9, false, "Tax ID: "+cfg.TaxID)
+    }
+
+    y = pdfPageHeight - 170
+    lines := receiptLines(rc)
+    for i, kv := range lines {
+        bold := i == len(lines)-1
+        d.fillColor(gray)
+        d.text(left, y, 10, bold, kv[0])
+        d.fillColor(black)
+        d.textRight(right, y, 10, bold, kv[1])
+        y -= 8
+        d.line(left, y, right, y)
+        y -= 14
+    }
+
+    if cfg.Footer != "" {
+        d.fillColor(gray)
+        d.text(left, 40, 8, false, cfg.Footer)
+    }
+    return d.bytes()
+}
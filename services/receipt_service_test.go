@@ -0,0 +1,97 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"spodemy-backend/config"
+	"spodemy-backend/models"
+)
+
+func TestReceiptFileMail(t *testing.T) {
+    dir := t.TempDir()
+    cfg := config.InvoiceConfig{BusinessName: "Spodemy", AccentColor: "#0B5FFF"}
+    s := NewReceiptService(nil, NewFileMailer(dir, "billing@spodemy.test"), cfg, config.MailConfig{MaxAttempts: 5})
+    rc := &models.Receipt{
+        Number:  "RC-000042",
+        Student: &models.User{FirstName: "Asha", LastName: "Rao", Email: "asha@example.com"},
+        FeePayment: &models.FeePayment{
+            AmountCents: 118000,
+            Currency:    "INR",
+            PaidOn:      time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+            Method:      "upi",
+        },
+    }
+    rc.Text = receiptText(rc, cfg)
+    rc.PDF = renderReceipt(rc, cfg)
+    if err := s.mailer.Send(s.mail(rc, rc.Student.Email)); err != nil {
+        t.Fatal(err)
+    }
+
+    files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+    if err != nil || len(files) != 1 {
+        t.Fatalf("wrote %v (%v), want one .eml", files, err)
+    }
+    raw, err := os.ReadFile(files[0])
+    if err != nil {
+        t.Fatal(err)
+    }
+    msg, err := mail.ReadMessage(bytes.NewReader(raw))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := msg.Header.Get("Subject"); got != "Payment receipt RC-000042 from Spodemy" {
+        t.Errorf("subject = %q", got)
+    }
+    if got := msg.Header.Get("To"); got != "asha@example.com" {
+        t.Errorf("to = %q", got)
+    }
+    mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+    if err != nil || mediaType != "multipart/mixed" {
+        t.Fatalf("content type = %q (%v), want multipart/mixed", mediaType, err)
+    }
+
+    r := multipart.NewReader(msg.Body, params["boundary"])
+    text, err := r.NextPart()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if ct := text.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+        t.Errorf("first part is %q, want the text", ct)
+    }
+    body, _ := io.ReadAll(text)
+    if !strings.Contains(string(body), "RC-000042") || !strings.Contains(string(body), "INR 1,180.00") {
+        t.Errorf("text = %q, want the number and amount", body)
+    }
+
+    pdf, err := r.NextPart()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if ct := pdf.Header.Get("Content-Type"); ct != "application/pdf" {
+        t.Errorf("attachment is %q, want application/pdf", ct)
+    }
+    if pdf.FileName() != "RC-000042.pdf" {
+        t.Errorf("attachment name = %q", pdf.FileName())
+    }
+    enc, _ := io.ReadAll(pdf)
+    data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(enc), "\r\n", ""))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !bytes.Equal(data, rc.PDF) || !bytes.HasPrefix(data, []byte("%PDF-")) {
+        t.Errorf("attachment is not the rendered PDF (%d bytes, want %d)", len(data), len(rc.PDF))
+    }
+    if _, err := r.NextPart(); err != io.EOF {
+        t.Errorf("more parts after the PDF: %v", err)
+    }
+}
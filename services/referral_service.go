@@ -0,0 +1,173 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"spodemy-backend/config"
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Referral errors.
+var (
+    ErrReferralCode     = invalid("unknown referral code")
+    ErrSelfReferral     = invalid("students cannot refer themselves")
+    ErrReferralExisting = invalid("only students without a paid enrollment can be referred")
+    ErrAlreadyReferred  = conflict("student has already been referred")
+)
+
+// ReferralRequest records who referred a student.
+type ReferralRequest struct {
+    Code       string    `json:"code" binding:"required"`
+    ReferredID uuid.UUID `json:"referred_id" binding:"required"`
+}
+
+// Wallet is a user's credit balances, one per currency credited, with the
+// entries behind them.
+type Wallet struct {
+    Balances []repositories.WalletBalance `json:"balances"`
+    Entries  []models.WalletEntry         `json:"entries"`
+}
+
+// ReferralService encapsulates business logic for referrals.
+type ReferralService struct {
+    repo        *repositories.ReferralRepository
+    users       *repositories.UserRepository
+    enrollments *repositories.EnrollmentRepository
+    cfg         config.ReferralConfig
+    currency    string
+}
+
+// NewReferralService creates a new ReferralService. Rewards are paid in the
+// base currency of currency.
+func NewReferralService(r *repositories.ReferralRepository, users *repositories.UserRepository, enrollments *repositories.EnrollmentRepository, cfg config.ReferralConfig, currency config.CurrencyConfig) *ReferralService {
+    return &ReferralService{repo: r, users: users, enrollments: enrollments, cfg: cfg, currency: currency.Base}
+}
+
+// List returns referrals, optionally only those of one referrer.
+func (s *ReferralService) List(referrerID *uuid.UUID) ([]models.Referral, error) {
+    return s.repo.FindAll(referrerID)
+}
+
+// Record stores that the owner of code referred a student. Referrals that
+// look like the same person on both sides are kept but rejected, so that
+// they never earn a reward.
+func (s *ReferralService) Record(req ReferralRequest) (*models.Referral, error) {
+    referrer, err := s.FindReferrer(req.Code)
+    if err != nil {
+        return nil, err
+    }
+    if referrer.ID == req.ReferredID {
+        return nil, ErrSelfReferral
+    }
+    referred, err := s.users.FindByID(req.ReferredID)
+    if err != nil {
+        return nil, err
+    }
+    referredBefore, err := s.repo.IsReferred(referred.ID)
+    if err != nil {
+        return nil, err
+    }
+    if referredBefore {
+        return nil, ErrAlreadyReferred
+    }
+    enrolled, err := s.enrollments.CountRegularByStudent(referred.ID, uuid.Nil)
+    if err != nil {
+        return nil, err
+    }
+    if enrolled > 0 {
+        return nil, ErrReferralExisting
+    }
+    ref := s.newReferral(referrer, referred)
+    if err := s.repo.Create(ref); err != nil {
+        return nil, err
+    }
+    return s.repo.FindByID(ref.ID)
+}
+
+// FindReferrer returns the user owning a referral code, which is matched
+// regardless of case and surrounding spaces.
+func (s *ReferralService) FindReferrer(code string) (*models.User, error) {
+    referrer, err := s.repo.FindReferrer(strings.ToUpper(strings.TrimSpace(code)))
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, ErrReferralCode
+    }
+    return referrer, err
+}
+
+// newReferral is the referral of referred by referrer, rejected if they look
+// like the same person.
+func (s *ReferralService) newReferral(referrer, referred *models.User) *models.Referral {
+    ref := &models.Referral{
+        ReferrerID:  referrer.ID,
+        ReferredID:  referred.ID,
+        Code:        referrer.ReferralCode,
+        Status:      models.ReferralPending,
+        RewardType:  s.cfg.RewardType,
+        RewardCents: s.cfg.RewardCents,
+        Currency:    s.currency,
+    }
+    if reason := s.abuseReason(referrer, referred); reason != "" {
+        ref.Status = models.ReferralRejected
+        ref.RejectReason = reason
+    }
+    return ref
+}
+
+// Wallet returns a user's credit balances and entries.
+func (s *ReferralService) Wallet(userID uuid.UUID) (*Wallet, error) {
+    if _, err := s.users.FindByID(userID); err != nil {
+        return nil, err
+    }
+    balances, err := s.repo.Balances(userID)
+    if err != nil {
+        return nil, err
+    }
+    entries, err := s.repo.FindWallet(userID)
+    if err != nil {
+        return nil, err
+    }
+    return &Wallet{Balances: balances, Entries: entries}, nil
+}
+
+// abuseReason says why a referral looks like one person referring themselves
+// under another account, or returns "" if it does not.
+func (s *ReferralService) abuseReason(referrer, referred *models.User) string {
+    if d := emailDomain(referrer.Email); d != "" && d == emailDomain(referred.Email) &&
+        !slices.Contains(s.cfg.PublicEmailDomains, d) {
+        return "same email domain as referrer"
+    }
+    if p := phoneDigits(referrer.Phone); p != "" && p == phoneDigits(referred.Phone) {
+        return "same phone as referrer"
+    }
+    return ""
+}
+
+func emailDomain(email string) string {
+    _, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+    if !ok {
+        return ""
+    }
+    return domain
+}
+
+// phoneDigits keeps the last 10 digits of a phone number, so that the same
+// number with and without a country code compares equal.
+func phoneDigits(phone string) string {
+    var b strings.Builder
+    for _, r := range phone {
+        if r >= '0' && r <= '9' {
+            b.WriteRune(r)
+        }
+    }
+    d := b.String()
+    if len(d) > 10 {
+        d = d[len(d)-10:]
+    }
+    return d
+}
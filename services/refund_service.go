@@ -0,0 +1,136 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Refund errors.
+var (
+    ErrRefundReason   = invalid("reason is required")
+    ErrRefundApprover = invalid("approved_by_id is required")
+    ErrRefundAmount   = invalid("amount_cents must not be negative")
+)
+
+// RefundService records refunds, returning online payments through the
+// gateway they were taken with.
+type RefundService struct {
+    repo    *repositories.RefundRepository
+    orders  *repositories.CheckoutRepository
+    gateway PaymentGateway
+}
+
+// NewRefundService creates a new RefundService.
+func NewRefundService(r *repositories.RefundRepository, orders *repositories.CheckoutRepository, gateway PaymentGateway) *RefundService {
+    return &RefundService{repo: r, orders: orders, gateway: gateway}
+}
+
+// List returns the refunds matching f.
+func (s *RefundService) List(f repositories.RefundFilter) ([]models.Refund, error) {
+    return s.repo.FindAll(f)
+}
+
+// Get retrieves a single refund. A refund still pending at the gateway is
+// checked with it first, in case its webhook was missed.
+func (s *RefundService) Get(id uuid.UUID) (*models.Refund, error) {
+    rf, err := s.repo.FindByID(id)
+    if err != nil || rf.Status != models.RefundPending || rf.Method != s.gateway.Name() || rf.GatewayRef == "" {
+        return rf, err
+    }
+    gr, err := s.gateway.FetchRefund(rf.GatewayRef)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.settle(rf.ID, gr); err != nil {
+        return nil, err
+    }
+    return s.repo.FindByID(id)
+}
+
+// Create refunds a payment, by default whatever of it is not yet refunded.
+// A payment taken online is refunded through its gateway: the refund is
+// recorded as pending first so its amount is held against the payment, and
+// stays pending until the gateway reports it processed, when it is posted to
+// the ledger, or failed, when the amount is given back. Other refunds are
+// paid out by hand and recorded as processed.
+func (s *RefundService) Create(rf *models.Refund) error {
+    rf.Reason = strings.TrimSpace(rf.Reason)
+    switch {
+    case rf.Reason == "":
+        return ErrRefundReason
+    case rf.ApprovedByID == uuid.Nil:
+        return ErrRefundApprover
+    case rf.AmountCents < 0:
+        return ErrRefundAmount
+    }
+    if rf.RefundedOn.IsZero() {
+        rf.RefundedOn = time.Now()
+    }
+    o, err := s.orders.FindByFeePayment(rf.FeePaymentID)
+    if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+        return err
+    }
+    online := err == nil
+    if online && o.Provider != s.gateway.Name() {
+        return conflict(fmt.Sprintf("payment was taken through %s; refund it there", o.Provider))
+    }
+    rf.Method, rf.Status = "manual", models.RefundProcessed
+    if online {
+        rf.Method, rf.Status = o.Provider, models.RefundPending
+    }
+    if err := s.repo.Create(rf); err != nil {
+        return fromRepo(err)
+    }
+    if !online {
+        return nil
+    }
+
+    gr, err := s.gateway.Refund(o.PaymentRef, rf.AmountCents)
+    if err != nil {
+        if ferr := s.repo.Fail(rf.ID, err.Error()); ferr != nil {
+            return ferr
+        }
+        return fmt.Errorf("gateway refused the refund: %w", err)
+    }
+    rf.GatewayRef = gr.Ref
+    if gr.Status == models.RefundProcessed || gr.Status == models.RefundFailed {
+        rf.Status = gr.Status
+    }
+    return s.settle(rf.ID, gr)
+}
+
+// Settle applies the state of a refund the gateway reports in a webhook.
+// Refunds not made here are ignored.
+func (s *RefundService) Settle(gr *GatewayRefund) error {
+    rf, err := s.repo.FindByGatewayRef(s.gateway.Name(), gr.Ref)
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    return s.settle(rf.ID, gr)
+}
+
+// settle records the gateway's reference for refund id and moves it on to
+// processed or failed once the gateway says so.
+func (s *RefundService) settle(id uuid.UUID, gr *GatewayRefund) error {
+    switch gr.Status {
+    case models.RefundProcessed:
+        return s.repo.Complete(id, gr.Ref, models.RefundProcessed)
+    case models.RefundFailed:
+        if err := s.repo.Complete(id, gr.Ref, models.RefundPending); err != nil {
+            return err
+        }
+        return s.repo.Fail(id, "refund failed at the gateway")
+    }
+    return s.repo.Complete(id, gr.Ref, models.RefundPending)
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"time"
+
+	"spodemy-backend/repositories"
+)
+
+// ErrReportPeriod is returned when a report's period is empty or inverted.
+var ErrReportPeriod = invalid("report period must end after it starts")
+
+// TrialConversion is one row of the trial conversion report.
+type TrialConversion struct {
+    repositories.TrialConversionRow
+    ConversionRate float64 `json:"conversion_rate"` // converted / trials, 0..1
+}
+
+// ReportService builds management reports.
+type ReportService struct {
+    repo       *repositories.ReportRepository
+    currencies *CurrencyService
+}
+
+// NewReportService creates a new ReportService.
+func NewReportService(r *repositories.ReportRepository, currencies *CurrencyService) *ReportService {
+    return &ReportService{repo: r, currencies: currencies}
+}
+
+// TrialConversions reports trial-to-paid conversion by venue, sport and coach
+// for trials started in [from, to).
+func (s *ReportService) TrialConversions(from, to time.Time) ([]TrialConversion, error) {
+    if !to.After(from) {
+        return nil, ErrReportPeriod
+    }
+    rows, err := s.repo.TrialConversions(from, to)
+    if err != nil {
+        return nil, err
+    }
+    out := make([]TrialConversion, len(rows))
+    for i, row := range rows {
+        out[i] = TrialConversion{TrialConversionRow: row}
+        if row.Trials > 0 {
+            out[i].ConversionRate = float64(row.Converted) / float64(row.Trials)
+        }
+    }
+    return out, nil
+}
+
+// ReferralLeaderboard ranks referrers by referrals made in [from, to).
+func (s *ReportService) ReferralLeaderboard(from, to time.Time) ([]repositories.ReferralLeaderboardRow, error) {
+    if !to.After(from) {
+        return nil, ErrReportPeriod
+    }
+    return s.repo.ReferralLeaderboard(from, to)
+}
+
+// GSTSummary is a month of tax collected, by venue, currency and tax rate.
+// Rows are in their own currency; the totals are in the reporting currency,
+// converted at the rates of the month's last day.
+type GSTSummary struct {
+    Month        string          `json:"month"`    // YYYY-MM
+    Currency     string          `json:"currency"` // reporting currency
+    RateDate     time.Time       `json:"rate_date"`
+    Rows         []GSTSummaryRow `json:"rows"`
+    AmountCents  int64           `json:"amount_cents"`
+    TaxableCents int64           `json:"taxable_cents"`
+    TaxCents     int64           `json:"tax_cents"`
+}
+
+// GSTSummaryRow is a row of the GST summary with its amounts converted to
+// the reporting currency.
+type GSTSummaryRow struct {
+    repositories.GSTRow
+    Reporting ConvertedAmounts `json:"reporting"`
+}
+
+// ConvertedAmounts are a row's amounts in the reporting currency.
+type ConvertedAmounts struct {
+    AmountCents  int64 `json:"amount_cents"`
+    TaxableCents int64 `json:"taxable_cents"`
+    TaxCents     int64 `json:"tax_cents"`
+}
+
+// GSTSummary totals the tax in payments received in the month of month, in
+// the reporting currency, or the base currency if it is empty.
+func (s *ReportService) GSTSummary(month time.Time, currency string) (*GSTSummary, error) {
+    currency, err := s.currencies.Reporting(currency)
+    if err != nil {
+        return nil, err
+    }
+    from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+    to := from.AddDate(0, 1, 0)
+    rows, err := s.repo.GSTSummary(from, to)
+    if err != nil {
+        return nil, err
+    }
+    sum := &GSTSummary{Month: from.Format("2006-01"), Currency: currency, RateDate: to.AddDate(0, 0, -1),
+        Rows: make([]GSTSummaryRow, len(rows))}
+    for i, row := range rows {
+        rate, err := s.currencies.Rate(row.Currency, currency, sum.RateDate)
+        if err != nil {
+            return nil, err
+        }
+        r := ConvertedAmounts{
+            AmountCents:  convertCents(row.AmountCents, rate),
+            TaxableCents: convertCents(row.TaxableCents, rate),
+            TaxCents:     convertCents(row.TaxCents, rate),
+        }
+        sum.Rows[i] = GSTSummaryRow{GSTRow: row, Reporting: r}
+        sum.AmountCents += r.AmountCents
+        sum.TaxableCents += r.TaxableCents
+        sum.TaxCents += r.TaxCents
+    }
+    return sum, nil
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"time"
+
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+
+	"github.com/google/uuid"
+)
+
+// Rollover errors.
+var (
+    ErrRolloverShift      = invalid("shift_days must be positive")
+    ErrRolloverWrongVenue = invalid("batch does not belong to this venue")
+)
+
+// RolloverRequest selects the batches to clone into the next term.
+type RolloverRequest struct {
+    BatchIDs  []uuid.UUID `json:"batch_ids"` // empty means every batch of the term not yet rolled over
+    TermDate  *time.Time  `json:"term_date"` // a day of the term rolled over with empty batch_ids; defaults to today
+    ShiftDays int         `json:"shift_days" binding:"required"`
+    DryRun    bool        `json:"dry_run"`
+}
+
+// RolloverPlan lists what a rollover creates. In a dry run the IDs are
+// provisional and nothing is saved.
+type RolloverPlan struct {
+    DryRun      bool                `json:"dry_run"`
+    Batches     []models.Batch      `json:"batches"`
+    Enrollments []models.Enrollment `json:"enrollments"`
+}
+
+// RolloverService clones a venue's batches into the next term.
+type RolloverService struct {
+    batches     *repositories.BatchRepository
+    enrollments *repositories.EnrollmentRepository
+    pricing     *PricingService
+}
+
+// NewRolloverService creates a new RolloverService.
+func NewRolloverService(batches *repositories.BatchRepository, enrollments *repositories.EnrollmentRepository, pricing *PricingService) *RolloverService {
+    return &RolloverService{batches: batches, enrollments: enrollments, pricing: pricing}
+}
+
+// Rollover clones the selected batches with dates shifted by req.ShiftDays,
+// keeping coach and weekly schedule, and creates enrollments pending payment
+// on the current version of their plan for active students who opted in to
+// continue.
+func (s *RolloverService) Rollover(venueID uuid.UUID, req RolloverRequest) (*RolloverPlan, error) {
+    if req.ShiftDays <= 0 {
+        return nil, ErrRolloverShift
+    }
+    termDate := time.Now()
+    if req.TermDate != nil {
+        termDate = *req.TermDate
+    }
+    sources, err := s.sourceBatches(venueID, req.BatchIDs, dateOnly(termDate))
+    if err != nil {
+        return nil, err
+    }
+    plan := &RolloverPlan{DryRun: req.DryRun, Batches: []models.Batch{}, Enrollments: []models.Enrollment{}}
+    if len(sources) == 0 {
+        return plan, nil
+    }
+
+    ids := make([]uuid.UUID, len(sources))
+    clones := make(map[uuid.UUID]*models.Batch, len(sources))
+    plan.Batches = make([]models.Batch, len(sources))
+    for i, src := range sources {
+        ids[i] = src.ID
+        plan.Batches[i] = cloneBatch(src, req.ShiftDays)
+        clones[src.ID] = &plan.Batches[i]
+    }
+
+    optedIn, err := s.enrollments.FindOptedIn(ids)
+    if err != nil {
+        return nil, err
+    }
+    for _, e := range optedIn {
+        next := clones[e.BatchID]
+        var price PriceBreakdown
+        var version *uuid.UUID
+        if e.Plan != nil {
+            version = e.Plan.CurrentVersionID
+            ctx, err := s.pricing.offerContext(e.Plan.DurationDays, nil, e.StudentID, next.VenueID, next, next.StartDate, uuid.Nil)
+            if err != nil {
+                return nil, err
+            }
+            if price, err = s.pricing.priceFor(e.Plan, nil, ctx); err != nil {
+                return nil, err
+            }
+        }
+        plan.Enrollments = append(plan.Enrollments, models.Enrollment{
+            ID:                   uuid.New(),
+            StudentID:            e.StudentID,
+            Student:              e.Student,
+            BatchID:              next.ID,
+            EnrolledOn:           next.StartDate,
+            Status:               models.EnrollmentPendingPayment,
+            Type:                 models.EnrollmentRegular,
+            PlanID:               e.PlanID,
+            PlanVersionID:        version,
+            ValidUntil:           planValidUntil(next.StartDate, e.Plan),
+            PriceCents:           price.TotalCents,
+            SiblingDiscountCents: price.SiblingDiscountCents,
+            TaxBreakdown:         price.taxBreakdown(),
+        })
+    }
+
+    if req.DryRun {
+        return plan, nil
+    }
+    if err := s.batches.CreateRollover(plan.Batches, plan.Enrollments); err != nil {
+        return nil, fromRepo(err)
+    }
+    return plan, nil
+}
+
+// sourceBatches resolves the batches to roll over and rejects ones that were
+// already cloned. Without batchIDs they are the venue's batches running on
+// termDate, so batches of earlier terms are left alone.
+func (s *RolloverService) sourceBatches(venueID uuid.UUID, batchIDs []uuid.UUID, termDate time.Time) ([]models.Batch, error) {
+    var sources []models.Batch
+    if len(batchIDs) == 0 {
+        all, err := s.batches.FindRunningOn(venueID, termDate)
+        if err != nil {
+            return nil, err
+        }
+        sources = all
+    } else {
+        for _, id := range batchIDs {
+            b, err := s.batches.FindByID(id)
+            if err != nil {
+                return nil, err
+            }
+            if b.VenueID != venueID {
+                return nil, ErrRolloverWrongVenue
+            }
+            sources = append(sources, *b)
+        }
+    }
+    if len(sources) == 0 {
+        return nil, nil
+    }
+
+    ids := make([]uuid.UUID, len(sources))
+    for i, b := range sources {
+        ids[i] = b.ID
+    }
+    rolled, err := s.batches.FindRolledFrom(ids)
+    if err != nil {
+        return nil, err
+    }
+    done := make(map[uuid.UUID]bool, len(rolled))
+    for _, b := range rolled {
+        done[*b.RolledFromID] = true
+    }
+    pending := sources[:0]
+    for _, b := range sources {
+        if !done[b.ID] {
+            pending = append(pending, b)
+        } else if len(batchIDs) > 0 {
+            return nil, ErrRolloverAlreadyDone
+        }
+    }
+    return pending, nil
+}
+
+// cloneBatch copies a batch into the next term with a fresh ID.
+func cloneBatch(src models.Batch, shiftDays int) models.Batch {
+    srcID := src.ID
+    next := models.Batch{
+        ID:           uuid.New(),
+        VenueID:      src.VenueID,
+        Name:         src.Name,
+        Sport:        src.Sport,
+        Capacity:     src.Capacity,
+        CoachID:      src.CoachID,
+        StartDate:    src.StartDate.AddDate(0, 0, shiftDays),
+        EndDate:      src.EndDate.AddDate(0, 0, shiftDays),
+        RolledFromID: &srcID,
+    }
+    for _, sch := range src.Schedules {
+        next.Schedules = append(next.Schedules, models.BatchSchedule{
+            BatchID:   next.ID,
+            Weekday:   sch.Weekday,
+            StartTime: sch.StartTime,
+            EndTime:   sch.EndTime,
+        })
+    }
+    return next
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"spodemy-backend/config"
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+
+	"github.com/google/uuid"
+)
+
+// Subscription errors.
+var (
+    ErrNotSubscription   = invalid("enrollment does not renew automatically")
+    ErrSubscriptionEnded = conflict("subscription has already ended")
+    ErrChargeStatus      = invalid("status must be empty, \"due\", \"paid\", \"overdue\" or \"void\"")
+)
+
+// SubscriptionService renews enrollments on billed plans at the end of each
+// cycle and tracks the charges this creates.
+type SubscriptionService struct {
+    repo        *repositories.SubscriptionRepository
+    enrollments *repositories.EnrollmentRepository
+    pricing     *PricingService
+    cfg         config.SubscriptionConfig
+}
+
+// NewSubscriptionService creates a new SubscriptionService.
+func NewSubscriptionService(r *repositories.SubscriptionRepository, enrollments *repositories.EnrollmentRepository, pricing *PricingService, cfg config.SubscriptionConfig) *SubscriptionService {
+    return &SubscriptionService{repo: r, enrollments: enrollments, pricing: pricing, cfg: cfg}
+}
+
+// RenewDue starts the next cycle of every renewing enrollment whose current
+// cycle has ended by now, creating a charge due on its first day. Renewals
+// are charged at the price of the plan version the enrollment bought, less
+// any sibling discount, plus tax; sign-up offers and coupons only cover the
+// first cycle. Enrollments whose plan terms are not billed are set to end
+// with the current cycle. A failed renewal does not stop the others; all
+// failures are returned together.
+func (s *SubscriptionService) RenewDue(now time.Time) (int, error) {
+    ens, err := s.repo.FindDueRenewals(dateOnly(now))
+    if err != nil {
+        return 0, err
+    }
+    n := 0
+    var errs []error
+    for i := range ens {
+        ok, err := s.renew(&ens[i])
+        if err != nil {
+            errs = append(errs, fmt.Errorf("enrollment %s: %w", ens[i].ID, err))
+        } else if ok {
+            n++
+        }
+    }
+    return n, errors.Join(errs...)
+}
+
+// MarkOverdue turns charges unpaid past the grace period overdue and pauses
+// their enrollments until they are paid.
+func (s *SubscriptionService) MarkOverdue(now time.Time) (int64, error) {
+    return s.repo.MarkOverdue(dateOnly(now).AddDate(0, 0, -s.cfg.GraceDays))
+}
+
+// Cancel stops an enrollment from renewing. It stays in its status until the
+// current cycle ends and is then completed.
+func (s *SubscriptionService) Cancel(id uuid.UUID) (*models.Enrollment, error) {
+    e, err := s.enrollments.FindByID(id)
+    if err != nil {
+        return nil, err
+    }
+    if !e.AutoRenew {
+        return nil, ErrNotSubscription
+    }
+    if e.CancelAtPeriodEnd {
+        return e, nil
+    }
+    now := time.Now()
+    if err := s.repo.SetCancelAtPeriodEnd(e.ID, true, &now); err != nil {
+        return nil, err
+    }
+    e.CancelAtPeriodEnd = true
+    e.CanceledAt = &now
+    return e, nil
+}
+
+// Reinstate undoes Cancel while the current cycle is still running.
+func (s *SubscriptionService) Reinstate(id uuid.UUID) (*models.Enrollment, error) {
+    e, err := s.enrollments.FindByID(id)
+    if err != nil {
+        return nil, err
+    }
+    if !e.AutoRenew {
+        return nil, ErrNotSubscription
+    }
+    if e.Status == models.EnrollmentCompleted || e.Status == models.EnrollmentDropped {
+        return nil, ErrSubscriptionEnded
+    }
+    if !e.CancelAtPeriodEnd {
+        return e, nil
+    }
+    if err := s.repo.SetCancelAtPeriodEnd(e.ID, false, nil); err != nil {
+        return nil, err
+    }
+    e.CancelAtPeriodEnd = false
+    e.CanceledAt = nil
+    return e, nil
+}
+
+// Charges returns an enrollment's charges, newest cycle first.
+func (s *SubscriptionService) Charges(enrID uuid.UUID) ([]models.Charge, error) {
+    if _, err := s.enrollments.FindByID(enrID); err != nil {
+        return nil, err
+    }
+    return s.repo.FindChargesByEnrollment(enrID)
+}
+
+// ListCharges returns charges in a status, or all of them, oldest due first.
+func (s *SubscriptionService) ListCharges(status string) ([]models.Charge, error) {
+    switch status {
+    case "", models.ChargeDue, models.ChargePaid, models.ChargeOverdue, models.ChargeVoid:
+    default:
+        return nil, ErrChargeStatus
+    }
+    return s.repo.FindChargesByStatus(status)
+}
+
+// GetCharge retrieves a single charge.
+func (s *SubscriptionService) GetCharge(id uuid.UUID) (*models.Charge, error) {
+    return s.repo.FindCharge(id)
+}
+
+// renew prices and records the next cycle of e on the plan version it bought.
+func (s *SubscriptionService) renew(e *models.Enrollment) (bool, error) {
+    plan := pinnedPlan(e.Plan, e.PlanVersion)
+    if plan == nil || plan.BillingInterval == "" || !plan.AutoRenew {
+        now := time.Now()
+        return false, s.repo.SetCancelAtPeriodEnd(e.ID, true, &now)
+    }
+    start := *e.ValidUntil
+    ctx, err := s.pricing.offerContext(plan.DurationDays, nil, e.StudentID, e.Batch.VenueID, &e.Batch, start, e.ID)
+    if err != nil {
+        return false, err
+    }
+    price, err := s.pricing.priceFor(plan, nil, ctx)
+    if err != nil {
+        return false, err
+    }
+    return s.repo.Renew(&models.Charge{
+        EnrollmentID: e.ID,
+        PeriodStart:  start,
+        PeriodEnd:    addInterval(start, plan.BillingInterval),
+        AmountCents:  price.TotalCents,
+        TaxBreakdown: price.taxBreakdown(),
+        DueOn:        start,
+        Status:       models.ChargeDue,
+    })
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"strings"
+
+	"spodemy-backend/models"
+	"spodemy-backend/repositories"
+
+	"github.com/google/uuid"
+)
+
+// Tax rate errors.
+var (
+    ErrTaxCode    = invalid("code is required")
+    ErrTaxPct     = invalid("pct must be between 0 and 100")
+    ErrTaxUnknown = invalid("tax_code does not match a tax rate")
+    ErrTaxInUse   = conflict("tax rate is used by plans")
+)
+
+// TaxService encapsulates business logic for tax rates.
+type TaxService struct {
+    repo *repositories.TaxRepository
+}
+
+// NewTaxService creates a new TaxService.
+func NewTaxService(repo *repositories.TaxRepository) *TaxService {
+    return &TaxService{repo: repo}
+}
+
+// List returns all tax rates.
+func (s *TaxService) List() ([]models.TaxRate, error) {
+    return s.repo.FindAll()
+}
+
+// Get retrieves a single tax rate.
+func (s *TaxService) Get(id uuid.UUID) (*models.TaxRate, error) {
+    return s.repo.FindByID(id)
+}
+
+// Create adds a new tax rate.
+func (s *TaxService) Create(t *models.TaxRate) error {
+    t.Code = strings.ToLower(strings.TrimSpace(t.Code))
+    if t.Code == "" {
+        return ErrTaxCode
+    }
+    if err := validateTaxRate(t); err != nil {
+        return err
+    }
+    return s.repo.Create(t)
+}
+
+// Update changes a tax rate's name or percentage. Amounts already priced
+// keep the rate they were priced with.
+func (s *TaxService) Update(t *models.TaxRate) error {
+    cur, err := s.repo.FindByID(t.ID)
+    if err != nil {
+        return err
+    }
+    if err := validateTaxRate(t); err != nil {
+        return err
+    }
+    t.Code = cur.Code
+    t.CreatedAt = cur.CreatedAt
+    return s.repo.Update(t)
+}
+
+// Delete removes a tax rate no plan is priced with.
+func (s *TaxService) Delete(id uuid.UUID) error {
+    t, err := s.repo.FindByID(id)
+    if err != nil {
+        return err
+    }
+    n, err := s.repo.CountPlans(t.Code)
+    if err != nil {
+        return err
+    }
+    if n > 0 {
+        return ErrTaxInUse
+    }
+    return s.repo.Delete(id)
+}
+
+func validateTaxRate(t *models.TaxRate) error {
+    if t.Pct < 0 || t.Pct > 100 {
+        return ErrTaxPct
+    }
+    return nil
+}
@@ -9,12 +9,13 @@ import (
 
 // UserService encapsulates business logic for users.
 type UserService struct {
-    repo *repositories.UserRepository
+    repo      *repositories.UserRepository
+    referrals *ReferralService
 }
 
 // NewUserService creates a new UserService.
-func NewUserService(r *repositories.UserRepository) *UserService {
-    return &UserService{repo: r}
+func NewUserService(r *repositories.UserRepository, referrals *ReferralService) *UserService {
+    return &UserService{repo: r, referrals: referrals}
 }
 
 // List returns all users.
@@ -27,9 +28,17 @@ func (s *UserService) Get(id uuid.UUID) (*models.User, error) {
     return s.repo.FindByID(id)
 }
 
-// Create adds a new user.
+// Create adds a new user, recording who referred them if a referral code
+// was given. The user is not created if the referral cannot be recorded.
 func (s *UserService) Create(u *models.User) error {
-    return s.repo.Create(u)
+    if u.ReferredBy == "" {
+        return s.repo.Create(u, nil)
+    }
+    referrer, err := s.referrals.FindReferrer(u.ReferredBy)
+    if err != nil {
+        return err
+    }
+    return s.repo.Create(u, s.referrals.newReferral(referrer, u))
 }
 
 // Update modifies a user.
@@ -1,21 +1,28 @@
 package services
 
 import (
+	"spodemy-backend/config"
 	"spodemy-backend/models"
 	"spodemy-backend/repositories"
 
 	"github.com/google/uuid"
 )
 
+// ErrVenueCurrencyInUse is returned when changing the currency of a venue
+// that already has money recorded in it.
+var ErrVenueCurrencyInUse = conflict("venue currency cannot change once enrollments, investments, expenses or ledger entries are recorded at it")
+
 //VenueService encapsulates business logic for venues.
 
 type VenueService struct {
 	repo *repositories.VenueRepository
+	base string
 }
 
-// NewVenueService creates a new VenueService.
-func NewVenueService(r *repositories.VenueRepository) *VenueService {
-    return &VenueService{repo: r}
+// NewVenueService creates a new VenueService. Venues default to the base
+// currency of cfg.
+func NewVenueService(r *repositories.VenueRepository, cfg config.CurrencyConfig) *VenueService {
+    return &VenueService{repo: r, base: cfg.Base}
 }
 
 // List returns all venues.
@@ -30,11 +37,32 @@ func (s *VenueService) GetAVenue(id uuid.UUID) (*models.Venue, error) {
 
 // Create adds a new venue.
 func (s *VenueService) CreateVenue(v *models.Venue) error {
+    var err error
+    if v.Currency, err = normalizeCurrency(v.Currency, s.base); err != nil {
+        return err
+    }
     return s.repo.Create(v)
 }
 
-// Update modifies a venue.
+// Update modifies a venue. Its currency can only change while nothing has
+// been recorded in it.
 func (s *VenueService) UpdateVenue(v *models.Venue) error {
+    old, err := s.repo.FindByID(v.ID)
+    if err != nil {
+        return err
+    }
+    if v.Currency, err = normalizeCurrency(v.Currency, old.Currency); err != nil {
+        return err
+    }
+    if v.Currency != old.Currency {
+        used, err := s.repo.HasMoneyRecords(v.ID)
+        if err != nil {
+            return err
+        }
+        if used {
+            return ErrVenueCurrencyInUse
+        }
+    }
     return s.repo.Update(v)
 }
 